@@ -0,0 +1,137 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultAmiCacheTTL is how long a resolved AMI is reused when no cache period is configured.
+const defaultAmiCacheTTL = time.Hour
+
+// amiCacheKey identifies the source an AMI was resolved from, and the account it was resolved with, since the
+// same parameter or filter can resolve to different AMIs in different accounts.
+type amiCacheKey struct {
+	secret       string
+	namespace    string
+	roleArn      string
+	region       string
+	ssmParameter string
+	owner        string
+	name         string
+}
+
+// amiCacheEntry is a resolved AMI and the time it must be resolved again.
+type amiCacheEntry struct {
+	ami     string
+	expires time.Time
+}
+
+var (
+	// amiCache holds the resolved AMIs, so that SSM and EC2 are not queried for every launch.
+	amiCache   = map[amiCacheKey]amiCacheEntry{}
+	amiCacheMu sync.Mutex
+)
+
+// resolveAmi returns the AMI instances are launched from. A fixed Ami is returned as is. Otherwise, the AMI is read
+// from the AmiSsmParameter SSM parameter, or is the newest available AMI of AmiOwner matching AmiName, and is
+// cached for AmiCacheTTL. An empty AMI is returned when no AMI is configured, leaving it to the launch template.
+func (ec AWSEc2DynamicConfig) resolveAmi(kubeClient client.Client, ctx context.Context, ec2Client ec2API) (string, error) {
+	if ec.Ami != "" || (ec.AmiSsmParameter == "" && ec.AmiName == "") {
+		return ec.Ami, nil
+	}
+	log := logr.FromContextOrDiscard(ctx)
+
+	key := amiCacheKey{
+		secret:       ec.Secret,
+		namespace:    ec.SystemNamespace,
+		roleArn:      ec.RoleArn,
+		region:       ec.Region,
+		ssmParameter: ec.AmiSsmParameter,
+		owner:        ec.AmiOwner,
+		name:         ec.AmiName,
+	}
+	amiCacheMu.Lock()
+	entry, ok := amiCache[key]
+	amiCacheMu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.ami, nil
+	}
+
+	var ami string
+	var err error
+	if ec.AmiSsmParameter != "" {
+		ami, err = ec.resolveAmiFromSsmParameter(kubeClient, ctx)
+	} else {
+		ami, err = resolveAmiFromName(ctx, ec2Client, ec.AmiOwner, ec.AmiName)
+	}
+	if err != nil {
+		return "", err
+	}
+	log.Info("Resolved AWS AMI", "ami", ami, "ssmParameter", ec.AmiSsmParameter, "owner", ec.AmiOwner, "name", ec.AmiName)
+
+	ttl := ec.AmiCacheTTL
+	if ttl <= 0 {
+		ttl = defaultAmiCacheTTL
+	}
+	amiCacheMu.Lock()
+	amiCache[key] = amiCacheEntry{ami: ami, expires: time.Now().Add(ttl)}
+	amiCacheMu.Unlock()
+	return ami, nil
+}
+
+// resolveAmiFromSsmParameter returns the AMI ID held by the AmiSsmParameter SSM parameter.
+func (ec AWSEc2DynamicConfig) resolveAmiFromSsmParameter(kubeClient client.Client, ctx context.Context) (string, error) {
+	ssmClient, err := ec.getSSMClient(kubeClient, ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create an SSM client: %w", err)
+	}
+	output, err := ssmClient.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(ec.AmiSsmParameter)})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the SSM parameter %s: %w", ec.AmiSsmParameter, err)
+	}
+	if output.Parameter == nil || aws.ToString(output.Parameter.Value) == "" {
+		return "", fmt.Errorf("the SSM parameter %s has no value", ec.AmiSsmParameter)
+	}
+	return aws.ToString(output.Parameter.Value), nil
+}
+
+// resolveAmiFromName returns the ID of the most recently created available AMI owned by owner whose name
+// matches name.
+func resolveAmiFromName(ctx context.Context, ec2Client ec2API, owner string, name string) (string, error) {
+	if owner == "" {
+		return "", errors.New("an AMI owner is required to resolve an AMI by name")
+	}
+	output, err := ec2Client.DescribeImages(ctx, &ec2.DescribeImagesInput{
+		Owners: []string{owner},
+		Filters: []types.Filter{
+			{Name: aws.String("name"), Values: []string{name}},
+			{Name: aws.String("state"), Values: []string{string(types.ImageStateAvailable)}},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve the AMIs of %s named %s: %w", owner, name, err)
+	}
+
+	var newest *types.Image
+	for i := range output.Images {
+		image := &output.Images[i]
+		// Creation dates are in ISO 8601 format, so they sort lexicographically
+		if newest == nil || aws.ToString(image.CreationDate) > aws.ToString(newest.CreationDate) {
+			newest = image
+		}
+	}
+	if newest == nil {
+		return "", fmt.Errorf("no available AMI of %s is named %s", owner, name)
+	}
+	return aws.ToString(newest.ImageId), nil
+}
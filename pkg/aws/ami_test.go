@@ -0,0 +1,149 @@
+package aws
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("resolveAmi", func() {
+	const ssmParameter = "/aws/service/ami-amazon-linux-latest/al2023-ami-kernel-default-arm64"
+
+	var (
+		ec2Mock *mockEC2Client
+		ssmMock *mockSSMClient
+		cfg     AWSEc2DynamicConfig
+	)
+
+	BeforeEach(func() {
+		amiCacheMu.Lock()
+		amiCache = map[amiCacheKey]amiCacheEntry{}
+		amiCacheMu.Unlock()
+
+		ec2Mock = &mockEC2Client{}
+		ssmMock = &mockSSMClient{Parameters: map[string]string{ssmParameter: "ami-0ssm"}}
+		cfg = AWSEc2DynamicConfig{
+			Region:      "us-east-1",
+			AmiCacheTTL: time.Hour,
+			ec2Client:   ec2Mock,
+			ssmClient:   ssmMock,
+		}
+	})
+
+	It("should return a fixed AMI without resolving it", func(ctx SpecContext) {
+		cfg.Ami = "ami-0fixed"
+		cfg.AmiSsmParameter = ssmParameter
+
+		Expect(cfg.resolveAmi(nil, ctx, ec2Mock)).Should(Equal("ami-0fixed"))
+		Expect(ssmMock.GetParameterInputs).Should(BeEmpty())
+	})
+
+	It("should return no AMI when none is configured", func(ctx SpecContext) {
+		Expect(cfg.resolveAmi(nil, ctx, ec2Mock)).Should(BeEmpty())
+	})
+
+	When("an SSM parameter is configured", func() {
+		BeforeEach(func() {
+			cfg.AmiSsmParameter = ssmParameter
+		})
+
+		It("should resolve the AMI from the parameter and cache it", func(ctx SpecContext) {
+			Expect(cfg.resolveAmi(nil, ctx, ec2Mock)).Should(Equal("ami-0ssm"))
+			Expect(aws.ToString(ssmMock.GetParameterInputs[0].Name)).Should(Equal(ssmParameter))
+
+			ssmMock.Parameters[ssmParameter] = "ami-0rotated"
+			Expect(cfg.resolveAmi(nil, ctx, ec2Mock)).Should(Equal("ami-0ssm"))
+			Expect(ssmMock.GetParameterInputs).Should(HaveLen(1))
+		})
+
+		It("should not share the resolved AMI between accounts", func(ctx SpecContext) {
+			Expect(cfg.resolveAmi(nil, ctx, ec2Mock)).Should(Equal("ami-0ssm"))
+
+			ssmMock.Parameters[ssmParameter] = "ami-0other"
+			other := cfg
+			other.RoleArn = "arn:aws:iam::210987654321:role/multi-platform-builder"
+			Expect(other.resolveAmi(nil, ctx, ec2Mock)).Should(Equal("ami-0other"))
+			other = cfg
+			other.Secret = "other-aws-creds"
+			Expect(other.resolveAmi(nil, ctx, ec2Mock)).Should(Equal("ami-0other"))
+			Expect(ssmMock.GetParameterInputs).Should(HaveLen(3))
+		})
+
+		It("should resolve the AMI again once the cache period is over", func(ctx SpecContext) {
+			Expect(cfg.resolveAmi(nil, ctx, ec2Mock)).Should(Equal("ami-0ssm"))
+
+			amiCacheMu.Lock()
+			for key, entry := range amiCache {
+				entry.expires = time.Now().Add(-time.Second)
+				amiCache[key] = entry
+			}
+			amiCacheMu.Unlock()
+			ssmMock.Parameters[ssmParameter] = "ami-0rotated"
+
+			Expect(cfg.resolveAmi(nil, ctx, ec2Mock)).Should(Equal("ami-0rotated"))
+			Expect(ssmMock.GetParameterInputs).Should(HaveLen(2))
+		})
+
+		It("should return an error when the parameter cannot be read", func(ctx SpecContext) {
+			cfg.AmiSsmParameter = "/missing"
+
+			_, err := cfg.resolveAmi(nil, ctx, ec2Mock)
+
+			Expect(err).Should(MatchError(ContainSubstring("failed to get the SSM parameter /missing")))
+			Expect(amiCache).Should(BeEmpty())
+		})
+	})
+
+	When("an owner and a name filter are configured", func() {
+		BeforeEach(func() {
+			cfg.AmiOwner = "amazon"
+			cfg.AmiName = "al2023-ami-2023.*-arm64"
+		})
+
+		It("should resolve the newest available matching AMI", func(ctx SpecContext) {
+			ec2Mock.DescribeImagesOutput = &ec2.DescribeImagesOutput{Images: []types.Image{
+				{ImageId: aws.String("ami-0old"), CreationDate: aws.String("2026-01-10T08:00:00.000Z")},
+				{ImageId: aws.String("ami-0new"), CreationDate: aws.String("2026-03-02T08:00:00.000Z")},
+				{ImageId: aws.String("ami-0mid"), CreationDate: aws.String("2026-02-14T08:00:00.000Z")},
+			}}
+
+			Expect(cfg.resolveAmi(nil, ctx, ec2Mock)).Should(Equal("ami-0new"))
+			input := ec2Mock.DescribeImagesInputs[0]
+			Expect(input.Owners).Should(ConsistOf("amazon"))
+			Expect(input.Filters).Should(ContainElement(types.Filter{Name: aws.String("name"), Values: []string{cfg.AmiName}}))
+			Expect(input.Filters).Should(ContainElement(types.Filter{Name: aws.String("state"), Values: []string{"available"}}))
+		})
+
+		It("should return an error when no AMI matches", func(ctx SpecContext) {
+			ec2Mock.DescribeImagesOutput = &ec2.DescribeImagesOutput{}
+
+			_, err := cfg.resolveAmi(nil, ctx, ec2Mock)
+
+			Expect(err).Should(MatchError(ContainSubstring("no available AMI of amazon is named al2023-ami-2023.*-arm64")))
+		})
+
+		It("should return an error when the AMIs cannot be retrieved", func(ctx SpecContext) {
+			ec2Mock.DescribeImagesErr = errors.New("api error")
+
+			_, err := cfg.resolveAmi(nil, ctx, ec2Mock)
+
+			Expect(err).Should(MatchError(ContainSubstring("api error")))
+		})
+	})
+
+	It("should launch instances from the resolved AMI", func(ctx SpecContext) {
+		cfg.AmiSsmParameter = ssmParameter
+		cfg.InstanceType = "t4g.medium"
+		ec2Mock.RunInstancesOutput = &ec2.RunInstancesOutput{Instances: []types.Instance{{InstanceId: aws.String("i-abc123")}}}
+
+		_, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(aws.ToString(ec2Mock.RunInstancesInputs[0].ImageId)).Should(Equal("ami-0ssm"))
+	})
+})
@@ -0,0 +1,149 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	v1 "k8s.io/api/core/v1"
+	types2 "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// webIdentityTokenFileEnv and webIdentityRoleArnEnv are set in the controller's pod by IRSA or OpenShift STS
+	// when its service account is bound to an IAM role.
+	webIdentityTokenFileEnv = "AWS_WEB_IDENTITY_TOKEN_FILE"
+	webIdentityRoleArnEnv   = "AWS_ROLE_ARN"
+
+	// roleSessionName identifies the controller in the CloudTrail events of the roles it assumes.
+	roleSessionName = "multi-platform-controller"
+
+	// credentialsExpiryWindow is how long before they expire temporary credentials are refreshed, so that
+	// requests are not signed with credentials that expire in flight.
+	credentialsExpiryWindow = time.Minute
+)
+
+// credentialsCacheKey identifies the source of a set of cached credentials.
+type credentialsCacheKey struct {
+	secret               string
+	namespace            string
+	webIdentityTokenFile string
+	webIdentityRoleArn   string
+	roleArn              string
+	region               string
+
+	// secretVersion is the resource version of the secret, so that the keys of a rotated secret are no longer used
+	// to assume roles.
+	secretVersion string
+}
+
+var (
+	// credentialsCaches holds the providers of temporary credentials, so that the credentials are only refreshed
+	// shortly before they expire instead of for every EC2 API call.
+	credentialsCaches   = map[credentialsCacheKey]*aws.CredentialsCache{}
+	credentialsCachesMu sync.Mutex
+)
+
+// getCredentialsProvider returns the provider of the credentials used to call the AWS APIs. The base credentials
+// are:
+//   - the web identity token of the controller's service account (IRSA or OpenShift STS) when no secret is
+//     configured and the token is available;
+//   - the static keys of the Secret when one is configured, or the MULTI_ARCH_* environment variables without a
+//     Kubernetes client;
+//   - the default credential chain of the AWS SDK (e.g. the instance profile of the node) when RoleArn is set
+//     without a secret or a web identity token.
+//
+// When RoleArn is set, the base credentials are used to assume that role. Temporary credentials are cached and
+// refreshed when they are about to expire, and for as long as the secret is not updated.
+func (ec AWSEc2DynamicConfig) getCredentialsProvider(kubeClient client.Client, ctx context.Context) (aws.CredentialsProvider, error) {
+	key := credentialsCacheKey{
+		secret:    ec.Secret,
+		namespace: ec.SystemNamespace,
+		roleArn:   ec.RoleArn,
+		region:    ec.Region,
+	}
+	if ec.Secret == "" {
+		key.webIdentityTokenFile = os.Getenv(webIdentityTokenFileEnv)
+		key.webIdentityRoleArn = os.Getenv(webIdentityRoleArnEnv)
+	}
+	if key.webIdentityTokenFile == "" && key.roleArn == "" {
+		// Static credentials never expire, so reading them every time picks up rotated keys
+		return SecretCredentialsProvider{Name: ec.Secret, Namespace: ec.SystemNamespace, Client: kubeClient}, nil
+	}
+
+	if key.webIdentityTokenFile == "" && ec.Secret != "" && kubeClient != nil {
+		secret := v1.Secret{}
+		if err := kubeClient.Get(ctx, types2.NamespacedName{Name: ec.Secret, Namespace: ec.SystemNamespace}, &secret); err != nil {
+			return nil, fmt.Errorf("failed to retrieve the secret %s/%s: %w", ec.SystemNamespace, ec.Secret, err)
+		}
+		key.secretVersion = secret.ResourceVersion
+	}
+
+	credentialsCachesMu.Lock()
+	defer credentialsCachesMu.Unlock()
+	if cache, ok := credentialsCaches[key]; ok {
+		return cache, nil
+	}
+	// Forget the credentials assumed with previous versions of the secret
+	for cached := range credentialsCaches {
+		previous := cached
+		previous.secretVersion = key.secretVersion
+		if previous == key {
+			delete(credentialsCaches, cached)
+		}
+	}
+
+	var provider aws.CredentialsProvider = SecretCredentialsProvider{Name: ec.Secret, Namespace: ec.SystemNamespace, Client: kubeClient}
+	if key.webIdentityTokenFile == "" && ec.Secret == "" && kubeClient != nil {
+		defaultConfig, err := config.LoadDefaultConfig(ctx, config.WithRegion(ec.Region))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the default AWS credentials: %w", err)
+		}
+		provider = defaultConfig.Credentials
+	}
+	if key.webIdentityTokenFile != "" {
+		if key.webIdentityRoleArn == "" {
+			return nil, fmt.Errorf("%s is set without %s", webIdentityTokenFileEnv, webIdentityRoleArnEnv)
+		}
+		// AssumeRoleWithWebIdentity is not signed, so the STS client needs no credentials
+		stsClient, err := ec.getSTSClient(ctx, aws.AnonymousCredentials{})
+		if err != nil {
+			return nil, err
+		}
+		provider = stscreds.NewWebIdentityRoleProvider(stsClient, key.webIdentityRoleArn, stscreds.IdentityTokenFile(key.webIdentityTokenFile),
+			func(o *stscreds.WebIdentityRoleOptions) { o.RoleSessionName = roleSessionName })
+	}
+	if key.roleArn != "" {
+		stsClient, err := ec.getSTSClient(ctx, aws.NewCredentialsCache(provider))
+		if err != nil {
+			return nil, err
+		}
+		provider = stscreds.NewAssumeRoleProvider(stsClient, key.roleArn,
+			func(o *stscreds.AssumeRoleOptions) { o.RoleSessionName = roleSessionName })
+	}
+
+	cache := aws.NewCredentialsCache(provider, func(o *aws.CredentialsCacheOptions) { o.ExpiryWindow = credentialsExpiryWindow })
+	credentialsCaches[key] = cache
+	return cache, nil
+}
+
+// getSTSClient returns the injected mock client if set, otherwise builds a real STS client using credentials.
+func (ec AWSEc2DynamicConfig) getSTSClient(ctx context.Context, credentials aws.CredentialsProvider) (stsAPI, error) {
+	if ec.stsClient != nil {
+		return ec.stsClient, nil
+	}
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithCredentialsProvider(credentials),
+		config.WithRegion(ec.Region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create an AWS config for an STS client: %w", err)
+	}
+	return sts.NewFromConfig(cfg), nil
+}
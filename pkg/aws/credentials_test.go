@@ -0,0 +1,195 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("getCredentialsProvider", func() {
+	var (
+		stsMock *mockSTSClient
+		cfg     AWSEc2DynamicConfig
+	)
+
+	// setEnv sets an environment variable for the duration of the test.
+	setEnv := func(key, value string) {
+		previous, ok := os.LookupEnv(key)
+		Expect(os.Setenv(key, value)).To(Succeed())
+		DeferCleanup(func() {
+			if ok {
+				Expect(os.Setenv(key, previous)).To(Succeed())
+			} else {
+				Expect(os.Unsetenv(key)).To(Succeed())
+			}
+		})
+	}
+
+	BeforeEach(func() {
+		credentialsCachesMu.Lock()
+		credentialsCaches = map[credentialsCacheKey]*aws.CredentialsCache{}
+		credentialsCachesMu.Unlock()
+
+		stsMock = &mockSTSClient{Expiry: time.Hour}
+		cfg = AWSEc2DynamicConfig{
+			Region:          "us-east-1",
+			Secret:          "aws-creds",
+			SystemNamespace: "multi-platform-controller",
+			stsClient:       stsMock,
+		}
+		setEnv(webIdentityTokenFileEnv, "")
+		setEnv(webIdentityRoleArnEnv, "")
+	})
+
+	When("no role and no web identity are configured", func() {
+		It("should read the static keys of the secret every time", func(ctx SpecContext) {
+			provider, err := cfg.getCredentialsProvider(nil, ctx)
+
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(provider).Should(Equal(SecretCredentialsProvider{Name: "aws-creds", Namespace: "multi-platform-controller"}))
+			Expect(credentialsCaches).Should(BeEmpty())
+		})
+	})
+
+	When("a role ARN is configured", func() {
+		BeforeEach(func() {
+			cfg.RoleArn = "arn:aws:iam::123456789012:role/multi-platform-builder"
+		})
+
+		It("should assume the role and cache its credentials across providers", func(ctx SpecContext) {
+			provider, err := cfg.getCredentialsProvider(nil, ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			creds, err := provider.Retrieve(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(creds.AccessKeyID).Should(Equal("ASIA-TEMPORARY"))
+			Expect(creds.SessionToken).Should(Equal("temporary-token"))
+			Expect(stsMock.AssumeRoleInputs).Should(HaveLen(1))
+			Expect(aws.ToString(stsMock.AssumeRoleInputs[0].RoleArn)).Should(Equal(cfg.RoleArn))
+			Expect(aws.ToString(stsMock.AssumeRoleInputs[0].RoleSessionName)).Should(Equal(roleSessionName))
+
+			again, err := cfg.getCredentialsProvider(nil, ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(again).Should(BeIdenticalTo(provider))
+			_, err = again.Retrieve(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(stsMock.AssumeRoleInputs).Should(HaveLen(1))
+		})
+
+		It("should refresh the credentials when they are about to expire", func(ctx SpecContext) {
+			stsMock.Expiry = credentialsExpiryWindow / 2
+			provider, err := cfg.getCredentialsProvider(nil, ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = provider.Retrieve(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = provider.Retrieve(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(stsMock.AssumeRoleInputs).Should(HaveLen(2))
+		})
+
+		It("should stop using the keys of the secret once it is rotated", func(ctx SpecContext) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "aws-creds", Namespace: "multi-platform-controller"},
+				Data:       map[string][]byte{"access-key-id": []byte("AKID"), "secret-access-key": []byte("SECRET")},
+			}
+			kubeClient := fake.NewClientBuilder().WithObjects(secret).Build()
+			provider, err := cfg.getCredentialsProvider(kubeClient, ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			again, err := cfg.getCredentialsProvider(kubeClient, ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(again).Should(BeIdenticalTo(provider))
+
+			secret.Data["access-key-id"] = []byte("ROTATED")
+			Expect(kubeClient.Update(ctx, secret)).To(Succeed())
+			rotated, err := cfg.getCredentialsProvider(kubeClient, ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(rotated).ShouldNot(BeIdenticalTo(provider))
+			Expect(credentialsCaches).Should(HaveLen(1))
+		})
+
+		It("should return an error when the secret does not exist", func(ctx SpecContext) {
+			_, err := cfg.getCredentialsProvider(fake.NewClientBuilder().Build(), ctx)
+			Expect(err).Should(MatchError(ContainSubstring("failed to retrieve the secret")))
+		})
+
+		It("should assume the role with the default credentials without a secret", func(ctx SpecContext) {
+			cfg.Secret = ""
+			provider, err := cfg.getCredentialsProvider(fake.NewClientBuilder().Build(), ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			creds, err := provider.Retrieve(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(creds.AccessKeyID).Should(Equal("ASIA-TEMPORARY"))
+			Expect(stsMock.AssumeRoleInputs).Should(HaveLen(1))
+			Expect(aws.ToString(stsMock.AssumeRoleInputs[0].RoleArn)).Should(Equal(cfg.RoleArn))
+		})
+
+		It("should not share credentials between roles", func(ctx SpecContext) {
+			provider, err := cfg.getCredentialsProvider(nil, ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			cfg.RoleArn = "arn:aws:iam::123456789012:role/other-builder"
+			other, err := cfg.getCredentialsProvider(nil, ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(other).ShouldNot(BeIdenticalTo(provider))
+		})
+	})
+
+	When("the controller has a web identity token", func() {
+		BeforeEach(func() {
+			tokenFile := filepath.Join(GinkgoT().TempDir(), "token")
+			Expect(os.WriteFile(tokenFile, []byte("web-identity-token"), 0o600)).To(Succeed())
+			setEnv(webIdentityTokenFileEnv, tokenFile)
+			setEnv(webIdentityRoleArnEnv, "arn:aws:iam::123456789012:role/multi-platform-controller")
+			cfg.Secret = ""
+		})
+
+		It("should exchange the token for the credentials of the service account's role", func(ctx SpecContext) {
+			provider, err := cfg.getCredentialsProvider(nil, ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			creds, err := provider.Retrieve(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(creds.AccessKeyID).Should(Equal("ASIA-TEMPORARY"))
+			Expect(stsMock.AssumeRoleWithWebIdentityInputs).Should(HaveLen(1))
+			Expect(aws.ToString(stsMock.AssumeRoleWithWebIdentityInputs[0].RoleArn)).Should(Equal("arn:aws:iam::123456789012:role/multi-platform-controller"))
+			Expect(aws.ToString(stsMock.AssumeRoleWithWebIdentityInputs[0].WebIdentityToken)).Should(Equal("web-identity-token"))
+			Expect(stsMock.AssumeRoleInputs).Should(BeEmpty())
+		})
+
+		It("should assume the configured role on top of the service account's role", func(ctx SpecContext) {
+			cfg.RoleArn = "arn:aws:iam::210987654321:role/multi-platform-builder"
+			provider, err := cfg.getCredentialsProvider(nil, ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			_, err = provider.Retrieve(ctx)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(stsMock.AssumeRoleInputs).Should(HaveLen(1))
+			Expect(aws.ToString(stsMock.AssumeRoleInputs[0].RoleArn)).Should(Equal(cfg.RoleArn))
+		})
+
+		It("should prefer the static keys of a configured secret", func(ctx SpecContext) {
+			cfg.Secret = "aws-creds"
+			provider, err := cfg.getCredentialsProvider(nil, ctx)
+
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(provider).Should(BeAssignableToTypeOf(SecretCredentialsProvider{}))
+		})
+
+		It("should return an error when the role of the service account is unknown", func(ctx SpecContext) {
+			setEnv(webIdentityRoleArnEnv, "")
+
+			_, err := cfg.getCredentialsProvider(nil, ctx)
+
+			Expect(err).Should(MatchError(ContainSubstring(webIdentityRoleArnEnv)))
+		})
+	})
+})
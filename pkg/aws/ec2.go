@@ -13,6 +13,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
@@ -22,13 +23,27 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-const MultiPlatformManaged = "MultiPlatformManaged"
+const (
+	MultiPlatformManaged = "MultiPlatformManaged"
+
+	// SubnetTag is the tag recording the subnet an instance was launched in.
+	SubnetTag = "MultiPlatformSubnet"
+
+	// CreationTimeTag is the tag recording when an instance was created, as its launch time is reset whenever it is
+	// started again after being stopped.
+	CreationTimeTag = "MultiPlatformCreationTime"
+)
 
 // CreateEc2CloudConfig returns an AWS EC2 cloud configuration that implements the CloudProvider interface.
 func CreateEc2CloudConfig(platformName string, config map[string]string, systemNamespace string) cloud.CloudProvider {
+	launchTemplateId := config["dynamic."+platformName+".launch-template-id"]
 	disk, err := strconv.ParseInt(config["dynamic."+platformName+".disk"], 10, 32)
 	if err != nil {
 		disk = 40
+		// Keep the volumes of the launch template unless the disk size is overridden
+		if launchTemplateId != "" {
+			disk = 0
+		}
 	}
 
 	var iops *int32
@@ -56,14 +71,35 @@ func CreateEc2CloudConfig(platformName string, config map[string]string, systemN
 		userDataPtr = &base54val
 	}
 
+	amiCacheTTL, err := time.ParseDuration(config["dynamic."+platformName+".ami-cache-ttl"])
+	if err != nil || amiCacheTTL <= 0 {
+		amiCacheTTL = defaultAmiCacheTTL
+	}
+
+	// The instance type may be an ordered list of instance types to fall back to
+	var instanceType string
+	var fallbackInstanceTypes []string
+	if instanceTypes := splitList(config["dynamic."+platformName+".instance-type"]); len(instanceTypes) > 0 {
+		instanceType = instanceTypes[0]
+		if len(instanceTypes) > 1 {
+			fallbackInstanceTypes = instanceTypes[1:]
+		}
+	}
+
 	return AWSEc2DynamicConfig{Region: config["dynamic."+platformName+".region"],
 		Ami:                     config["dynamic."+platformName+".ami"],
-		InstanceType:            config["dynamic."+platformName+".instance-type"],
+		AmiSsmParameter:         config["dynamic."+platformName+".ami-ssm-parameter"],
+		AmiOwner:                config["dynamic."+platformName+".ami-owner"],
+		AmiName:                 config["dynamic."+platformName+".ami-name"],
+		AmiCacheTTL:             amiCacheTTL,
+		InstanceType:            instanceType,
+		FallbackInstanceTypes:   fallbackInstanceTypes,
 		KeyName:                 config["dynamic."+platformName+".key-name"],
 		Secret:                  config["dynamic."+platformName+".aws-secret"],
 		SecurityGroup:           config["dynamic."+platformName+".security-group"],
 		SecurityGroupId:         config["dynamic."+platformName+".security-group-id"],
 		SubnetId:                config["dynamic."+platformName+".subnet-id"],
+		SubnetIds:               splitList(config["dynamic."+platformName+".subnet-ids"]),
 		InstanceProfileName:     config["dynamic."+platformName+".instance-profile-name"],
 		InstanceProfileArn:      config["dynamic."+platformName+".instance-profile-arn"],
 		StrictPublicAddress:     config["dynamic."+platformName+".strict-public-address"] == "true",
@@ -75,10 +111,18 @@ func CreateEc2CloudConfig(platformName string, config map[string]string, systemN
 		Tenancy:                 config["dynamic."+platformName+".tenancy"],
 		HostResourceGroupArn:    config["dynamic."+platformName+".host-resource-group-arn"],
 		LicenseConfigurationArn: config["dynamic."+platformName+".license-configuration-arn"],
+		Spot:                    config["dynamic."+platformName+".spot"] == "true",
+		SpotMaxPrice:            config["dynamic."+platformName+".spot-max-price"],
+		LaunchTemplateId:        launchTemplateId,
+		LaunchTemplateVersion:   config["dynamic."+platformName+".launch-template-version"],
+		RoleArn:                 config["dynamic."+platformName+".role-arn"],
 	}
 }
 
-// LaunchInstance creates an EC2 instance and returns its identifier.
+// LaunchInstance creates an EC2 instance and returns its identifier. Spot instances are requested when Spot is
+// set; if no Spot capacity is available at the maximum price, an on-demand instance is launched instead. When
+// several subnets are configured, they are tried in turn until one has capacity for the instance, and when
+// fallback instance types are configured, they are tried in turn until one is available.
 func (ec AWSEc2DynamicConfig) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (cloud.InstanceIdentifier, error) {
 	err := cloud.ValidateTaskRunID(taskRunID)
 	if err != nil {
@@ -97,7 +141,11 @@ func (ec AWSEc2DynamicConfig) LaunchInstance(kubeClient client.Client, ctx conte
 		return "", fmt.Errorf("failed to create an EC2 client: %w", err)
 	}
 
-	// Launch the new EC2 instance
+	// Resolve the AMI, if it is not fixed, before launching the new EC2 instance
+	ec.Ami, err = ec.resolveAmi(kubeClient, ctx, ec2Client)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the AMI for %s: %w", taskRunName, err)
+	}
 	launchInput, err := ec.configureInstance(taskRunName, instanceTag, additionalInstanceTags)
 	if err != nil {
 		if strings.Contains(err.Error(), "MacOS") {
@@ -105,7 +153,8 @@ func (ec AWSEc2DynamicConfig) LaunchInstance(kubeClient client.Client, ctx conte
 		}
 		return "", fmt.Errorf("failed to configure EC2 instance for %s: %w", taskRunName, err)
 	}
-	runInstancesOutput, err := ec2Client.RunInstances(ctx, launchInput)
+	launchInput.TagSpecifications[0].Tags = append(launchInput.TagSpecifications[0].Tags, types.Tag{Key: aws.String(CreationTimeTag), Value: aws.String(time.Now().UTC().Format(time.RFC3339))})
+	runInstancesOutput, err := ec.runInstance(ctx, ec2Client, launchInput)
 	if err != nil {
 		return "", fmt.Errorf("failed to launch EC2 instance for %s: %w", taskRunName, err)
 	}
@@ -147,7 +196,7 @@ func (ec AWSEc2DynamicConfig) CountInstances(kubeClient client.Client, ctx conte
 	for _, reservation := range instancesOutput.Reservations {
 		for _, instance := range reservation.Instances {
 			// Verify the instance is running an is of the specified VM "flavor"
-			if instance.State.Name != types.InstanceStateNameTerminated && string(instance.InstanceType) == ec.InstanceType {
+			if instance.State.Name != types.InstanceStateNameTerminated && ec.hasInstanceType(&instance) {
 				log.Info("Counting instance towards running count", "instanceID", *instance.InstanceId)
 				count++
 			}
@@ -207,7 +256,81 @@ func (ec AWSEc2DynamicConfig) TerminateInstance(kubeClient client.Client, ctx co
 	return err
 }
 
-// ListInstances returns a collection of accessible EC2 instances whose names start with instanceTag.
+// StopInstance tries to stop the instanceID EC2 instance, e.g. an instance on an expensive dedicated host, so that it
+// can be started again rather than launched.
+func (ec AWSEc2DynamicConfig) StopInstance(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) error {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to stop AWS EC2 instance", "instanceID", instanceID)
+
+	ec2Client, err := ec.getEC2Client(kubeClient, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create an EC2 client: %w", err)
+	}
+
+	_, err = ec2Client.StopInstances(ctx, &ec2.StopInstancesInput{InstanceIds: []string{string(instanceID)}})
+	return err
+}
+
+// StartInstance tries to start the stopped instanceID EC2 instance.
+func (ec AWSEc2DynamicConfig) StartInstance(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) error {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to start AWS EC2 instance", "instanceID", instanceID)
+
+	ec2Client, err := ec.getEC2Client(kubeClient, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create an EC2 client: %w", err)
+	}
+
+	_, err = ec2Client.StartInstances(ctx, &ec2.StartInstancesInput{InstanceIds: []string{string(instanceID)}})
+	return err
+}
+
+// ListStoppedInstances returns the stopped EC2 instances whose names start with instanceTag, along with when they were
+// created.
+func (ec AWSEc2DynamicConfig) ListStoppedInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]cloud.CloudVMInstance, error) {
+	ec2Client, err := ec.getEC2Client(kubeClient, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create an EC2 client: %w", err)
+	}
+
+	instancesOutput, err := ec2Client.DescribeInstances(
+		ctx,
+		&ec2.DescribeInstancesInput{
+			Filters: []types.Filter{
+				{Name: aws.String("tag:" + cloud.InstanceTag), Values: []string{instanceTag}},
+				{Name: aws.String("tag:" + MultiPlatformManaged), Values: []string{"true"}},
+				{Name: aws.String("instance-state-name"), Values: []string{string(types.InstanceStateNameStopped)}},
+			},
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve stopped EC2 instances starting with %s: %w", instanceTag, err)
+	}
+
+	vmInstances := []cloud.CloudVMInstance{}
+	for _, reservation := range instancesOutput.Reservations {
+		for i := range reservation.Instances {
+			instance := reservation.Instances[i]
+			if instance.State.Name != types.InstanceStateNameStopped || !ec.hasInstanceType(&instance) {
+				continue
+			}
+			newVmInstance := cloud.CloudVMInstance{
+				InstanceId: cloud.InstanceIdentifier(*instance.InstanceId),
+				StartTime:  creationTime(&instance),
+			}
+			for _, tag := range instance.Tags {
+				if aws.ToString(tag.Key) == cloud.TaskRunTagKey {
+					newVmInstance.TaskRunID = aws.ToString(tag.Value)
+				}
+			}
+			vmInstances = append(vmInstances, newVmInstance)
+		}
+	}
+	return vmInstances, nil
+}
+
+// ListInstances returns a collection of accessible EC2 instances whose names start with instanceTag, along with when
+// they were created.
 func (ec AWSEc2DynamicConfig) ListInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]cloud.CloudVMInstance, error) {
 	log := logr.FromContextOrDiscard(ctx)
 	log.Info("Attempting to list AWS EC2 instances")
@@ -239,15 +362,20 @@ func (ec AWSEc2DynamicConfig) ListInstances(kubeClient client.Client, ctx contex
 		for i := range reservation.Instances {
 			instance := reservation.Instances[i]
 			// Verify the instance is running an is of the specified VM "flavor"
-			if instance.State.Name != types.InstanceStateNameTerminated && string(instance.InstanceType) == ec.InstanceType {
+			if instance.State.Name != types.InstanceStateNameTerminated && !isStopped(&instance) && ec.hasInstanceType(&instance) {
 				// Only list instance if it has an accessible IP
 				ip, err := ec.validateIPAddress(ctx, &instance)
 				if err == nil {
 					newVmInstance := cloud.CloudVMInstance{
 						InstanceId: cloud.InstanceIdentifier(*instance.InstanceId),
-						StartTime:  *instance.LaunchTime,
+						StartTime:  creationTime(&instance),
 						Address:    ip,
 					}
+					for _, tag := range instance.Tags {
+						if aws.ToString(tag.Key) == cloud.TaskRunTagKey {
+							newVmInstance.TaskRunID = aws.ToString(tag.Value)
+						}
+					}
 					vmInstances = append(vmInstances, newVmInstance)
 					log.Info("Counting instance towards running count", "instanceID", *instance.InstanceId)
 				}
@@ -257,13 +385,31 @@ func (ec AWSEc2DynamicConfig) ListInstances(kubeClient client.Client, ctx contex
 	return vmInstances, nil
 }
 
+// GetInstanceDetails returns the instance type instanceID was launched as, which may be one of the fallback
+// instance types, and the AMI it was launched from, which may have been resolved at launch time.
+func (ec AWSEc2DynamicConfig) GetInstanceDetails(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (cloud.InstanceDetails, error) {
+	ec2Client, err := ec.getEC2Client(kubeClient, ctx)
+	if err != nil {
+		return cloud.InstanceDetails{}, fmt.Errorf("failed to create an EC2 client: %w", err)
+	}
+	instancesOutput, err := ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{string(instanceID)}})
+	if err != nil {
+		return cloud.InstanceDetails{}, fmt.Errorf("failed to retrieve instance %s: %w", instanceID, err)
+	}
+	if len(instancesOutput.Reservations) == 0 || len(instancesOutput.Reservations[0].Instances) == 0 {
+		return cloud.InstanceDetails{}, fmt.Errorf("instance %s not found", instanceID)
+	}
+	instance := instancesOutput.Reservations[0].Instances[0]
+	return cloud.InstanceDetails{InstanceType: string(instance.InstanceType), Image: aws.ToString(instance.ImageId)}, nil
+}
+
 func (r AWSEc2DynamicConfig) SshUser() string {
 	return "ec2-user"
 }
 
 // GetState returns instanceID's VM state from the ec cloud in AWS. See
 // https://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_InstanceState.html
-// for valid states.
+// for valid states. A Spot instance reclaimed by AWS is in an interrupted state.
 func (ec AWSEc2DynamicConfig) GetState(kubeClient client.Client, ctx context.Context, instanceId cloud.InstanceIdentifier) (cloud.VMState, error) {
 	log := logr.FromContextOrDiscard(ctx)
 	log.Info("Attempting to get AWS EC2 instance's IP address", "instanceId", instanceId)
@@ -293,6 +439,10 @@ func (ec AWSEc2DynamicConfig) GetState(kubeClient client.Client, ctx context.Con
 	if len(instancesOutput.Reservations) > 0 {
 		if len(instancesOutput.Reservations[0].Instances) > 0 {
 			instance := instancesOutput.Reservations[0].Instances[0]
+			if isSpotInterrupted(&instance) {
+				log.Info("AWS EC2 Spot instance was interrupted", "instanceId", instanceId, "reason", aws.ToString(instance.StateReason.Message))
+				return cloud.InterruptedState, nil
+			}
 			if slices.Contains(okStates, string(instance.State.Name)) {
 				return cloud.OKState, nil
 			}
@@ -311,14 +461,34 @@ type AWSEc2DynamicConfig struct {
 	Region string
 
 	// Ami is the Amazon Machine Image used to provide the software to the instance.
+	// When empty, the AMI is resolved from AmiSsmParameter or AmiOwner and AmiName.
 	Ami string
 
+	// AmiSsmParameter is the path of the SSM parameter holding the ID of the AMI,
+	// e.g. "/aws/service/ami-amazon-linux-latest/al2023-ami-kernel-default-arm64".
+	AmiSsmParameter string
+
+	// AmiOwner is the account ID or alias ("amazon", "self", ...) owning the AMI.
+	// The newest AMI of AmiOwner whose name matches AmiName is used.
+	AmiOwner string
+
+	// AmiName is the name filter of the AMI, which may contain "*" and "?" wildcards.
+	AmiName string
+
+	// AmiCacheTTL is how long a resolved AMI is reused before it is resolved again.
+	AmiCacheTTL time.Duration
+
 	// InstanceType corresponds to the AWS instance type, which specifies the
 	// hardware of the host computer used for the instance. See the
 	// [AWS instance naming docs](https://docs.aws.amazon.com/ec2/latest/instancetypes/instance-type-names.html)
-	// for proper instance type naming conventions.
+	// for proper instance type naming conventions. It may be empty when the
+	// launch template specifies the instance type.
 	InstanceType string
 
+	// FallbackInstanceTypes are the instance types to try in order when AWS has
+	// no capacity for InstanceType or it is over quota.
+	FallbackInstanceTypes []string
+
 	// KeyName is the name of the SSH key inside of AWS.
 	KeyName string
 
@@ -340,7 +510,12 @@ type AWSEc2DynamicConfig struct {
 	// SubnetId is the ID of the subnet to use when creating the instance.
 	SubnetId string
 
-	// Disk is the amount of permanent storage (in GB) to allocate the instance.
+	// SubnetIds are the IDs of the subnets, typically in different availability zones,
+	// to try in order when creating the instance. When set, SubnetId is ignored.
+	SubnetIds []string
+
+	// Disk is the amount of permanent storage (in GB) to allocate the instance. When
+	// launching from a launch template, zero keeps the template's volumes.
 	Disk int32
 
 	// InstanceProfileName is the name of the instance profile (a container for
@@ -376,10 +551,38 @@ type AWSEc2DynamicConfig struct {
 	// If false, it would be also possible for the instance to use a private IP address.
 	StrictPublicAddress bool
 
+	// Spot specifies whether Spot instances are requested. An on-demand instance is
+	// launched instead when no Spot capacity is available.
+	Spot bool
+
+	// SpotMaxPrice is the maximum hourly price (in USD) paid for a Spot instance. When
+	// empty, the on-demand price is the maximum.
+	SpotMaxPrice string
+
+	// LaunchTemplateId is the ID of the EC2 launch template the instance is launched
+	// from. The other fields override the template's settings when they are set.
+	LaunchTemplateId string
+
+	// LaunchTemplateVersion is the version of the launch template: a version number,
+	// "$Latest" or "$Default". When empty, the template's default version is used.
+	LaunchTemplateVersion string
+
+	// RoleArn is the ARN of the IAM role to assume, using the Secret's keys or the
+	// controller's web identity, to manage the platform's instances.
+	RoleArn string
+
 	// ec2Client allows tests to inject a mock EC2 API client.
 	// When nil, getEC2Client builds a real client from AWS credentials.
 	ec2Client ec2API
 
+	// stsClient allows tests to inject a mock STS API client.
+	// When nil, getSTSClient builds a real client.
+	stsClient stsAPI
+
+	// ssmClient allows tests to inject a mock SSM API client.
+	// When nil, getSSMClient builds a real client from AWS credentials.
+	ssmClient ssmAPI
+
 	// pingFunc allows tests to inject a mock for SSH connectivity checks.
 	// When nil, the real pingIPAddress (TCP dial to port 22) is used.
 	pingFunc func(ip string) error
@@ -6,12 +6,16 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/smithy-go"
 	"github.com/go-logr/logr"
 	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
 	v1 "k8s.io/api/core/v1"
@@ -74,17 +78,39 @@ func (ec AWSEc2DynamicConfig) getEC2Client(kubeClient client.Client, ctx context
 	if ec.ec2Client != nil {
 		return ec.ec2Client, nil
 	}
-	secretCredentials := SecretCredentialsProvider{Name: ec.Secret, Namespace: ec.SystemNamespace, Client: kubeClient}
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithCredentialsProvider(secretCredentials),
-		config.WithRegion(ec.Region))
+	cfg, err := ec.loadAWSConfig(kubeClient, ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create an AWS config for an EC2 client: %w", err)
 	}
 	return ec2.NewFromConfig(cfg), nil
 }
 
-// configureInstance creates and returns an EC2 instance configuration.
+// getSSMClient returns the injected mock client if set, otherwise builds a real
+// SSM client from AWS credentials.
+func (ec AWSEc2DynamicConfig) getSSMClient(kubeClient client.Client, ctx context.Context) (ssmAPI, error) {
+	if ec.ssmClient != nil {
+		return ec.ssmClient, nil
+	}
+	cfg, err := ec.loadAWSConfig(kubeClient, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create an AWS config for an SSM client: %w", err)
+	}
+	return ssm.NewFromConfig(cfg), nil
+}
+
+// loadAWSConfig returns the AWS configuration for the region of ec, using the credentials of ec.
+func (ec AWSEc2DynamicConfig) loadAWSConfig(kubeClient client.Client, ctx context.Context) (aws.Config, error) {
+	credentials, err := ec.getCredentialsProvider(kubeClient, ctx)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to get AWS credentials: %w", err)
+	}
+	return config.LoadDefaultConfig(ctx,
+		config.WithCredentialsProvider(credentials),
+		config.WithRegion(ec.Region))
+}
+
+// configureInstance creates and returns an EC2 instance configuration. When a launch template is configured, only
+// the configured fields are set so that they override the template's settings and the template provides the rest.
 func (ec AWSEc2DynamicConfig) configureInstance(taskRunName string, instanceTag string, additionalInstanceTags map[string]string) (*ec2.RunInstancesInput, error) {
 	// Validate that MacOS-specific fields are either all set or all empty
 	tenancySet := ec.Tenancy != ""
@@ -103,9 +129,15 @@ func (ec AWSEc2DynamicConfig) configureInstance(taskRunName string, instanceTag
 	var instanceProfile *types.IamInstanceProfileSpecification
 	var placement *types.Placement
 	var licenseSpecifications []types.LicenseConfigurationRequest
+	var marketOptions *types.InstanceMarketOptionsRequest
+	var launchTemplate *types.LaunchTemplateSpecification
+	var blockDeviceMappings []types.BlockDeviceMapping
+	keyName := aws.String(ec.KeyName)
+	imageId := aws.String(ec.Ami)
+	ebsOptimized := aws.Bool(true)
 
-	if ec.SubnetId != "" {
-		subnet = aws.String(ec.SubnetId)
+	if subnets := ec.subnetIds(); len(subnets) > 0 {
+		subnet = aws.String(subnets[0])
 	}
 	if ec.SecurityGroup != "" {
 		securityGroups = []string{ec.SecurityGroup}
@@ -135,6 +167,51 @@ func (ec AWSEc2DynamicConfig) configureInstance(taskRunName string, instanceTag
 		}
 	}
 
+	if ec.Disk > 0 || ec.LaunchTemplateId == "" {
+		blockDeviceMappings = []types.BlockDeviceMapping{{
+			DeviceName:  aws.String("/dev/sda1"),
+			VirtualName: aws.String("ephemeral0"),
+			Ebs: &types.EbsBlockDevice{
+				DeleteOnTermination: aws.Bool(true),
+				VolumeSize:          aws.Int32(ec.Disk),
+				VolumeType:          types.VolumeTypeGp3,
+				Iops:                ec.Iops,
+				Throughput:          ec.Throughput,
+			},
+		}}
+	}
+
+	// Leave the fields that are not configured to the launch template
+	if ec.LaunchTemplateId != "" {
+		launchTemplate = &types.LaunchTemplateSpecification{LaunchTemplateId: aws.String(ec.LaunchTemplateId)}
+		if ec.LaunchTemplateVersion != "" {
+			launchTemplate.Version = aws.String(ec.LaunchTemplateVersion)
+		}
+		keyName = nil
+		if ec.KeyName != "" {
+			keyName = aws.String(ec.KeyName)
+		}
+		imageId = nil
+		if ec.Ami != "" {
+			imageId = aws.String(ec.Ami)
+		}
+		ebsOptimized = nil
+	}
+
+	// Request a one-time Spot instance, which AWS terminates when it reclaims the capacity
+	if ec.Spot {
+		marketOptions = &types.InstanceMarketOptionsRequest{
+			MarketType: types.MarketTypeSpot,
+			SpotOptions: &types.SpotMarketOptions{
+				SpotInstanceType:             types.SpotInstanceTypeOneTime,
+				InstanceInterruptionBehavior: types.InstanceInterruptionBehaviorTerminate,
+			},
+		}
+		if ec.SpotMaxPrice != "" {
+			marketOptions.SpotOptions.MaxPrice = aws.String(ec.SpotMaxPrice)
+		}
+	}
+
 	instanceTags := []types.Tag{
 		{Key: aws.String(MultiPlatformManaged), Value: aws.String("true")},
 		{Key: aws.String(cloud.InstanceTag), Value: aws.String(instanceTag)},
@@ -145,37 +222,211 @@ func (ec AWSEc2DynamicConfig) configureInstance(taskRunName string, instanceTag
 	}
 
 	return &ec2.RunInstancesInput{
-		KeyName:            aws.String(ec.KeyName),
-		ImageId:            aws.String(ec.Ami), //ARM RHEL
-		InstanceType:       types.InstanceType(ec.InstanceType),
-		MinCount:           aws.Int32(1),
-		MaxCount:           aws.Int32(1),
-		EbsOptimized:       aws.Bool(true),
-		SecurityGroups:     securityGroups,
-		SecurityGroupIds:   securityGroupIds,
-		IamInstanceProfile: instanceProfile,
-		SubnetId:           subnet,
-		UserData:           ec.UserData,
-		BlockDeviceMappings: []types.BlockDeviceMapping{{
-			DeviceName:  aws.String("/dev/sda1"),
-			VirtualName: aws.String("ephemeral0"),
-			Ebs: &types.EbsBlockDevice{
-				DeleteOnTermination: aws.Bool(true),
-				VolumeSize:          aws.Int32(ec.Disk),
-				VolumeType:          types.VolumeTypeGp3,
-				Iops:                ec.Iops,
-				Throughput:          ec.Throughput,
-			},
-		}},
+		LaunchTemplate:                    launchTemplate,
+		KeyName:                           keyName,
+		ImageId:                           imageId, //ARM RHEL
+		InstanceType:                      types.InstanceType(ec.InstanceType),
+		MinCount:                          aws.Int32(1),
+		MaxCount:                          aws.Int32(1),
+		EbsOptimized:                      ebsOptimized,
+		SecurityGroups:                    securityGroups,
+		SecurityGroupIds:                  securityGroupIds,
+		IamInstanceProfile:                instanceProfile,
+		SubnetId:                          subnet,
+		UserData:                          ec.UserData,
+		BlockDeviceMappings:               blockDeviceMappings,
 		InstanceInitiatedShutdownBehavior: types.ShutdownBehaviorTerminate,
 		Placement:                         placement,
 		LicenseSpecifications:             licenseSpecifications,
+		InstanceMarketOptions:             marketOptions,
 		TagSpecifications: []types.TagSpecification{
 			{ResourceType: types.ResourceTypeInstance, Tags: instanceTags},
 		},
 	}, nil
 }
 
+// hasInstanceType returns whether instance is of one of the configured instance types. Any instance type matches
+// when the instance type is left to the launch template.
+func (ec AWSEc2DynamicConfig) hasInstanceType(instance *types.Instance) bool {
+	return ec.InstanceType == "" || slices.Contains(ec.instanceTypes(), string(instance.InstanceType))
+}
+
+// instanceTypes returns the instance types instances can be launched as, in order of preference. It contains a
+// single empty instance type when the instance type is left to the launch template.
+func (ec AWSEc2DynamicConfig) instanceTypes() []string {
+	return append([]string{ec.InstanceType}, ec.FallbackInstanceTypes...)
+}
+
+// capacityErrorCodes are the EC2 error codes returned when there is not enough capacity for the instance type in
+// the requested availability zone.
+var capacityErrorCodes = []string{
+	"InsufficientInstanceCapacity",
+	"InsufficientCapacity",
+	"InsufficientHostCapacity",
+	"UnfulfillableCapacity",
+}
+
+// spotUnavailableErrorCodes are the EC2 error codes, besides capacity errors, returned when a Spot request cannot
+// be fulfilled because of the Spot instance limit or because the maximum price is below the current Spot price.
+var spotUnavailableErrorCodes = []string{
+	"MaxSpotInstanceCountExceeded",
+	"SpotMaxPriceTooLow",
+}
+
+// quotaErrorCodes are the EC2 error codes returned when launching the instance would exceed the account's
+// instance or vCPU quota for the instance type.
+var quotaErrorCodes = []string{
+	"VcpuLimitExceeded",
+	"InstanceLimitExceeded",
+}
+
+// hasErrorCode returns whether err is an EC2 API error with one of codes.
+func hasErrorCode(err error, codes []string) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return slices.Contains(codes, apiErr.ErrorCode())
+}
+
+// isCapacityError returns whether err is an EC2 API error that warrants trying another availability zone.
+func isCapacityError(err error) bool {
+	return hasErrorCode(err, capacityErrorCodes)
+}
+
+// isQuotaError returns whether err is an EC2 API error that warrants trying another instance type.
+func isQuotaError(err error) bool {
+	return hasErrorCode(err, quotaErrorCodes)
+}
+
+// isSpotUnavailable returns whether err is an EC2 API error that warrants falling back to an on-demand instance.
+func isSpotUnavailable(err error) bool {
+	return isCapacityError(err) || hasErrorCode(err, spotUnavailableErrorCodes)
+}
+
+// splitList splits a comma-separated list, ignoring surrounding whitespace and empty elements.
+func splitList(list string) []string {
+	var elements []string
+	for _, element := range strings.Split(list, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// subnetIds returns the IDs of the subnets instances can be launched in, in order of preference.
+func (ec AWSEc2DynamicConfig) subnetIds() []string {
+	if len(ec.SubnetIds) > 0 {
+		return ec.SubnetIds
+	}
+	if ec.SubnetId != "" {
+		return []string{ec.SubnetId}
+	}
+	return nil
+}
+
+// runInstance launches the instance described by launchInput. A Spot instance is tried for each of the configured
+// instance types before falling back to an on-demand instance. Each instance type is tried in each subnet in turn
+// while AWS reports a lack of capacity, and the next instance type is tried when no subnet has capacity for it or
+// the instance type is over quota. The subnet used is recorded in the SubnetTag tag of the instance.
+func (ec AWSEc2DynamicConfig) runInstance(ctx context.Context, ec2Client ec2API, launchInput *ec2.RunInstancesInput) (*ec2.RunInstancesOutput, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	marketOptions := []*types.InstanceMarketOptionsRequest{launchInput.InstanceMarketOptions}
+	if launchInput.InstanceMarketOptions != nil {
+		marketOptions = append(marketOptions, nil)
+	}
+	subnets := ec.subnetIds()
+	if len(subnets) == 0 {
+		// Leave the subnet to the default VPC or the launch template
+		subnets = []string{""}
+	}
+
+	var err error
+	for _, market := range marketOptions {
+		launchInput.InstanceMarketOptions = market
+	instanceTypes:
+		for _, instanceType := range ec.instanceTypes() {
+			launchInput.InstanceType = types.InstanceType(instanceType)
+			for _, subnet := range subnets {
+				setSubnet(launchInput, subnet)
+				var output *ec2.RunInstancesOutput
+				output, err = ec2Client.RunInstances(ctx, launchInput)
+				if err == nil {
+					return output, nil
+				}
+				if market != nil && isSpotUnavailable(err) {
+					log.Info("Spot capacity unavailable in subnet", "instanceType", instanceType, "subnetId", subnet, "reason", err.Error())
+					continue
+				}
+				if isQuotaError(err) {
+					// Quotas apply to the whole region, so other subnets will not help
+					log.Info("Instance type over quota", "instanceType", instanceType, "reason", err.Error())
+					continue instanceTypes
+				}
+				if !isCapacityError(err) {
+					return nil, err
+				}
+				log.Info("Insufficient capacity in subnet", "instanceType", instanceType, "subnetId", subnet, "reason", err.Error())
+			}
+		}
+		if market != nil {
+			log.Info("Spot capacity unavailable, falling back to an on-demand instance")
+		}
+	}
+	return nil, err
+}
+
+// setSubnet sets the subnet of launchInput to subnet and records it in the SubnetTag tag of the instance. An empty
+// subnet leaves the subnet unset.
+func setSubnet(launchInput *ec2.RunInstancesInput, subnet string) {
+	launchInput.SubnetId = nil
+	if subnet != "" {
+		launchInput.SubnetId = aws.String(subnet)
+	}
+	for i := range launchInput.TagSpecifications {
+		if launchInput.TagSpecifications[i].ResourceType != types.ResourceTypeInstance {
+			continue
+		}
+		tags := slices.DeleteFunc(slices.Clone(launchInput.TagSpecifications[i].Tags), func(tag types.Tag) bool {
+			return aws.ToString(tag.Key) == SubnetTag
+		})
+		if subnet != "" {
+			tags = append(tags, types.Tag{Key: aws.String(SubnetTag), Value: aws.String(subnet)})
+		}
+		launchInput.TagSpecifications[i].Tags = tags
+	}
+}
+
+// isSpotInterrupted returns whether instance is a Spot instance that AWS stopped or terminated to reclaim its
+// capacity. See https://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_StateReason.html for the reason codes.
+func isSpotInterrupted(instance *types.Instance) bool {
+	if instance.InstanceLifecycle != types.InstanceLifecycleTypeSpot || instance.StateReason == nil {
+		return false
+	}
+	return strings.HasPrefix(aws.ToString(instance.StateReason.Code), "Server.SpotInstance")
+}
+
+// isStopped returns whether instance is stopped or being stopped, so that it cannot run tasks until it is started.
+func isStopped(instance *types.Instance) bool {
+	return instance.State != nil && (instance.State.Name == types.InstanceStateNameStopping || instance.State.Name == types.InstanceStateNameStopped)
+}
+
+// creationTime returns when instance was created, as recorded in its CreationTimeTag tag, or its launch time if it was
+// launched without the tag.
+func creationTime(instance *types.Instance) time.Time {
+	for _, tag := range instance.Tags {
+		if aws.ToString(tag.Key) != CreationTimeTag {
+			continue
+		}
+		if created, err := time.Parse(time.RFC3339, aws.ToString(tag.Value)); err == nil {
+			return created
+		}
+	}
+	return aws.ToTime(instance.LaunchTime)
+}
+
 // A SecretCredentialsProvider is a collection of information needed to generate
 // AWS credentials. It implements the AWS CredentialsProvider interface.
 type SecretCredentialsProvider struct {
@@ -263,6 +263,96 @@ var _ = Describe("AWS EC2 Helper Functions", func() {
 			})
 		})
 
+		When("configuring instances from a launch template", func() {
+			BeforeEach(func() {
+				ecConfig = AWSEc2DynamicConfig{
+					LaunchTemplateId:      "lt-0abc123def4567890",
+					LaunchTemplateVersion: "$Latest",
+				}
+			})
+
+			It("should leave the fields that are not configured to the launch template", func() {
+				runInput, err := ecConfig.configureInstance(taskRunName, instanceTag, additionalTags)
+
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(runInput.LaunchTemplate).ShouldNot(BeNil())
+				Expect(runInput.LaunchTemplate.LaunchTemplateId).Should(PointTo(Equal("lt-0abc123def4567890")))
+				Expect(runInput.LaunchTemplate.Version).Should(PointTo(Equal("$Latest")))
+				Expect(runInput.KeyName).Should(BeNil())
+				Expect(runInput.ImageId).Should(BeNil())
+				Expect(runInput.InstanceType).Should(BeEmpty())
+				Expect(runInput.EbsOptimized).Should(BeNil())
+				Expect(runInput.SubnetId).Should(BeNil())
+				Expect(runInput.SecurityGroupIds).Should(BeEmpty())
+				Expect(runInput.IamInstanceProfile).Should(BeNil())
+				Expect(runInput.UserData).Should(BeNil())
+				Expect(runInput.BlockDeviceMappings).Should(BeEmpty())
+			})
+
+			It("should use the template's default version when no version is configured", func() {
+				ecConfig.LaunchTemplateVersion = ""
+				runInput, err := ecConfig.configureInstance(taskRunName, instanceTag, additionalTags)
+
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(runInput.LaunchTemplate.Version).Should(BeNil())
+			})
+
+			It("should override the template's settings with the configured fields", func() {
+				ecConfig.Ami = "ami-override"
+				ecConfig.InstanceType = "m6g.large"
+				ecConfig.KeyName = "override-key"
+				ecConfig.SubnetId = "subnet-override"
+				ecConfig.Disk = 100
+				runInput, err := ecConfig.configureInstance(taskRunName, instanceTag, additionalTags)
+
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(runInput.ImageId).Should(PointTo(Equal("ami-override")))
+				Expect(runInput.InstanceType).Should(Equal(types.InstanceType("m6g.large")))
+				Expect(runInput.KeyName).Should(PointTo(Equal("override-key")))
+				Expect(runInput.SubnetId).Should(PointTo(Equal("subnet-override")))
+				Expect(runInput.BlockDeviceMappings).Should(HaveLen(1))
+				Expect(runInput.BlockDeviceMappings[0].Ebs.VolumeSize).Should(PointTo(Equal(int32(100))))
+			})
+
+			It("should always tag the instance and terminate it on shutdown", func() {
+				runInput, err := ecConfig.configureInstance(taskRunName, instanceTag, additionalTags)
+
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(runInput.InstanceInitiatedShutdownBehavior).Should(Equal(types.ShutdownBehaviorTerminate))
+				Expect(runInput.TagSpecifications).Should(HaveLen(1))
+				Expect(runInput.TagSpecifications[0].Tags).Should(ContainElement(types.Tag{Key: aws.String(cloud.InstanceTag), Value: aws.String(instanceTag)}))
+			})
+		})
+
+		When("configuring Spot instances", func() {
+			It("should not request Spot capacity by default", func() {
+				runInput, err := ecConfig.configureInstance(taskRunName, instanceTag, additionalTags)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(runInput.InstanceMarketOptions).Should(BeNil())
+			})
+
+			It("should request a one-time Spot instance with the maximum price", func() {
+				ecConfig.Spot = true
+				ecConfig.SpotMaxPrice = "0.05"
+				runInput, err := ecConfig.configureInstance(taskRunName, instanceTag, additionalTags)
+
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(runInput.InstanceMarketOptions).ShouldNot(BeNil())
+				Expect(runInput.InstanceMarketOptions.MarketType).Should(Equal(types.MarketTypeSpot))
+				Expect(runInput.InstanceMarketOptions.SpotOptions.SpotInstanceType).Should(Equal(types.SpotInstanceTypeOneTime))
+				Expect(runInput.InstanceMarketOptions.SpotOptions.InstanceInterruptionBehavior).Should(Equal(types.InstanceInterruptionBehaviorTerminate))
+				Expect(runInput.InstanceMarketOptions.SpotOptions.MaxPrice).Should(PointTo(Equal("0.05")))
+			})
+
+			It("should default the maximum price to the on-demand price", func() {
+				ecConfig.Spot = true
+				runInput, err := ecConfig.configureInstance(taskRunName, instanceTag, additionalTags)
+
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(runInput.InstanceMarketOptions.SpotOptions.MaxPrice).Should(BeNil())
+			})
+		})
+
 		When("configuring MacOS dedicated host instances", func() {
 			It("should successfully configure when all three MacOS fields are set", func() {
 				ecConfig.Tenancy = "host"
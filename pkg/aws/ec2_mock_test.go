@@ -2,22 +2,54 @@ package aws
 
 import (
 	"context"
+	"slices"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
 )
 
 // mockEC2Client satisfies the ec2API interface for testing.
 // Output/Err fields control return values; Input fields capture the last call.
 type mockEC2Client struct {
-	RunInstancesOutput      *ec2.RunInstancesOutput
-	RunInstancesErr         error
-	DescribeInstancesOutput *ec2.DescribeInstancesOutput
-	DescribeInstancesErr    error
-	DescribeInstancesInput  *ec2.DescribeInstancesInput
-	TerminateInstancesErr   error
+	RunInstancesOutput     *ec2.RunInstancesOutput
+	RunInstancesErr        error
+	RunInstancesInputs     []*ec2.RunInstancesInput
+	SpotRunInstancesErr    error
+	SubnetRunInstancesErrs map[string]error
+	// InstanceTypeRunInstancesErrs are returned for launches of the instance types, after SpotRunInstancesErr
+	InstanceTypeRunInstancesErrs map[string]error
+	DescribeInstancesOutput      *ec2.DescribeInstancesOutput
+	DescribeInstancesErr         error
+	DescribeInstancesInput       *ec2.DescribeInstancesInput
+	TerminateInstancesErr        error
+	StopInstancesInput           *ec2.StopInstancesInput
+	StopInstancesErr             error
+	StartInstancesInput          *ec2.StartInstancesInput
+	StartInstancesErr            error
+	DescribeImagesOutput         *ec2.DescribeImagesOutput
+	DescribeImagesErr            error
+	DescribeImagesInputs         []*ec2.DescribeImagesInput
 }
 
-func (m *mockEC2Client) RunInstances(_ context.Context, _ *ec2.RunInstancesInput, _ ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error) {
+func (m *mockEC2Client) RunInstances(_ context.Context, input *ec2.RunInstancesInput, _ ...func(*ec2.Options)) (*ec2.RunInstancesOutput, error) {
+	// Copy the input since LaunchInstance may change it between calls
+	captured := *input
+	captured.TagSpecifications = slices.Clone(input.TagSpecifications)
+	m.RunInstancesInputs = append(m.RunInstancesInputs, &captured)
+	if input.InstanceMarketOptions != nil && m.SpotRunInstancesErr != nil {
+		return nil, m.SpotRunInstancesErr
+	}
+	if err := m.InstanceTypeRunInstancesErrs[string(input.InstanceType)]; err != nil {
+		return nil, err
+	}
+	if err := m.SubnetRunInstancesErrs[aws.ToString(input.SubnetId)]; err != nil {
+		return nil, err
+	}
 	return m.RunInstancesOutput, m.RunInstancesErr
 }
 
@@ -29,3 +61,61 @@ func (m *mockEC2Client) DescribeInstances(_ context.Context, input *ec2.Describe
 func (m *mockEC2Client) TerminateInstances(_ context.Context, _ *ec2.TerminateInstancesInput, _ ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
 	return nil, m.TerminateInstancesErr
 }
+
+func (m *mockEC2Client) StopInstances(_ context.Context, input *ec2.StopInstancesInput, _ ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error) {
+	m.StopInstancesInput = input
+	return nil, m.StopInstancesErr
+}
+
+func (m *mockEC2Client) StartInstances(_ context.Context, input *ec2.StartInstancesInput, _ ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	m.StartInstancesInput = input
+	return nil, m.StartInstancesErr
+}
+
+func (m *mockEC2Client) DescribeImages(_ context.Context, input *ec2.DescribeImagesInput, _ ...func(*ec2.Options)) (*ec2.DescribeImagesOutput, error) {
+	m.DescribeImagesInputs = append(m.DescribeImagesInputs, input)
+	return m.DescribeImagesOutput, m.DescribeImagesErr
+}
+
+// mockSTSClient satisfies the stsAPI interface for testing. Each call issues credentials that expire after
+// Expiry; Input fields capture the calls.
+type mockSTSClient struct {
+	Expiry                          time.Duration
+	AssumeRoleInputs                []*sts.AssumeRoleInput
+	AssumeRoleWithWebIdentityInputs []*sts.AssumeRoleWithWebIdentityInput
+}
+
+func (m *mockSTSClient) credentials() *ststypes.Credentials {
+	return &ststypes.Credentials{
+		AccessKeyId:     aws.String("ASIA-TEMPORARY"),
+		SecretAccessKey: aws.String("temporary-secret"),
+		SessionToken:    aws.String("temporary-token"),
+		Expiration:      aws.Time(time.Now().Add(m.Expiry)),
+	}
+}
+
+func (m *mockSTSClient) AssumeRole(_ context.Context, input *sts.AssumeRoleInput, _ ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	m.AssumeRoleInputs = append(m.AssumeRoleInputs, input)
+	return &sts.AssumeRoleOutput{Credentials: m.credentials()}, nil
+}
+
+func (m *mockSTSClient) AssumeRoleWithWebIdentity(_ context.Context, input *sts.AssumeRoleWithWebIdentityInput, _ ...func(*sts.Options)) (*sts.AssumeRoleWithWebIdentityOutput, error) {
+	m.AssumeRoleWithWebIdentityInputs = append(m.AssumeRoleWithWebIdentityInputs, input)
+	return &sts.AssumeRoleWithWebIdentityOutput{Credentials: m.credentials()}, nil
+}
+
+// mockSSMClient satisfies the ssmAPI interface for testing. Parameters holds the values of the parameters;
+// GetParameterInputs captures the calls.
+type mockSSMClient struct {
+	Parameters         map[string]string
+	GetParameterInputs []*ssm.GetParameterInput
+}
+
+func (m *mockSSMClient) GetParameter(_ context.Context, input *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	m.GetParameterInputs = append(m.GetParameterInputs, input)
+	value, ok := m.Parameters[aws.ToString(input.Name)]
+	if !ok {
+		return nil, &ssmtypes.ParameterNotFound{}
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssmtypes.Parameter{Name: input.Name, Value: aws.String(value)}}, nil
+}
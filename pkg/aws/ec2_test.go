@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
 	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -33,21 +34,26 @@ var _ = Describe("Ec2 Unit Test Suit", func() {
 		DescribeTable("Testing the creation of AwsDynamicConfig properly no matter the values",
 			func(platformName string, testConfig map[string]string, expectedDisk int, expectedIops *int32, expectedThroughput *int32, expectedUserData *string, expectedStrictPublicAddress bool) {
 				config := map[string]string{
-					"dynamic." + platformName + ".region":                "test-region",
-					"dynamic." + platformName + ".ami":                   "test-ami",
-					"dynamic." + platformName + ".instance-type":         "test-instance-type",
-					"dynamic." + platformName + ".key-name":              "test-key-name",
-					"dynamic." + platformName + ".aws-secret":            "test-secret",
-					"dynamic." + platformName + ".security-group":        "test-security-group",
-					"dynamic." + platformName + ".security-group-id":     "test-security-group-id",
-					"dynamic." + platformName + ".subnet-id":             "test-subnet-id",
-					"dynamic." + platformName + ".instance-profile-name": "test-instance-profile-name",
-					"dynamic." + platformName + ".instance-profile-arn":  "test-instance-profile-arn",
-					"dynamic." + platformName + ".disk":                  testConfig["disk"],
-					"dynamic." + platformName + ".iops":                  testConfig["iops"],
-					"dynamic." + platformName + ".throughput":            testConfig["throughput"],
-					"dynamic." + platformName + ".user-data":             testConfig["user-data"],
-					"dynamic." + platformName + ".strict-public-address": testConfig["strict-public-address"],
+					"dynamic." + platformName + ".region":                  "test-region",
+					"dynamic." + platformName + ".ami":                     "test-ami",
+					"dynamic." + platformName + ".instance-type":           "test-instance-type",
+					"dynamic." + platformName + ".key-name":                "test-key-name",
+					"dynamic." + platformName + ".aws-secret":              "test-secret",
+					"dynamic." + platformName + ".security-group":          "test-security-group",
+					"dynamic." + platformName + ".security-group-id":       "test-security-group-id",
+					"dynamic." + platformName + ".subnet-id":               "test-subnet-id",
+					"dynamic." + platformName + ".instance-profile-name":   "test-instance-profile-name",
+					"dynamic." + platformName + ".instance-profile-arn":    "test-instance-profile-arn",
+					"dynamic." + platformName + ".disk":                    testConfig["disk"],
+					"dynamic." + platformName + ".iops":                    testConfig["iops"],
+					"dynamic." + platformName + ".throughput":              testConfig["throughput"],
+					"dynamic." + platformName + ".user-data":               testConfig["user-data"],
+					"dynamic." + platformName + ".strict-public-address":   testConfig["strict-public-address"],
+					"dynamic." + platformName + ".spot":                    testConfig["spot"],
+					"dynamic." + platformName + ".spot-max-price":          testConfig["spot-max-price"],
+					"dynamic." + platformName + ".launch-template-id":      testConfig["launch-template-id"],
+					"dynamic." + platformName + ".launch-template-version": testConfig["launch-template-version"],
+					"dynamic." + platformName + ".subnet-ids":              testConfig["subnet-ids"],
 				}
 				provider := CreateEc2CloudConfig(platformName, config, systemNamespace)
 				Expect(provider).ToNot(BeNil())
@@ -62,6 +68,7 @@ var _ = Describe("Ec2 Unit Test Suit", func() {
 				Expect(providerConfig.SecurityGroup).To(Equal("test-security-group"))
 				Expect(providerConfig.SecurityGroupId).To(Equal("test-security-group-id"))
 				Expect(providerConfig.SubnetId).To(Equal("test-subnet-id"))
+				Expect(providerConfig.SubnetIds).To(Equal(splitList(testConfig["subnet-ids"])))
 				Expect(providerConfig.InstanceProfileName).To(Equal("test-instance-profile-name"))
 				Expect(providerConfig.InstanceProfileArn).To(Equal("test-instance-profile-arn"))
 				Expect(providerConfig.Disk).To(Equal(int32(expectedDisk)))
@@ -69,13 +76,20 @@ var _ = Describe("Ec2 Unit Test Suit", func() {
 				Expect(providerConfig.Throughput).To(Equal(expectedThroughput))
 				Expect(providerConfig.UserData).Should(SatisfyAny(Equal(stringEncode(expectedUserData)), BeNil()))
 				Expect(providerConfig.StrictPublicAddress).To(Equal(expectedStrictPublicAddress))
+				Expect(providerConfig.Spot).To(Equal(testConfig["spot"] == "true"))
+				Expect(providerConfig.SpotMaxPrice).To(Equal(testConfig["spot-max-price"]))
+				Expect(providerConfig.LaunchTemplateId).To(Equal(testConfig["launch-template-id"]))
+				Expect(providerConfig.LaunchTemplateVersion).To(Equal(testConfig["launch-template-version"]))
 			},
 			Entry("Positive - valid config map keys", "linux-largecpu-x86_64", map[string]string{
 				"disk":                  "200",
 				"iops":                  "100",
 				"throughput":            "50",
 				"user-data":             commonUserData,
-				"strict-public-address": "true"},
+				"strict-public-address": "true",
+				"spot":                  "true",
+				"spot-max-price":        "0.05",
+				"subnet-ids":            "subnet-a, subnet-b,"},
 				200, aws.Int32(100), aws.Int32(50), aws.String(commonUserData), true),
 			Entry("Negative - nonexistant platform name", "koko-hazamar", map[string]string{
 				"disk":                  "200",
@@ -98,6 +112,49 @@ var _ = Describe("Ec2 Unit Test Suit", func() {
 				"user-data":             commonUserData,
 				"strict-public-address": "invalid"},
 				40, nil, nil, aws.String(commonUserData), false),
+			Entry("Positive - launch template keeps its volumes without a disk override", "linux-m2xlarge-arm64", map[string]string{
+				"launch-template-id":      "lt-0abc123def4567890",
+				"launch-template-version": "$Latest"},
+				0, nil, nil, aws.String(""), false),
+			Entry("Positive - launch template with a disk override", "linux-m2xlarge-arm64", map[string]string{
+				"disk":               "100",
+				"launch-template-id": "lt-0abc123def4567890"},
+				100, nil, nil, aws.String(""), false),
+		)
+
+		DescribeTable("Testing the parsing of the instance type and its fallback instance types",
+			func(instanceType string, expectedInstanceType string, expectedFallbackInstanceTypes []string) {
+				config := map[string]string{"dynamic.linux-arm64.instance-type": instanceType}
+				providerConfig := CreateEc2CloudConfig("linux-arm64", config, systemNamespace).(AWSEc2DynamicConfig)
+
+				Expect(providerConfig.InstanceType).To(Equal(expectedInstanceType))
+				Expect(providerConfig.FallbackInstanceTypes).To(Equal(expectedFallbackInstanceTypes))
+			},
+			Entry("a single instance type", "m6g.large", "m6g.large", nil),
+			Entry("an ordered list of instance types", "m6g.large, m7g.large,c6g.large", "m6g.large", []string{"m7g.large", "c6g.large"}),
+			Entry("no instance type", "", "", nil),
+		)
+
+		DescribeTable("Testing the parsing of the AMI resolution settings",
+			func(testConfig map[string]string, expectedAmiCacheTTL time.Duration) {
+				config := map[string]string{}
+				for key, value := range testConfig {
+					config["dynamic.linux-arm64."+key] = value
+				}
+				providerConfig := CreateEc2CloudConfig("linux-arm64", config, systemNamespace).(AWSEc2DynamicConfig)
+
+				Expect(providerConfig.AmiSsmParameter).To(Equal(testConfig["ami-ssm-parameter"]))
+				Expect(providerConfig.AmiOwner).To(Equal(testConfig["ami-owner"]))
+				Expect(providerConfig.AmiName).To(Equal(testConfig["ami-name"]))
+				Expect(providerConfig.AmiCacheTTL).To(Equal(expectedAmiCacheTTL))
+			},
+			Entry("an SSM parameter with a cache period", map[string]string{
+				"ami-ssm-parameter": "/aws/service/ami-amazon-linux-latest/al2023-ami-kernel-default-arm64",
+				"ami-cache-ttl":     "15m"}, 15*time.Minute),
+			Entry("an owner and a name filter with the default cache period", map[string]string{
+				"ami-owner": "amazon",
+				"ami-name":  "al2023-ami-2023.*-arm64"}, defaultAmiCacheTTL),
+			Entry("an invalid cache period", map[string]string{"ami-cache-ttl": "soon"}, defaultAmiCacheTTL),
 		)
 	})
 
@@ -139,6 +196,18 @@ var _ = Describe("Ec2 Unit Test Suit", func() {
 					Expect(err).ShouldNot(HaveOccurred())
 					Expect(string(id)).Should(Equal("i-abc123"))
 				})
+
+				It("should record when the instance was created", func(ctx SpecContext) {
+					mock.RunInstancesOutput = &ec2.RunInstancesOutput{
+						Instances: []types.Instance{{InstanceId: aws.String("i-abc123")}},
+					}
+
+					_, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+					Expect(err).ShouldNot(HaveOccurred())
+					instance := types.Instance{Tags: mock.RunInstancesInputs[0].TagSpecifications[0].Tags}
+					Expect(creationTime(&instance)).Should(BeTemporally("~", time.Now(), time.Minute))
+				})
 			})
 
 			When("the EC2 API returns an error", func() {
@@ -161,6 +230,199 @@ var _ = Describe("Ec2 Unit Test Suit", func() {
 				})
 			})
 
+			When("Spot instances are requested", func() {
+				BeforeEach(func() {
+					cfg.Spot = true
+					cfg.SpotMaxPrice = "0.05"
+					mock.RunInstancesOutput = &ec2.RunInstancesOutput{
+						Instances: []types.Instance{{InstanceId: aws.String("i-abc123")}},
+					}
+				})
+
+				It("should launch a Spot instance", func(ctx SpecContext) {
+					id, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(string(id)).Should(Equal("i-abc123"))
+					Expect(mock.RunInstancesInputs).Should(HaveLen(1))
+					Expect(mock.RunInstancesInputs[0].InstanceMarketOptions).ShouldNot(BeNil())
+				})
+
+				DescribeTable("should fall back to an on-demand instance when Spot is unavailable",
+					func(ctx SpecContext, code string) {
+						mock.SpotRunInstancesErr = &smithy.GenericAPIError{Code: code, Message: "no Spot for you"}
+
+						id, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(string(id)).Should(Equal("i-abc123"))
+						Expect(mock.RunInstancesInputs).Should(HaveLen(2))
+						Expect(mock.RunInstancesInputs[0].InstanceMarketOptions).ShouldNot(BeNil())
+						Expect(mock.RunInstancesInputs[1].InstanceMarketOptions).Should(BeNil())
+					},
+					Entry("with insufficient capacity", "InsufficientInstanceCapacity"),
+					Entry("with a maximum price below the Spot price", "SpotMaxPriceTooLow"),
+					Entry("with the Spot instance limit reached", "MaxSpotInstanceCountExceeded"),
+				)
+
+				It("should not fall back to an on-demand instance for other errors", func(ctx SpecContext) {
+					mock.SpotRunInstancesErr = &smithy.GenericAPIError{Code: "InvalidAMIID.NotFound", Message: "no such AMI"}
+
+					_, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+					Expect(err).Should(MatchError(ContainSubstring("failed to launch EC2 instance")))
+					Expect(mock.RunInstancesInputs).Should(HaveLen(1))
+				})
+			})
+
+			When("several subnets are configured", func() {
+				capacityErr := &smithy.GenericAPIError{Code: "InsufficientInstanceCapacity", Message: "no capacity in this zone"}
+
+				// subnetTag returns the value of the SubnetTag tag of input, or an empty string.
+				subnetTag := func(input *ec2.RunInstancesInput) string {
+					for _, tag := range input.TagSpecifications[0].Tags {
+						if aws.ToString(tag.Key) == SubnetTag {
+							return aws.ToString(tag.Value)
+						}
+					}
+					return ""
+				}
+				usedSubnets := func() []string {
+					var subnets []string
+					for _, input := range mock.RunInstancesInputs {
+						subnets = append(subnets, aws.ToString(input.SubnetId))
+					}
+					return subnets
+				}
+
+				BeforeEach(func() {
+					cfg.SubnetIds = []string{"subnet-a", "subnet-b", "subnet-c"}
+					mock.RunInstancesOutput = &ec2.RunInstancesOutput{
+						Instances: []types.Instance{{InstanceId: aws.String("i-abc123")}},
+					}
+				})
+
+				It("should launch in the first subnet and record it on the instance", func(ctx SpecContext) {
+					_, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(usedSubnets()).Should(Equal([]string{"subnet-a"}))
+					Expect(subnetTag(mock.RunInstancesInputs[0])).Should(Equal("subnet-a"))
+				})
+
+				It("should try the next subnet when a subnet has no capacity", func(ctx SpecContext) {
+					mock.SubnetRunInstancesErrs = map[string]error{"subnet-a": capacityErr}
+
+					id, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(string(id)).Should(Equal("i-abc123"))
+					Expect(usedSubnets()).Should(Equal([]string{"subnet-a", "subnet-b"}))
+					Expect(subnetTag(mock.RunInstancesInputs[0])).Should(Equal("subnet-a"))
+					Expect(subnetTag(mock.RunInstancesInputs[1])).Should(Equal("subnet-b"))
+				})
+
+				It("should return the last error when no subnet has capacity", func(ctx SpecContext) {
+					mock.SubnetRunInstancesErrs = map[string]error{"subnet-a": capacityErr, "subnet-b": capacityErr, "subnet-c": capacityErr}
+
+					_, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+					Expect(err).Should(MatchError(ContainSubstring("InsufficientInstanceCapacity")))
+					Expect(usedSubnets()).Should(Equal([]string{"subnet-a", "subnet-b", "subnet-c"}))
+				})
+
+				It("should not try other subnets for errors other than capacity errors", func(ctx SpecContext) {
+					mock.SubnetRunInstancesErrs = map[string]error{"subnet-a": &smithy.GenericAPIError{Code: "UnauthorizedOperation", Message: "denied"}}
+
+					_, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+					Expect(err).Should(MatchError(ContainSubstring("failed to launch EC2 instance")))
+					Expect(usedSubnets()).Should(Equal([]string{"subnet-a"}))
+				})
+
+				It("should try Spot instances in every subnet before falling back to on-demand instances", func(ctx SpecContext) {
+					cfg.Spot = true
+					mock.SpotRunInstancesErr = capacityErr
+					mock.SubnetRunInstancesErrs = map[string]error{"subnet-a": capacityErr}
+
+					_, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(usedSubnets()).Should(Equal([]string{"subnet-a", "subnet-b", "subnet-c", "subnet-a", "subnet-b"}))
+					Expect(mock.RunInstancesInputs[2].InstanceMarketOptions).ShouldNot(BeNil())
+					Expect(mock.RunInstancesInputs[3].InstanceMarketOptions).Should(BeNil())
+				})
+			})
+
+			When("fallback instance types are configured", func() {
+				capacityErr := &smithy.GenericAPIError{Code: "InsufficientInstanceCapacity", Message: "no capacity in this zone"}
+				quotaErr := &smithy.GenericAPIError{Code: "VcpuLimitExceeded", Message: "vCPU limit exceeded"}
+
+				// usedLaunches returns the instance type and subnet of each launch attempt.
+				usedLaunches := func() []string {
+					var launches []string
+					for _, input := range mock.RunInstancesInputs {
+						launches = append(launches, string(input.InstanceType)+"/"+aws.ToString(input.SubnetId))
+					}
+					return launches
+				}
+
+				BeforeEach(func() {
+					cfg.FallbackInstanceTypes = []string{"m6g.large", "m7g.large"}
+					cfg.SubnetIds = []string{"subnet-a", "subnet-b"}
+					mock.RunInstancesOutput = &ec2.RunInstancesOutput{
+						Instances: []types.Instance{{InstanceId: aws.String("i-abc123")}},
+					}
+				})
+
+				It("should launch the preferred instance type when it is available", func(ctx SpecContext) {
+					_, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(usedLaunches()).Should(Equal([]string{"t4g.medium/subnet-a"}))
+				})
+
+				It("should try the next instance type when no subnet has capacity for an instance type", func(ctx SpecContext) {
+					mock.InstanceTypeRunInstancesErrs = map[string]error{"t4g.medium": capacityErr}
+
+					id, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(string(id)).Should(Equal("i-abc123"))
+					Expect(usedLaunches()).Should(Equal([]string{"t4g.medium/subnet-a", "t4g.medium/subnet-b", "m6g.large/subnet-a"}))
+				})
+
+				It("should try the next instance type without trying other subnets when an instance type is over quota", func(ctx SpecContext) {
+					mock.InstanceTypeRunInstancesErrs = map[string]error{"t4g.medium": quotaErr, "m6g.large": capacityErr}
+
+					_, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(usedLaunches()).Should(Equal([]string{"t4g.medium/subnet-a", "m6g.large/subnet-a", "m6g.large/subnet-b", "m7g.large/subnet-a"}))
+				})
+
+				It("should return the last error when no instance type is available", func(ctx SpecContext) {
+					mock.InstanceTypeRunInstancesErrs = map[string]error{"t4g.medium": quotaErr, "m6g.large": quotaErr, "m7g.large": capacityErr}
+
+					_, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+					Expect(err).Should(MatchError(ContainSubstring("InsufficientInstanceCapacity")))
+					Expect(usedLaunches()).Should(HaveLen(4))
+				})
+
+				It("should try Spot instances of every instance type before falling back to on-demand instances", func(ctx SpecContext) {
+					cfg.Spot = true
+					cfg.SubnetIds = nil
+					mock.SpotRunInstancesErr = capacityErr
+
+					_, err := cfg.LaunchInstance(nil, ctx, "ns:task", "tag", map[string]string{})
+
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(usedLaunches()).Should(Equal([]string{"t4g.medium/", "m6g.large/", "m7g.large/", "t4g.medium/"}))
+					Expect(mock.RunInstancesInputs[3].InstanceMarketOptions).Should(BeNil())
+				})
+			})
+
 			When("the TaskRun ID is invalid", func() {
 				It("should return a validation error", func(ctx SpecContext) {
 					_, err := cfg.LaunchInstance(nil, ctx, "invalid-no-colon", "tag", map[string]string{})
@@ -235,6 +497,37 @@ var _ = Describe("Ec2 Unit Test Suit", func() {
 				),
 			)
 
+			When("the instance type is left to the launch template", func() {
+				It("should count instances of any type", func(ctx SpecContext) {
+					cfg.InstanceType = ""
+					cfg.LaunchTemplateId = "lt-0abc123def4567890"
+					mock.DescribeInstancesOutput = &ec2.DescribeInstancesOutput{
+						Reservations: []types.Reservation{{Instances: []types.Instance{
+							{InstanceId: aws.String("i-1"), State: &types.InstanceState{Name: types.InstanceStateNameRunning}, InstanceType: "m5.large"},
+							{InstanceId: aws.String("i-2"), State: &types.InstanceState{Name: types.InstanceStateNameRunning}, InstanceType: "t4g.medium"},
+							{InstanceId: aws.String("i-3"), State: &types.InstanceState{Name: types.InstanceStateNameTerminated}, InstanceType: "t4g.medium"},
+						}}},
+					}
+
+					Expect(cfg.CountInstances(nil, ctx, "tag")).Should(Equal(2))
+				})
+			})
+
+			When("fallback instance types are configured", func() {
+				It("should count instances of all the instance types", func(ctx SpecContext) {
+					cfg.FallbackInstanceTypes = []string{"m6g.large"}
+					mock.DescribeInstancesOutput = &ec2.DescribeInstancesOutput{
+						Reservations: []types.Reservation{{Instances: []types.Instance{
+							{InstanceId: aws.String("i-1"), State: &types.InstanceState{Name: types.InstanceStateNameRunning}, InstanceType: "t4g.medium"},
+							{InstanceId: aws.String("i-2"), State: &types.InstanceState{Name: types.InstanceStateNameRunning}, InstanceType: "m6g.large"},
+							{InstanceId: aws.String("i-3"), State: &types.InstanceState{Name: types.InstanceStateNameRunning}, InstanceType: "m5.large"},
+						}}},
+					}
+
+					Expect(cfg.CountInstances(nil, ctx, "tag")).Should(Equal(2))
+				})
+			})
+
 			When("the EC2 API returns an error", func() {
 				It("should return -1 and the error", func(ctx SpecContext) {
 					mock.DescribeInstancesErr = errors.New("api failure")
@@ -263,6 +556,73 @@ var _ = Describe("Ec2 Unit Test Suit", func() {
 			})
 		})
 
+		Describe("StopInstance and StartInstance", func() {
+			It("should stop and start the instance", func(ctx SpecContext) {
+				Expect(cfg.StopInstance(nil, ctx, "i-123")).Should(Succeed())
+				Expect(mock.StopInstancesInput.InstanceIds).Should(ConsistOf("i-123"))
+				Expect(cfg.StartInstance(nil, ctx, "i-123")).Should(Succeed())
+				Expect(mock.StartInstancesInput.InstanceIds).Should(ConsistOf("i-123"))
+			})
+
+			It("should return the errors", func(ctx SpecContext) {
+				mock.StopInstancesErr = errors.New("stop failed")
+				mock.StartInstancesErr = errors.New("insufficient host capacity")
+
+				Expect(cfg.StopInstance(nil, ctx, "i-123")).Should(MatchError("stop failed"))
+				Expect(cfg.StartInstance(nil, ctx, "i-123")).Should(MatchError("insufficient host capacity"))
+			})
+		})
+
+		Describe("ListStoppedInstances", func() {
+			It("should return only the stopped instances of the instance type", func(ctx SpecContext) {
+				now := time.Now()
+				mock.DescribeInstancesOutput = &ec2.DescribeInstancesOutput{
+					Reservations: []types.Reservation{{
+						Instances: []types.Instance{
+							{InstanceId: aws.String("i-stopped"), State: &types.InstanceState{Name: types.InstanceStateNameStopped}, InstanceType: "t4g.medium", LaunchTime: &now, Tags: []types.Tag{{Key: aws.String(cloud.TaskRunTagKey), Value: aws.String("test-ns:test-taskrun")}}},
+							{InstanceId: aws.String("i-wrong-type"), State: &types.InstanceState{Name: types.InstanceStateNameStopped}, InstanceType: "m5.large", LaunchTime: &now},
+						},
+					}},
+				}
+
+				instances, err := cfg.ListStoppedInstances(nil, ctx, "tag")
+
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instances).Should(HaveLen(1))
+				Expect(string(instances[0].InstanceId)).Should(Equal("i-stopped"))
+				Expect(instances[0].TaskRunID).Should(Equal("test-ns:test-taskrun"))
+				Expect(mock.DescribeInstancesInput.Filters).Should(ContainElement(types.Filter{Name: aws.String("instance-state-name"), Values: []string{"stopped"}}))
+			})
+
+			It("should return when the instances were created rather than last started", func(ctx SpecContext) {
+				now := time.Now()
+				created := now.Add(-48 * time.Hour).Truncate(time.Second)
+				mock.DescribeInstancesOutput = &ec2.DescribeInstancesOutput{
+					Reservations: []types.Reservation{{
+						Instances: []types.Instance{
+							{InstanceId: aws.String("i-restarted"), State: &types.InstanceState{Name: types.InstanceStateNameStopped}, InstanceType: "t4g.medium", LaunchTime: &now, Tags: []types.Tag{{Key: aws.String(CreationTimeTag), Value: aws.String(created.Format(time.RFC3339))}}},
+							{InstanceId: aws.String("i-untagged"), State: &types.InstanceState{Name: types.InstanceStateNameStopped}, InstanceType: "t4g.medium", LaunchTime: &now},
+						},
+					}},
+				}
+
+				instances, err := cfg.ListStoppedInstances(nil, ctx, "tag")
+
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(instances).Should(HaveLen(2))
+				Expect(instances[0].StartTime).Should(BeTemporally("==", created))
+				Expect(instances[1].StartTime).Should(BeTemporally("==", now))
+			})
+
+			It("should return the error", func(ctx SpecContext) {
+				mock.DescribeInstancesErr = errors.New("api failure")
+
+				_, err := cfg.ListStoppedInstances(nil, ctx, "tag")
+
+				Expect(err).Should(MatchError(ContainSubstring("failed to retrieve stopped EC2 instances")))
+			})
+		})
+
 		Describe("GetInstanceAddress", func() {
 			When("DescribeInstances returns an error", func() {
 				It("should return empty string without error (transient)", func(ctx SpecContext) {
@@ -287,6 +647,35 @@ var _ = Describe("Ec2 Unit Test Suit", func() {
 			})
 		})
 
+		Describe("GetInstanceDetails", func() {
+			It("should return the instance type and AMI the instance was launched with", func(ctx SpecContext) {
+				mock.DescribeInstancesOutput = &ec2.DescribeInstancesOutput{
+					Reservations: []types.Reservation{{Instances: []types.Instance{
+						{InstanceId: aws.String("i-123"), InstanceType: "m6g.large", ImageId: aws.String("ami-0abc")},
+					}}},
+				}
+
+				Expect(cfg.GetInstanceDetails(nil, ctx, "i-123")).Should(Equal(cloud.InstanceDetails{InstanceType: "m6g.large", Image: "ami-0abc"}))
+				Expect(mock.DescribeInstancesInput.InstanceIds).Should(ConsistOf("i-123"))
+			})
+
+			It("should return an error when the instance does not exist", func(ctx SpecContext) {
+				mock.DescribeInstancesOutput = &ec2.DescribeInstancesOutput{}
+
+				_, err := cfg.GetInstanceDetails(nil, ctx, "i-123")
+
+				Expect(err).Should(MatchError(ContainSubstring("instance i-123 not found")))
+			})
+
+			It("should return the error when DescribeInstances fails", func(ctx SpecContext) {
+				mock.DescribeInstancesErr = errors.New("api error")
+
+				_, err := cfg.GetInstanceDetails(nil, ctx, "i-123")
+
+				Expect(err).Should(MatchError(ContainSubstring("api error")))
+			})
+		})
+
 		Describe("GetState", func() {
 			DescribeTable("instance state mapping",
 				func(ctx SpecContext, stateName types.InstanceStateName) {
@@ -311,6 +700,33 @@ var _ = Describe("Ec2 Unit Test Suit", func() {
 				Entry("stopping instance should return OKState", types.InstanceStateNameStopping),
 			)
 
+			DescribeTable("Spot instance interruption",
+				func(ctx SpecContext, lifecycle types.InstanceLifecycleType, reasonCode string, expectedState cloud.VMState) {
+					mock.DescribeInstancesOutput = &ec2.DescribeInstancesOutput{
+						Reservations: []types.Reservation{{
+							Instances: []types.Instance{{
+								State:             &types.InstanceState{Name: types.InstanceStateNameTerminated},
+								InstanceLifecycle: lifecycle,
+								StateReason:       &types.StateReason{Code: aws.String(reasonCode), Message: aws.String(reasonCode)},
+							}},
+						}},
+					}
+
+					state, err := cfg.GetState(nil, ctx, "i-123")
+
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(state).Should(Equal(expectedState))
+				},
+				Entry("terminated Spot instance reclaimed by AWS should return InterruptedState",
+					types.InstanceLifecycleTypeSpot, "Server.SpotInstanceTermination", cloud.InterruptedState),
+				Entry("stopped Spot instance reclaimed by AWS should return InterruptedState",
+					types.InstanceLifecycleTypeSpot, "Server.SpotInstanceShutdown", cloud.InterruptedState),
+				Entry("Spot instance terminated by the user should return OKState",
+					types.InstanceLifecycleTypeSpot, "Client.UserInitiatedShutdown", cloud.OKState),
+				Entry("on-demand instance should return OKState",
+					types.InstanceLifecycleType(""), "Client.UserInitiatedShutdown", cloud.OKState),
+			)
+
 			When("DescribeInstances returns an error", func() {
 				It("should return empty state without error (transient)", func(ctx SpecContext) {
 					mock.DescribeInstancesErr = errors.New("api error")
@@ -363,9 +779,10 @@ var _ = Describe("Ec2 Unit Test Suit", func() {
 					mock.DescribeInstancesOutput = &ec2.DescribeInstancesOutput{
 						Reservations: []types.Reservation{{
 							Instances: []types.Instance{
-								{InstanceId: aws.String("i-match"), State: &types.InstanceState{Name: types.InstanceStateNameRunning}, InstanceType: "t4g.medium", PublicIpAddress: aws.String("10.0.0.1"), LaunchTime: &now},
+								{InstanceId: aws.String("i-match"), State: &types.InstanceState{Name: types.InstanceStateNameRunning}, InstanceType: "t4g.medium", PublicIpAddress: aws.String("10.0.0.1"), LaunchTime: &now, Tags: []types.Tag{{Key: aws.String(cloud.TaskRunTagKey), Value: aws.String("test-ns:test-taskrun")}}},
 								{InstanceId: aws.String("i-terminated"), State: &types.InstanceState{Name: types.InstanceStateNameTerminated}, InstanceType: "t4g.medium", PublicIpAddress: aws.String("10.0.0.2"), LaunchTime: &now},
 								{InstanceId: aws.String("i-wrong-type"), State: &types.InstanceState{Name: types.InstanceStateNameRunning}, InstanceType: "m5.large", PublicIpAddress: aws.String("10.0.0.3"), LaunchTime: &now},
+								{InstanceId: aws.String("i-stopping"), State: &types.InstanceState{Name: types.InstanceStateNameStopping}, InstanceType: "t4g.medium", PublicIpAddress: aws.String("10.0.0.4"), LaunchTime: &now},
 							},
 						}},
 					}
@@ -377,6 +794,7 @@ var _ = Describe("Ec2 Unit Test Suit", func() {
 					Expect(string(instances[0].InstanceId)).Should(Equal("i-match"))
 					Expect(instances[0].Address).Should(Equal("10.0.0.1"))
 					Expect(instances[0].StartTime).Should(BeTemporally("~", now, time.Second))
+					Expect(instances[0].TaskRunID).Should(Equal("test-ns:test-taskrun"))
 				})
 			})
 		})
@@ -0,0 +1,291 @@
+// Package azure implements methods described in the [cloud] package for interacting with Microsoft Azure instances.
+// Currently only Azure Virtual Machines are supported.
+//
+// All methods of the CloudProvider interface are implemented and separated from other helper functions used
+// across the methods.
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// MultiPlatformManaged is the Azure tag marking VMs that were created by this controller.
+	MultiPlatformManaged = "MultiPlatformManaged"
+
+	defaultSshUser  = "azureuser"
+	defaultDiskType = "StandardSSD_LRS"
+)
+
+// CreateAzureCloudConfig returns an Azure Virtual Machines cloud configuration that implements the CloudProvider interface.
+func CreateAzureCloudConfig(platformName string, config map[string]string, systemNamespace string) cloud.CloudProvider {
+	disk, err := strconv.ParseInt(config["dynamic."+platformName+".disk"], 10, 32)
+	if err != nil {
+		disk = 40
+	}
+	diskType := config["dynamic."+platformName+".disk-type"]
+	if diskType == "" {
+		diskType = defaultDiskType
+	}
+	sshUser := config["dynamic."+platformName+".ssh-user"]
+	if sshUser == "" {
+		sshUser = defaultSshUser
+	}
+	privateIp, _ := strconv.ParseBool(config["dynamic."+platformName+".private-ip"])
+
+	return AzureDynamicConfig{
+		SubscriptionID:         config["dynamic."+platformName+".subscription-id"],
+		ResourceGroup:          config["dynamic."+platformName+".resource-group"],
+		Location:               config["dynamic."+platformName+".location"],
+		VMSize:                 config["dynamic."+platformName+".vm-size"],
+		Image:                  config["dynamic."+platformName+".image"],
+		SubnetID:               config["dynamic."+platformName+".subnet-id"],
+		NetworkSecurityGroupID: config["dynamic."+platformName+".network-security-group-id"],
+		SshPublicKey:           config["dynamic."+platformName+".ssh-public-key"],
+		Secret:                 config["dynamic."+platformName+".azure-secret"],
+		UserData:               config["dynamic."+platformName+".user-data"],
+		User:                   sshUser,
+		Disk:                   int32(disk),
+		DiskType:               diskType,
+		PrivateIP:              privateIp,
+		SystemNamespace:        systemNamespace,
+	}
+}
+
+// LaunchInstance creates an Azure VM and returns its identifier, which is the VM name.
+func (az AzureDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (cloud.InstanceIdentifier, error) {
+	err := cloud.ValidateTaskRunID(taskRunID)
+	if err != nil {
+		return "", fmt.Errorf("invalid TaskRun ID: %w", err)
+	}
+	log := logr.FromContextOrDiscard(ctx)
+
+	vmName, err := createInstanceName(instanceTag)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a VM name: %w", err)
+	}
+	log.Info("Attempting to launch Azure VM", "vmName", vmName, "taskRunID", taskRunID)
+
+	azureClient, err := az.getAzureClient(kubeClient, ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create an Azure client: %w", err)
+	}
+
+	vm, err := az.configureVirtualMachine(vmName, taskRunID, instanceTag, additionalInstanceTags)
+	if err != nil {
+		return "", fmt.Errorf("failed to configure Azure VM %s: %w", vmName, err)
+	}
+	if err := azureClient.CreateVirtualMachine(ctx, vmName, vm); err != nil {
+		return "", fmt.Errorf("failed to launch Azure VM for %s: %w", taskRunID, err)
+	}
+	return cloud.InstanceIdentifier(vmName), nil
+}
+
+// CountInstances returns the number of Azure VMs tagged with instanceTag that are not being deleted.
+func (az AzureDynamicConfig) CountInstances(kubeClient client.Client, ctx context.Context, instanceTag string) (int, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to count Azure VMs")
+
+	azureClient, err := az.getAzureClient(kubeClient, ctx)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create an Azure client: %w", err)
+	}
+	vms, err := listTaggedVirtualMachines(ctx, azureClient, instanceTag)
+	if err != nil {
+		log.Error(err, "failed to retrieve Azure VMs", "instanceTag", instanceTag)
+		return -1, fmt.Errorf("failed to retrieve Azure VMs tagged with %s: %w", instanceTag, err)
+	}
+
+	count := 0
+	for _, vm := range vms {
+		if provisioningState(vm) != provisioningStateDeleting {
+			log.Info("Counting instance towards running count", "vmName", *vm.Name)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetInstanceAddress returns the IP address associated with the instanceID Azure VM. If none is found, an empty
+// string is returned.
+func (az AzureDynamicConfig) GetInstanceAddress(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to get Azure VM's IP address", "instanceID", instanceID)
+
+	azureClient, err := az.getAzureClient(kubeClient, ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create an Azure client: %w", err)
+	}
+	vm, err := azureClient.GetVirtualMachine(ctx, string(instanceID))
+	if err != nil {
+		// This might be a transient error, so only log it
+		log.Error(err, "failed to retrieve instance", "instanceID", instanceID)
+		return "", nil
+	}
+
+	ip, err := az.validateIPAddress(ctx, azureClient, vm)
+	// This might be a transient error, so only log it; wait longer for
+	// the instance to be ready
+	if err != nil {
+		return "", nil
+	}
+	return ip, nil
+}
+
+// TerminateInstance tries to delete the instanceID Azure VM. A VM that no longer exists is not an error.
+func (az AzureDynamicConfig) TerminateInstance(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) error {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to terminate Azure VM", "instanceID", instanceID)
+
+	azureClient, err := az.getAzureClient(kubeClient, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create an Azure client: %w", err)
+	}
+	err = azureClient.DeleteVirtualMachine(ctx, string(instanceID))
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete Azure VM %s: %w", instanceID, err)
+	}
+	return nil
+}
+
+// ListInstances returns a collection of accessible Azure VMs tagged with instanceTag.
+func (az AzureDynamicConfig) ListInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]cloud.CloudVMInstance, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to list Azure VMs")
+
+	azureClient, err := az.getAzureClient(kubeClient, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create an Azure client: %w", err)
+	}
+	vms, err := listTaggedVirtualMachines(ctx, azureClient, instanceTag)
+	if err != nil {
+		log.Error(err, "failed to retrieve Azure VMs", "instanceTag", instanceTag)
+		return nil, fmt.Errorf("failed to retrieve Azure VMs tagged with %s: %w", instanceTag, err)
+	}
+
+	vmInstances := []cloud.CloudVMInstance{}
+	for _, vm := range vms {
+		if provisioningState(vm) != provisioningStateSucceeded {
+			continue
+		}
+		// Only list VMs that have an accessible IP
+		ip, err := az.validateIPAddress(ctx, azureClient, vm)
+		if err != nil {
+			continue
+		}
+		var startTime time.Time
+		if vm.Properties != nil && vm.Properties.TimeCreated != nil {
+			startTime = *vm.Properties.TimeCreated
+		}
+		var taskRunID string
+		if tag := vm.Tags[cloud.TaskRunTagKey]; tag != nil {
+			taskRunID = *tag
+		}
+		vmInstances = append(vmInstances, cloud.CloudVMInstance{
+			InstanceId: cloud.InstanceIdentifier(*vm.Name),
+			StartTime:  startTime,
+			Address:    ip,
+			TaskRunID:  taskRunID,
+		})
+		log.Info("Counting instance towards running count", "vmName", *vm.Name)
+	}
+	return vmInstances, nil
+}
+
+func (az AzureDynamicConfig) SshUser() string {
+	return az.User
+}
+
+// GetState returns instanceID's VM state from Azure. A VM whose provisioning failed is in a failed state; see
+// https://learn.microsoft.com/en-us/azure/virtual-machines/states-billing for the VM lifecycle.
+func (az AzureDynamicConfig) GetState(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (cloud.VMState, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to get Azure VM's state", "instanceID", instanceID)
+
+	azureClient, err := az.getAzureClient(kubeClient, ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create an Azure client: %w", err)
+	}
+	vm, err := azureClient.GetVirtualMachine(ctx, string(instanceID))
+	if err != nil {
+		if isNotFound(err) {
+			return cloud.FailedState, nil
+		}
+		return "", fmt.Errorf("failed to retrieve instance %s: %w", instanceID, err)
+	}
+	if provisioningState(vm) == provisioningStateFailed {
+		return cloud.FailedState, nil
+	}
+	return cloud.OKState, nil
+}
+
+// An AzureDynamicConfig represents a configuration for an Azure VM.
+// The zero value (where each field will be assigned its type's zero value) is not a
+// valid AzureDynamicConfig.
+type AzureDynamicConfig struct {
+	// SubscriptionID is the ID of the Azure subscription VMs are billed to.
+	SubscriptionID string
+
+	// ResourceGroup is the name of the resource group VMs and their network resources are created in.
+	ResourceGroup string
+
+	// Location is the Azure region the VMs are created in, e.g. "eastus".
+	Location string
+
+	// VMSize is the Azure VM size, e.g. "Standard_D4ps_v5". See the
+	// [Azure VM sizes docs](https://learn.microsoft.com/en-us/azure/virtual-machines/sizes) for valid sizes.
+	VMSize string
+
+	// Image is either a Marketplace image URN in the format "publisher:offer:sku:version" or the
+	// resource ID of a custom image or Compute Gallery image version.
+	Image string
+
+	// SubnetID is the resource ID of the virtual network subnet the VM's network interface is attached to.
+	SubnetID string
+
+	// NetworkSecurityGroupID is the optional resource ID of a network security group applied to the
+	// VM's network interface.
+	NetworkSecurityGroupID string
+
+	// SshPublicKey is the public SSH key authorized for User.
+	SshPublicKey string
+
+	// Secret is the name of the Kubernetes secret that contains the service principal's
+	// tenant ID, client ID and client secret.
+	Secret string
+
+	// SystemNamespace is the name of the Kubernetes namespace where the specified
+	// secrets are stored.
+	SystemNamespace string
+
+	// UserData is the cloud-init user data passed to the VM.
+	UserData string
+
+	// User is the SSH user of the VM.
+	User string
+
+	// Disk is the size (in GB) of the VM's OS disk.
+	Disk int32
+
+	// DiskType is the storage account type of the VM's OS disk, e.g. "StandardSSD_LRS" or "Premium_LRS".
+	DiskType string
+
+	// PrivateIP specifies whether the VM is created without a public IP address and
+	// accessed by its private IP address instead.
+	PrivateIP bool
+
+	// azureClient allows tests to inject a mock Azure API client.
+	// When nil, getAzureClient builds a real client from the service principal credentials.
+	azureClient azureAPI
+
+	// pingFunc allows tests to inject a mock for SSH connectivity checks.
+	// When nil, the real pingIPAddress (TCP dial to port 22) is used.
+	pingFunc func(ip string) error
+}
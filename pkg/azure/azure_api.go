@@ -0,0 +1,112 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+)
+
+// azureAPI is the subset of the Azure Resource Manager API used by this package.
+// The Azure SDK exposes list and long-running operations through pagers and pollers,
+// so armClients adapts the SDK clients to this interface; tests can substitute a mock.
+type azureAPI interface {
+	CreateVirtualMachine(ctx context.Context, vmName string, vm armcompute.VirtualMachine) error
+	GetVirtualMachine(ctx context.Context, vmName string) (*armcompute.VirtualMachine, error)
+	ListVirtualMachines(ctx context.Context) ([]*armcompute.VirtualMachine, error)
+	DeleteVirtualMachine(ctx context.Context, vmName string) error
+	GetNetworkInterface(ctx context.Context, resourceID string) (*armnetwork.Interface, error)
+	GetPublicIPAddress(ctx context.Context, resourceID string) (*armnetwork.PublicIPAddress, error)
+}
+
+// armClients implements azureAPI with the Azure SDK clients for a single resource group.
+type armClients struct {
+	resourceGroup string
+	vms           *armcompute.VirtualMachinesClient
+	interfaces    *armnetwork.InterfacesClient
+	publicIPs     *armnetwork.PublicIPAddressesClient
+}
+
+// newArmClients returns the Azure SDK clients for subscriptionID authenticated with credential.
+func newArmClients(subscriptionID string, resourceGroup string, credential azcore.TokenCredential) (*armClients, error) {
+	vms, err := armcompute.NewVirtualMachinesClient(subscriptionID, credential, nil)
+	if err != nil {
+		return nil, err
+	}
+	interfaces, err := armnetwork.NewInterfacesClient(subscriptionID, credential, nil)
+	if err != nil {
+		return nil, err
+	}
+	publicIPs, err := armnetwork.NewPublicIPAddressesClient(subscriptionID, credential, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &armClients{resourceGroup: resourceGroup, vms: vms, interfaces: interfaces, publicIPs: publicIPs}, nil
+}
+
+// CreateVirtualMachine starts the creation of a VM. It does not wait for the long-running operation to finish;
+// the reconciler polls the VM state and address instead.
+func (c *armClients) CreateVirtualMachine(ctx context.Context, vmName string, vm armcompute.VirtualMachine) error {
+	_, err := c.vms.BeginCreateOrUpdate(ctx, c.resourceGroup, vmName, vm, nil)
+	return err
+}
+
+// GetVirtualMachine returns the VM including its instance view.
+func (c *armClients) GetVirtualMachine(ctx context.Context, vmName string) (*armcompute.VirtualMachine, error) {
+	resp, err := c.vms.Get(ctx, c.resourceGroup, vmName, &armcompute.VirtualMachinesClientGetOptions{
+		Expand: to.Ptr(armcompute.InstanceViewTypesInstanceView),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resp.VirtualMachine, nil
+}
+
+// ListVirtualMachines returns all VMs in the resource group.
+func (c *armClients) ListVirtualMachines(ctx context.Context) ([]*armcompute.VirtualMachine, error) {
+	var vms []*armcompute.VirtualMachine
+	pager := c.vms.NewListPager(c.resourceGroup, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		vms = append(vms, page.Value...)
+	}
+	return vms, nil
+}
+
+// DeleteVirtualMachine starts the deletion of a VM. Its NIC, public IP and OS disk are created with the
+// "Delete" delete option and are removed along with it.
+func (c *armClients) DeleteVirtualMachine(ctx context.Context, vmName string) error {
+	_, err := c.vms.BeginDelete(ctx, c.resourceGroup, vmName, nil)
+	return err
+}
+
+func (c *armClients) GetNetworkInterface(ctx context.Context, resourceID string) (*armnetwork.Interface, error) {
+	id, err := arm.ParseResourceID(resourceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network interface ID %s: %w", resourceID, err)
+	}
+	resp, err := c.interfaces.Get(ctx, id.ResourceGroupName, id.Name, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Interface, nil
+}
+
+func (c *armClients) GetPublicIPAddress(ctx context.Context, resourceID string) (*armnetwork.PublicIPAddress, error) {
+	id, err := arm.ParseResourceID(resourceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public IP address ID %s: %w", resourceID, err)
+	}
+	resp, err := c.publicIPs.Get(ctx, id.ResourceGroupName, id.Name, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.PublicIPAddress, nil
+}
@@ -0,0 +1,352 @@
+package azure
+
+import (
+	"context"
+	// #nosec is added to bypass the golang security scan since the cryptographic
+	// strength doesn't matter here
+	"crypto/md5" //#nosec
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	v1 "k8s.io/api/core/v1"
+	types2 "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxVMNameLength is the maximum length of a Linux VM name and computer name.
+	maxVMNameLength = 64
+
+	// Provisioning states of an Azure VM, see
+	// https://learn.microsoft.com/en-us/azure/virtual-machines/states-billing#provisioning-states
+	provisioningStateSucceeded = "Succeeded"
+	provisioningStateFailed    = "Failed"
+	provisioningStateDeleting  = "Deleting"
+)
+
+// validInstanceTagPattern validates that an instance tag can prefix an Azure VM name.
+var validInstanceTagPattern = regexp.MustCompile(`^[a-zA-Z][-a-zA-Z0-9]*$`)
+
+// pingIPAddress tries to connect to the SSH port on ipAddress with a 60-second timeout.
+// An error is returned if the connection fails.
+func pingIPAddress(ipAddress string) error {
+	conn, err := net.DialTimeout("tcp", ipAddress+":22", 60*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// createInstanceName returns a unique VM name in the format <instance_tag>-<hash>. The VM name doubles as the
+// VM's computer name, so the instance tag is truncated to keep the name within 64 characters.
+func createInstanceName(instanceTag string) (string, error) {
+	instanceTag = strings.ReplaceAll(instanceTag, "_", "-")
+	if !validInstanceTagPattern.MatchString(instanceTag) {
+		return "", fmt.Errorf("instance tag must start with a letter and contain only alphanumeric characters and hyphens, got: %s", instanceTag)
+	}
+	if len(instanceTag) > maxVMNameLength-17 {
+		instanceTag = strings.TrimRight(instanceTag[:maxVMNameLength-17], "-")
+	}
+
+	now := time.Now()
+	hashInput := fmt.Sprintf("%s-%d-%d", instanceTag, now.Unix(), now.Nanosecond())
+	// #nosec is added to bypass the golang security scan since the cryptographic
+	// strength doesn't matter here
+	md5Hash := md5.Sum([]byte(hashInput)) //#nosec
+	return fmt.Sprintf("%s-%s", instanceTag, hex.EncodeToString(md5Hash[:])[0:16]), nil
+}
+
+// instanceTags returns the Azure tags applied to a VM and the resources created with it.
+func instanceTags(taskRunID string, instanceTag string, additionalInstanceTags map[string]string) map[string]*string {
+	tags := map[string]*string{}
+	for k, v := range additionalInstanceTags {
+		tags[k] = to.Ptr(v)
+	}
+	tags[MultiPlatformManaged] = to.Ptr("true")
+	tags[cloud.InstanceTag] = to.Ptr(instanceTag)
+	tags[cloud.TaskRunTagKey] = to.Ptr(taskRunID)
+	return tags
+}
+
+// imageReference returns the Azure image reference for az.Image, which is either a Marketplace
+// image URN ("publisher:offer:sku:version") or an image resource ID.
+func (az AzureDynamicConfig) imageReference() (*armcompute.ImageReference, error) {
+	if strings.HasPrefix(az.Image, "/") {
+		return &armcompute.ImageReference{ID: to.Ptr(az.Image)}, nil
+	}
+	parts := strings.Split(az.Image, ":")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("image must be a 'publisher:offer:sku:version' URN or an image resource ID, got: '%s'", az.Image)
+	}
+	return &armcompute.ImageReference{
+		Publisher: to.Ptr(parts[0]),
+		Offer:     to.Ptr(parts[1]),
+		SKU:       to.Ptr(parts[2]),
+		Version:   to.Ptr(parts[3]),
+	}, nil
+}
+
+// configureVirtualMachine creates and returns an Azure VM configuration. The VM's network interface, public
+// IP address and OS disk are created along with it and are deleted when the VM is deleted.
+func (az AzureDynamicConfig) configureVirtualMachine(vmName string, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (armcompute.VirtualMachine, error) {
+	if az.SubnetID == "" {
+		return armcompute.VirtualMachine{}, errors.New("subnet-id must be set")
+	}
+	imageReference, err := az.imageReference()
+	if err != nil {
+		return armcompute.VirtualMachine{}, err
+	}
+
+	ipConfiguration := &armcompute.VirtualMachineNetworkInterfaceIPConfiguration{
+		Name: to.Ptr(vmName),
+		Properties: &armcompute.VirtualMachineNetworkInterfaceIPConfigurationProperties{
+			Primary: to.Ptr(true),
+			Subnet:  &armcompute.SubResource{ID: to.Ptr(az.SubnetID)},
+		},
+	}
+	if !az.PrivateIP {
+		ipConfiguration.Properties.PublicIPAddressConfiguration = &armcompute.VirtualMachinePublicIPAddressConfiguration{
+			Name: to.Ptr(vmName),
+			SKU: &armcompute.PublicIPAddressSKU{
+				Name: to.Ptr(armcompute.PublicIPAddressSKUNameStandard),
+			},
+			Properties: &armcompute.VirtualMachinePublicIPAddressConfigurationProperties{
+				DeleteOption:             to.Ptr(armcompute.DeleteOptionsDelete),
+				PublicIPAllocationMethod: to.Ptr(armcompute.PublicIPAllocationMethodStatic),
+			},
+		}
+	}
+	nicConfiguration := &armcompute.VirtualMachineNetworkInterfaceConfiguration{
+		Name: to.Ptr(vmName),
+		Properties: &armcompute.VirtualMachineNetworkInterfaceConfigurationProperties{
+			Primary:          to.Ptr(true),
+			DeleteOption:     to.Ptr(armcompute.DeleteOptionsDelete),
+			IPConfigurations: []*armcompute.VirtualMachineNetworkInterfaceIPConfiguration{ipConfiguration},
+		},
+	}
+	if az.NetworkSecurityGroupID != "" {
+		nicConfiguration.Properties.NetworkSecurityGroup = &armcompute.SubResource{ID: to.Ptr(az.NetworkSecurityGroupID)}
+	}
+
+	vm := armcompute.VirtualMachine{
+		Location: to.Ptr(az.Location),
+		Tags:     instanceTags(taskRunID, instanceTag, additionalInstanceTags),
+		Properties: &armcompute.VirtualMachineProperties{
+			HardwareProfile: &armcompute.HardwareProfile{
+				VMSize: to.Ptr(armcompute.VirtualMachineSizeTypes(az.VMSize)),
+			},
+			StorageProfile: &armcompute.StorageProfile{
+				ImageReference: imageReference,
+				OSDisk: &armcompute.OSDisk{
+					CreateOption: to.Ptr(armcompute.DiskCreateOptionTypesFromImage),
+					DeleteOption: to.Ptr(armcompute.DiskDeleteOptionTypesDelete),
+					DiskSizeGB:   to.Ptr(az.Disk),
+					ManagedDisk: &armcompute.ManagedDiskParameters{
+						StorageAccountType: to.Ptr(armcompute.StorageAccountTypes(az.DiskType)),
+					},
+				},
+			},
+			OSProfile: &armcompute.OSProfile{
+				ComputerName:  to.Ptr(vmName),
+				AdminUsername: to.Ptr(az.User),
+				LinuxConfiguration: &armcompute.LinuxConfiguration{
+					DisablePasswordAuthentication: to.Ptr(true),
+					SSH: &armcompute.SSHConfiguration{
+						PublicKeys: []*armcompute.SSHPublicKey{{
+							Path:    to.Ptr(fmt.Sprintf("/home/%s/.ssh/authorized_keys", az.User)),
+							KeyData: to.Ptr(az.SshPublicKey),
+						}},
+					},
+				},
+			},
+			NetworkProfile: &armcompute.NetworkProfile{
+				NetworkAPIVersion:              to.Ptr(armcompute.NetworkAPIVersionTwoThousandTwenty1101),
+				NetworkInterfaceConfigurations: []*armcompute.VirtualMachineNetworkInterfaceConfiguration{nicConfiguration},
+			},
+		},
+	}
+	if az.UserData != "" {
+		vm.Properties.UserData = to.Ptr(base64.StdEncoding.EncodeToString([]byte(az.UserData)))
+	}
+	return vm, nil
+}
+
+// listTaggedVirtualMachines returns the VMs in the resource group that are tagged with instanceTag and are managed
+// by this controller. The Azure List API cannot filter by tag, so VMs are filtered client-side.
+func listTaggedVirtualMachines(ctx context.Context, azureClient azureAPI, instanceTag string) ([]*armcompute.VirtualMachine, error) {
+	vms, err := azureClient.ListVirtualMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tagged []*armcompute.VirtualMachine
+	for _, vm := range vms {
+		if vm == nil || vm.Name == nil {
+			continue
+		}
+		if deref(vm.Tags[cloud.InstanceTag]) == instanceTag && deref(vm.Tags[MultiPlatformManaged]) == "true" {
+			tagged = append(tagged, vm)
+		}
+	}
+	return tagged, nil
+}
+
+// provisioningState returns the provisioning state of vm, or "" if it is not known.
+func provisioningState(vm *armcompute.VirtualMachine) string {
+	if vm.Properties == nil || vm.Properties.ProvisioningState == nil {
+		return ""
+	}
+	return *vm.Properties.ProvisioningState
+}
+
+// primaryIPConfiguration returns the primary IP configuration of the VM's primary network interface.
+func primaryIPConfiguration(ctx context.Context, azureClient azureAPI, vm *armcompute.VirtualMachine) (*armnetwork.InterfaceIPConfiguration, error) {
+	if vm.Properties == nil || vm.Properties.NetworkProfile == nil || len(vm.Properties.NetworkProfile.NetworkInterfaces) == 0 {
+		return nil, errors.New("VM has no network interface")
+	}
+	nicRef := vm.Properties.NetworkProfile.NetworkInterfaces[0]
+	for _, ref := range vm.Properties.NetworkProfile.NetworkInterfaces {
+		if ref.Properties != nil && deref(ref.Properties.Primary) {
+			nicRef = ref
+		}
+	}
+	nic, err := azureClient.GetNetworkInterface(ctx, deref(nicRef.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve network interface %s: %w", deref(nicRef.ID), err)
+	}
+	if nic.Properties == nil || len(nic.Properties.IPConfigurations) == 0 {
+		return nil, fmt.Errorf("network interface %s has no IP configuration", deref(nicRef.ID))
+	}
+	ipConfig := nic.Properties.IPConfigurations[0]
+	for _, c := range nic.Properties.IPConfigurations {
+		if c.Properties != nil && deref(c.Properties.Primary) {
+			ipConfig = c
+		}
+	}
+	return ipConfig, nil
+}
+
+// validateIPAddress returns the IP address of the Azure VM after verifying SSH connectivity. The public IP address
+// is used unless az.PrivateIP is set. Returns an error if no IP is available or if the VM is not reachable via SSH.
+func (az AzureDynamicConfig) validateIPAddress(ctx context.Context, azureClient azureAPI, vm *armcompute.VirtualMachine) (string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	vmName := deref(vm.Name)
+
+	ipConfig, err := primaryIPConfiguration(ctx, azureClient, vm)
+	if err != nil {
+		return "", err
+	}
+	var ip string
+	if ipConfig.Properties != nil {
+		if az.PrivateIP {
+			ip = deref(ipConfig.Properties.PrivateIPAddress)
+		} else if ipConfig.Properties.PublicIPAddress != nil && ipConfig.Properties.PublicIPAddress.ID != nil {
+			publicIP, err := azureClient.GetPublicIPAddress(ctx, *ipConfig.Properties.PublicIPAddress.ID)
+			if err != nil {
+				return "", fmt.Errorf("failed to retrieve public IP address of VM %s: %w", vmName, err)
+			}
+			if publicIP.Properties != nil {
+				ip = deref(publicIP.Properties.IPAddress)
+			}
+		}
+	}
+	if ip == "" {
+		return "", fmt.Errorf("VM %s has no accessible IP address", vmName)
+	}
+
+	// Verify SSH connectivity
+	ping := pingIPAddress
+	if az.pingFunc != nil {
+		ping = az.pingFunc
+	}
+	if err := ping(ip); err != nil {
+		log.Error(err, "failed to connect to Azure VM via SSH", "vmName", vmName, "ipAddress", ip)
+		return "", fmt.Errorf("failed to resolve IP address %s: %w", ip, err)
+	}
+	log.Info("Successfully validated IP address", "vmName", vmName, "ipAddress", ip)
+	return ip, nil
+}
+
+// isNotFound reports whether err is an Azure API error for a resource that does not exist.
+func isNotFound(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+// getAzureClient returns the injected mock client if set, otherwise builds a real
+// Azure client from the service principal credentials.
+func (az AzureDynamicConfig) getAzureClient(kubeClient client.Client, ctx context.Context) (azureAPI, error) {
+	if az.azureClient != nil {
+		return az.azureClient, nil
+	}
+	credentials := SecretCredentialsProvider{Name: az.Secret, Namespace: az.SystemNamespace, Client: kubeClient}
+	return newArmClients(az.SubscriptionID, az.ResourceGroup, credentials)
+}
+
+// A SecretCredentialsProvider is a collection of information needed to generate
+// Azure credentials. It implements the azcore TokenCredential interface.
+type SecretCredentialsProvider struct {
+	// Name is the name of the Kubernetes secret that contains the service
+	// principal's tenant ID, client ID and client secret.
+	Name string
+
+	// Namespace is the Kubernetes namespace the secret resides in.
+	Namespace string
+
+	// Client is the client (if any) to use to connect to Kubernetes.
+	Client client.Client
+}
+
+// GetToken is the azcore TokenCredential interface's method that uses external Kubernetes
+// secrets or local environment variables to obtain an Azure access token.
+func (r SecretCredentialsProvider) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	var tenantID, clientID, clientSecret string
+	// Use local environment variables for credentials
+	if r.Client == nil {
+		tenantID = os.Getenv("MULTI_ARCH_AZURE_TENANT_ID")
+		clientID = os.Getenv("MULTI_ARCH_AZURE_CLIENT_ID")
+		clientSecret = os.Getenv("MULTI_ARCH_AZURE_CLIENT_SECRET")
+	} else {
+		// Connect to Kubernetes to get credentials info
+		s := v1.Secret{}
+		nameSpacedSecret := types2.NamespacedName{Name: r.Name, Namespace: r.Namespace}
+		err := r.Client.Get(ctx, nameSpacedSecret, &s)
+		if err != nil {
+			return azcore.AccessToken{},
+				fmt.Errorf("failed to retrieve the secret %v from the Kubernetes client: %w", nameSpacedSecret, err)
+		}
+		tenantID = string(s.Data["tenant-id"])
+		clientID = string(s.Data["client-id"])
+		clientSecret = string(s.Data["client-secret"])
+	}
+
+	credential, err := azidentity.NewClientSecretCredential(tenantID, clientID, clientSecret, nil)
+	if err != nil {
+		return azcore.AccessToken{}, fmt.Errorf("failed to create Azure client secret credential: %w", err)
+	}
+	return credential.GetToken(ctx, options)
+}
+
+// deref returns the value p points to, or the zero value of T if p is nil.
+func deref[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
@@ -0,0 +1,133 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+)
+
+// mockAzureAPI is an in-memory implementation of azureAPI. Created VMs are given a NIC with a private IP address
+// and, if requested by the VM configuration, a public IP address.
+type mockAzureAPI struct {
+	mu          sync.Mutex
+	VMs         map[string]*armcompute.VirtualMachine
+	NICs        map[string]*armnetwork.Interface
+	PublicIPs   map[string]*armnetwork.PublicIPAddress
+	Created     *armcompute.VirtualMachine
+	Deleted     []string
+	CreateError error
+	ListError   error
+	GetError    error
+}
+
+func newMockAzureAPI() *mockAzureAPI {
+	return &mockAzureAPI{
+		VMs:       map[string]*armcompute.VirtualMachine{},
+		NICs:      map[string]*armnetwork.Interface{},
+		PublicIPs: map[string]*armnetwork.PublicIPAddress{},
+	}
+}
+
+// notFoundError returns the error the Azure SDK reports for a missing resource.
+func notFoundError() error {
+	return &azcore.ResponseError{StatusCode: http.StatusNotFound, ErrorCode: "ResourceNotFound"}
+}
+
+func (m *mockAzureAPI) CreateVirtualMachine(_ context.Context, vmName string, vm armcompute.VirtualMachine) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.CreateError != nil {
+		return m.CreateError
+	}
+	m.Created = &vm
+	nicID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/" + vmName
+	ipProperties := &armnetwork.InterfaceIPConfigurationPropertiesFormat{
+		Primary:          to.Ptr(true),
+		PrivateIPAddress: to.Ptr("10.0.0.4"),
+	}
+	ipConfig := vm.Properties.NetworkProfile.NetworkInterfaceConfigurations[0].Properties.IPConfigurations[0]
+	if ipConfig.Properties.PublicIPAddressConfiguration != nil {
+		publicIPID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/" + vmName
+		m.PublicIPs[publicIPID] = &armnetwork.PublicIPAddress{
+			ID:         to.Ptr(publicIPID),
+			Properties: &armnetwork.PublicIPAddressPropertiesFormat{IPAddress: to.Ptr("20.0.0.4")},
+		}
+		ipProperties.PublicIPAddress = &armnetwork.PublicIPAddress{ID: to.Ptr(publicIPID)}
+	}
+	m.NICs[nicID] = &armnetwork.Interface{
+		ID: to.Ptr(nicID),
+		Properties: &armnetwork.InterfacePropertiesFormat{
+			IPConfigurations: []*armnetwork.InterfaceIPConfiguration{{Properties: ipProperties}},
+		},
+	}
+	vm.Name = to.Ptr(vmName)
+	vm.Properties.ProvisioningState = to.Ptr(provisioningStateSucceeded)
+	vm.Properties.TimeCreated = to.Ptr(time.Now())
+	vm.Properties.NetworkProfile.NetworkInterfaces = []*armcompute.NetworkInterfaceReference{{ID: to.Ptr(nicID)}}
+	m.VMs[vmName] = &vm
+	return nil
+}
+
+func (m *mockAzureAPI) GetVirtualMachine(_ context.Context, vmName string) (*armcompute.VirtualMachine, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.GetError != nil {
+		return nil, m.GetError
+	}
+	vm, ok := m.VMs[vmName]
+	if !ok {
+		return nil, notFoundError()
+	}
+	return vm, nil
+}
+
+func (m *mockAzureAPI) ListVirtualMachines(_ context.Context) ([]*armcompute.VirtualMachine, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ListError != nil {
+		return nil, m.ListError
+	}
+	var vms []*armcompute.VirtualMachine
+	for _, vm := range m.VMs {
+		vms = append(vms, vm)
+	}
+	return vms, nil
+}
+
+func (m *mockAzureAPI) DeleteVirtualMachine(_ context.Context, vmName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.VMs[vmName]; !ok {
+		return notFoundError()
+	}
+	delete(m.VMs, vmName)
+	m.Deleted = append(m.Deleted, vmName)
+	return nil
+}
+
+func (m *mockAzureAPI) GetNetworkInterface(_ context.Context, resourceID string) (*armnetwork.Interface, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	nic, ok := m.NICs[resourceID]
+	if !ok {
+		return nil, notFoundError()
+	}
+	return nic, nil
+}
+
+func (m *mockAzureAPI) GetPublicIPAddress(_ context.Context, resourceID string) (*armnetwork.PublicIPAddress, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	publicIP, ok := m.PublicIPs[resourceID]
+	if !ok {
+		return nil, errors.New("public IP address not found")
+	}
+	return publicIP, nil
+}
@@ -0,0 +1,294 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const systemNamespace = "multi-platform-controller"
+
+var _ = Describe("Azure Unit Test Suite", func() {
+
+	Describe("Testing CreateAzureCloudConfig", func() {
+
+		DescribeTable("Testing the creation of AzureDynamicConfig properly no matter the values",
+			func(platformName string, testConfig map[string]string, expectedDisk int32, expectedDiskType string, expectedUser string, expectedPrivateIP bool) {
+				config := map[string]string{
+					"dynamic." + platformName + ".subscription-id":           "test-subscription",
+					"dynamic." + platformName + ".resource-group":            "test-resource-group",
+					"dynamic." + platformName + ".location":                  "test-location",
+					"dynamic." + platformName + ".vm-size":                   "test-vm-size",
+					"dynamic." + platformName + ".image":                     "test-image",
+					"dynamic." + platformName + ".subnet-id":                 "test-subnet-id",
+					"dynamic." + platformName + ".network-security-group-id": "test-nsg-id",
+					"dynamic." + platformName + ".ssh-public-key":            "test-ssh-public-key",
+					"dynamic." + platformName + ".azure-secret":              "test-secret",
+					"dynamic." + platformName + ".disk":                      testConfig["disk"],
+					"dynamic." + platformName + ".disk-type":                 testConfig["disk-type"],
+					"dynamic." + platformName + ".ssh-user":                  testConfig["ssh-user"],
+					"dynamic." + platformName + ".private-ip":                testConfig["private-ip"],
+				}
+				provider := CreateAzureCloudConfig(platformName, config, systemNamespace)
+				Expect(provider).ToNot(BeNil())
+				providerConfig := provider.(AzureDynamicConfig)
+
+				Expect(providerConfig.SubscriptionID).To(Equal("test-subscription"))
+				Expect(providerConfig.ResourceGroup).To(Equal("test-resource-group"))
+				Expect(providerConfig.Location).To(Equal("test-location"))
+				Expect(providerConfig.VMSize).To(Equal("test-vm-size"))
+				Expect(providerConfig.Image).To(Equal("test-image"))
+				Expect(providerConfig.SubnetID).To(Equal("test-subnet-id"))
+				Expect(providerConfig.NetworkSecurityGroupID).To(Equal("test-nsg-id"))
+				Expect(providerConfig.SshPublicKey).To(Equal("test-ssh-public-key"))
+				Expect(providerConfig.Secret).To(Equal("test-secret"))
+				Expect(providerConfig.SystemNamespace).To(Equal(systemNamespace))
+				Expect(providerConfig.Disk).To(Equal(expectedDisk))
+				Expect(providerConfig.DiskType).To(Equal(expectedDiskType))
+				Expect(providerConfig.SshUser()).To(Equal(expectedUser))
+				Expect(providerConfig.PrivateIP).To(Equal(expectedPrivateIP))
+			},
+			Entry("Positive - valid config map keys", "linux-arm64", map[string]string{
+				"disk":       "200",
+				"disk-type":  "Premium_LRS",
+				"ssh-user":   "builder",
+				"private-ip": "true"},
+				int32(200), "Premium_LRS", "builder", true),
+			Entry("Negative - missing config data", "linux-amd64", map[string]string{
+				"disk":       "",
+				"disk-type":  "",
+				"ssh-user":   "",
+				"private-ip": ""},
+				int32(40), defaultDiskType, defaultSshUser, false),
+			Entry("Negative - config data with bad data types", "linux-mlarge-amd64", map[string]string{
+				"disk":       "koko-hazamar",
+				"disk-type":  "",
+				"ssh-user":   "",
+				"private-ip": "koko-hazamar"},
+				int32(40), defaultDiskType, defaultSshUser, false),
+		)
+	})
+
+	Describe("CloudProvider methods", func() {
+		var (
+			mock *mockAzureAPI
+			cfg  AzureDynamicConfig
+			ctx  context.Context
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			mock = newMockAzureAPI()
+			cfg = AzureDynamicConfig{
+				SubscriptionID: "sub",
+				ResourceGroup:  "rg",
+				Location:       "eastus",
+				VMSize:         "Standard_D4ps_v5",
+				Image:          "RedHat:rhel-arm64:9_4-arm64:latest",
+				SubnetID:       "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/default",
+				SshPublicKey:   "ssh-rsa AAAA",
+				User:           defaultSshUser,
+				Disk:           40,
+				DiskType:       defaultDiskType,
+				azureClient:    mock,
+				pingFunc:       func(string) error { return nil },
+			}
+		})
+
+		// launch creates a VM through LaunchInstance for the given instance tag.
+		launch := func(instanceTag string) cloud.InstanceIdentifier {
+			id, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", instanceTag, map[string]string{})
+			Expect(err).ToNot(HaveOccurred())
+			return id
+		}
+
+		Describe("LaunchInstance", func() {
+			It("should create a VM with the controller's tags", func() {
+				cfg.UserData = "#cloud-config"
+				id, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{"Cost-Center": "Konflux"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(id)).To(HavePrefix("prod-arm64-"))
+
+				created := mock.Created
+				Expect(created).ToNot(BeNil())
+				Expect(*created.Location).To(Equal("eastus"))
+				Expect(created.Tags).To(HaveKeyWithValue(cloud.InstanceTag, to.Ptr("prod-arm64")))
+				Expect(created.Tags).To(HaveKeyWithValue(MultiPlatformManaged, to.Ptr("true")))
+				Expect(created.Tags).To(HaveKeyWithValue(cloud.TaskRunTagKey, to.Ptr("test-namespace:test-taskrun")))
+				Expect(created.Tags).To(HaveKeyWithValue("Cost-Center", to.Ptr("Konflux")))
+				Expect(*created.Properties.HardwareProfile.VMSize).To(Equal(armcompute.VirtualMachineSizeTypes("Standard_D4ps_v5")))
+				Expect(*created.Properties.StorageProfile.ImageReference.Publisher).To(Equal("RedHat"))
+				Expect(*created.Properties.StorageProfile.ImageReference.SKU).To(Equal("9_4-arm64"))
+				Expect(*created.Properties.StorageProfile.OSDisk.DiskSizeGB).To(Equal(int32(40)))
+				Expect(*created.Properties.OSProfile.ComputerName).To(Equal(string(id)))
+				Expect(*created.Properties.UserData).To(Equal(base64.StdEncoding.EncodeToString([]byte("#cloud-config"))))
+
+				ipConfig := created.Properties.NetworkProfile.NetworkInterfaceConfigurations[0].Properties.IPConfigurations[0]
+				Expect(ipConfig.Properties.PublicIPAddressConfiguration).ToNot(BeNil())
+			})
+
+			It("should not request a public IP address when private-ip is set", func() {
+				cfg.PrivateIP = true
+				launch("prod-arm64")
+				ipConfig := mock.Created.Properties.NetworkProfile.NetworkInterfaceConfigurations[0].Properties.IPConfigurations[0]
+				Expect(ipConfig.Properties.PublicIPAddressConfiguration).To(BeNil())
+			})
+
+			It("should use an image resource ID as is", func() {
+				cfg.Image = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/galleries/g/images/rhel/versions/1.0.0"
+				launch("prod-arm64")
+				Expect(*mock.Created.Properties.StorageProfile.ImageReference.ID).To(Equal(cfg.Image))
+			})
+
+			It("should reject an invalid image", func() {
+				cfg.Image = "rhel-9"
+				_, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{})
+				Expect(err).To(MatchError(ContainSubstring("failed to configure Azure VM")))
+				Expect(mock.Created).To(BeNil())
+			})
+
+			It("should reject an invalid TaskRun ID", func() {
+				_, err := cfg.LaunchInstance(nil, ctx, "invalid-id", "prod-arm64", map[string]string{})
+				Expect(err).To(MatchError(ContainSubstring("invalid TaskRun ID")))
+				Expect(mock.Created).To(BeNil())
+			})
+
+			It("should return an error when the API call fails", func() {
+				mock.CreateError = errors.New("quota exceeded")
+				_, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{})
+				Expect(err).To(MatchError(ContainSubstring("failed to launch Azure VM")))
+			})
+		})
+
+		Describe("CountInstances", func() {
+			It("should only count VMs with the instance tag that are not being deleted", func() {
+				launch("prod-arm64")
+				deleting := launch("prod-arm64")
+				mock.VMs[string(deleting)].Properties.ProvisioningState = to.Ptr(provisioningStateDeleting)
+				launch("prod-arm64")
+				launch("other-amd64")
+
+				Expect(cfg.CountInstances(nil, ctx, "prod-arm64")).To(Equal(2))
+			})
+
+			It("should return an error when the API call fails", func() {
+				mock.ListError = errors.New("throttled")
+				count, err := cfg.CountInstances(nil, ctx, "prod-arm64")
+				Expect(err).To(HaveOccurred())
+				Expect(count).To(Equal(-1))
+			})
+		})
+
+		Describe("ListInstances", func() {
+			It("should only list provisioned and reachable VMs", func() {
+				reachable := launch("prod-arm64")
+				creating := launch("prod-arm64")
+				mock.VMs[string(creating)].Properties.ProvisioningState = to.Ptr("Creating")
+				launch("other-amd64")
+
+				instances, err := cfg.ListInstances(nil, ctx, "prod-arm64")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instances).To(HaveLen(1))
+				Expect(instances[0].InstanceId).To(Equal(reachable))
+				Expect(instances[0].Address).To(Equal("20.0.0.4"))
+				Expect(instances[0].StartTime).To(Equal(*mock.VMs[string(reachable)].Properties.TimeCreated))
+				Expect(instances[0].TaskRunID).To(Equal("test-namespace:test-taskrun"))
+			})
+
+			It("should skip VMs that are not reachable via SSH", func() {
+				launch("prod-arm64")
+				cfg.pingFunc = func(string) error { return errors.New("unreachable") }
+				Expect(cfg.ListInstances(nil, ctx, "prod-arm64")).To(BeEmpty())
+			})
+		})
+
+		Describe("GetInstanceAddress", func() {
+			It("should return the public address by default", func() {
+				id := launch("prod-arm64")
+				Expect(cfg.GetInstanceAddress(nil, ctx, id)).To(Equal("20.0.0.4"))
+			})
+
+			It("should return the private address when private-ip is set", func() {
+				cfg.PrivateIP = true
+				id := launch("prod-arm64")
+				Expect(cfg.GetInstanceAddress(nil, ctx, id)).To(Equal("10.0.0.4"))
+			})
+
+			It("should return an empty address while the VM has no network interface yet", func() {
+				id := launch("prod-arm64")
+				mock.VMs[string(id)].Properties.NetworkProfile.NetworkInterfaces = nil
+				Expect(cfg.GetInstanceAddress(nil, ctx, id)).To(BeEmpty())
+			})
+
+			It("should treat a missing VM as transient", func() {
+				address, err := cfg.GetInstanceAddress(nil, ctx, "does-not-exist")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(address).To(BeEmpty())
+			})
+		})
+
+		Describe("GetState", func() {
+			DescribeTable("should map Azure provisioning states onto VM states",
+				func(state string, expected cloud.VMState) {
+					id := launch("prod-arm64")
+					mock.VMs[string(id)].Properties.ProvisioningState = to.Ptr(state)
+					Expect(cfg.GetState(nil, ctx, id)).To(Equal(expected))
+				},
+				Entry("creating", "Creating", cloud.OKState),
+				Entry("succeeded", provisioningStateSucceeded, cloud.OKState),
+				Entry("deleting", provisioningStateDeleting, cloud.OKState),
+				Entry("failed", provisioningStateFailed, cloud.FailedState),
+			)
+
+			It("should report a VM that no longer exists as failed", func() {
+				Expect(cfg.GetState(nil, ctx, "does-not-exist")).To(Equal(cloud.FailedState))
+			})
+
+			It("should return an error when the VM cannot be retrieved", func() {
+				id := launch("prod-arm64")
+				mock.GetError = errors.New("throttled")
+				_, err := cfg.GetState(nil, ctx, id)
+				Expect(err).To(MatchError(ContainSubstring("throttled")))
+			})
+		})
+
+		Describe("TerminateInstance", func() {
+			It("should delete the VM", func() {
+				id := launch("prod-arm64")
+				Expect(cfg.TerminateInstance(nil, ctx, id)).To(Succeed())
+				Expect(mock.Deleted).To(ConsistOf(string(id)))
+			})
+
+			It("should not fail for a VM that is already gone", func() {
+				Expect(cfg.TerminateInstance(nil, ctx, "does-not-exist")).To(Succeed())
+			})
+		})
+	})
+
+	Describe("Testing helper functions", func() {
+		It("createInstanceName creates unique, valid names", func() {
+			first, err := createInstanceName("prod_arm64")
+			Expect(err).ToNot(HaveOccurred())
+			second, err := createInstanceName("prod_arm64")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(first).To(HavePrefix("prod-arm64-"))
+			Expect(first).ToNot(Equal(second))
+
+			long, err := createInstanceName(strings.Repeat("a", 80))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(long)).To(BeNumerically("<=", maxVMNameLength))
+		})
+
+		It("createInstanceName rejects tags that cannot start a VM name", func() {
+			_, err := createInstanceName("1-starts-with-a-digit")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
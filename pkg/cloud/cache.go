@@ -0,0 +1,193 @@
+package cloud
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	mpcmetrics "github.com/konflux-ci/multi-platform-controller/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	listOperation  = "list"
+	countOperation = "count"
+)
+
+// instanceCacheKey identifies the cached result of an operation for the instances of an instance tag. Results are
+// cached per platform, since providers may only return the instances of their platform, e.g. of its instance type.
+type instanceCacheKey struct {
+	account     string
+	platform    string
+	instanceTag string
+	operation   string
+}
+
+// instanceCacheEntry is the cached result of an operation. Its lock is held while the result is fetched, so that
+// concurrent callers wait for that result instead of calling the cloud provider too.
+type instanceCacheEntry struct {
+	mu         sync.Mutex
+	instances  []CloudVMInstance
+	count      int
+	fetched    time.Time
+	generation int
+}
+
+// InstanceCache caches the instances listed and counted by cloud providers for a short time, so that a burst of
+// TaskRuns does not call the cloud provider for the same result over and over. The cached results of an account are
+// invalidated whenever an instance is launched or terminated through a provider wrapped by the same InstanceCache.
+type InstanceCache struct {
+	mu          sync.Mutex
+	entries     map[instanceCacheKey]*instanceCacheEntry
+	generations map[string]int
+}
+
+// NewInstanceCache returns an InstanceCache without any cached results.
+func NewInstanceCache() *InstanceCache {
+	return &InstanceCache{entries: map[instanceCacheKey]*instanceCacheEntry{}, generations: map[string]int{}}
+}
+
+// entry returns the entry of key.
+func (c *InstanceCache) entry(key instanceCacheKey) *instanceCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &instanceCacheEntry{generation: -1}
+		c.entries[key] = entry
+	}
+	return entry
+}
+
+// generation returns the current generation of account, which changes whenever its cached results are invalidated.
+func (c *InstanceCache) generation(account string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generations[account]
+}
+
+// invalidate discards the cached results of account, including the ones being fetched.
+func (c *InstanceCache) invalidate(account string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generations[account]++
+}
+
+// get returns the cached result of key if it is younger than ttl, or calls fetch and caches its result otherwise.
+// The returned instances are a copy that callers are free to modify.
+func (c *InstanceCache) get(key instanceCacheKey, ttl time.Duration, fetch func(entry *instanceCacheEntry) error) ([]CloudVMInstance, int, error) {
+	entry := c.entry(key)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	generation := c.generation(key.account)
+	if entry.generation == generation && time.Since(entry.fetched) < ttl {
+		mpcmetrics.CountInstanceCacheHit(key.operation)
+		return slices.Clone(entry.instances), entry.count, nil
+	}
+	mpcmetrics.CountInstanceCacheMiss(key.operation)
+	if err := fetch(entry); err != nil {
+		entry.generation = -1
+		return nil, 0, err
+	}
+	entry.fetched = time.Now()
+	entry.generation = generation
+	return slices.Clone(entry.instances), entry.count, nil
+}
+
+// cachedProvider is a CloudProvider that caches the instances listed and counted by the provider it wraps.
+type cachedProvider struct {
+	provider CloudProvider
+	account  string
+	platform string
+	ttl      time.Duration
+	cache    *InstanceCache
+}
+
+// cachedHibernation invalidates the instances cached for an account when instances are stopped or started. Stopped
+// instances are not cached.
+type cachedHibernation struct {
+	provider InstanceHibernator
+	account  string
+	cache    *InstanceCache
+}
+
+// Wrap wraps the provider of platform so that the instances it lists and counts for the cloud provider account
+// identified by account are cached for ttl. Results are not cached at all when ttl is not positive.
+func (c *InstanceCache) Wrap(provider CloudProvider, account string, platform string, ttl time.Duration) CloudProvider {
+	if ttl <= 0 {
+		return provider
+	}
+	return cachedProvider{provider: provider, account: account, platform: platform, ttl: ttl, cache: c}
+}
+
+// detailsReporter returns the provider it wraps, as instance details are not cached.
+func (p cachedProvider) detailsReporter() (InstanceDetailsReporter, bool) {
+	return DetailsReporter(p.provider)
+}
+
+// taskRunReporter returns the provider it wraps, as the TaskRuns of instances are not cached.
+func (p cachedProvider) taskRunReporter() (InstanceTaskRunReporter, bool) {
+	return TaskRunReporter(p.provider)
+}
+
+func (p cachedProvider) hibernator() (InstanceHibernator, bool) {
+	hibernator, ok := Hibernator(p.provider)
+	if !ok {
+		return nil, false
+	}
+	return cachedHibernation{provider: hibernator, account: p.account, cache: p.cache}, true
+}
+
+func (p cachedProvider) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (InstanceIdentifier, error) {
+	// Even a failed launch may have created an instance
+	defer p.cache.invalidate(p.account)
+	return p.provider.LaunchInstance(kubeClient, ctx, taskRunID, instanceTag, additionalInstanceTags)
+}
+
+func (p cachedProvider) TerminateInstance(kubeClient client.Client, ctx context.Context, instance InstanceIdentifier) error {
+	defer p.cache.invalidate(p.account)
+	return p.provider.TerminateInstance(kubeClient, ctx, instance)
+}
+
+func (p cachedProvider) GetInstanceAddress(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (string, error) {
+	return p.provider.GetInstanceAddress(kubeClient, ctx, instanceId)
+}
+
+func (p cachedProvider) CountInstances(kubeClient client.Client, ctx context.Context, instanceTag string) (int, error) {
+	_, count, err := p.cache.get(instanceCacheKey{account: p.account, platform: p.platform, instanceTag: instanceTag, operation: countOperation}, p.ttl, func(entry *instanceCacheEntry) (err error) {
+		entry.count, err = p.provider.CountInstances(kubeClient, ctx, instanceTag)
+		return err
+	})
+	return count, err
+}
+
+func (p cachedProvider) ListInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]CloudVMInstance, error) {
+	instances, _, err := p.cache.get(instanceCacheKey{account: p.account, platform: p.platform, instanceTag: instanceTag, operation: listOperation}, p.ttl, func(entry *instanceCacheEntry) (err error) {
+		entry.instances, err = p.provider.ListInstances(kubeClient, ctx, instanceTag)
+		return err
+	})
+	return instances, err
+}
+
+func (p cachedProvider) GetState(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (VMState, error) {
+	return p.provider.GetState(kubeClient, ctx, instanceId)
+}
+
+func (p cachedProvider) SshUser() string {
+	return p.provider.SshUser()
+}
+
+func (p cachedHibernation) StopInstance(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) error {
+	defer p.cache.invalidate(p.account)
+	return p.provider.StopInstance(kubeClient, ctx, instanceId)
+}
+
+func (p cachedHibernation) StartInstance(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) error {
+	defer p.cache.invalidate(p.account)
+	return p.provider.StartInstance(kubeClient, ctx, instanceId)
+}
+
+func (p cachedHibernation) ListStoppedInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]CloudVMInstance, error) {
+	return p.provider.ListStoppedInstances(kubeClient, ctx, instanceTag)
+}
@@ -0,0 +1,172 @@
+package cloud
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InstanceCache", func() {
+	var (
+		cache *InstanceCache
+		stub  *stubProvider
+	)
+
+	BeforeEach(func() {
+		cache = NewInstanceCache()
+		stub = &stubProvider{}
+	})
+
+	It("should answer repeated list and count calls from the cache", func(ctx SpecContext) {
+		provider := cache.Wrap(stub, "aws/account", "linux-arm64", time.Minute)
+		for range 5 {
+			instances, err := provider.ListInstances(nil, ctx, "tag")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instances).Should(HaveLen(1))
+			count, err := provider.CountInstances(nil, ctx, "tag")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(count).Should(Equal(1))
+		}
+		Expect(stub.calls).Should(Equal(2))
+	})
+
+	It("should cache the instances of each instance tag and account separately", func(ctx SpecContext) {
+		provider := cache.Wrap(stub, "aws/account", "linux-arm64", time.Minute)
+		other := cache.Wrap(stub, "aws/other-account", "linux-arm64", time.Minute)
+		_, err := provider.ListInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = provider.ListInstances(nil, ctx, "other-tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = other.ListInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stub.calls).Should(Equal(3))
+	})
+
+	It("should cache the instances of each platform of an account separately", func(ctx SpecContext) {
+		provider := cache.Wrap(stub, "aws/account", "linux-arm64", time.Minute)
+		other := cache.Wrap(stub, "aws/account", "linux-amd64", time.Minute)
+		_, err := provider.CountInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = other.CountInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stub.calls).Should(Equal(2))
+
+		// Launching an instance for either platform invalidates the cached instances of both
+		_, err = other.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "tag", nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = provider.CountInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stub.calls).Should(Equal(4))
+	})
+
+	It("should share the cached instances between the providers of a platform", func(ctx SpecContext) {
+		_, err := cache.Wrap(stub, "aws/account", "linux-arm64", time.Minute).CountInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = cache.Wrap(stub, "aws/account", "linux-arm64", time.Minute).CountInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stub.calls).Should(Equal(1))
+	})
+
+	It("should call the provider again once the cached instances expire", func(ctx SpecContext) {
+		provider := cache.Wrap(stub, "aws/account", "linux-arm64", 20*time.Millisecond)
+		_, err := provider.CountInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		time.Sleep(30 * time.Millisecond)
+		_, err = provider.CountInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stub.calls).Should(Equal(2))
+	})
+
+	It("should invalidate the cached instances of the account when an instance is launched or terminated", func(ctx SpecContext) {
+		provider := cache.Wrap(stub, "aws/account", "linux-arm64", time.Minute)
+		_, err := provider.ListInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		_, err = provider.LaunchInstance(nil, ctx, "ns:name", "tag", nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = provider.ListInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stub.calls).Should(Equal(3))
+
+		Expect(provider.TerminateInstance(nil, ctx, "instance")).Should(Succeed())
+		_, err = provider.ListInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stub.calls).Should(Equal(5))
+	})
+
+	It("should not cache failures", func(ctx SpecContext) {
+		provider := cache.Wrap(stub, "aws/account", "linux-arm64", time.Minute)
+		stub.err = errors.New("service unavailable")
+		_, err := provider.CountInstances(nil, ctx, "tag")
+		Expect(err).Should(MatchError("service unavailable"))
+
+		stub.err = nil
+		count, err := provider.CountInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(count).Should(Equal(1))
+		Expect(stub.calls).Should(Equal(2))
+	})
+
+	It("should return instances that callers are free to modify", func(ctx SpecContext) {
+		provider := cache.Wrap(stub, "aws/account", "linux-arm64", time.Minute)
+		instances, err := provider.ListInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		instances[0].Address = "modified"
+
+		instances, err = provider.ListInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(instances[0].Address).Should(BeEmpty())
+	})
+
+	It("should call the provider once for concurrent calls", func(ctx SpecContext) {
+		provider := cache.Wrap(stub, "aws/account", "linux-arm64", time.Minute)
+		var wg sync.WaitGroup
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer GinkgoRecover()
+				_, err := provider.ListInstances(nil, ctx, "tag")
+				Expect(err).ShouldNot(HaveOccurred())
+			}()
+		}
+		wg.Wait()
+		Expect(stub.calls).Should(Equal(1))
+	})
+
+	It("should not cache anything when the TTL is zero", func(ctx SpecContext) {
+		Expect(cache.Wrap(stub, "aws/account", "linux-arm64", 0)).Should(BeIdenticalTo(stub))
+	})
+
+	It("should only report instance details when the wrapped provider does", func() {
+		_, ok := DetailsReporter(cache.Wrap(stub, "aws/account", "linux-arm64", time.Minute))
+		Expect(ok).Should(BeFalse())
+		_, ok = DetailsReporter(cache.Wrap(&stubDetailsReporter{}, "aws/account", "linux-arm64", time.Minute))
+		Expect(ok).Should(BeTrue())
+	})
+
+	It("should invalidate the cached instances when instances are stopped or started", func(ctx SpecContext) {
+		_, ok := Hibernator(cache.Wrap(stub, "aws/account", "linux-arm64", time.Minute))
+		Expect(ok).Should(BeFalse())
+
+		hibernatingStub := &stubHibernatingDetailsReporter{}
+		provider := cache.Wrap(hibernatingStub, "aws/account", "linux-arm64", time.Minute)
+		_, ok = DetailsReporter(provider)
+		Expect(ok).Should(BeTrue())
+		hibernator, ok := Hibernator(provider)
+		Expect(ok).Should(BeTrue())
+
+		_, err := provider.ListInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(hibernator.StopInstance(nil, ctx, "instance")).Should(Succeed())
+		_, err = provider.ListInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(hibernator.StartInstance(nil, ctx, "instance")).Should(Succeed())
+		_, err = provider.ListInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(hibernatingStub.calls).Should(Equal(5))
+	})
+})
@@ -15,6 +15,8 @@ const (
 	TaskRunTagKey         = "taskRunID"
 	OKState       VMState = "OK"
 	FailedState   VMState = "FAILED"
+	// InterruptedState is reported for instances reclaimed by the cloud provider, e.g. interrupted Spot instances.
+	InterruptedState VMState = "INTERRUPTED"
 )
 
 // Regular expression for RFC 1123 Label Names, used for K8s namespaces validation for ValidateTaskRunID.
@@ -23,18 +25,95 @@ var rfc1123Regex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
 type CloudProvider interface {
 	LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (InstanceIdentifier, error)
 	TerminateInstance(kubeClient client.Client, ctx context.Context, instance InstanceIdentifier) error
-	// GetInstanceAddress this only returns an error if it is a permanent error and the host will not ever be available
+	// GetInstanceAddress this only returns an error if it is a permanent error and the host will not ever be available.
+	// Transient failures return an empty address, like an instance that is still booting, so they do not count
+	// towards the circuit of the account; GetState, which is called next, reports them instead.
 	GetInstanceAddress(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (string, error)
 	CountInstances(kubeClient client.Client, ctx context.Context, instanceTag string) (int, error)
 	ListInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]CloudVMInstance, error)
+	// GetState returns an error when the state of the instance cannot be retrieved, e.g. because the API of the
+	// cloud provider is unavailable, so that the failure counts towards the circuit of the account.
 	GetState(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (VMState, error)
 	SshUser() string
 }
 
+// InstanceDetailsReporter is implemented by cloud providers that choose some details of an instance at launch
+// time, such as one of several instance types or the latest image, to report the details that were chosen. Use
+// DetailsReporter to get it from a provider.
+type InstanceDetailsReporter interface {
+	GetInstanceDetails(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (InstanceDetails, error)
+}
+
+// InstanceHibernator is implemented by cloud providers that can stop instances and start them again, so that the idle
+// instances of expensive dynamic pool platforms can be stopped rather than terminated. Stopped instances are not
+// returned by ListInstances, but they are still counted by CountInstances. Use Hibernator to get it from a provider.
+type InstanceHibernator interface {
+	StopInstance(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) error
+	// StartInstance starts a stopped instance, which is returned by ListInstances again once it is ready.
+	StartInstance(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) error
+	ListStoppedInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]CloudVMInstance, error)
+}
+
+// InstanceTaskRunReporter is implemented by cloud providers whose listed instances do not include the TaskRun they
+// were launched for, to look it up for a single instance. Looking it up takes at least one more API call, so it is
+// only done when the TaskRun of an instance is needed, e.g. to collect orphaned instances. Use TaskRunReporter to
+// get it from a provider.
+type InstanceTaskRunReporter interface {
+	// GetInstanceTaskRunID returns the taskRunID tag the instance was launched with, or an empty string if it has
+	// none.
+	GetInstanceTaskRunID(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (string, error)
+}
+
+// wrappingProvider is implemented by the providers wrapping another provider, which implement the optional
+// interfaces of the provider they wrap through these methods rather than directly.
+type wrappingProvider interface {
+	detailsReporter() (InstanceDetailsReporter, bool)
+	hibernator() (InstanceHibernator, bool)
+	taskRunReporter() (InstanceTaskRunReporter, bool)
+}
+
+// DetailsReporter returns provider as an InstanceDetailsReporter, or false if it does not report the details of its
+// instances.
+func DetailsReporter(provider CloudProvider) (InstanceDetailsReporter, bool) {
+	if wrapper, ok := provider.(wrappingProvider); ok {
+		return wrapper.detailsReporter()
+	}
+	reporter, ok := provider.(InstanceDetailsReporter)
+	return reporter, ok
+}
+
+// Hibernator returns provider as an InstanceHibernator, or false if it cannot stop and start its instances.
+func Hibernator(provider CloudProvider) (InstanceHibernator, bool) {
+	if wrapper, ok := provider.(wrappingProvider); ok {
+		return wrapper.hibernator()
+	}
+	hibernator, ok := provider.(InstanceHibernator)
+	return hibernator, ok
+}
+
+// TaskRunReporter returns provider as an InstanceTaskRunReporter, or false if its listed instances already include
+// the TaskRun they were launched for, or if it cannot report it at all.
+func TaskRunReporter(provider CloudProvider) (InstanceTaskRunReporter, bool) {
+	if wrapper, ok := provider.(wrappingProvider); ok {
+		return wrapper.taskRunReporter()
+	}
+	reporter, ok := provider.(InstanceTaskRunReporter)
+	return reporter, ok
+}
+
+// InstanceDetails are the details an instance was launched with. Details that are not known are empty.
+type InstanceDetails struct {
+	InstanceType string
+	Image        string
+}
+
 type CloudVMInstance struct {
 	InstanceId InstanceIdentifier
-	StartTime  time.Time
-	Address    string
+	// StartTime is when the instance was created, which does not change when a stopped instance is started again.
+	StartTime time.Time
+	Address   string
+	// TaskRunID is the taskRunID tag the instance was launched with, empty when the provider cannot report it.
+	TaskRunID string
 }
 
 type InstanceIdentifier string
@@ -0,0 +1,338 @@
+// Package fake implements an in-memory CloudProvider that simulates a cloud without any cloud account. It is
+// selected with the "fake" type in the host configuration and is meant for running the controller against a
+// local cluster to reproduce allocation, queueing and timeout behaviour.
+//
+// Boot latency, IP address assignment delays, launch failures, instances entering a failed state and quota
+// errors are all scripted through the dynamic.<platform>.* configuration keys. The state of the simulated cloud
+// is shared by all providers created for the same platform, so it survives host configuration reloads, but it
+// is lost when the controller restarts.
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultSshUser = "root"
+)
+
+var (
+	// ErrQuotaExceeded is returned by LaunchInstance when the simulated account has reached its instance quota.
+	ErrQuotaExceeded = errors.New("instance quota exceeded")
+
+	// ErrLaunchFailed is returned by LaunchInstance for a simulated launch failure.
+	ErrLaunchFailed = errors.New("simulated launch failure")
+
+	// clouds holds the simulated cloud of each platform.
+	clouds   = map[string]*fakeCloud{}
+	cloudsMu sync.Mutex
+)
+
+// CreateFakeCloudConfig returns a simulated cloud configuration that implements the CloudProvider interface.
+// Invalid values of the optional keys are ignored and their defaults are used instead.
+func CreateFakeCloudConfig(platformName string, config map[string]string, systemNamespace string) cloud.CloudProvider {
+	duration := func(key string) time.Duration {
+		d, err := time.ParseDuration(config["dynamic."+platformName+"."+key])
+		if err != nil || d < 0 {
+			return 0
+		}
+		return d
+	}
+	rate := func(key string) float64 {
+		r, err := strconv.ParseFloat(config["dynamic."+platformName+"."+key], 64)
+		if err != nil || r < 0 {
+			return 0
+		}
+		return min(r, 1)
+	}
+	quota, err := strconv.Atoi(config["dynamic."+platformName+".quota"])
+	if err != nil || quota < 0 {
+		quota = 0
+	}
+	seed, err := strconv.ParseInt(config["dynamic."+platformName+".seed"], 10, 64)
+	if err != nil {
+		seed = time.Now().UnixNano()
+	}
+	sshUser := config["dynamic."+platformName+".ssh-user"]
+	if sshUser == "" {
+		sshUser = defaultSshUser
+	}
+
+	return FakeDynamicConfig{
+		LaunchLatency:     duration("launch-latency"),
+		AddressDelay:      duration("address-delay"),
+		LaunchFailureRate: rate("launch-failure-rate"),
+		FailedStateRate:   rate("failed-state-rate"),
+		Quota:             quota,
+		Address:           config["dynamic."+platformName+".address"],
+		User:              sshUser,
+		cloud:             cloudFor(platformName, seed),
+	}
+}
+
+// LaunchInstance simulates launching an instance and returns its identifier. The call takes LaunchLatency and
+// fails with ErrQuotaExceeded or ErrLaunchFailed as configured.
+func (f FakeDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (cloud.InstanceIdentifier, error) {
+	err := cloud.ValidateTaskRunID(taskRunID)
+	if err != nil {
+		return "", fmt.Errorf("invalid TaskRun ID: %w", err)
+	}
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to launch fake instance", "taskRunID", taskRunID, "latency", f.LaunchLatency)
+
+	if err := sleep(ctx, f.LaunchLatency); err != nil {
+		return "", fmt.Errorf("failed to launch fake instance for %s: %w", taskRunID, err)
+	}
+
+	f.cloud.mu.Lock()
+	defer f.cloud.mu.Unlock()
+	if f.Quota > 0 && len(f.cloud.instances) >= f.Quota {
+		return "", fmt.Errorf("failed to launch fake instance for %s: %w (%d instances)", taskRunID, ErrQuotaExceeded, f.Quota)
+	}
+	if f.cloud.rand.Float64() < f.LaunchFailureRate {
+		return "", fmt.Errorf("failed to launch fake instance for %s: %w", taskRunID, ErrLaunchFailed)
+	}
+
+	f.cloud.next++
+	now := f.cloud.now()
+	instance := &fakeInstance{
+		id:          cloud.InstanceIdentifier(fmt.Sprintf("fake-%d", f.cloud.next)),
+		instanceTag: instanceTag,
+		taskRunID:   taskRunID,
+		startTime:   now,
+		readyTime:   now.Add(f.AddressDelay),
+		failed:      f.cloud.rand.Float64() < f.FailedStateRate,
+		address:     f.Address,
+	}
+	if instance.address == "" {
+		instance.address = fmt.Sprintf("192.0.2.%d", (f.cloud.next-1)%254+1)
+	}
+	f.cloud.instances[instance.id] = instance
+	log.Info("Launched fake instance", "instanceID", instance.id, "willFail", instance.failed)
+	return instance.id, nil
+}
+
+// CountInstances returns the number of simulated instances tagged with instanceTag, including the stopped ones.
+func (f FakeDynamicConfig) CountInstances(kubeClient client.Client, ctx context.Context, instanceTag string) (int, error) {
+	f.cloud.mu.Lock()
+	defer f.cloud.mu.Unlock()
+	count := 0
+	for _, instance := range f.cloud.instances {
+		if instance.instanceTag == instanceTag {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetInstanceAddress returns the address of the instanceID simulated instance once AddressDelay has passed since
+// it was launched; before that, an empty string is returned. An instance that is scripted to fail never gets an
+// address and reports a permanent error instead.
+func (f FakeDynamicConfig) GetInstanceAddress(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (string, error) {
+	f.cloud.mu.Lock()
+	defer f.cloud.mu.Unlock()
+	instance, ok := f.cloud.instances[instanceID]
+	if !ok {
+		return "", fmt.Errorf("fake instance %s does not exist", instanceID)
+	}
+	if instance.stopped || f.cloud.now().Before(instance.readyTime) {
+		return "", nil
+	}
+	if instance.failed {
+		return "", fmt.Errorf("fake instance %s failed", instanceID)
+	}
+	return instance.address, nil
+}
+
+// TerminateInstance removes the instanceID simulated instance. An instance that no longer exists is not an error.
+func (f FakeDynamicConfig) TerminateInstance(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) error {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to terminate fake instance", "instanceID", instanceID)
+
+	f.cloud.mu.Lock()
+	defer f.cloud.mu.Unlock()
+	delete(f.cloud.instances, instanceID)
+	return nil
+}
+
+// StopInstance stops the instanceID simulated instance, which loses its address until it is started again.
+func (f FakeDynamicConfig) StopInstance(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) error {
+	f.cloud.mu.Lock()
+	defer f.cloud.mu.Unlock()
+	instance, ok := f.cloud.instances[instanceID]
+	if !ok {
+		return fmt.Errorf("fake instance %s does not exist", instanceID)
+	}
+	instance.stopped = true
+	return nil
+}
+
+// StartInstance starts the instanceID simulated instance, which gets its address again once AddressDelay has passed.
+func (f FakeDynamicConfig) StartInstance(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) error {
+	f.cloud.mu.Lock()
+	defer f.cloud.mu.Unlock()
+	instance, ok := f.cloud.instances[instanceID]
+	if !ok {
+		return fmt.Errorf("fake instance %s does not exist", instanceID)
+	}
+	if instance.stopped {
+		instance.stopped = false
+		instance.readyTime = f.cloud.now().Add(f.AddressDelay)
+	}
+	return nil
+}
+
+// ListStoppedInstances returns the stopped simulated instances tagged with instanceTag.
+func (f FakeDynamicConfig) ListStoppedInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]cloud.CloudVMInstance, error) {
+	f.cloud.mu.Lock()
+	defer f.cloud.mu.Unlock()
+	vmInstances := []cloud.CloudVMInstance{}
+	for _, instance := range f.cloud.instances {
+		if instance.instanceTag != instanceTag || !instance.stopped {
+			continue
+		}
+		vmInstances = append(vmInstances, cloud.CloudVMInstance{
+			InstanceId: instance.id,
+			StartTime:  instance.startTime,
+			TaskRunID:  instance.taskRunID,
+		})
+	}
+	return vmInstances, nil
+}
+
+// ListInstances returns the simulated instances tagged with instanceTag that have an address and are not stopped.
+func (f FakeDynamicConfig) ListInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]cloud.CloudVMInstance, error) {
+	f.cloud.mu.Lock()
+	defer f.cloud.mu.Unlock()
+	now := f.cloud.now()
+	vmInstances := []cloud.CloudVMInstance{}
+	for _, instance := range f.cloud.instances {
+		if instance.instanceTag != instanceTag || instance.failed || instance.stopped || now.Before(instance.readyTime) {
+			continue
+		}
+		vmInstances = append(vmInstances, cloud.CloudVMInstance{
+			InstanceId: instance.id,
+			StartTime:  instance.startTime,
+			Address:    instance.address,
+			TaskRunID:  instance.taskRunID,
+		})
+	}
+	return vmInstances, nil
+}
+
+func (f FakeDynamicConfig) SshUser() string {
+	return f.User
+}
+
+// GetState returns instanceID's simulated state. An instance that is scripted to fail enters the failed state
+// once AddressDelay has passed since it was launched; an instance that no longer exists is in a failed state.
+func (f FakeDynamicConfig) GetState(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (cloud.VMState, error) {
+	f.cloud.mu.Lock()
+	defer f.cloud.mu.Unlock()
+	instance, ok := f.cloud.instances[instanceID]
+	if !ok {
+		return cloud.FailedState, nil
+	}
+	if instance.failed && !f.cloud.now().Before(instance.readyTime) {
+		return cloud.FailedState, nil
+	}
+	return cloud.OKState, nil
+}
+
+// A FakeDynamicConfig represents a configuration for a simulated cloud.
+// The zero value (where each field will be assigned its type's zero value) is not a
+// valid FakeDynamicConfig.
+type FakeDynamicConfig struct {
+	// LaunchLatency is how long LaunchInstance takes to return.
+	LaunchLatency time.Duration
+
+	// AddressDelay is how long after launching an instance gets an address, or enters
+	// the failed state if it is scripted to fail.
+	AddressDelay time.Duration
+
+	// LaunchFailureRate is the probability (0 to 1) of LaunchInstance failing with ErrLaunchFailed.
+	LaunchFailureRate float64
+
+	// FailedStateRate is the probability (0 to 1) of a launched instance entering the failed state
+	// instead of getting an address.
+	FailedStateRate float64
+
+	// Quota is the maximum number of instances of the platform, regardless of their tags. LaunchInstance
+	// fails with ErrQuotaExceeded once it is reached. Zero means unlimited.
+	Quota int
+
+	// Address is the address of all instances, e.g. an SSH server reachable from the controller. When
+	// empty, each instance gets a distinct address from the 192.0.2.0/24 documentation range.
+	Address string
+
+	// User is the SSH user of the instances.
+	User string
+
+	// cloud is the simulated cloud shared by all providers of the platform.
+	cloud *fakeCloud
+}
+
+// fakeCloud is the state of the simulated cloud of a platform.
+type fakeCloud struct {
+	mu        sync.Mutex
+	instances map[cloud.InstanceIdentifier]*fakeInstance
+	next      int
+	rand      *rand.Rand
+
+	// now allows tests to control the simulated time.
+	now func() time.Time
+}
+
+// fakeInstance is an instance in the simulated cloud.
+type fakeInstance struct {
+	id          cloud.InstanceIdentifier
+	instanceTag string
+	taskRunID   string
+	startTime   time.Time
+	readyTime   time.Time
+	failed      bool
+	stopped     bool
+	address     string
+}
+
+// cloudFor returns the simulated cloud of platformName, creating it with the random seed if it does not exist.
+func cloudFor(platformName string, seed int64) *fakeCloud {
+	cloudsMu.Lock()
+	defer cloudsMu.Unlock()
+	c, ok := clouds[platformName]
+	if !ok {
+		c = &fakeCloud{
+			instances: map[cloud.InstanceIdentifier]*fakeInstance{},
+			// #nosec G404 -- simulated failures do not need a cryptographically secure random number generator
+			rand: rand.New(rand.NewSource(seed)),
+			now:  time.Now,
+		}
+		clouds[platformName] = c
+	}
+	return c
+}
+
+// sleep waits for d or until ctx is done, whichever happens first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
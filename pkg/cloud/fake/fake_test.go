@@ -0,0 +1,232 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	platformName    = "linux-arm64"
+	systemNamespace = "multi-platform-controller"
+)
+
+var _ = Describe("Fake Unit Test Suite", func() {
+
+	BeforeEach(func() {
+		cloudsMu.Lock()
+		delete(clouds, platformName)
+		cloudsMu.Unlock()
+	})
+
+	Describe("Testing CreateFakeCloudConfig", func() {
+
+		DescribeTable("Testing the creation of FakeDynamicConfig properly no matter the values",
+			func(testConfig map[string]string, expected FakeDynamicConfig) {
+				config := map[string]string{}
+				for key, value := range testConfig {
+					config["dynamic."+platformName+"."+key] = value
+				}
+				provider := CreateFakeCloudConfig(platformName, config, systemNamespace)
+				Expect(provider).ToNot(BeNil())
+				providerConfig := provider.(FakeDynamicConfig)
+
+				Expect(providerConfig.cloud).ToNot(BeNil())
+				providerConfig.cloud = nil
+				Expect(providerConfig).To(Equal(expected))
+			},
+			Entry("Positive - valid config map keys",
+				map[string]string{
+					"launch-latency":      "2s",
+					"address-delay":       "1m",
+					"launch-failure-rate": "0.25",
+					"failed-state-rate":   "0.5",
+					"quota":               "3",
+					"address":             "sshd.multi-platform-controller.svc",
+					"ssh-user":            "builder",
+				},
+				FakeDynamicConfig{
+					LaunchLatency:     2 * time.Second,
+					AddressDelay:      time.Minute,
+					LaunchFailureRate: 0.25,
+					FailedStateRate:   0.5,
+					Quota:             3,
+					Address:           "sshd.multi-platform-controller.svc",
+					User:              "builder",
+				}),
+			Entry("Negative - missing config data",
+				map[string]string{},
+				FakeDynamicConfig{User: defaultSshUser}),
+			Entry("Negative - invalid values",
+				map[string]string{
+					"launch-latency":      "soon",
+					"address-delay":       "-1s",
+					"launch-failure-rate": "-0.5",
+					"failed-state-rate":   "2",
+					"quota":               "-1",
+				},
+				FakeDynamicConfig{FailedStateRate: 1, User: defaultSshUser}),
+		)
+
+		It("should share the simulated cloud between providers of the same platform", func() {
+			first := CreateFakeCloudConfig(platformName, map[string]string{}, systemNamespace).(FakeDynamicConfig)
+			second := CreateFakeCloudConfig(platformName, map[string]string{}, systemNamespace).(FakeDynamicConfig)
+			other := CreateFakeCloudConfig("linux-amd64", map[string]string{}, systemNamespace).(FakeDynamicConfig)
+			Expect(first.cloud).To(BeIdenticalTo(second.cloud))
+			Expect(first.cloud).ToNot(BeIdenticalTo(other.cloud))
+		})
+	})
+
+	Describe("CloudProvider methods", func() {
+		var (
+			cfg FakeDynamicConfig
+			ctx context.Context
+			now time.Time
+		)
+
+		// newConfig creates a provider with the given configuration keys whose simulated time is controlled by now.
+		newConfig := func(testConfig map[string]string) FakeDynamicConfig {
+			config := map[string]string{"dynamic." + platformName + ".seed": "1"}
+			for key, value := range testConfig {
+				config["dynamic."+platformName+"."+key] = value
+			}
+			provider := CreateFakeCloudConfig(platformName, config, systemNamespace).(FakeDynamicConfig)
+			provider.cloud.now = func() time.Time { return now }
+			return provider
+		}
+
+		launch := func() cloud.InstanceIdentifier {
+			instanceID, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", cloud.InstanceTag, nil)
+			Expect(err).ToNot(HaveOccurred())
+			return instanceID
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			now = time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+			cfg = newConfig(map[string]string{"address-delay": "30s"})
+		})
+
+		It("should only give an instance an address after the address delay", func() {
+			instanceID := launch()
+
+			address, err := cfg.GetInstanceAddress(nil, ctx, instanceID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(address).To(BeEmpty())
+			Expect(cfg.ListInstances(nil, ctx, cloud.InstanceTag)).To(BeEmpty())
+			Expect(cfg.GetState(nil, ctx, instanceID)).To(Equal(cloud.OKState))
+
+			now = now.Add(30 * time.Second)
+			address, err = cfg.GetInstanceAddress(nil, ctx, instanceID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(address).To(Equal("192.0.2.1"))
+			instances, err := cfg.ListInstances(nil, ctx, cloud.InstanceTag)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instances).To(ConsistOf(cloud.CloudVMInstance{
+				InstanceId: instanceID,
+				StartTime:  now.Add(-30 * time.Second),
+				Address:    "192.0.2.1",
+				TaskRunID:  "test-namespace:test-taskrun",
+			}))
+		})
+
+		It("should give all instances the configured address", func() {
+			cfg = newConfig(map[string]string{"address": "10.96.0.22"})
+			instanceID := launch()
+			Expect(cfg.GetInstanceAddress(nil, ctx, instanceID)).To(Equal("10.96.0.22"))
+		})
+
+		It("should count and terminate instances by tag", func() {
+			first := launch()
+			launch()
+			_, err := cfg.LaunchInstance(nil, ctx, "test-namespace:other-taskrun", "other-tag", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg.CountInstances(nil, ctx, cloud.InstanceTag)).To(Equal(2))
+
+			Expect(cfg.TerminateInstance(nil, ctx, first)).To(Succeed())
+			Expect(cfg.CountInstances(nil, ctx, cloud.InstanceTag)).To(Equal(1))
+			Expect(cfg.GetState(nil, ctx, first)).To(Equal(cloud.FailedState))
+			_, err = cfg.GetInstanceAddress(nil, ctx, first)
+			Expect(err).To(HaveOccurred())
+
+			// Terminating an instance that no longer exists is not an error
+			Expect(cfg.TerminateInstance(nil, ctx, first)).To(Succeed())
+		})
+
+		It("should fail launches once the quota is reached", func() {
+			cfg = newConfig(map[string]string{"quota": "1"})
+			instanceID := launch()
+			_, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", cloud.InstanceTag, nil)
+			Expect(errors.Is(err, ErrQuotaExceeded)).To(BeTrue())
+
+			Expect(cfg.TerminateInstance(nil, ctx, instanceID)).To(Succeed())
+			launch()
+		})
+
+		It("should fail every launch with a launch failure rate of 1", func() {
+			cfg = newConfig(map[string]string{"launch-failure-rate": "1"})
+			_, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", cloud.InstanceTag, nil)
+			Expect(errors.Is(err, ErrLaunchFailed)).To(BeTrue())
+			Expect(cfg.CountInstances(nil, ctx, cloud.InstanceTag)).To(Equal(0))
+		})
+
+		It("should put instances in the failed state after the address delay with a failed state rate of 1", func() {
+			cfg = newConfig(map[string]string{"address-delay": "30s", "failed-state-rate": "1"})
+			instanceID := launch()
+			Expect(cfg.GetState(nil, ctx, instanceID)).To(Equal(cloud.OKState))
+			Expect(cfg.GetInstanceAddress(nil, ctx, instanceID)).To(BeEmpty())
+
+			now = now.Add(30 * time.Second)
+			Expect(cfg.GetState(nil, ctx, instanceID)).To(Equal(cloud.FailedState))
+			_, err := cfg.GetInstanceAddress(nil, ctx, instanceID)
+			Expect(err).To(HaveOccurred())
+			Expect(cfg.ListInstances(nil, ctx, cloud.InstanceTag)).To(BeEmpty())
+			Expect(cfg.CountInstances(nil, ctx, cloud.InstanceTag)).To(Equal(1))
+		})
+
+		It("should take the launch latency to launch an instance unless the context is done", func() {
+			cfg = newConfig(map[string]string{"launch-latency": "50ms"})
+			start := time.Now()
+			launch()
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+
+			cfg = newConfig(map[string]string{"launch-latency": "1h"})
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+			_, err := cfg.LaunchInstance(nil, cancelled, "test-namespace:test-taskrun", cloud.InstanceTag, nil)
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		})
+
+		It("should stop instances and start them again after the address delay", func() {
+			instanceID := launch()
+			now = now.Add(30 * time.Second)
+
+			Expect(cfg.StopInstance(nil, ctx, instanceID)).To(Succeed())
+			Expect(cfg.ListInstances(nil, ctx, cloud.InstanceTag)).To(BeEmpty())
+			Expect(cfg.GetInstanceAddress(nil, ctx, instanceID)).To(BeEmpty())
+			Expect(cfg.CountInstances(nil, ctx, cloud.InstanceTag)).To(Equal(1))
+			stopped, err := cfg.ListStoppedInstances(nil, ctx, cloud.InstanceTag)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stopped).To(HaveLen(1))
+			Expect(stopped[0].InstanceId).To(Equal(instanceID))
+
+			Expect(cfg.StartInstance(nil, ctx, instanceID)).To(Succeed())
+			Expect(cfg.ListStoppedInstances(nil, ctx, cloud.InstanceTag)).To(BeEmpty())
+			Expect(cfg.GetInstanceAddress(nil, ctx, instanceID)).To(BeEmpty())
+			now = now.Add(30 * time.Second)
+			Expect(cfg.GetInstanceAddress(nil, ctx, instanceID)).To(Equal("192.0.2.1"))
+			Expect(cfg.ListInstances(nil, ctx, cloud.InstanceTag)).To(HaveLen(1))
+
+			Expect(cfg.StopInstance(nil, ctx, "fake-404")).ToNot(Succeed())
+		})
+
+		It("should reject an invalid TaskRun ID", func() {
+			_, err := cfg.LaunchInstance(nil, ctx, "invalid", cloud.InstanceTag, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
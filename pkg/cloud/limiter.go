@@ -0,0 +1,338 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Limits are the limits applied to the calls made to a single cloud provider account.
+type Limits struct {
+	// RateLimit is the number of calls per second allowed to the account.
+	RateLimit float64
+	// Burst is the number of calls that can be made at once before RateLimit applies.
+	Burst int
+	// FailureThreshold is the number of consecutive transient failures that opens the circuit of the account.
+	FailureThreshold int
+	// OpenDuration is how long the circuit stays open before calls to the account are attempted again.
+	OpenDuration time.Duration
+}
+
+// CircuitOpenError is returned instead of calling a cloud provider account whose circuit is open after repeated
+// transient failures. Callers are expected to wait for RetryAfter rather than treat the call as failed.
+type CircuitOpenError struct {
+	Account    string
+	Failures   int
+	RetryAfter time.Duration
+	// Err is the last failure of the account.
+	Err error
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("calls to cloud provider account %s are suspended for %s after %d consecutive failures: %v", e.Account, e.RetryAfter.Round(time.Second), e.Failures, e.Err)
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return e.Err
+}
+
+// halfOpenRetryAfter is how long callers wait while a call probes whether an account recovered.
+const halfOpenRetryAfter = 10 * time.Second
+
+// throttlingErrorCodes are the API error codes cloud providers report when calls are throttled or the service is
+// temporarily unavailable.
+var throttlingErrorCodes = []string{
+	"Throttling", "ThrottlingException", "RequestLimitExceeded", "RequestThrottled", "RequestThrottledException",
+	"TooManyRequestsException", "ServiceUnavailable", "Unavailable", "InternalError", "InternalFailure",
+}
+
+// isTransient returns whether err is a failure of the cloud provider account that is likely to go away on its own:
+// network failures, throttling and server errors. Other errors, e.g. invalid credentials, parameters or quotas, are
+// caused by the configuration of a platform and say nothing about the health of the account. Only the API errors
+// reporting their HTTP status or error code through the methods of the AWS, Google and OpenStack SDKs are told
+// apart, so the other cloud providers only open the circuit of their account on network failures.
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	transientStatus := func(status int) bool {
+		return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+	}
+	var awsErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &awsErr) && transientStatus(awsErr.HTTPStatusCode()) {
+		return true
+	}
+	var googleErr interface{ HTTPCode() int }
+	if errors.As(err, &googleErr) && transientStatus(googleErr.HTTPCode()) {
+		return true
+	}
+	var openStackErr interface{ GetStatusCode() int }
+	if errors.As(err, &openStackErr) && transientStatus(openStackErr.GetStatusCode()) {
+		return true
+	}
+	var apiErr interface{ ErrorCode() string }
+	return errors.As(err, &apiErr) && slices.Contains(throttlingErrorCodes, apiErr.ErrorCode())
+}
+
+// accountGuard rate limits the calls to a cloud provider account and tracks their consecutive transient failures.
+// Once the circuit of the account has been open for its duration, it is half-open: a single call probes whether the
+// account recovered, closing the circuit if it did and opening it again otherwise.
+type accountGuard struct {
+	limiter   *rate.Limiter
+	mu        sync.Mutex
+	limits    Limits
+	failures  int
+	lastErr   error
+	openUntil time.Time
+	probing   bool
+}
+
+// Limiters holds the limits of the cloud provider accounts. Providers wrapped by the same Limiters for the same
+// account share their limits, so that they apply to the account as a whole rather than to each platform using it.
+type Limiters struct {
+	mu       sync.Mutex
+	accounts map[string]*accountGuard
+}
+
+// NewLimiters returns Limiters that do not limit any account yet.
+func NewLimiters() *Limiters {
+	return &Limiters{accounts: map[string]*accountGuard{}}
+}
+
+// accountGuard returns the guard of account, updating its limits if they changed.
+func (l *Limiters) accountGuard(account string, limits Limits) *accountGuard {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	guard, ok := l.accounts[account]
+	if !ok {
+		guard = &accountGuard{limiter: rate.NewLimiter(rate.Limit(limits.RateLimit), limits.Burst), limits: limits}
+		l.accounts[account] = guard
+		return guard
+	}
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	if guard.limits != limits {
+		guard.limiter.SetLimit(rate.Limit(limits.RateLimit))
+		guard.limiter.SetBurst(limits.Burst)
+		guard.limits = limits
+	}
+	return guard
+}
+
+// call calls f once a rate limit token is available, unless the circuit of the account is open or another call is
+// probing whether the account recovered.
+func (g *accountGuard) call(ctx context.Context, account string, f func() error) error {
+	g.mu.Lock()
+	probe := false
+	if !g.openUntil.IsZero() {
+		wait := time.Until(g.openUntil)
+		if wait <= 0 && g.probing {
+			wait = halfOpenRetryAfter
+		}
+		if wait > 0 {
+			circuitErr := &CircuitOpenError{Account: account, Failures: g.failures, RetryAfter: wait, Err: g.lastErr}
+			g.mu.Unlock()
+			return circuitErr
+		}
+		g.probing = true
+		probe = true
+	}
+	g.mu.Unlock()
+
+	if err := g.limiter.Wait(ctx); err != nil {
+		if probe {
+			// The account was not called, so the next call probes it instead
+			g.mu.Lock()
+			g.probing = false
+			g.mu.Unlock()
+		}
+		return fmt.Errorf("rate limit of cloud provider account %s: %w", account, err)
+	}
+	err := f()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if probe {
+		g.probing = false
+	}
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		// The caller gave up, which says nothing about the health of the account
+	case err == nil || (probe && !isTransient(err)):
+		// The account answered, so it recovered even if the call itself failed
+		g.failures = 0
+		g.lastErr = nil
+		g.openUntil = time.Time{}
+	case isTransient(err):
+		// A probe failing opens the circuit again straight away
+		g.failures++
+		g.lastErr = err
+		if probe || g.failures >= g.limits.FailureThreshold {
+			g.openUntil = time.Now().Add(g.limits.OpenDuration)
+		}
+	}
+	return err
+}
+
+// limitedProvider is a CloudProvider that applies the limits of its account to the calls to the provider it wraps.
+type limitedProvider struct {
+	provider CloudProvider
+	account  string
+	guard    *accountGuard
+}
+
+// limitedDetailsReporter applies the limits of an account to the calls to get the details of instances.
+type limitedDetailsReporter struct {
+	provider InstanceDetailsReporter
+	account  string
+	guard    *accountGuard
+}
+
+// limitedHibernation applies the limits of an account to the calls to stop and start instances.
+type limitedHibernation struct {
+	provider InstanceHibernator
+	account  string
+	guard    *accountGuard
+}
+
+// limitedTaskRunReporter applies the limits of an account to the calls to look up the TaskRuns of instances.
+type limitedTaskRunReporter struct {
+	provider InstanceTaskRunReporter
+	account  string
+	guard    *accountGuard
+}
+
+// Wrap wraps provider so that its calls to the cloud provider account identified by account are rate limited, and
+// are not made at all while the circuit of the account is open after repeated failures.
+func (l *Limiters) Wrap(provider CloudProvider, account string, limits Limits) CloudProvider {
+	return limitedProvider{provider: provider, account: account, guard: l.accountGuard(account, limits)}
+}
+
+func (l limitedProvider) detailsReporter() (InstanceDetailsReporter, bool) {
+	reporter, ok := DetailsReporter(l.provider)
+	if !ok {
+		return nil, false
+	}
+	return limitedDetailsReporter{provider: reporter, account: l.account, guard: l.guard}, true
+}
+
+func (l limitedProvider) hibernator() (InstanceHibernator, bool) {
+	hibernator, ok := Hibernator(l.provider)
+	if !ok {
+		return nil, false
+	}
+	return limitedHibernation{provider: hibernator, account: l.account, guard: l.guard}, true
+}
+
+func (l limitedProvider) taskRunReporter() (InstanceTaskRunReporter, bool) {
+	reporter, ok := TaskRunReporter(l.provider)
+	if !ok {
+		return nil, false
+	}
+	return limitedTaskRunReporter{provider: reporter, account: l.account, guard: l.guard}, true
+}
+
+func (l limitedProvider) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (InstanceIdentifier, error) {
+	var ret InstanceIdentifier
+	err := l.guard.call(ctx, l.account, func() (err error) {
+		ret, err = l.provider.LaunchInstance(kubeClient, ctx, taskRunID, instanceTag, additionalInstanceTags)
+		return err
+	})
+	return ret, err
+}
+
+func (l limitedProvider) TerminateInstance(kubeClient client.Client, ctx context.Context, instance InstanceIdentifier) error {
+	return l.guard.call(ctx, l.account, func() error {
+		return l.provider.TerminateInstance(kubeClient, ctx, instance)
+	})
+}
+
+func (l limitedProvider) GetInstanceAddress(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (string, error) {
+	var ret string
+	err := l.guard.call(ctx, l.account, func() (err error) {
+		ret, err = l.provider.GetInstanceAddress(kubeClient, ctx, instanceId)
+		return err
+	})
+	return ret, err
+}
+
+func (l limitedProvider) CountInstances(kubeClient client.Client, ctx context.Context, instanceTag string) (int, error) {
+	var ret int
+	err := l.guard.call(ctx, l.account, func() (err error) {
+		ret, err = l.provider.CountInstances(kubeClient, ctx, instanceTag)
+		return err
+	})
+	return ret, err
+}
+
+func (l limitedProvider) ListInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]CloudVMInstance, error) {
+	var ret []CloudVMInstance
+	err := l.guard.call(ctx, l.account, func() (err error) {
+		ret, err = l.provider.ListInstances(kubeClient, ctx, instanceTag)
+		return err
+	})
+	return ret, err
+}
+
+func (l limitedProvider) GetState(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (VMState, error) {
+	var ret VMState
+	err := l.guard.call(ctx, l.account, func() (err error) {
+		ret, err = l.provider.GetState(kubeClient, ctx, instanceId)
+		return err
+	})
+	return ret, err
+}
+
+func (l limitedProvider) SshUser() string {
+	return l.provider.SshUser()
+}
+
+func (l limitedDetailsReporter) GetInstanceDetails(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (InstanceDetails, error) {
+	var ret InstanceDetails
+	err := l.guard.call(ctx, l.account, func() (err error) {
+		ret, err = l.provider.GetInstanceDetails(kubeClient, ctx, instanceId)
+		return err
+	})
+	return ret, err
+}
+
+func (l limitedTaskRunReporter) GetInstanceTaskRunID(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (string, error) {
+	var ret string
+	err := l.guard.call(ctx, l.account, func() (err error) {
+		ret, err = l.provider.GetInstanceTaskRunID(kubeClient, ctx, instanceId)
+		return err
+	})
+	return ret, err
+}
+
+func (l limitedHibernation) StopInstance(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) error {
+	return l.guard.call(ctx, l.account, func() error {
+		return l.provider.StopInstance(kubeClient, ctx, instanceId)
+	})
+}
+
+func (l limitedHibernation) StartInstance(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) error {
+	return l.guard.call(ctx, l.account, func() error {
+		return l.provider.StartInstance(kubeClient, ctx, instanceId)
+	})
+}
+
+func (l limitedHibernation) ListStoppedInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]CloudVMInstance, error) {
+	var ret []CloudVMInstance
+	err := l.guard.call(ctx, l.account, func() (err error) {
+		ret, err = l.provider.ListStoppedInstances(kubeClient, ctx, instanceTag)
+		return err
+	})
+	return ret, err
+}
@@ -0,0 +1,405 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// stubProvider is a CloudProvider counting its calls, whose calls fail while err is set.
+type stubProvider struct {
+	calls int
+	err   error
+}
+
+func (s *stubProvider) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (InstanceIdentifier, error) {
+	s.calls++
+	return "instance", s.err
+}
+
+func (s *stubProvider) TerminateInstance(kubeClient client.Client, ctx context.Context, instance InstanceIdentifier) error {
+	s.calls++
+	return s.err
+}
+
+func (s *stubProvider) GetInstanceAddress(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (string, error) {
+	s.calls++
+	return "10.0.0.1", s.err
+}
+
+func (s *stubProvider) CountInstances(kubeClient client.Client, ctx context.Context, instanceTag string) (int, error) {
+	s.calls++
+	return 1, s.err
+}
+
+func (s *stubProvider) ListInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]CloudVMInstance, error) {
+	s.calls++
+	return []CloudVMInstance{{InstanceId: "instance"}}, s.err
+}
+
+func (s *stubProvider) GetState(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (VMState, error) {
+	s.calls++
+	return OKState, s.err
+}
+
+func (s *stubProvider) SshUser() string {
+	return "root"
+}
+
+// stubDetailsReporter is a stubProvider that also reports the details of its instances.
+type stubDetailsReporter struct {
+	stubProvider
+}
+
+func (s *stubDetailsReporter) GetInstanceDetails(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (InstanceDetails, error) {
+	s.calls++
+	return InstanceDetails{InstanceType: "m5.large"}, s.err
+}
+
+// stubTaskRunReporter is a stubProvider that looks up the TaskRuns of its instances.
+type stubTaskRunReporter struct {
+	stubProvider
+}
+
+func (s *stubTaskRunReporter) GetInstanceTaskRunID(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (string, error) {
+	s.calls++
+	return "test-ns:test-taskrun", s.err
+}
+
+// stubHibernator is a stubProvider that can also stop and start its instances.
+type stubHibernator struct {
+	stubProvider
+	stopped []InstanceIdentifier
+}
+
+func (s *stubHibernator) StopInstance(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) error {
+	s.calls++
+	s.stopped = append(s.stopped, instanceId)
+	return s.err
+}
+
+func (s *stubHibernator) StartInstance(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) error {
+	s.calls++
+	return s.err
+}
+
+func (s *stubHibernator) ListStoppedInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]CloudVMInstance, error) {
+	s.calls++
+	return []CloudVMInstance{{InstanceId: "stopped"}}, s.err
+}
+
+// stubHibernatingDetailsReporter is a stubDetailsReporter that can also stop and start its instances.
+type stubHibernatingDetailsReporter struct {
+	stubHibernator
+}
+
+func (s *stubHibernatingDetailsReporter) GetInstanceDetails(kubeClient client.Client, ctx context.Context, instanceId InstanceIdentifier) (InstanceDetails, error) {
+	s.calls++
+	return InstanceDetails{InstanceType: "mac2.metal"}, s.err
+}
+
+// apiError is an API error reporting its HTTP status like the errors of the AWS SDK.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func (e *apiError) HTTPStatusCode() int {
+	return e.status
+}
+
+// errUnavailable is a transient failure of a cloud provider account.
+var errUnavailable = &apiError{status: http.StatusServiceUnavailable, message: "service unavailable"}
+
+var _ = Describe("Limiters", func() {
+	var (
+		limiters *Limiters
+		stub     *stubProvider
+		limits   Limits
+	)
+
+	BeforeEach(func() {
+		limiters = NewLimiters()
+		stub = &stubProvider{}
+		limits = Limits{RateLimit: 1000, Burst: 1000, FailureThreshold: 3, OpenDuration: time.Minute}
+	})
+
+	It("should pass calls and their results through to the wrapped provider", func(ctx SpecContext) {
+		provider := limiters.Wrap(stub, "aws/account", limits)
+		count, err := provider.CountInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(count).Should(Equal(1))
+		address, err := provider.GetInstanceAddress(nil, ctx, "instance")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(address).Should(Equal("10.0.0.1"))
+		Expect(provider.SshUser()).Should(Equal("root"))
+		Expect(stub.calls).Should(Equal(2))
+	})
+
+	It("should only report instance details when the wrapped provider does", func(ctx SpecContext) {
+		_, ok := DetailsReporter(limiters.Wrap(stub, "aws/account", limits))
+		Expect(ok).Should(BeFalse())
+
+		reporter, ok := DetailsReporter(limiters.Wrap(&stubDetailsReporter{}, "aws/account", limits))
+		Expect(ok).Should(BeTrue())
+		details, err := reporter.GetInstanceDetails(nil, ctx, "instance")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(details.InstanceType).Should(Equal("m5.large"))
+	})
+
+	It("should only look up the TaskRuns of instances when the wrapped provider does", func(ctx SpecContext) {
+		_, ok := TaskRunReporter(limiters.Wrap(stub, "aws/account", limits))
+		Expect(ok).Should(BeFalse())
+
+		reportingStub := &stubTaskRunReporter{}
+		reporter, ok := TaskRunReporter(NewInstanceCache().Wrap(limiters.Wrap(reportingStub, "aws/account", limits), "aws/account", "linux-s390x", time.Minute))
+		Expect(ok).Should(BeTrue())
+		Expect(reporter.GetInstanceTaskRunID(nil, ctx, "instance")).Should(Equal("test-ns:test-taskrun"))
+
+		reportingStub.err = errUnavailable
+		for range limits.FailureThreshold {
+			_, err := reporter.GetInstanceTaskRunID(nil, ctx, "instance")
+			Expect(err).Should(MatchError("service unavailable"))
+		}
+		_, err := reporter.GetInstanceTaskRunID(nil, ctx, "instance")
+		var circuitErr *CircuitOpenError
+		Expect(errors.As(err, &circuitErr)).Should(BeTrue())
+		Expect(reportingStub.calls).Should(Equal(limits.FailureThreshold + 1))
+	})
+
+	It("should only stop and start instances when the wrapped provider does", func(ctx SpecContext) {
+		_, ok := Hibernator(limiters.Wrap(stub, "aws/account", limits))
+		Expect(ok).Should(BeFalse())
+
+		hibernatingStub := &stubHibernator{}
+		hibernator, ok := Hibernator(limiters.Wrap(hibernatingStub, "aws/account", limits))
+		Expect(ok).Should(BeTrue())
+		Expect(hibernator.StopInstance(nil, ctx, "instance")).Should(Succeed())
+		Expect(hibernatingStub.stopped).Should(ConsistOf(InstanceIdentifier("instance")))
+		stopped, err := hibernator.ListStoppedInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stopped).Should(HaveLen(1))
+
+		provider := limiters.Wrap(&stubHibernatingDetailsReporter{}, "aws/account", limits)
+		_, ok = Hibernator(provider)
+		Expect(ok).Should(BeTrue())
+		reporter, ok := DetailsReporter(provider)
+		Expect(ok).Should(BeTrue())
+		details, err := reporter.GetInstanceDetails(nil, ctx, "instance")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(details.InstanceType).Should(Equal("mac2.metal"))
+	})
+
+	It("should limit the optional calls of providers wrapped by an instance cache", func(ctx SpecContext) {
+		hibernatingStub := &stubHibernatingDetailsReporter{}
+		provider := NewInstanceCache().Wrap(limiters.Wrap(hibernatingStub, "aws/account", limits), "aws/account", "linux-arm64", time.Minute)
+		hibernatingStub.err = errUnavailable
+		hibernator, ok := Hibernator(provider)
+		Expect(ok).Should(BeTrue())
+		for range limits.FailureThreshold {
+			Expect(hibernator.StopInstance(nil, ctx, "instance")).Should(MatchError("service unavailable"))
+		}
+
+		reporter, ok := DetailsReporter(provider)
+		Expect(ok).Should(BeTrue())
+		_, err := reporter.GetInstanceDetails(nil, ctx, "instance")
+		var circuitErr *CircuitOpenError
+		Expect(errors.As(err, &circuitErr)).Should(BeTrue())
+	})
+
+	It("should count the failures to stop instances towards the circuit of the account", func(ctx SpecContext) {
+		hibernatingStub := &stubHibernator{stubProvider: stubProvider{err: errUnavailable}}
+		hibernator, _ := Hibernator(limiters.Wrap(hibernatingStub, "aws/account", limits))
+		for range limits.FailureThreshold {
+			Expect(hibernator.StopInstance(nil, ctx, "instance")).Should(MatchError("service unavailable"))
+		}
+		var circuitErr *CircuitOpenError
+		Expect(errors.As(hibernator.StartInstance(nil, ctx, "instance"), &circuitErr)).Should(BeTrue())
+		Expect(hibernatingStub.calls).Should(Equal(limits.FailureThreshold))
+	})
+
+	It("should open the circuit after consecutive failures and stop calling the provider", func(ctx SpecContext) {
+		provider := limiters.Wrap(stub, "aws/account", limits)
+		stub.err = errUnavailable
+		for range limits.FailureThreshold {
+			_, err := provider.GetState(nil, ctx, "instance")
+			Expect(err).Should(MatchError("service unavailable"))
+		}
+
+		_, err := provider.CountInstances(nil, ctx, "tag")
+		var circuitErr *CircuitOpenError
+		Expect(errors.As(err, &circuitErr)).Should(BeTrue())
+		Expect(circuitErr.Account).Should(Equal("aws/account"))
+		Expect(circuitErr.Failures).Should(Equal(limits.FailureThreshold))
+		Expect(circuitErr.RetryAfter).Should(BeNumerically("~", time.Minute, time.Second))
+		Expect(circuitErr).Should(MatchError(errUnavailable))
+		Expect(stub.calls).Should(Equal(limits.FailureThreshold))
+	})
+
+	It("should not open the circuit when failures are not consecutive", func(ctx SpecContext) {
+		provider := limiters.Wrap(stub, "aws/account", limits)
+		for range 2 * limits.FailureThreshold {
+			stub.err = errUnavailable
+			Expect(provider.TerminateInstance(nil, ctx, "instance")).ShouldNot(Succeed())
+			stub.err = nil
+			Expect(provider.TerminateInstance(nil, ctx, "instance")).Should(Succeed())
+		}
+	})
+
+	It("should share the circuit between the providers of the same account only", func(ctx SpecContext) {
+		first := limiters.Wrap(stub, "aws/account", limits)
+		second := limiters.Wrap(&stubProvider{}, "aws/account", limits)
+		other := limiters.Wrap(&stubProvider{}, "aws/other-account", limits)
+		stub.err = errUnavailable
+		for range limits.FailureThreshold {
+			_, err := first.ListInstances(nil, ctx, "tag")
+			Expect(err).Should(HaveOccurred())
+		}
+
+		_, err := second.ListInstances(nil, ctx, "tag")
+		Expect(err).Should(BeAssignableToTypeOf(&CircuitOpenError{}))
+		_, err = other.ListInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("should call the provider again once the circuit has been open for its duration", func(ctx SpecContext) {
+		limits.OpenDuration = 50 * time.Millisecond
+		provider := limiters.Wrap(stub, "aws/account", limits)
+		stub.err = errUnavailable
+		for range limits.FailureThreshold {
+			_, err := provider.LaunchInstance(nil, ctx, "ns:name", "tag", nil)
+			Expect(err).Should(HaveOccurred())
+		}
+		stub.err = nil
+
+		Eventually(func() error {
+			_, err := provider.LaunchInstance(nil, ctx, "ns:name", "tag", nil)
+			return err
+		}).Should(Succeed())
+	})
+
+	It("should not count calls abandoned by their caller as failures", func(ctx SpecContext) {
+		provider := limiters.Wrap(stub, "aws/account", limits)
+		stub.err = context.DeadlineExceeded
+		for range 2 * limits.FailureThreshold {
+			_, err := provider.CountInstances(nil, ctx, "tag")
+			Expect(err).Should(MatchError(context.DeadlineExceeded))
+		}
+		Expect(stub.calls).Should(Equal(2 * limits.FailureThreshold))
+	})
+
+	It("should not open the circuit on errors caused by the configuration of a platform", func(ctx SpecContext) {
+		provider := limiters.Wrap(stub, "aws/account", limits)
+		stub.err = &apiError{status: http.StatusBadRequest, message: "invalid parameter"}
+		for range 2 * limits.FailureThreshold {
+			_, err := provider.LaunchInstance(nil, ctx, "ns:name", "tag", nil)
+			Expect(err).Should(MatchError("invalid parameter"))
+		}
+		Expect(stub.calls).Should(Equal(2 * limits.FailureThreshold))
+	})
+
+	It("should let a single call probe the account once the circuit has been open for its duration", func(ctx SpecContext) {
+		limits.OpenDuration = 50 * time.Millisecond
+		provider := limiters.Wrap(stub, "aws/account", limits)
+		stub.err = errUnavailable
+		for range limits.FailureThreshold {
+			_, err := provider.CountInstances(nil, ctx, "tag")
+			Expect(err).Should(HaveOccurred())
+		}
+		time.Sleep(limits.OpenDuration)
+		guard := limiters.accountGuard("aws/account", limits)
+
+		// The probe fails, which opens the circuit again straight away
+		_, err := provider.CountInstances(nil, ctx, "tag")
+		Expect(err).Should(MatchError("service unavailable"))
+		_, err = provider.CountInstances(nil, ctx, "tag")
+		Expect(err).Should(BeAssignableToTypeOf(&CircuitOpenError{}))
+		Expect(stub.calls).Should(Equal(limits.FailureThreshold + 1))
+
+		// Other calls wait while the probe is in flight
+		time.Sleep(limits.OpenDuration)
+		stub.err = nil
+		Expect(guard.call(ctx, "aws/account", func() error {
+			_, err := provider.CountInstances(nil, ctx, "tag")
+			Expect(err).Should(BeAssignableToTypeOf(&CircuitOpenError{}))
+			return nil
+		})).Should(Succeed())
+
+		// The probe succeeded, so the circuit is closed and the failures are forgotten
+		stub.err = errUnavailable
+		for range limits.FailureThreshold - 1 {
+			_, err := provider.CountInstances(nil, ctx, "tag")
+			Expect(err).Should(MatchError("service unavailable"))
+		}
+		stub.err = nil
+		_, err = provider.CountInstances(nil, ctx, "tag")
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("should close the circuit when a probe fails with an error that is not transient", func(ctx SpecContext) {
+		limits.OpenDuration = 50 * time.Millisecond
+		provider := limiters.Wrap(stub, "aws/account", limits)
+		stub.err = errUnavailable
+		for range limits.FailureThreshold {
+			_, err := provider.GetState(nil, ctx, "instance")
+			Expect(err).Should(HaveOccurred())
+		}
+		time.Sleep(limits.OpenDuration)
+
+		stub.err = &apiError{status: http.StatusNotFound, message: "instance not found"}
+		_, err := provider.GetState(nil, ctx, "instance")
+		Expect(err).Should(MatchError("instance not found"))
+		stub.err = nil
+		_, err = provider.GetState(nil, ctx, "instance")
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	DescribeTable("should only count transient failures",
+		func(err error, transient bool) {
+			Expect(isTransient(err)).Should(Equal(transient))
+		},
+		Entry("network failure", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true),
+		Entry("connection reset", fmt.Errorf("failed to list instances: %w", syscall.ECONNRESET), true),
+		Entry("throttling", &apiError{status: http.StatusTooManyRequests}, true),
+		Entry("server error", fmt.Errorf("failed to launch instance: %w", errUnavailable), true),
+		Entry("invalid parameter", &apiError{status: http.StatusBadRequest}, false),
+		Entry("invalid credentials", &apiError{status: http.StatusUnauthorized}, false),
+		Entry("other error", errors.New("quota exceeded"), false),
+	)
+
+	It("should rate limit the calls to an account", func(ctx SpecContext) {
+		limits.RateLimit = 20
+		limits.Burst = 1
+		provider := limiters.Wrap(stub, "aws/account", limits)
+		start := time.Now()
+		for range 3 {
+			_, err := provider.CountInstances(nil, ctx, "tag")
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+		Expect(time.Since(start)).Should(BeNumerically(">=", 90*time.Millisecond))
+	})
+
+	It("should apply changed limits to the existing providers of an account", func(ctx SpecContext) {
+		provider := limiters.Wrap(stub, "aws/account", limits)
+		limits.FailureThreshold = 1
+		limiters.Wrap(stub, "aws/account", limits)
+		stub.err = errUnavailable
+		_, err := provider.CountInstances(nil, ctx, "tag")
+		Expect(err).Should(MatchError("service unavailable"))
+
+		_, err = provider.CountInstances(nil, ctx, "tag")
+		Expect(err).Should(BeAssignableToTypeOf(&CircuitOpenError{}))
+	})
+})
@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -13,6 +15,24 @@ var (
 
 	// Default concurrency for static hosts
 	defaultStaticHostsConcurrency = 0
+
+	// Default time an instance is left alone by the orphaned instance garbage collector after it was launched or
+	// its TaskRun finished
+	defaultOrphanedInstanceGracePeriod = 30 * time.Minute
+
+	// Default timeout of a single SSH probe of a host, and consecutive failed probes after which it is unhealthy
+	defaultHostHealthProbeTimeout          = 10 * time.Second
+	defaultHostHealthProbeFailureThreshold = 3
+
+	// Default limits of the calls to a single cloud provider account
+	defaultCloudAPIRateLimit        = 10.0
+	defaultCloudAPIBurst            = 20
+	defaultCloudAPIFailureThreshold = 5
+	defaultCloudAPIOpenDuration     = time.Minute
+	defaultInstanceCacheTTL         = 10 * time.Second
+
+	// Default maximum age of the warm instances of dynamic platforms in minutes
+	defaultWarmInstanceMaxAge = 60
 )
 
 type PlatformType string
@@ -134,20 +154,57 @@ func parseDynamicOptionalInstanceTagField(data map[string]string, prefix, platfo
 	return "", nil
 }
 
+// parseDynamicOptionalQuotaGroupFields extracts and validates the optional quota-group and vcpus fields from dynamic platform configuration
+// This helper function is specifically for dynamic and dynamic pool platform parsers, validating the account-level
+// quota group the instances of the platform count towards.
+//
+// Parameters:
+// - data: The ConfigMap data map containing platform configuration
+// - prefix: The configuration prefix (e.g., "dynamic.linux-amd64.")
+// - platform: The platform name for error messages
+// - platformType: The platform type for error messages ("dynamic platform" or "dynamic pool platform")
+//
+// Returns:
+// - string: The quota group name (empty string if not provided)
+// - int: The vCPUs of each instance (0 if not provided, >= 1 and required if the quota group is provided)
+// - error: Error if the quota group is not configured or the vcpus field is missing or invalid
+func parseDynamicOptionalQuotaGroupFields(data map[string]string, prefix, platform, platformType string) (string, int, error) {
+	vcpus := 0
+	if vcpusStr := data[prefix+"vcpus"]; vcpusStr != "" {
+		var err error
+		vcpus, err = validateNonZeroPositiveNumber(vcpusStr)
+		if err != nil {
+			return "", 0, fmt.Errorf("%s '%s': invalid vcpus '%s': %w", platformType, platform, vcpusStr, err)
+		}
+	}
+
+	quotaGroup := strings.TrimSpace(data[prefix+"quota-group"])
+	if quotaGroup == "" {
+		return "", vcpus, nil
+	}
+	if _, err := ParseQuotaGroupConfig(data, quotaGroup); err != nil {
+		return "", 0, fmt.Errorf("%s '%s': %w", platformType, platform, err)
+	}
+	if vcpus == 0 {
+		return "", 0, fmt.Errorf("%s '%s': vcpus field is required with quota-group", platformType, platform)
+	}
+	return quotaGroup, vcpus, nil
+}
+
 // parseRequiredSSHSecretField extracts and validates the required ssh-secret field from platform configuration
 // This helper function validates SSH secret configuration for cloud instances across all platform types.
 // It receives the already-validated cloud provider type from the config struct being built.
 // Validation differs based on cloud provider type:
-// - AWS: Validates non-empty value (any non-empty trimmed value is valid)
+// - AWS, GCP, Azure, KubeVirt, libvirt, OpenStack, external plugins and fake: Validates non-empty value (any non-empty trimmed value is valid)
 // - IBM (ibmz, ibmp): Uses validateIBMHostSecret for additional platform-specific validation
-// - Other types: Returns error (currently, only "aws", "ibmz", and "ibmp" are supported)
+// - Other types: Returns error (currently, only "aws", "gcp", "azure", "kubevirt", "libvirt", "openstack", "external", "fake", "ibmz", and "ibmp" are supported)
 //
 // Parameters:
 // - data: The ConfigMap data map containing platform configuration
 // - prefix: The configuration prefix (e.g., "dynamic.linux-amd64.")
 // - platform: The platform name for error messages
 // - platformType: The platform type for error messages (e.g., "dynamic platform" or "dynamic pool platform")
-// - cloudProviderType: The cloud provider type from the config struct ("aws", "ibmz", or "ibmp")
+// - cloudProviderType: The cloud provider type from the config struct ("aws", "gcp", "azure", "kubevirt", "libvirt", "openstack", "external", "fake", "ibmz", or "ibmp")
 //
 // Returns:
 // - string: The SSH secret name
@@ -159,8 +216,8 @@ func parseRequiredSSHSecretField(data map[string]string, prefix, platform, platf
 	}
 
 	switch cloudProviderType {
-	case "aws":
-		// For AWS platforms, the trimmed non-empty value is valid
+	case "aws", "gcp", "azure", "kubevirt", "libvirt", "openstack", "external", "fake":
+		// For all non-IBM platforms, the trimmed non-empty value is valid
 		// (dynamic platforms require non-empty after trim, pool platforms accept any non-empty value)
 		return sshSecret, nil
 	case "ibmz", "ibmp":
@@ -170,22 +227,31 @@ func parseRequiredSSHSecretField(data map[string]string, prefix, platform, platf
 		}
 		return sshSecret, nil
 	default:
-		return "", fmt.Errorf("invalid type: expect 'aws', 'ibmz', or 'ibmp', got '%s'", cloudProviderType)
+		return "", fmt.Errorf("invalid type: expect 'aws', 'gcp', 'azure', 'kubevirt', 'libvirt', 'openstack', 'external', 'fake', 'ibmz', or 'ibmp', got '%s'", cloudProviderType)
 	}
 }
 
 // ParseDynamicPlatformConfig parses and validates a single dynamic platform configuration
 // This function extracts configuration for a dynamic platform from the ConfigMap data,
 // validates all required and optional fields, and returns a structured DynamicPlatformConfig.
-// Dynamic platforms support on-demand cloud instances (AWS EC2, IBM Cloud PowerPC and s390x) for now.
+// Dynamic platforms support on-demand cloud instances (AWS EC2, Google Compute Engine, Azure Virtual Machines, KubeVirt VirtualMachines, libvirt domains, OpenStack Nova servers, out-of-process cloud provider plugins, IBM Cloud PowerPC and s390x, and an in-memory fake cloud for testing) for now.
 //
 // Configuration format in ConfigMap and its validation rules:
-// - dynamic.<platform-config-name>.type (required): Cloud provider type - must be "aws" or "ibm" for now
+// - dynamic.<platform-config-name>.type (required): Cloud provider type - must be "aws", "gcp", "azure", "kubevirt", "libvirt", "openstack", "external", "fake", "ibmz" or "ibmp" for now
 // - dynamic.<platform-config-name>.max-instances (required): Maximum number of instances - must be >= 1 (no upper limit)
 // - dynamic.<platform-config-name>.instance-tag (optional): Instance tag for cost control must pass validateDynamicInstanceTag if provided
 // - dynamic.<platform-config-name>.allocation-timeout (optional): Timeout in seconds - must be >= 1 (no upper limit, defaults to 600)
+// - dynamic.<platform-config-name>.quota-group (optional): Quota group the instances count towards - quota-group.<name>.max-vcpus must be configured
+// - dynamic.<platform-config-name>.vcpus (optional, required with quota-group): vCPUs of each instance - must be >= 1
 // - dynamic.<platform-config-name>.ssh-secret (required): non-empty SSH secret name (AWS platforms) or pass validateIBMHostSecret (IBM platforms)
 // - dynamic.<platform-config-name>.sudo-commands (optional): Sudo commands to execute
+// - dynamic.<platform-config-name>.min-warm (optional): Booted, unassigned instances kept ready for TaskRuns - must be between 0 and max-instances (defaults to 0)
+// - dynamic.<platform-config-name>.max-age (optional): Maximum age in minutes of unassigned warm instances (1-1440, defaults to 60)
+// - dynamic.<platform-config-name>.spot, spot-max-price, launch-template-id, launch-template-version, subnet-ids, instance-type, role-arn, ami-ssm-parameter, ami-owner, ami-name, ami-cache-ttl (optional, AWS platforms): must pass validateAWSConfig
+// - dynamic.<platform-config-name>.auth-url, openstack-secret, flavor, image, network (required for OpenStack platforms): must pass validateOpenStackConfig
+// - dynamic.<platform-config-name>.address, insecure (external platforms): must pass validateExternalConfig
+// - dynamic.<platform-config-name>.tagging-url (optional, IBM platforms): must pass validateIBMConfig
+// - dynamic.<platform-config-name>.proc-type, cores, storage-tier (optional, IBM Power platforms): must pass validateIBMPowerConfig
 //
 // Parameters:
 // - data: The ConfigMap data map containing platform configuration
@@ -221,6 +287,14 @@ func ParseDynamicPlatformConfig(data map[string]string, platform string) (Dynami
 	}
 	dynamicConfig.InstanceTag = instanceTag
 
+	// Quota group (optional)
+	quotaGroup, vcpus, err := parseDynamicOptionalQuotaGroupFields(data, prefix, platform, "dynamic platform")
+	if err != nil {
+		return DynamicPlatformConfig{}, err
+	}
+	dynamicConfig.QuotaGroup = quotaGroup
+	dynamicConfig.VCPUs = vcpus
+
 	// Allocation timeout (optional)
 	if timeoutStr := data[prefix+"allocation-timeout"]; timeoutStr != "" {
 		timeout, err := validateNonZeroPositiveNumber(timeoutStr)
@@ -252,11 +326,64 @@ func ParseDynamicPlatformConfig(data map[string]string, platform string) (Dynami
 	}
 	dynamicConfig.SSHSecret = sshSecret
 
+	// AWS-specific fields
+	if dynamicConfig.Type == "aws" {
+		if err := validateAWSConfig(data, prefix); err != nil {
+			return DynamicPlatformConfig{}, fmt.Errorf("dynamic platform '%s': %w", platform, err)
+		}
+	}
+
+	// OpenStack-specific fields
+	if dynamicConfig.Type == "openstack" {
+		if err := validateOpenStackConfig(data, prefix); err != nil {
+			return DynamicPlatformConfig{}, fmt.Errorf("dynamic platform '%s': %w", platform, err)
+		}
+	}
+
+	// External-specific fields
+	if dynamicConfig.Type == "external" {
+		if err := validateExternalConfig(data, prefix); err != nil {
+			return DynamicPlatformConfig{}, fmt.Errorf("dynamic platform '%s': %w", platform, err)
+		}
+	}
+
+	// IBM-specific fields
+	if dynamicConfig.Type == "ibmz" || dynamicConfig.Type == "ibmp" {
+		if err := validateIBMConfig(data, prefix); err != nil {
+			return DynamicPlatformConfig{}, fmt.Errorf("dynamic platform '%s': %w", platform, err)
+		}
+	}
+	if dynamicConfig.Type == "ibmp" {
+		if err := validateIBMPowerConfig(data, prefix); err != nil {
+			return DynamicPlatformConfig{}, fmt.Errorf("dynamic platform '%s': %w", platform, err)
+		}
+	}
+
 	// Sudo commands (optional)
 	if sudoCommands := data[prefix+"sudo-commands"]; sudoCommands != "" {
 		dynamicConfig.SudoCommands = sudoCommands
 	}
 
+	// Warm instances (optional)
+	if minWarmStr := data[prefix+"min-warm"]; minWarmStr != "" {
+		minWarm, err := strconv.Atoi(minWarmStr)
+		if err != nil || minWarm < 0 || minWarm > dynamicConfig.MaxInstances {
+			return DynamicPlatformConfig{}, fmt.Errorf("dynamic platform '%s': invalid min-warm '%s': must be an integer between 0 and max-instances", platform, minWarmStr)
+		}
+		dynamicConfig.MinWarm = minWarm
+	}
+
+	// Max age of warm instances (optional)
+	if maxAgeStr := data[prefix+"max-age"]; maxAgeStr != "" {
+		maxAge, err := validateNonZeroPositiveNumberWithMax(maxAgeStr, maxPoolHostAge)
+		if err != nil {
+			return DynamicPlatformConfig{}, fmt.Errorf("dynamic platform '%s': invalid max-age '%s': %w", platform, maxAgeStr, err)
+		}
+		dynamicConfig.MaxAge = int64(maxAge)
+	} else {
+		dynamicConfig.MaxAge = int64(defaultWarmInstanceMaxAge)
+	}
+
 	return dynamicConfig, nil
 }
 
@@ -266,12 +393,21 @@ func ParseDynamicPlatformConfig(data map[string]string, platform string) (Dynami
 // Dynamic pool platforms combine fixed and dynamic allocation strategies with auto-scaling and TTL-based lifecycle.
 //
 // Configuration format in ConfigMap and its validation rules:
-// - dynamic.<platform-config-name>.type (required): Cloud provider type - must be "aws" or "ibm" for now
+// - dynamic.<platform-config-name>.type (required): Cloud provider type - must be "aws", "gcp", "azure", "kubevirt", "libvirt", "openstack", "external", "fake", "ibmz" or "ibmp" for now
 // - dynamic.<platform-config-name>.max-instances (required): Maximum number of instances - must be >= 1 (no upper limit)
 // - dynamic.<platform-config-name>.concurrency (required): Concurrent jobs per host - must be between 1 and 8
 // - dynamic.<platform-config-name>.max-age (required): Host maximum age in minutes (1-1440)
+// - dynamic.<platform-config-name>.idle-timeout (optional): Minutes after which a host without assigned TaskRuns is terminated (1-1440)
+// - dynamic.<platform-config-name>.hibernate (optional, requires idle-timeout): Stop idle hosts rather than terminate them, and start them again on demand, if the cloud provider supports it - must be a boolean (defaults to false)
 // - dynamic.<platform-config-name>.instance-tag (optional): Instance tag for cost control must pass validateDynamicInstanceTag if provided
+// - dynamic.<platform-config-name>.quota-group (optional): Quota group the instances count towards - quota-group.<name>.max-vcpus must be configured
+// - dynamic.<platform-config-name>.vcpus (optional, required with quota-group): vCPUs of each instance - must be >= 1
 // - dynamic.<platform-config-name>.ssh-secret (required): non-empty SSH secret name (AWS platforms) or pass validateIBMHostSecret (IBM platforms)
+// - dynamic.<platform-config-name>.spot, spot-max-price, launch-template-id, launch-template-version, subnet-ids, instance-type, role-arn, ami-ssm-parameter, ami-owner, ami-name, ami-cache-ttl (optional, AWS platforms): must pass validateAWSConfig
+// - dynamic.<platform-config-name>.auth-url, openstack-secret, flavor, image, network (required for OpenStack platforms): must pass validateOpenStackConfig
+// - dynamic.<platform-config-name>.address, insecure (external platforms): must pass validateExternalConfig
+// - dynamic.<platform-config-name>.tagging-url (optional, IBM platforms): must pass validateIBMConfig
+// - dynamic.<platform-config-name>.proc-type, cores, storage-tier (optional, IBM Power platforms): must pass validateIBMPowerConfig
 //
 // Parameters:
 // - data: The ConfigMap data map containing platform configuration
@@ -322,6 +458,27 @@ func ParseDynamicPoolPlatformConfig(data map[string]string, platform string) (Dy
 		return DynamicPoolPlatformConfig{}, fmt.Errorf("dynamic pool platform '%s': max-age field is required", platform)
 	}
 
+	// Idle timeout (optional)
+	if idleTimeoutStr := data[prefix+"idle-timeout"]; idleTimeoutStr != "" {
+		idleTimeout, err := validateNonZeroPositiveNumberWithMax(idleTimeoutStr, maxPoolHostAge)
+		if err != nil {
+			return DynamicPoolPlatformConfig{}, fmt.Errorf("dynamic pool platform '%s': invalid idle-timeout '%s': %w", platform, idleTimeoutStr, err)
+		}
+		poolConfig.IdleTimeout = int64(idleTimeout)
+	}
+
+	// Hibernate (optional)
+	if hibernateStr := data[prefix+"hibernate"]; hibernateStr != "" {
+		hibernate, err := strconv.ParseBool(hibernateStr)
+		if err != nil {
+			return DynamicPoolPlatformConfig{}, fmt.Errorf("dynamic pool platform '%s': invalid hibernate '%s': must be a boolean", platform, hibernateStr)
+		}
+		if hibernate && poolConfig.IdleTimeout == 0 {
+			return DynamicPoolPlatformConfig{}, fmt.Errorf("dynamic pool platform '%s': idle-timeout field is required with hibernate", platform)
+		}
+		poolConfig.Hibernate = hibernate
+	}
+
 	// Instance tag (optional)
 	instanceTag, err := parseDynamicOptionalInstanceTagField(data, prefix, platformConfigName, platform, "dynamic pool platform")
 	if err != nil {
@@ -329,6 +486,14 @@ func ParseDynamicPoolPlatformConfig(data map[string]string, platform string) (Dy
 	}
 	poolConfig.InstanceTag = instanceTag
 
+	// Quota group (optional)
+	quotaGroup, vcpus, err := parseDynamicOptionalQuotaGroupFields(data, prefix, platform, "dynamic pool platform")
+	if err != nil {
+		return DynamicPoolPlatformConfig{}, err
+	}
+	poolConfig.QuotaGroup = quotaGroup
+	poolConfig.VCPUs = vcpus
+
 	// SSH secret (required)
 	sshSecret, err := parseRequiredSSHSecretField(data, prefix, platform, "dynamic pool platform", poolConfig.Type)
 	if err != nil {
@@ -336,6 +501,39 @@ func ParseDynamicPoolPlatformConfig(data map[string]string, platform string) (Dy
 	}
 	poolConfig.SSHSecret = sshSecret
 
+	// AWS-specific fields
+	if poolConfig.Type == "aws" {
+		if err := validateAWSConfig(data, prefix); err != nil {
+			return DynamicPoolPlatformConfig{}, fmt.Errorf("dynamic pool platform '%s': %w", platform, err)
+		}
+	}
+
+	// OpenStack-specific fields
+	if poolConfig.Type == "openstack" {
+		if err := validateOpenStackConfig(data, prefix); err != nil {
+			return DynamicPoolPlatformConfig{}, fmt.Errorf("dynamic pool platform '%s': %w", platform, err)
+		}
+	}
+
+	// External-specific fields
+	if poolConfig.Type == "external" {
+		if err := validateExternalConfig(data, prefix); err != nil {
+			return DynamicPoolPlatformConfig{}, fmt.Errorf("dynamic pool platform '%s': %w", platform, err)
+		}
+	}
+
+	// IBM-specific fields
+	if poolConfig.Type == "ibmz" || poolConfig.Type == "ibmp" {
+		if err := validateIBMConfig(data, prefix); err != nil {
+			return DynamicPoolPlatformConfig{}, fmt.Errorf("dynamic pool platform '%s': %w", platform, err)
+		}
+	}
+	if poolConfig.Type == "ibmp" {
+		if err := validateIBMPowerConfig(data, prefix); err != nil {
+			return DynamicPoolPlatformConfig{}, fmt.Errorf("dynamic pool platform '%s': %w", platform, err)
+		}
+	}
+
 	return poolConfig, nil
 }
 
@@ -409,6 +607,213 @@ func ParseStaticHostConfig(data map[string]string, hostName string) (StaticHostC
 	return hostConfig, nil
 }
 
+// ParseOrphanedInstanceGCConfig parses and validates the configuration of the orphaned cloud instance garbage collector
+// The garbage collector is opt-in, since it terminates any tagged instance whose TaskRun is not found in this cluster:
+// dynamic platforms must not share their instance tag with another cluster in the same cloud account.
+//
+// Configuration format in ConfigMap and its validation rules:
+// - orphaned-instance-gc.enabled (optional): Whether the garbage collector runs - must be a boolean (defaults to false)
+// - orphaned-instance-gc.dry-run (optional): Only report orphaned instances instead of terminating them - must be a boolean (defaults to false)
+// - orphaned-instance-gc.grace-period (optional): Minimum age of an instance, and time since its TaskRun finished, before it is orphaned - must be a positive duration (defaults to 30m)
+//
+// Parameters:
+// - data: The ConfigMap data map containing the garbage collector configuration
+//
+// Returns:
+// - OrphanedInstanceGCConfig: The parsed and validated configuration
+// - error: Validation error if any field is invalid
+func ParseOrphanedInstanceGCConfig(data map[string]string) (OrphanedInstanceGCConfig, error) {
+	gcConfig := OrphanedInstanceGCConfig{GracePeriod: defaultOrphanedInstanceGracePeriod}
+
+	for key, field := range map[string]*bool{"enabled": &gcConfig.Enabled, "dry-run": &gcConfig.DryRun} {
+		if value := data["orphaned-instance-gc."+key]; value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return OrphanedInstanceGCConfig{}, fmt.Errorf("orphaned instance garbage collector: invalid %s '%s': must be a boolean", key, value)
+			}
+			*field = parsed
+		}
+	}
+
+	if value := data["orphaned-instance-gc.grace-period"]; value != "" {
+		gracePeriod, err := time.ParseDuration(value)
+		if err != nil || gracePeriod <= 0 {
+			return OrphanedInstanceGCConfig{}, fmt.Errorf("orphaned instance garbage collector: invalid grace-period '%s': must be a positive duration", value)
+		}
+		gcConfig.GracePeriod = gracePeriod
+	}
+
+	return gcConfig, nil
+}
+
+// ParseHostHealthProbeConfig parses and validates the configuration of the SSH health prober of static hosts and
+// dynamic pool instances
+// The prober is opt-in, since hosts it finds unhealthy no longer receive TaskRuns until they are reachable again.
+//
+// Configuration format in ConfigMap and its validation rules:
+// - host-health-probe.enabled (optional): Whether the prober runs - must be a boolean (defaults to false)
+// - host-health-probe.timeout (optional): How long a single probe waits for the SSH server of a host - must be a positive duration (defaults to 10s)
+// - host-health-probe.failure-threshold (optional): Consecutive failed probes after which a host is unhealthy - must be a positive integer (defaults to 3)
+//
+// Parameters:
+// - data: The ConfigMap data map containing the prober configuration
+//
+// Returns:
+// - HostHealthProbeConfig: The parsed and validated configuration
+// - error: Validation error if any field is invalid
+func ParseHostHealthProbeConfig(data map[string]string) (HostHealthProbeConfig, error) {
+	probeConfig := HostHealthProbeConfig{
+		Timeout:          defaultHostHealthProbeTimeout,
+		FailureThreshold: defaultHostHealthProbeFailureThreshold,
+	}
+
+	if value := data["host-health-probe.enabled"]; value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return HostHealthProbeConfig{}, fmt.Errorf("host health prober: invalid enabled '%s': must be a boolean", value)
+		}
+		probeConfig.Enabled = enabled
+	}
+
+	if value := data["host-health-probe.timeout"]; value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return HostHealthProbeConfig{}, fmt.Errorf("host health prober: invalid timeout '%s': must be a positive duration", value)
+		}
+		probeConfig.Timeout = timeout
+	}
+
+	if value := data["host-health-probe.failure-threshold"]; value != "" {
+		threshold, err := strconv.Atoi(value)
+		if err != nil || threshold <= 0 {
+			return HostHealthProbeConfig{}, fmt.Errorf("host health prober: invalid failure-threshold '%s': must be a positive integer", value)
+		}
+		probeConfig.FailureThreshold = threshold
+	}
+
+	return probeConfig, nil
+}
+
+// ParseHostStates parses and validates the hosts taken out of rotation for maintenance
+// Hosts are either static host names or dynamic pool instance IDs. Cordoned hosts no longer receive TaskRuns, while
+// draining hosts also no longer receive TaskRuns and are reported as drained once their running TaskRuns finished.
+//
+// Validation rules:
+// - cordoned, draining: Comma-separated host names - empty names are ignored
+// - A host must not be both cordoned and draining
+//
+// Parameters:
+// - cordoned: The comma-separated names of the cordoned hosts
+// - draining: The comma-separated names of the draining hosts
+//
+// Returns:
+// - map[string]HostState: The state of every cordoned or draining host
+// - error: Validation error if a host is both cordoned and draining
+func ParseHostStates(cordoned string, draining string) (map[string]HostState, error) {
+	states := map[string]HostState{}
+	for state, hosts := range map[HostState]string{HostStateCordoned: cordoned, HostStateDraining: draining} {
+		for _, host := range strings.Split(hosts, ",") {
+			host = strings.TrimSpace(host)
+			if host == "" {
+				continue
+			}
+			if existing, ok := states[host]; ok && existing != state {
+				return nil, fmt.Errorf("host '%s' is both cordoned and draining", host)
+			}
+			states[host] = state
+		}
+	}
+	return states, nil
+}
+
+// ParseCloudAPILimitsConfig parses and validates the limits of the calls made to each cloud provider account
+// The limits are shared by all the dynamic and dynamic pool platforms using the same account.
+//
+// Configuration format in ConfigMap and its validation rules:
+// - cloud-api.rate-limit (optional): Calls per second allowed to an account - must be a positive number (defaults to 10)
+// - cloud-api.burst (optional): Calls that can be made at once before the rate limit applies - must be a positive integer (defaults to 20)
+// - cloud-api.failure-threshold (optional): Consecutive transient failures (network, throttling and server errors) that suspend the calls to an account - must be a positive integer (defaults to 5)
+// - cloud-api.open-duration (optional): How long the calls to an account are suspended for - must be a positive duration (defaults to 1m)
+// - cloud-api.instance-cache-ttl (optional): How long listed and counted instances are cached for, 0 disables the cache - must be a non-negative duration (defaults to 10s)
+//
+// Parameters:
+// - data: The ConfigMap data map containing the cloud API limits configuration
+//
+// Returns:
+// - CloudAPILimitsConfig: The parsed and validated configuration
+// - error: Validation error if any field is invalid
+func ParseCloudAPILimitsConfig(data map[string]string) (CloudAPILimitsConfig, error) {
+	limitsConfig := CloudAPILimitsConfig{
+		RateLimit:        defaultCloudAPIRateLimit,
+		Burst:            defaultCloudAPIBurst,
+		FailureThreshold: defaultCloudAPIFailureThreshold,
+		OpenDuration:     defaultCloudAPIOpenDuration,
+		InstanceCacheTTL: defaultInstanceCacheTTL,
+	}
+
+	if value := data["cloud-api.rate-limit"]; value != "" {
+		rateLimit, err := strconv.ParseFloat(value, 64)
+		if err != nil || rateLimit <= 0 {
+			return CloudAPILimitsConfig{}, fmt.Errorf("cloud API limits: invalid rate-limit '%s': must be a positive number", value)
+		}
+		limitsConfig.RateLimit = rateLimit
+	}
+
+	for key, field := range map[string]*int{"burst": &limitsConfig.Burst, "failure-threshold": &limitsConfig.FailureThreshold} {
+		if value := data["cloud-api."+key]; value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return CloudAPILimitsConfig{}, fmt.Errorf("cloud API limits: invalid %s '%s': must be a positive integer", key, value)
+			}
+			*field = parsed
+		}
+	}
+
+	if value := data["cloud-api.open-duration"]; value != "" {
+		openDuration, err := time.ParseDuration(value)
+		if err != nil || openDuration <= 0 {
+			return CloudAPILimitsConfig{}, fmt.Errorf("cloud API limits: invalid open-duration '%s': must be a positive duration", value)
+		}
+		limitsConfig.OpenDuration = openDuration
+	}
+
+	if value := data["cloud-api.instance-cache-ttl"]; value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl < 0 {
+			return CloudAPILimitsConfig{}, fmt.Errorf("cloud API limits: invalid instance-cache-ttl '%s': must be a non-negative duration", value)
+		}
+		limitsConfig.InstanceCacheTTL = ttl
+	}
+
+	return limitsConfig, nil
+}
+
+// ParseQuotaGroupConfig parses and validates the configuration of an account-level quota group
+// A quota group limits the vCPUs of the instances of all the dynamic and dynamic pool platforms whose quota-group is
+// the group, e.g. because they share the vCPU quota of a cloud provider account.
+//
+// Configuration format in ConfigMap and its validation rules:
+// - quota-group.<name>.max-vcpus (required): Maximum vCPUs of the instances of the group - must be >= 1 (no upper limit)
+//
+// Parameters:
+// - data: The ConfigMap data map containing the quota group configuration
+// - name: The quota group name used in the ConfigMap keys
+//
+// Returns:
+// - QuotaGroupConfig: The parsed and validated configuration
+// - error: Validation error if max-vcpus is missing or invalid
+func ParseQuotaGroupConfig(data map[string]string, name string) (QuotaGroupConfig, error) {
+	maxVCPUsStr := data["quota-group."+name+".max-vcpus"]
+	if maxVCPUsStr == "" {
+		return QuotaGroupConfig{}, fmt.Errorf("quota group '%s': max-vcpus field is required", name)
+	}
+	maxVCPUs, err := validateNonZeroPositiveNumber(maxVCPUsStr)
+	if err != nil {
+		return QuotaGroupConfig{}, fmt.Errorf("quota group '%s': invalid max-vcpus '%s': %w", name, maxVCPUsStr, err)
+	}
+	return QuotaGroupConfig{Name: name, MaxVCPUs: maxVCPUs}, nil
+}
+
 // DynamicPlatformConfig holds configuration for a single dynamic platform
 type DynamicPlatformConfig struct {
 	Type              string `mapstructure:"type"`
@@ -418,6 +823,10 @@ type DynamicPlatformConfig struct {
 	CheckInterval     int64  `mapstructure:"check-interval,omitempty"`
 	SSHSecret         string `mapstructure:"ssh-secret"`
 	SudoCommands      string `mapstructure:"sudo-commands,omitempty"`
+	QuotaGroup        string `mapstructure:"quota-group,omitempty"`
+	VCPUs             int    `mapstructure:"vcpus,omitempty"`
+	MinWarm           int    `mapstructure:"min-warm,omitempty"`
+	MaxAge            int64  `mapstructure:"max-age,omitempty"` // in minutes, of warm instances
 }
 
 // DynamicPoolPlatformConfig holds configuration for a single dynamic platform in a host pool
@@ -425,9 +834,13 @@ type DynamicPoolPlatformConfig struct {
 	Type         string `mapstructure:"type"`
 	MaxInstances int    `mapstructure:"max-instances"`
 	Concurrency  int    `mapstructure:"concurrency"`
-	MaxAge       int64  `mapstructure:"max-age"` // in minutes
+	MaxAge       int64  `mapstructure:"max-age"`                // in minutes
+	IdleTimeout  int64  `mapstructure:"idle-timeout,omitempty"` // in minutes, 0 to keep idle instances until max-age
+	Hibernate    bool   `mapstructure:"hibernate,omitempty"`
 	InstanceTag  string `mapstructure:"instance-tag,omitempty"`
 	SSHSecret    string `mapstructure:"ssh-secret"`
+	QuotaGroup   string `mapstructure:"quota-group,omitempty"`
+	VCPUs        int    `mapstructure:"vcpus,omitempty"`
 }
 
 // StaticHostConfig represents a single static host configuration
@@ -438,3 +851,42 @@ type StaticHostConfig struct {
 	Secret      string `mapstructure:"secret"`
 	Concurrency int    `mapstructure:"concurrency"`
 }
+
+// OrphanedInstanceGCConfig holds configuration for the orphaned cloud instance garbage collector
+type OrphanedInstanceGCConfig struct {
+	Enabled     bool          `mapstructure:"enabled,omitempty"`
+	DryRun      bool          `mapstructure:"dry-run,omitempty"`
+	GracePeriod time.Duration `mapstructure:"grace-period,omitempty"`
+}
+
+// HostState is the maintenance state of a static host or dynamic pool instance taken out of rotation
+type HostState string
+
+const (
+	// HostStateCordoned hosts no longer receive TaskRuns
+	HostStateCordoned HostState = "cordoned"
+	// HostStateDraining hosts no longer receive TaskRuns, and are drained once their running TaskRuns finished
+	HostStateDraining HostState = "draining"
+)
+
+// HostHealthProbeConfig holds configuration for the SSH health prober of static hosts and dynamic pool instances
+type HostHealthProbeConfig struct {
+	Enabled          bool          `mapstructure:"enabled,omitempty"`
+	Timeout          time.Duration `mapstructure:"timeout,omitempty"`
+	FailureThreshold int           `mapstructure:"failure-threshold,omitempty"`
+}
+
+// CloudAPILimitsConfig holds the limits of the calls made to each cloud provider account
+type CloudAPILimitsConfig struct {
+	RateLimit        float64       `mapstructure:"rate-limit,omitempty"`
+	Burst            int           `mapstructure:"burst,omitempty"`
+	FailureThreshold int           `mapstructure:"failure-threshold,omitempty"`
+	OpenDuration     time.Duration `mapstructure:"open-duration,omitempty"`
+	InstanceCacheTTL time.Duration `mapstructure:"instance-cache-ttl,omitempty"`
+}
+
+// QuotaGroupConfig holds configuration for an account-level quota group
+type QuotaGroupConfig struct {
+	Name     string `mapstructure:"name"`
+	MaxVCPUs int    `mapstructure:"max-vcpus"`
+}
@@ -4,6 +4,7 @@ package config
 
 import (
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -159,6 +160,48 @@ var _ = Describe("Host Configuration Parsing and Validation Tests", func() {
 	})
 
 	// This section tests the parseRequiredSSHSecretField helper function
+	Describe("The parseDynamicOptionalQuotaGroupFields helper function", func() {
+		prefix := "dynamic.linux-arm64."
+
+		When("extracting valid quota group fields", func() {
+			DescribeTable("should extract the quota group and vCPUs",
+				func(ctx SpecContext, data map[string]string, expectedQuotaGroup string, expectedVCPUs int) {
+					quotaGroup, vcpus, err := parseDynamicOptionalQuotaGroupFields(data, prefix, "linux/arm64", "dynamic platform")
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(quotaGroup).Should(Equal(expectedQuotaGroup))
+					Expect(vcpus).Should(Equal(expectedVCPUs))
+				},
+				Entry("without a quota group", map[string]string{}, "", 0),
+				Entry("with vCPUs but no quota group", map[string]string{prefix + "vcpus": "4"}, "", 4),
+				Entry("with a configured quota group",
+					map[string]string{prefix + "quota-group": "aws-arm", prefix + "vcpus": "8", "quota-group.aws-arm.max-vcpus": "64"},
+					"aws-arm", 8,
+				),
+			)
+		})
+
+		When("quota group fields are invalid", func() {
+			DescribeTable("should return validation error",
+				func(ctx SpecContext, data map[string]string, expectedErrorSubstring string) {
+					_, _, err := parseDynamicOptionalQuotaGroupFields(data, prefix, "linux/arm64", "dynamic platform")
+					Expect(err).Should(MatchError(ContainSubstring(expectedErrorSubstring)))
+				},
+				Entry("for a quota group without max-vcpus",
+					map[string]string{prefix + "quota-group": "aws-arm", prefix + "vcpus": "8"},
+					"dynamic platform 'linux/arm64': quota group 'aws-arm': max-vcpus field is required",
+				),
+				Entry("for a quota group without vcpus",
+					map[string]string{prefix + "quota-group": "aws-arm", "quota-group.aws-arm.max-vcpus": "64"},
+					"dynamic platform 'linux/arm64': vcpus field is required with quota-group",
+				),
+				Entry("for zero vcpus",
+					map[string]string{prefix + "quota-group": "aws-arm", prefix + "vcpus": "0", "quota-group.aws-arm.max-vcpus": "64"},
+					"invalid vcpus '0'",
+				),
+			)
+		})
+	})
+
 	Describe("The parseRequiredSSHSecretField helper function", func() {
 
 		When("extracting valid ssh-secret for AWS", func() {
@@ -170,6 +213,60 @@ var _ = Describe("Host Configuration Parsing and Validation Tests", func() {
 			})
 		})
 
+		When("extracting valid ssh-secret for GCP", func() {
+			It("should extract ssh-secret successfully", func(ctx SpecContext) {
+				data := map[string]string{
+					"dynamic.linux-arm64.ssh-secret": "gcp-secret-name",
+				}
+				Expect(parseRequiredSSHSecretField(data, "dynamic.linux-arm64.", "linux/arm64", "dynamic platform", "gcp")).Should(Equal("gcp-secret-name"))
+			})
+		})
+
+		When("extracting valid ssh-secret for Azure", func() {
+			It("should extract ssh-secret successfully", func(ctx SpecContext) {
+				data := map[string]string{
+					"dynamic.linux-arm64.ssh-secret": "azure-secret-name",
+				}
+				Expect(parseRequiredSSHSecretField(data, "dynamic.linux-arm64.", "linux/arm64", "dynamic platform", "azure")).Should(Equal("azure-secret-name"))
+			})
+		})
+
+		When("extracting valid ssh-secret for KubeVirt", func() {
+			It("should extract ssh-secret successfully", func(ctx SpecContext) {
+				data := map[string]string{
+					"dynamic.linux-arm64.ssh-secret": "kubevirt-secret-name",
+				}
+				Expect(parseRequiredSSHSecretField(data, "dynamic.linux-arm64.", "linux/arm64", "dynamic platform", "kubevirt")).Should(Equal("kubevirt-secret-name"))
+			})
+		})
+
+		When("extracting valid ssh-secret for libvirt", func() {
+			It("should extract ssh-secret successfully", func(ctx SpecContext) {
+				data := map[string]string{
+					"dynamic.linux-arm64.ssh-secret": "libvirt-secret-name",
+				}
+				Expect(parseRequiredSSHSecretField(data, "dynamic.linux-arm64.", "linux/arm64", "dynamic platform", "libvirt")).Should(Equal("libvirt-secret-name"))
+			})
+		})
+
+		When("extracting valid ssh-secret for OpenStack", func() {
+			It("should extract ssh-secret successfully", func(ctx SpecContext) {
+				data := map[string]string{
+					"dynamic.linux-arm64.ssh-secret": "openstack-secret-name",
+				}
+				Expect(parseRequiredSSHSecretField(data, "dynamic.linux-arm64.", "linux/arm64", "dynamic platform", "openstack")).Should(Equal("openstack-secret-name"))
+			})
+		})
+
+		When("extracting valid ssh-secret for the fake cloud", func() {
+			It("should extract ssh-secret successfully", func(ctx SpecContext) {
+				data := map[string]string{
+					"dynamic.linux-arm64.ssh-secret": "fake-secret-name",
+				}
+				Expect(parseRequiredSSHSecretField(data, "dynamic.linux-arm64.", "linux/arm64", "dynamic platform", "fake")).Should(Equal("fake-secret-name"))
+			})
+		})
+
 		When("ssh-secret field is missing", func() {
 			It("should return error", func(ctx SpecContext) {
 				data := map[string]string{
@@ -230,7 +327,7 @@ var _ = Describe("Host Configuration Parsing and Validation Tests", func() {
 				}
 				_, err := parseRequiredSSHSecretField(data, "dynamic.linux-amd64.", "linux/amd64", "dynamic platform", "KokoHazamar")
 				Expect(err).Should(HaveOccurred())
-				Expect(err.Error()).Should(ContainSubstring("invalid type: expect 'aws', 'ibmz', or 'ibmp'"))
+				Expect(err.Error()).Should(ContainSubstring("invalid type: expect 'aws', 'gcp', 'azure', 'kubevirt', 'libvirt', 'openstack', 'external', 'fake', 'ibmz', or 'ibmp'"))
 			})
 		})
 	})
@@ -276,6 +373,62 @@ var _ = Describe("Host Configuration Parsing and Validation Tests", func() {
 				Expect(ibmzConfig.InstanceTag).Should(BeEmpty())
 				Expect(ibmzConfig.SudoCommands).Should(BeEmpty())
 			})
+
+			It("should parse OpenStack platform with its provider-specific fields", func(ctx SpecContext) {
+				data := map[string]string{
+					"dynamic.linux-arm64.type":                "openstack",
+					"dynamic.linux-arm64.max-instances":       "5",
+					"dynamic.linux-arm64.ssh-secret":          "openstack-ssh-key",
+					"dynamic.linux-arm64.auth-url":            "https://keystone.example.com:5000/v3",
+					"dynamic.linux-arm64.openstack-secret":    "openstack-credentials",
+					"dynamic.linux-arm64.flavor":              "m1.large",
+					"dynamic.linux-arm64.image":               "fedora-42-aarch64",
+					"dynamic.linux-arm64.network":             "private-net",
+					"dynamic.linux-arm64.floating-ip-network": "public-net",
+				}
+
+				openstackConfig, err := ParseDynamicPlatformConfig(data, "linux/arm64")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(openstackConfig.Type).Should(Equal("openstack"))
+				Expect(openstackConfig.MaxInstances).Should(Equal(5))
+				Expect(openstackConfig.SSHSecret).Should(Equal("openstack-ssh-key"))
+			})
+
+			It("should parse external platform with its plugin address", func(ctx SpecContext) {
+				data := map[string]string{
+					"dynamic.linux-arm64.type":          "external",
+					"dynamic.linux-arm64.max-instances": "5",
+					"dynamic.linux-arm64.ssh-secret":    "external-ssh-key",
+					"dynamic.linux-arm64.address":       "localhost:50051",
+					"dynamic.linux-arm64.insecure":      "true",
+				}
+
+				externalConfig, err := ParseDynamicPlatformConfig(data, "linux/arm64")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(externalConfig.Type).Should(Equal("external"))
+				Expect(externalConfig.MaxInstances).Should(Equal(5))
+				Expect(externalConfig.SSHSecret).Should(Equal("external-ssh-key"))
+			})
+
+			It("should parse a warm pool with its defaults", func(ctx SpecContext) {
+				data := map[string]string{
+					"dynamic.linux-arm64.type":          "aws",
+					"dynamic.linux-arm64.max-instances": "5",
+					"dynamic.linux-arm64.ssh-secret":    "aws-ssh-key",
+				}
+
+				dynamicConfig, err := ParseDynamicPlatformConfig(data, "linux/arm64")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(dynamicConfig.MinWarm).Should(Equal(0))
+				Expect(dynamicConfig.MaxAge).Should(Equal(int64(60)))
+
+				data["dynamic.linux-arm64.min-warm"] = "5"
+				data["dynamic.linux-arm64.max-age"] = "30"
+				dynamicConfig, err = ParseDynamicPlatformConfig(data, "linux/arm64")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(dynamicConfig.MinWarm).Should(Equal(5))
+				Expect(dynamicConfig.MaxAge).Should(Equal(int64(30)))
+			})
 		})
 
 		When("parsing invalid dynamic platform configurations", func() {
@@ -346,6 +499,76 @@ var _ = Describe("Host Configuration Parsing and Validation Tests", func() {
 					"linux/s390x",
 					"invalid ssh-secret 'invalid-secret'",
 				),
+				Entry("for IBM Power platform with fractional dedicated cores",
+					map[string]string{
+						"dynamic.linux-ppc64le.type":          "ibmp",
+						"dynamic.linux-ppc64le.max-instances": "3",
+						"dynamic.linux-ppc64le.ssh-secret":    "ibm-ppc64le-secret",
+						"dynamic.linux-ppc64le.proc-type":     "dedicated",
+						"dynamic.linux-ppc64le.cores":         "0.5",
+					},
+					"linux/ppc64le",
+					"dedicated processors must be allocated in whole cores",
+				),
+				Entry("for OpenStack platform without provider-specific fields",
+					map[string]string{
+						"dynamic.linux-arm64.type":          "openstack",
+						"dynamic.linux-arm64.max-instances": "5",
+						"dynamic.linux-arm64.ssh-secret":    "openstack-ssh-key",
+					},
+					"linux/arm64",
+					"auth-url field is required for type 'openstack'",
+				),
+				Entry("for external platform without a plugin address",
+					map[string]string{
+						"dynamic.linux-arm64.type":          "external",
+						"dynamic.linux-arm64.max-instances": "5",
+						"dynamic.linux-arm64.ssh-secret":    "external-ssh-key",
+					},
+					"linux/arm64",
+					"address field is required for type 'external'",
+				),
+				Entry("for AWS platform with an invalid Spot maximum price",
+					map[string]string{
+						"dynamic.linux-arm64.type":           "aws",
+						"dynamic.linux-arm64.max-instances":  "5",
+						"dynamic.linux-arm64.ssh-secret":     "aws-ssh-key",
+						"dynamic.linux-arm64.spot":           "true",
+						"dynamic.linux-arm64.spot-max-price": "-1",
+					},
+					"linux/arm64",
+					"dynamic platform 'linux/arm64': invalid spot-max-price '-1'",
+				),
+				Entry("for min-warm above max-instances",
+					map[string]string{
+						"dynamic.linux-arm64.type":          "aws",
+						"dynamic.linux-arm64.max-instances": "5",
+						"dynamic.linux-arm64.ssh-secret":    "aws-ssh-key",
+						"dynamic.linux-arm64.min-warm":      "6",
+					},
+					"linux/arm64",
+					"dynamic platform 'linux/arm64': invalid min-warm '6'",
+				),
+				Entry("for a negative min-warm",
+					map[string]string{
+						"dynamic.linux-arm64.type":          "aws",
+						"dynamic.linux-arm64.max-instances": "5",
+						"dynamic.linux-arm64.ssh-secret":    "aws-ssh-key",
+						"dynamic.linux-arm64.min-warm":      "-1",
+					},
+					"linux/arm64",
+					"dynamic platform 'linux/arm64': invalid min-warm '-1'",
+				),
+				Entry("for an invalid warm instance max-age",
+					map[string]string{
+						"dynamic.linux-arm64.type":          "aws",
+						"dynamic.linux-arm64.max-instances": "5",
+						"dynamic.linux-arm64.ssh-secret":    "aws-ssh-key",
+						"dynamic.linux-arm64.max-age":       "0",
+					},
+					"linux/arm64",
+					"dynamic platform 'linux/arm64': invalid max-age '0'",
+				),
 			)
 		})
 	})
@@ -371,6 +594,49 @@ var _ = Describe("Host Configuration Parsing and Validation Tests", func() {
 				Expect(poolConfig.MaxAge).Should(Equal(int64(60)))
 			})
 
+			It("should parse pool platform with a quota group", func(ctx SpecContext) {
+				data := map[string]string{
+					"dynamic.linux-amd64.type":          "aws",
+					"dynamic.linux-amd64.max-instances": "20",
+					"dynamic.linux-amd64.concurrency":   "4",
+					"dynamic.linux-amd64.max-age":       "60",
+					"dynamic.linux-amd64.ssh-secret":    "aws-pool-secret",
+					"dynamic.linux-amd64.quota-group":   "aws-x86",
+					"dynamic.linux-amd64.vcpus":         "4",
+					"quota-group.aws-x86.max-vcpus":     "32",
+				}
+
+				poolConfig, err := ParseDynamicPoolPlatformConfig(data, "linux/amd64")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(poolConfig.QuotaGroup).Should(Equal("aws-x86"))
+				Expect(poolConfig.VCPUs).Should(Equal(4))
+			})
+
+			It("should parse pool platform with an idle timeout", func(ctx SpecContext) {
+				data := map[string]string{
+					"dynamic.linux-amd64.type":          "aws",
+					"dynamic.linux-amd64.max-instances": "20",
+					"dynamic.linux-amd64.concurrency":   "4",
+					"dynamic.linux-amd64.max-age":       "600",
+					"dynamic.linux-amd64.ssh-secret":    "aws-pool-secret",
+				}
+
+				poolConfig, err := ParseDynamicPoolPlatformConfig(data, "linux/amd64")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(poolConfig.IdleTimeout).Should(Equal(int64(0)))
+
+				data["dynamic.linux-amd64.idle-timeout"] = "15"
+				poolConfig, err = ParseDynamicPoolPlatformConfig(data, "linux/amd64")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(poolConfig.IdleTimeout).Should(Equal(int64(15)))
+				Expect(poolConfig.Hibernate).Should(BeFalse())
+
+				data["dynamic.linux-amd64.hibernate"] = "true"
+				poolConfig, err = ParseDynamicPoolPlatformConfig(data, "linux/amd64")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(poolConfig.Hibernate).Should(BeTrue())
+			})
+
 			DescribeTable("should parse valid concurrency values",
 				func(ctx SpecContext, value string, expected int) {
 					data := map[string]string{
@@ -423,6 +689,43 @@ var _ = Describe("Host Configuration Parsing and Validation Tests", func() {
 					"linux/amd64",
 					"concurrency field is required",
 				),
+				Entry("for an invalid idle-timeout",
+					map[string]string{
+						"dynamic.linux-amd64.type":          "aws",
+						"dynamic.linux-amd64.max-instances": "10",
+						"dynamic.linux-amd64.concurrency":   "4",
+						"dynamic.linux-amd64.max-age":       "60",
+						"dynamic.linux-amd64.idle-timeout":  "0",
+						"dynamic.linux-amd64.ssh-secret":    "aws-secret",
+					},
+					"linux/amd64",
+					"invalid idle-timeout '0'",
+				),
+				Entry("for an invalid hibernate",
+					map[string]string{
+						"dynamic.linux-amd64.type":          "aws",
+						"dynamic.linux-amd64.max-instances": "10",
+						"dynamic.linux-amd64.concurrency":   "4",
+						"dynamic.linux-amd64.max-age":       "60",
+						"dynamic.linux-amd64.idle-timeout":  "15",
+						"dynamic.linux-amd64.hibernate":     "sometimes",
+						"dynamic.linux-amd64.ssh-secret":    "aws-secret",
+					},
+					"linux/amd64",
+					"invalid hibernate 'sometimes': must be a boolean",
+				),
+				Entry("for hibernate without an idle-timeout",
+					map[string]string{
+						"dynamic.linux-amd64.type":          "aws",
+						"dynamic.linux-amd64.max-instances": "10",
+						"dynamic.linux-amd64.concurrency":   "4",
+						"dynamic.linux-amd64.max-age":       "60",
+						"dynamic.linux-amd64.hibernate":     "true",
+						"dynamic.linux-amd64.ssh-secret":    "aws-secret",
+					},
+					"linux/amd64",
+					"idle-timeout field is required with hibernate",
+				),
 				Entry("when max-age field is missing",
 					map[string]string{
 						"dynamic.linux-amd64.type":          "aws",
@@ -433,6 +736,22 @@ var _ = Describe("Host Configuration Parsing and Validation Tests", func() {
 					"linux/amd64",
 					"max-age field is required",
 				),
+				Entry("for OpenStack platform without a floating IP network",
+					map[string]string{
+						"dynamic.linux-arm64.type":             "openstack",
+						"dynamic.linux-arm64.max-instances":    "5",
+						"dynamic.linux-arm64.concurrency":      "4",
+						"dynamic.linux-arm64.max-age":          "60",
+						"dynamic.linux-arm64.ssh-secret":       "openstack-ssh-key",
+						"dynamic.linux-arm64.auth-url":         "https://keystone.example.com:5000/v3",
+						"dynamic.linux-arm64.openstack-secret": "openstack-credentials",
+						"dynamic.linux-arm64.flavor":           "m1.large",
+						"dynamic.linux-arm64.image":            "fedora-42-aarch64",
+						"dynamic.linux-arm64.network":          "private-net",
+					},
+					"linux/arm64",
+					"floating-ip-network field is required",
+				),
 				Entry("for invalid concurrency value",
 					map[string]string{
 						"dynamic.linux-amd64.type":          "aws",
@@ -590,4 +909,158 @@ var _ = Describe("Host Configuration Parsing and Validation Tests", func() {
 			)
 		})
 	})
+
+	// This section tests parsing of the orphaned cloud instance garbage collector configuration
+	Describe("The ParseOrphanedInstanceGCConfig function", func() {
+
+		When("parsing a valid configuration", func() {
+			DescribeTable("it should return the garbage collector configuration",
+				func(data map[string]string, expected OrphanedInstanceGCConfig) {
+					Expect(ParseOrphanedInstanceGCConfig(data)).Should(Equal(expected))
+				},
+				Entry("with defaults when nothing is configured",
+					map[string]string{},
+					OrphanedInstanceGCConfig{GracePeriod: 30 * time.Minute},
+				),
+				Entry("when enabled in dry-run mode with a custom grace period",
+					map[string]string{
+						"orphaned-instance-gc.enabled":      "true",
+						"orphaned-instance-gc.dry-run":      "true",
+						"orphaned-instance-gc.grace-period": "2h",
+					},
+					OrphanedInstanceGCConfig{Enabled: true, DryRun: true, GracePeriod: 2 * time.Hour},
+				),
+			)
+		})
+
+		When("parsing an invalid configuration", func() {
+			DescribeTable("it should return a descriptive error",
+				func(data map[string]string, expectedErrorSubstring string) {
+					_, err := ParseOrphanedInstanceGCConfig(data)
+					Expect(err).Should(MatchError(ContainSubstring(expectedErrorSubstring)))
+				},
+				Entry("for a non-boolean enabled", map[string]string{"orphaned-instance-gc.enabled": "yes please"}, "invalid enabled 'yes please'"),
+				Entry("for a non-boolean dry-run", map[string]string{"orphaned-instance-gc.dry-run": "maybe"}, "invalid dry-run 'maybe'"),
+				Entry("for a negative grace period", map[string]string{"orphaned-instance-gc.grace-period": "-1h"}, "invalid grace-period '-1h'"),
+				Entry("for a grace period without a unit", map[string]string{"orphaned-instance-gc.grace-period": "30"}, "invalid grace-period '30'"),
+			)
+		})
+	})
+
+	// This section tests parsing of the hosts taken out of rotation for maintenance
+	Describe("The ParseHostStates function", func() {
+
+		It("should return the state of the cordoned and draining hosts", func() {
+			states, err := ParseHostStates("host1, i-0abc", " host2,,")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(states).Should(Equal(map[string]HostState{
+				"host1":  HostStateCordoned,
+				"i-0abc": HostStateCordoned,
+				"host2":  HostStateDraining,
+			}))
+		})
+
+		It("should return no states when no host is out of rotation", func() {
+			Expect(ParseHostStates("", "")).Should(BeEmpty())
+		})
+
+		It("should reject a host that is both cordoned and draining", func() {
+			_, err := ParseHostStates("host1", "host2,host1")
+			Expect(err).Should(MatchError(ContainSubstring("host 'host1' is both cordoned and draining")))
+		})
+	})
+
+	// This section tests parsing of the SSH health prober configuration
+	Describe("The ParseHostHealthProbeConfig function", func() {
+
+		When("parsing a valid configuration", func() {
+			DescribeTable("it should return the prober configuration",
+				func(data map[string]string, expected HostHealthProbeConfig) {
+					Expect(ParseHostHealthProbeConfig(data)).Should(Equal(expected))
+				},
+				Entry("with defaults when nothing is configured",
+					map[string]string{},
+					HostHealthProbeConfig{Timeout: 10 * time.Second, FailureThreshold: 3},
+				),
+				Entry("when enabled with a custom timeout and failure threshold",
+					map[string]string{
+						"host-health-probe.enabled":           "true",
+						"host-health-probe.timeout":           "5s",
+						"host-health-probe.failure-threshold": "1",
+					},
+					HostHealthProbeConfig{Enabled: true, Timeout: 5 * time.Second, FailureThreshold: 1},
+				),
+			)
+		})
+
+		When("parsing an invalid configuration", func() {
+			DescribeTable("it should return a descriptive error",
+				func(data map[string]string, expectedErrorSubstring string) {
+					_, err := ParseHostHealthProbeConfig(data)
+					Expect(err).Should(MatchError(ContainSubstring(expectedErrorSubstring)))
+				},
+				Entry("for a non-boolean enabled", map[string]string{"host-health-probe.enabled": "always"}, "invalid enabled 'always'"),
+				Entry("for a zero timeout", map[string]string{"host-health-probe.timeout": "0s"}, "invalid timeout '0s'"),
+				Entry("for a non-numeric failure threshold", map[string]string{"host-health-probe.failure-threshold": "three"}, "invalid failure-threshold 'three'"),
+				Entry("for a zero failure threshold", map[string]string{"host-health-probe.failure-threshold": "0"}, "invalid failure-threshold '0'"),
+			)
+		})
+	})
+
+	// This section tests parsing of the limits of the calls made to each cloud provider account
+	Describe("The ParseCloudAPILimitsConfig function", func() {
+
+		When("parsing a valid configuration", func() {
+			DescribeTable("it should return the cloud API limits",
+				func(data map[string]string, expected CloudAPILimitsConfig) {
+					Expect(ParseCloudAPILimitsConfig(data)).Should(Equal(expected))
+				},
+				Entry("with defaults when nothing is configured",
+					map[string]string{},
+					CloudAPILimitsConfig{RateLimit: 10, Burst: 20, FailureThreshold: 5, OpenDuration: time.Minute, InstanceCacheTTL: 10 * time.Second},
+				),
+				Entry("with custom limits",
+					map[string]string{
+						"cloud-api.rate-limit":         "2.5",
+						"cloud-api.burst":              "5",
+						"cloud-api.failure-threshold":  "3",
+						"cloud-api.open-duration":      "30s",
+						"cloud-api.instance-cache-ttl": "0s",
+					},
+					CloudAPILimitsConfig{RateLimit: 2.5, Burst: 5, FailureThreshold: 3, OpenDuration: 30 * time.Second},
+				),
+			)
+		})
+
+		When("parsing an invalid configuration", func() {
+			DescribeTable("it should return a descriptive error",
+				func(data map[string]string, expectedErrorSubstring string) {
+					_, err := ParseCloudAPILimitsConfig(data)
+					Expect(err).Should(MatchError(ContainSubstring(expectedErrorSubstring)))
+				},
+				Entry("for a zero rate limit", map[string]string{"cloud-api.rate-limit": "0"}, "invalid rate-limit '0'"),
+				Entry("for a non-numeric burst", map[string]string{"cloud-api.burst": "lots"}, "invalid burst 'lots'"),
+				Entry("for a negative failure threshold", map[string]string{"cloud-api.failure-threshold": "-1"}, "invalid failure-threshold '-1'"),
+				Entry("for an open duration without a unit", map[string]string{"cloud-api.open-duration": "60"}, "invalid open-duration '60'"),
+				Entry("for a negative instance cache TTL", map[string]string{"cloud-api.instance-cache-ttl": "-5s"}, "invalid instance-cache-ttl '-5s'"),
+			)
+		})
+	})
+
+	Describe("The ParseQuotaGroupConfig function", func() {
+		It("should parse a valid quota group", func(ctx SpecContext) {
+			Expect(ParseQuotaGroupConfig(map[string]string{"quota-group.aws-arm.max-vcpus": "64"}, "aws-arm")).
+				Should(Equal(QuotaGroupConfig{Name: "aws-arm", MaxVCPUs: 64}))
+		})
+
+		DescribeTable("it should return a descriptive error for invalid quota groups",
+			func(ctx SpecContext, data map[string]string, expectedErrorSubstring string) {
+				_, err := ParseQuotaGroupConfig(data, "aws-arm")
+				Expect(err).Should(MatchError(ContainSubstring(expectedErrorSubstring)))
+			},
+			Entry("for a missing max-vcpus", map[string]string{"quota-group.other.max-vcpus": "64"}, "quota group 'aws-arm': max-vcpus field is required"),
+			Entry("for a non-numeric max-vcpus", map[string]string{"quota-group.aws-arm.max-vcpus": "many"}, "invalid max-vcpus 'many'"),
+			Entry("for a zero max-vcpus", map[string]string{"quota-group.aws-arm.max-vcpus": "0"}, "invalid max-vcpus '0'"),
+		)
+	})
 })
@@ -3,10 +3,15 @@ package config
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net"
+	"net/url"
+	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	"k8s.io/apimachinery/pkg/util/validation"
@@ -18,6 +23,13 @@ var (
 	ErrInvalidIPFormat          = errors.New("value must be a valid IP address in dotted decimal notation")
 
 	errIBMHostSecretPlatformMismatch = errors.New("host secret key and value must contain matching platform substring")
+
+	// launchTemplateIdRegex matches EC2 launch template IDs, e.g. "lt-0abcd1234efgh5678"
+	launchTemplateIdRegex = regexp.MustCompile(`^lt-[0-9a-f]+$`)
+	// roleArnRegex matches IAM role ARNs, e.g. "arn:aws:iam::123456789012:role/path/name"
+	roleArnRegex = regexp.MustCompile(`^arn:aws[a-z-]*:iam::[0-9]{12}:role/[A-Za-z0-9+=,.@_/-]+$`)
+	// ibmPowerStorageTiers are the storage tiers available to IBM Power boot volumes
+	ibmPowerStorageTiers = []string{"tier0", "tier1", "tier3", "tier5k"}
 )
 
 const (
@@ -33,6 +45,9 @@ const (
 	// These limits prevent collision issues by using 64-bit hash space
 	maxInstanceTagLengthPPC   = 29 // PowerPC: maxLength=47, format is tag + "-" + 16-char-hash + "x" = tag + 18
 	maxInstanceTagLengthS390x = 45 // System Z: maxLength=63, format is tag + "-" + 16-char-hash + "x" = tag + 18
+
+	// Smallest number of cores IBM Power shared and capped processors can be allocated in
+	ibmPowerSharedCoreIncrement = 0.25
 )
 
 // validatePlatformFormat validates a platform string according to the controller's rules
@@ -190,6 +205,236 @@ func validateIBMHostSecret(key, value string) error {
 	return errIBMHostSecretPlatformMismatch
 }
 
+// validateOpenStackConfig validates the OpenStack-specific keys of a dynamic platform configuration
+// Validation rules:
+// - auth-url, openstack-secret, flavor, image and network are required
+// - auth-url must be an absolute http or https URL
+// - private-ip must be a boolean if provided
+// - floating-ip-network is required unless private-ip is true
+//
+// Returns:
+// - nil if validation passes
+// - a descriptive error naming the first invalid key otherwise
+func validateOpenStackConfig(data map[string]string, prefix string) error {
+	for _, key := range []string{"auth-url", "openstack-secret", "flavor", "image", "network"} {
+		if strings.TrimSpace(data[prefix+key]) == "" {
+			return fmt.Errorf("%s field is required for type 'openstack'", key)
+		}
+	}
+
+	authURL, err := url.Parse(data[prefix+"auth-url"])
+	if err != nil || (authURL.Scheme != "http" && authURL.Scheme != "https") || authURL.Host == "" {
+		return fmt.Errorf("invalid auth-url '%s': must be an absolute http or https URL", data[prefix+"auth-url"])
+	}
+
+	privateIP := false
+	if privateIPStr := data[prefix+"private-ip"]; privateIPStr != "" {
+		privateIP, err = strconv.ParseBool(privateIPStr)
+		if err != nil {
+			return fmt.Errorf("invalid private-ip '%s': must be a boolean", privateIPStr)
+		}
+	}
+	if !privateIP && strings.TrimSpace(data[prefix+"floating-ip-network"]) == "" {
+		return errors.New("floating-ip-network field is required for type 'openstack' unless private-ip is true")
+	}
+	return nil
+}
+
+// validateExternalConfig validates the keys of a dynamic platform configuration served by an external cloud provider
+// plugin
+// Validation rules:
+// - address is required
+// - insecure must be a boolean if provided
+//
+// Returns:
+// - nil if validation passes
+// - a descriptive error naming the first invalid key otherwise
+func validateExternalConfig(data map[string]string, prefix string) error {
+	if strings.TrimSpace(data[prefix+"address"]) == "" {
+		return errors.New("address field is required for type 'external'")
+	}
+	if insecureStr := data[prefix+"insecure"]; insecureStr != "" {
+		if _, err := strconv.ParseBool(insecureStr); err != nil {
+			return fmt.Errorf("invalid insecure '%s': must be a boolean", insecureStr)
+		}
+	}
+	return nil
+}
+
+// validateIBMConfig validates the IBM-specific keys of a dynamic platform configuration
+// Validation rules:
+// - tagging-url must be an absolute https URL if provided
+//
+// The additional-instance-tags are shared with the other platforms, so the ones that are not valid IBM Cloud user
+// tags are skipped when instances are launched rather than rejected here.
+//
+// Returns:
+// - nil if validation passes
+// - a descriptive error naming the first invalid key otherwise
+func validateIBMConfig(data map[string]string, prefix string) error {
+	if taggingURLStr := data[prefix+"tagging-url"]; taggingURLStr != "" {
+		taggingURL, err := url.Parse(taggingURLStr)
+		if err != nil || taggingURL.Scheme != "https" || taggingURL.Host == "" {
+			return fmt.Errorf("invalid tagging-url '%s': must be an absolute https URL", taggingURLStr)
+		}
+	}
+	return nil
+}
+
+// validateIBMPowerConfig validates the IBM Power-specific keys of a dynamic platform configuration
+// Validation rules:
+// - proc-type must be "shared", "capped" or "dedicated" if provided
+// - cores must be a multiple of 0.25 for shared and capped processors, and a whole number for dedicated processors
+// - storage-tier must be "tier0", "tier1", "tier3" or "tier5k" if provided
+//
+// Returns:
+// - nil if validation passes
+// - a descriptive error naming the first invalid key otherwise
+func validateIBMPowerConfig(data map[string]string, prefix string) error {
+	procType := data[prefix+"proc-type"]
+	switch procType {
+	case "", "shared", "capped", "dedicated":
+	default:
+		return fmt.Errorf("invalid proc-type '%s': must be 'shared', 'capped' or 'dedicated'", procType)
+	}
+
+	if coresStr := data[prefix+"cores"]; coresStr != "" {
+		cores, err := strconv.ParseFloat(coresStr, 64)
+		if err != nil || cores <= 0 {
+			return fmt.Errorf("invalid cores '%s': must be a positive number", coresStr)
+		}
+		if procType == "dedicated" {
+			if cores != math.Trunc(cores) {
+				return fmt.Errorf("invalid cores '%s': dedicated processors must be allocated in whole cores", coresStr)
+			}
+		} else if quarters := cores / ibmPowerSharedCoreIncrement; math.Abs(quarters-math.Round(quarters)) > 1e-9 {
+			return fmt.Errorf("invalid cores '%s': shared and capped processors must be allocated in increments of %g cores", coresStr, ibmPowerSharedCoreIncrement)
+		}
+	}
+
+	if storageTier := data[prefix+"storage-tier"]; storageTier != "" && !slices.Contains(ibmPowerStorageTiers, storageTier) {
+		return fmt.Errorf("invalid storage-tier '%s': must be one of %s", storageTier, strings.Join(ibmPowerStorageTiers, ", "))
+	}
+	return nil
+}
+
+// validateAWSConfig validates the AWS-specific keys of a dynamic platform configuration
+// Validation rules:
+// - spot must be a boolean if provided
+// - spot-max-price must be a positive decimal price in USD and requires spot to be true
+// - spot cannot be combined with the "host" tenancy, since Spot instances cannot run on dedicated hosts
+// - launch-template-id must be an EC2 launch template ID ("lt-" followed by hexadecimal digits) if provided
+// - launch-template-version must be a positive version number, "$Latest" or "$Default" and requires launch-template-id
+// - subnet-ids must be a comma-separated list of subnet IDs and cannot be combined with subnet-id
+// - instance-type may be a comma-separated list of instance types, in order of preference, without duplicates
+// - role-arn must be the ARN of an IAM role if provided
+// - ami, ami-ssm-parameter and ami-name are alternative sources of the AMI; only one can be used
+// - ami-ssm-parameter must be a parameter path and ami-name requires ami-owner (and vice versa)
+// - ami-cache-ttl must be a positive duration if provided
+//
+// Returns:
+// - nil if validation passes
+// - a descriptive error naming the first invalid key otherwise
+func validateAWSConfig(data map[string]string, prefix string) error {
+	spot := false
+	if spotStr := data[prefix+"spot"]; spotStr != "" {
+		var err error
+		spot, err = strconv.ParseBool(spotStr)
+		if err != nil {
+			return fmt.Errorf("invalid spot '%s': must be a boolean", spotStr)
+		}
+	}
+	if maxPrice := data[prefix+"spot-max-price"]; maxPrice != "" {
+		if !spot {
+			return errors.New("spot-max-price requires spot to be true")
+		}
+		price, err := strconv.ParseFloat(maxPrice, 64)
+		if err != nil || price <= 0 {
+			return fmt.Errorf("invalid spot-max-price '%s': must be a positive decimal price", maxPrice)
+		}
+	}
+	if spot && data[prefix+"tenancy"] == "host" {
+		return errors.New("spot cannot be used with the 'host' tenancy")
+	}
+
+	launchTemplateId := data[prefix+"launch-template-id"]
+	if launchTemplateId != "" && !launchTemplateIdRegex.MatchString(launchTemplateId) {
+		return fmt.Errorf("invalid launch-template-id '%s': must be 'lt-' followed by hexadecimal digits", launchTemplateId)
+	}
+	if version := data[prefix+"launch-template-version"]; version != "" {
+		if launchTemplateId == "" {
+			return errors.New("launch-template-version requires launch-template-id")
+		}
+		if version != "$Latest" && version != "$Default" {
+			if _, err := validateNonZeroPositiveNumber(version); err != nil {
+				return fmt.Errorf("invalid launch-template-version '%s': must be a version number, '$Latest' or '$Default'", version)
+			}
+		}
+	}
+
+	if subnetIds := data[prefix+"subnet-ids"]; subnetIds != "" {
+		if data[prefix+"subnet-id"] != "" {
+			return errors.New("subnet-id and subnet-ids cannot be used together")
+		}
+		for _, subnetId := range strings.Split(subnetIds, ",") {
+			if subnetId = strings.TrimSpace(subnetId); subnetId != "" && !strings.HasPrefix(subnetId, "subnet-") {
+				return fmt.Errorf("invalid subnet-ids '%s': '%s' is not a subnet ID", subnetIds, subnetId)
+			}
+		}
+	}
+
+	if instanceTypes := data[prefix+"instance-type"]; strings.Contains(instanceTypes, ",") {
+		seen := map[string]bool{}
+		for _, instanceType := range strings.Split(instanceTypes, ",") {
+			instanceType = strings.TrimSpace(instanceType)
+			if instanceType == "" {
+				continue
+			}
+			if seen[instanceType] {
+				return fmt.Errorf("invalid instance-type '%s': '%s' is listed more than once", instanceTypes, instanceType)
+			}
+			seen[instanceType] = true
+		}
+	}
+
+	if roleArn := data[prefix+"role-arn"]; roleArn != "" && !roleArnRegex.MatchString(roleArn) {
+		return fmt.Errorf("invalid role-arn '%s': must be the ARN of an IAM role", roleArn)
+	}
+
+	return validateAWSAmiConfig(data, prefix)
+}
+
+// validateAWSAmiConfig validates the settings AWS platforms use to find the AMI instances are launched from.
+func validateAWSAmiConfig(data map[string]string, prefix string) error {
+	ssmParameter := data[prefix+"ami-ssm-parameter"]
+	owner := data[prefix+"ami-owner"]
+	name := data[prefix+"ami-name"]
+
+	sources := 0
+	for _, source := range []string{data[prefix+"ami"], ssmParameter, name} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return errors.New("only one of ami, ami-ssm-parameter and ami-name can be used")
+	}
+	if ssmParameter != "" && !strings.HasPrefix(ssmParameter, "/") {
+		return fmt.Errorf("invalid ami-ssm-parameter '%s': must be a parameter path starting with '/'", ssmParameter)
+	}
+	if (owner == "") != (name == "") {
+		return errors.New("ami-owner and ami-name must be used together")
+	}
+
+	if ttl := data[prefix+"ami-cache-ttl"]; ttl != "" {
+		duration, err := time.ParseDuration(ttl)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("invalid ami-cache-ttl '%s': must be a positive duration, e.g. '1h'", ttl)
+		}
+	}
+	return nil
+}
+
 // validateDynamicInstanceTag validates dynamic host instance-tag configuration.
 // It ensures the platform and instance type match between the key (platformConfigName) and the value (instanceTag).
 // For IBM platforms, it also enforces maximum length limits to prevent hash collision issues.
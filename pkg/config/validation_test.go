@@ -286,6 +286,197 @@ var _ = Describe("Host Configuration Validation Tests", func() {
 		})
 	})
 
+	// This section tests validation of the OpenStack-specific dynamic platform configuration keys.
+	Describe("The validateOpenStackConfig function", func() {
+		prefix := "dynamic.linux-arm64."
+		validConfig := func(overrides map[string]string) map[string]string {
+			data := map[string]string{
+				prefix + "auth-url":            "https://keystone.example.com:5000/v3",
+				prefix + "openstack-secret":    "openstack-credentials",
+				prefix + "flavor":              "m1.large",
+				prefix + "image":               "fedora-42-aarch64",
+				prefix + "network":             "private-net",
+				prefix + "floating-ip-network": "public-net",
+			}
+			for k, v := range overrides {
+				data[prefix+k] = v
+			}
+			return data
+		}
+
+		When("validating valid OpenStack configurations", func() {
+			DescribeTable("it should accept the configuration",
+				func(overrides map[string]string) {
+					Expect(validateOpenStackConfig(validConfig(overrides), prefix)).ShouldNot(HaveOccurred())
+				},
+				Entry("with a floating IP network", map[string]string{}),
+				Entry("with private IP addresses and no floating IP network", map[string]string{"floating-ip-network": "", "private-ip": "true"}),
+			)
+		})
+
+		When("validating invalid OpenStack configurations", func() {
+			DescribeTable("it should return a descriptive error",
+				func(overrides map[string]string, expectedErrorSubstring string) {
+					Expect(validateOpenStackConfig(validConfig(overrides), prefix)).Should(MatchError(ContainSubstring(expectedErrorSubstring)))
+				},
+				Entry("with a missing auth-url", map[string]string{"auth-url": ""}, "auth-url field is required"),
+				Entry("with a relative auth-url", map[string]string{"auth-url": "keystone:5000/v3"}, "invalid auth-url"),
+				Entry("with a missing credentials secret", map[string]string{"openstack-secret": " "}, "openstack-secret field is required"),
+				Entry("with a missing flavor", map[string]string{"flavor": ""}, "flavor field is required"),
+				Entry("with a missing image", map[string]string{"image": ""}, "image field is required"),
+				Entry("with a missing network", map[string]string{"network": ""}, "network field is required"),
+				Entry("with an invalid private-ip", map[string]string{"private-ip": "sometimes"}, "invalid private-ip 'sometimes'"),
+				Entry("with a missing floating IP network", map[string]string{"floating-ip-network": ""}, "floating-ip-network field is required"),
+			)
+		})
+	})
+
+	// This section tests validation of the dynamic platform configuration keys of external cloud provider plugins.
+	Describe("The validateExternalConfig function", func() {
+		prefix := "dynamic.linux-arm64."
+
+		DescribeTable("it should accept valid configurations",
+			func(data map[string]string) {
+				Expect(validateExternalConfig(data, prefix)).ShouldNot(HaveOccurred())
+			},
+			Entry("with an address", map[string]string{prefix + "address": "dns:///plugin.multi-platform-controller.svc:443"}),
+			Entry("with an insecure address", map[string]string{prefix + "address": "localhost:50051", prefix + "insecure": "true"}),
+		)
+
+		DescribeTable("it should return a descriptive error for invalid configurations",
+			func(data map[string]string, expectedErrorSubstring string) {
+				Expect(validateExternalConfig(data, prefix)).Should(MatchError(ContainSubstring(expectedErrorSubstring)))
+			},
+			Entry("with a missing address", map[string]string{prefix + "insecure": "true"}, "address field is required for type 'external'"),
+			Entry("with a blank address", map[string]string{prefix + "address": " "}, "address field is required for type 'external'"),
+			Entry("with an invalid insecure", map[string]string{prefix + "address": "localhost:50051", prefix + "insecure": "maybe"}, "invalid insecure 'maybe'"),
+		)
+	})
+
+	Describe("The validateIBMConfig function", func() {
+		prefix := "dynamic.linux-s390x."
+
+		When("validating valid IBM configurations", func() {
+			DescribeTable("it should accept the configuration",
+				func(data map[string]string) {
+					Expect(validateIBMConfig(data, prefix)).ShouldNot(HaveOccurred())
+				},
+				Entry("without a tagging endpoint", map[string]string{}),
+				Entry("with additional instance tags that are not valid user tags", map[string]string{"additional-instance-tags": "owner=build@example.com,path=a/b,cost-center"}),
+				Entry("with a private tagging endpoint", map[string]string{prefix + "tagging-url": "https://tags.private.global-search-tagging.cloud.ibm.com"}),
+			)
+		})
+
+		When("validating invalid IBM configurations", func() {
+			DescribeTable("it should return a descriptive error",
+				func(data map[string]string, expectedErrorSubstring string) {
+					Expect(validateIBMConfig(data, prefix)).Should(MatchError(ContainSubstring(expectedErrorSubstring)))
+				},
+				Entry("with a plain http tagging endpoint", map[string]string{prefix + "tagging-url": "http://tags.example.com"}, "invalid tagging-url"),
+			)
+		})
+	})
+
+	Describe("The validateIBMPowerConfig function", func() {
+		prefix := "dynamic.linux-ppc64le."
+		config := func(values map[string]string) map[string]string {
+			data := map[string]string{}
+			for k, v := range values {
+				data[prefix+k] = v
+			}
+			return data
+		}
+
+		When("validating valid IBM Power configurations", func() {
+			DescribeTable("it should accept the configuration",
+				func(values map[string]string) {
+					Expect(validateIBMPowerConfig(config(values), prefix)).ShouldNot(HaveOccurred())
+				},
+				Entry("without processor or storage settings", map[string]string{}),
+				Entry("with fractional shared cores", map[string]string{"proc-type": "shared", "cores": "0.75"}),
+				Entry("with fractional cores and the default processor type", map[string]string{"cores": "2.25"}),
+				Entry("with fractional capped cores", map[string]string{"proc-type": "capped", "cores": "1.5"}),
+				Entry("with whole dedicated cores", map[string]string{"proc-type": "dedicated", "cores": "4"}),
+				Entry("with a storage tier", map[string]string{"storage-tier": "tier1"}),
+			)
+		})
+
+		When("validating invalid IBM Power configurations", func() {
+			DescribeTable("it should return a descriptive error",
+				func(values map[string]string, expectedErrorSubstring string) {
+					Expect(validateIBMPowerConfig(config(values), prefix)).Should(MatchError(ContainSubstring(expectedErrorSubstring)))
+				},
+				Entry("with an unknown processor type", map[string]string{"proc-type": "turbo"}, "invalid proc-type 'turbo'"),
+				Entry("with non-numeric cores", map[string]string{"cores": "many"}, "invalid cores 'many'"),
+				Entry("with zero cores", map[string]string{"cores": "0"}, "invalid cores '0'"),
+				Entry("with shared cores off the increment", map[string]string{"proc-type": "shared", "cores": "0.3"}, "increments of 0.25 cores"),
+				Entry("with fractional dedicated cores", map[string]string{"proc-type": "dedicated", "cores": "1.5"}, "dedicated processors must be allocated in whole cores"),
+				Entry("with an unknown storage tier", map[string]string{"storage-tier": "tier2"}, "invalid storage-tier 'tier2'"),
+			)
+		})
+	})
+
+	Describe("The validateAWSConfig function", func() {
+		prefix := "dynamic.linux-arm64."
+		config := func(values map[string]string) map[string]string {
+			data := map[string]string{
+				prefix + "region":        "us-east-1",
+				prefix + "instance-type": "m6g.large",
+			}
+			for k, v := range values {
+				data[prefix+k] = v
+			}
+			return data
+		}
+
+		When("validating valid AWS configurations", func() {
+			DescribeTable("it should accept the configuration",
+				func(values map[string]string) {
+					Expect(validateAWSConfig(config(values), prefix)).ShouldNot(HaveOccurred())
+				},
+				Entry("without Spot instances", map[string]string{}),
+				Entry("with Spot instances explicitly disabled", map[string]string{"spot": "false"}),
+				Entry("with Spot instances at the on-demand price", map[string]string{"spot": "true"}),
+				Entry("with Spot instances and a maximum price", map[string]string{"spot": "true", "spot-max-price": "0.0525"}),
+				Entry("with a dedicated tenancy", map[string]string{"spot": "true", "tenancy": "dedicated"}),
+				Entry("with a launch template", map[string]string{"launch-template-id": "lt-0abc123def4567890"}),
+				Entry("with a launch template version number", map[string]string{"launch-template-id": "lt-0abc123def4567890", "launch-template-version": "3"}),
+				Entry("with the latest launch template version", map[string]string{"launch-template-id": "lt-0abc123def4567890", "launch-template-version": "$Latest"}),
+				Entry("with the default launch template version", map[string]string{"launch-template-id": "lt-0abc123def4567890", "launch-template-version": "$Default"}),
+				Entry("with several subnets", map[string]string{"subnet-ids": "subnet-0a1b2c, subnet-3d4e5f"}),
+				Entry("with fallback instance types", map[string]string{"instance-type": "m6g.large, m7g.large"}),
+				Entry("with an AMI SSM parameter", map[string]string{"ami-ssm-parameter": "/aws/service/ami-amazon-linux-latest/al2023-ami-kernel-default-arm64", "ami-cache-ttl": "30m"}),
+				Entry("with an AMI name filter", map[string]string{"ami-owner": "amazon", "ami-name": "al2023-ami-2023.*-arm64"}),
+				Entry("with a role to assume", map[string]string{"role-arn": "arn:aws:iam::123456789012:role/builders/multi-platform-builder"}),
+			)
+		})
+
+		When("validating invalid AWS configurations", func() {
+			DescribeTable("it should return a descriptive error",
+				func(values map[string]string, expectedErrorSubstring string) {
+					Expect(validateAWSConfig(config(values), prefix)).Should(MatchError(ContainSubstring(expectedErrorSubstring)))
+				},
+				Entry("with an invalid spot", map[string]string{"spot": "sometimes"}, "invalid spot 'sometimes'"),
+				Entry("with a maximum price without Spot instances", map[string]string{"spot-max-price": "0.05"}, "spot-max-price requires spot to be true"),
+				Entry("with a non-numeric maximum price", map[string]string{"spot": "true", "spot-max-price": "cheap"}, "invalid spot-max-price 'cheap'"),
+				Entry("with a zero maximum price", map[string]string{"spot": "true", "spot-max-price": "0"}, "invalid spot-max-price '0'"),
+				Entry("with the host tenancy", map[string]string{"spot": "true", "tenancy": "host"}, "spot cannot be used with the 'host' tenancy"),
+				Entry("with an invalid launch template ID", map[string]string{"launch-template-id": "my-template"}, "invalid launch-template-id 'my-template'"),
+				Entry("with a launch template version without a launch template", map[string]string{"launch-template-version": "1"}, "launch-template-version requires launch-template-id"),
+				Entry("with an invalid launch template version", map[string]string{"launch-template-id": "lt-0abc123def4567890", "launch-template-version": "newest"}, "invalid launch-template-version 'newest'"),
+				Entry("with a zero launch template version", map[string]string{"launch-template-id": "lt-0abc123def4567890", "launch-template-version": "0"}, "invalid launch-template-version '0'"),
+				Entry("with both a subnet and several subnets", map[string]string{"subnet-id": "subnet-0a1b2c", "subnet-ids": "subnet-3d4e5f"}, "subnet-id and subnet-ids cannot be used together"),
+				Entry("with a duplicate fallback instance type", map[string]string{"instance-type": "m6g.large,m7g.large,m6g.large"}, "'m6g.large' is listed more than once"),
+				Entry("with both a fixed AMI and an AMI SSM parameter", map[string]string{"ami": "ami-0abc", "ami-ssm-parameter": "/images/builder"}, "only one of ami, ami-ssm-parameter and ami-name can be used"),
+				Entry("with an AMI SSM parameter name instead of a path", map[string]string{"ami-ssm-parameter": "builder-ami"}, "invalid ami-ssm-parameter 'builder-ami'"),
+				Entry("with an AMI name filter without an owner", map[string]string{"ami-name": "al2023-ami-*"}, "ami-owner and ami-name must be used together"),
+				Entry("with an invalid AMI cache period", map[string]string{"ami-ssm-parameter": "/images/builder", "ami-cache-ttl": "-5m"}, "invalid ami-cache-ttl '-5m'"),
+				Entry("with the ARN of a user instead of a role", map[string]string{"role-arn": "arn:aws:iam::123456789012:user/builder"}, "invalid role-arn"),
+				Entry("with an invalid subnet in the subnets", map[string]string{"subnet-ids": "subnet-0a1b2c,us-east-1a"}, "'us-east-1a' is not a subnet ID"),
+			)
+		})
+	})
+
 	// This section tests parsing of dynamic host instance type configuration keys for AWS EC2.
 	Describe("The parseDynamicHostInstanceTypeKey function", func() {
 
@@ -98,7 +98,24 @@ func NewManager(cfg *rest.Config, managerOptions ctrl.Options, controllerOptions
 	}
 	controllerLog.Info("deployed in namespace", "namespace", operatorNamespace)
 	controllerLog.Info("controller concurrency", "maxConcurrentReconciles", controllerOptions.MaxConcurrentReconciles)
-	if err := taskrun.SetupNewReconcilerWithManager(mgr, operatorNamespace, controllerOptions); err != nil {
+	// The reconciler and its runnables share the limits, caches and host state of the platforms
+	state := taskrun.NewSharedState()
+	if err := taskrun.SetupNewReconcilerWithManager(mgr, operatorNamespace, state, controllerOptions); err != nil {
+		return nil, err
+	}
+	if err := taskrun.SetupOrphanedInstanceCollectorWithManager(mgr, operatorNamespace, state); err != nil {
+		return nil, err
+	}
+	if err := taskrun.SetupWarmPoolWithManager(mgr, operatorNamespace, state); err != nil {
+		return nil, err
+	}
+	if err := taskrun.SetupDynamicPoolScaleDownWithManager(mgr, operatorNamespace, state); err != nil {
+		return nil, err
+	}
+	if err := taskrun.SetupHostHealthProberWithManager(mgr, operatorNamespace, state); err != nil {
+		return nil, err
+	}
+	if err := taskrun.SetupHostDrainWithManager(mgr, operatorNamespace, state); err != nil {
 		return nil, err
 	}
 
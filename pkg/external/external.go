@@ -0,0 +1,166 @@
+// Package external implements methods described in the [cloud] package by forwarding them over gRPC to a cloud
+// provider plugin running out of process, e.g. as a sidecar of the controller or as a service in the cluster.
+//
+// Plugins implement the multiplatform.cloud.v1.CloudProvider service, which mirrors the CloudProvider interface.
+// Its messages are encoded as JSON, so plugins written in Go only need to implement a regular CloudProvider and
+// serve it with RegisterCloudProviderServer and NewCloudProviderServer, and plugins written in other languages do
+// not need any generated code.
+package external
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"google.golang.org/grpc"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultSshUser = "root"
+
+	// sshUserTimeout bounds the call asking the plugin for its SSH user, which has no context of its own.
+	sshUserTimeout = 30 * time.Second
+)
+
+// CreateExternalCloudConfig returns an external cloud provider configuration that implements the CloudProvider
+// interface.
+func CreateExternalCloudConfig(platformName string, config map[string]string, systemNamespace string) cloud.CloudProvider {
+	insecure, _ := strconv.ParseBool(config["dynamic."+platformName+".insecure"])
+	prefix := "dynamic." + platformName + "."
+	platformConfig := map[string]string{}
+	for key, value := range config {
+		if strings.HasPrefix(key, prefix) {
+			platformConfig[key] = value
+		}
+	}
+
+	return ExternalDynamicConfig{
+		Address:         config[prefix+"address"],
+		Insecure:        insecure,
+		User:            config[prefix+"ssh-user"],
+		Platform:        platformName,
+		PlatformConfig:  platformConfig,
+		SystemNamespace: systemNamespace,
+	}
+}
+
+// LaunchInstance asks the plugin to launch an instance and returns its identifier.
+func (e ExternalDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (cloud.InstanceIdentifier, error) {
+	err := cloud.ValidateTaskRunID(taskRunID)
+	if err != nil {
+		return "", fmt.Errorf("invalid TaskRun ID: %w", err)
+	}
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to launch external cloud provider instance", "address", e.Address, "taskRunID", taskRunID)
+
+	resp, err := call[LaunchInstanceResponse](ctx, e, "LaunchInstance", &LaunchInstanceRequest{
+		PlatformRequest:        e.platformRequest(),
+		TaskRunID:              taskRunID,
+		InstanceTag:            instanceTag,
+		AdditionalInstanceTags: additionalInstanceTags,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to launch external cloud provider instance for %s: %w", taskRunID, err)
+	}
+	return cloud.InstanceIdentifier(resp.InstanceID), nil
+}
+
+// CountInstances returns the number of instances the plugin reports for instanceTag.
+func (e ExternalDynamicConfig) CountInstances(kubeClient client.Client, ctx context.Context, instanceTag string) (int, error) {
+	resp, err := call[CountInstancesResponse](ctx, e, "CountInstances", &InstanceTagRequest{PlatformRequest: e.platformRequest(), InstanceTag: instanceTag})
+	if err != nil {
+		return -1, fmt.Errorf("failed to count external cloud provider instances: %w", err)
+	}
+	return resp.Count, nil
+}
+
+// GetInstanceAddress returns the address of an instance, or an empty string while the plugin does not know it yet.
+func (e ExternalDynamicConfig) GetInstanceAddress(kubeClient client.Client, ctx context.Context, instanceId cloud.InstanceIdentifier) (string, error) {
+	resp, err := call[GetInstanceAddressResponse](ctx, e, "GetInstanceAddress", &InstanceRequest{PlatformRequest: e.platformRequest(), InstanceID: string(instanceId)})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the address of external cloud provider instance %s: %w", instanceId, err)
+	}
+	return resp.Address, nil
+}
+
+// TerminateInstance asks the plugin to terminate an instance.
+func (e ExternalDynamicConfig) TerminateInstance(kubeClient client.Client, ctx context.Context, instanceId cloud.InstanceIdentifier) error {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to terminate external cloud provider instance", "instanceId", instanceId)
+
+	if _, err := call[TerminateInstanceResponse](ctx, e, "TerminateInstance", &InstanceRequest{PlatformRequest: e.platformRequest(), InstanceID: string(instanceId)}); err != nil {
+		return fmt.Errorf("failed to terminate external cloud provider instance %s: %w", instanceId, err)
+	}
+	return nil
+}
+
+// GetState returns the state the plugin reports for an instance.
+func (e ExternalDynamicConfig) GetState(kubeClient client.Client, ctx context.Context, instanceId cloud.InstanceIdentifier) (cloud.VMState, error) {
+	resp, err := call[GetStateResponse](ctx, e, "GetState", &InstanceRequest{PlatformRequest: e.platformRequest(), InstanceID: string(instanceId)})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the state of external cloud provider instance %s: %w", instanceId, err)
+	}
+	return cloud.VMState(resp.State), nil
+}
+
+// ListInstances returns the instances the plugin reports for instanceTag.
+func (e ExternalDynamicConfig) ListInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]cloud.CloudVMInstance, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to list external cloud provider instances")
+
+	resp, err := call[ListInstancesResponse](ctx, e, "ListInstances", &InstanceTagRequest{PlatformRequest: e.platformRequest(), InstanceTag: instanceTag})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list external cloud provider instances: %w", err)
+	}
+	vmInstances := make([]cloud.CloudVMInstance, 0, len(resp.Instances))
+	for _, instance := range resp.Instances {
+		vmInstances = append(vmInstances, cloud.CloudVMInstance{
+			InstanceId: cloud.InstanceIdentifier(instance.InstanceID),
+			StartTime:  instance.StartTime,
+			Address:    instance.Address,
+			TaskRunID:  instance.TaskRunID,
+		})
+	}
+	log.Info("Finished listing external cloud provider instances.", "count", len(vmInstances))
+	return vmInstances, nil
+}
+
+// SshUser returns the configured SSH user, or else the one reported by the plugin.
+func (e ExternalDynamicConfig) SshUser() string {
+	if e.User != "" {
+		return e.User
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sshUserTimeout)
+	defer cancel()
+	platformRequest := e.platformRequest()
+	resp, err := call[SshUserResponse](ctx, e, "SshUser", &platformRequest)
+	if err != nil || resp.User == "" {
+		return defaultSshUser
+	}
+	return resp.User
+}
+
+// An ExternalDynamicConfig represents a configuration for a cloud provider plugin. The struct implements the
+// CloudProvider interface.
+type ExternalDynamicConfig struct {
+	// Address is the gRPC target of the plugin, e.g. "localhost:50051" for a sidecar, "dns:///plugin.namespace.svc:443"
+	// for a service or "unix:///var/run/plugin/plugin.sock" for a socket shared with the controller.
+	Address string
+	// Insecure disables TLS, which should only be used when the plugin is not reached over the network.
+	Insecure bool
+	// User is the SSH user of the instances, which is asked from the plugin when it is empty.
+	User string
+	// Platform is the platform configuration name the plugin is called for.
+	Platform string
+	// PlatformConfig holds every dynamic.<platform>.* key of the host configuration, which is passed on to the plugin.
+	PlatformConfig  map[string]string
+	SystemNamespace string
+
+	// dialOptions are added to the options the plugin is dialed with.
+	dialOptions []grpc.DialOption
+}
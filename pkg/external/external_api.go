@@ -0,0 +1,237 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ServiceName is the full name of the gRPC service implemented by external cloud provider plugins.
+	ServiceName = "multiplatform.cloud.v1.CloudProvider"
+
+	// codecName is the content subtype of the service, whose messages are encoded as JSON rather than protobuf
+	// so that plugins can be written without generated code: requests are sent as "application/grpc+json".
+	codecName = "json"
+)
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec encodes the messages of the service as JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return codecName
+}
+
+// PlatformRequest identifies the platform a call is made for by its configuration name, e.g. "linux-arm64". Config
+// holds every dynamic.<platform>.* key of the host configuration, so that plugins can be configured through the same
+// ConfigMap as the controller.
+type PlatformRequest struct {
+	Platform string            `json:"platform"`
+	Config   map[string]string `json:"config,omitempty"`
+}
+
+type LaunchInstanceRequest struct {
+	PlatformRequest
+	TaskRunID              string            `json:"taskRunID"`
+	InstanceTag            string            `json:"instanceTag"`
+	AdditionalInstanceTags map[string]string `json:"additionalInstanceTags,omitempty"`
+}
+
+type LaunchInstanceResponse struct {
+	InstanceID string `json:"instanceID"`
+}
+
+// InstanceRequest is the request of the calls made for a single instance.
+type InstanceRequest struct {
+	PlatformRequest
+	InstanceID string `json:"instanceID"`
+}
+
+// InstanceTagRequest is the request of the calls made for all the instances of an instance tag.
+type InstanceTagRequest struct {
+	PlatformRequest
+	InstanceTag string `json:"instanceTag"`
+}
+
+type TerminateInstanceResponse struct{}
+
+type GetInstanceAddressResponse struct {
+	Address string `json:"address"`
+}
+
+type CountInstancesResponse struct {
+	Count int `json:"count"`
+}
+
+type Instance struct {
+	InstanceID string    `json:"instanceID"`
+	StartTime  time.Time `json:"startTime"`
+	Address    string    `json:"address,omitempty"`
+	TaskRunID  string    `json:"taskRunID,omitempty"`
+}
+
+type ListInstancesResponse struct {
+	Instances []Instance `json:"instances"`
+}
+
+type GetStateResponse struct {
+	State string `json:"state"`
+}
+
+type SshUserResponse struct {
+	User string `json:"user"`
+}
+
+// CloudProviderServer is the server API of the service, mirroring the cloud.CloudProvider interface.
+type CloudProviderServer interface {
+	LaunchInstance(context.Context, *LaunchInstanceRequest) (*LaunchInstanceResponse, error)
+	TerminateInstance(context.Context, *InstanceRequest) (*TerminateInstanceResponse, error)
+	GetInstanceAddress(context.Context, *InstanceRequest) (*GetInstanceAddressResponse, error)
+	CountInstances(context.Context, *InstanceTagRequest) (*CountInstancesResponse, error)
+	ListInstances(context.Context, *InstanceTagRequest) (*ListInstancesResponse, error)
+	GetState(context.Context, *InstanceRequest) (*GetStateResponse, error)
+	SshUser(context.Context, *PlatformRequest) (*SshUserResponse, error)
+}
+
+// RegisterCloudProviderServer registers srv as the implementation of the service on s.
+func RegisterCloudProviderServer(s grpc.ServiceRegistrar, srv CloudProviderServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+// unaryHandler returns the handler of a method of the service calling call on the CloudProviderServer.
+func unaryHandler[Req any, Resp any](method string, call func(CloudProviderServer, context.Context, *Req) (*Resp, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			req := new(Req)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(srv.(CloudProviderServer), ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/" + method}
+			return interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return call(srv.(CloudProviderServer), ctx, req.(*Req))
+			})
+		},
+	}
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*CloudProviderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryHandler("LaunchInstance", CloudProviderServer.LaunchInstance),
+		unaryHandler("TerminateInstance", CloudProviderServer.TerminateInstance),
+		unaryHandler("GetInstanceAddress", CloudProviderServer.GetInstanceAddress),
+		unaryHandler("CountInstances", CloudProviderServer.CountInstances),
+		unaryHandler("ListInstances", CloudProviderServer.ListInstances),
+		unaryHandler("GetState", CloudProviderServer.GetState),
+		unaryHandler("SshUser", CloudProviderServer.SshUser),
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// invoke calls method of the service through conn.
+func invoke[Resp any](ctx context.Context, conn grpc.ClientConnInterface, method string, req any) (*Resp, error) {
+	resp := new(Resp)
+	if err := conn.Invoke(ctx, "/"+ServiceName+"/"+method, req, resp, grpc.CallContentSubtype(codecName)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// providerServer is a CloudProviderServer that serves the calls with cloud.CloudProvider implementations.
+type providerServer struct {
+	kubeClient client.Client
+	create     func(platform string, config map[string]string) cloud.CloudProvider
+}
+
+// NewCloudProviderServer returns a CloudProviderServer serving the calls of each platform with the CloudProvider
+// returned by create, so that plugins can be written as a regular CloudProvider. The providers are called with
+// kubeClient, which may be nil if they do not need one.
+func NewCloudProviderServer(kubeClient client.Client, create func(platform string, config map[string]string) cloud.CloudProvider) CloudProviderServer {
+	return providerServer{kubeClient: kubeClient, create: create}
+}
+
+func (s providerServer) provider(req PlatformRequest) cloud.CloudProvider {
+	return s.create(req.Platform, req.Config)
+}
+
+func (s providerServer) LaunchInstance(ctx context.Context, req *LaunchInstanceRequest) (*LaunchInstanceResponse, error) {
+	instanceID, err := s.provider(req.PlatformRequest).LaunchInstance(s.kubeClient, ctx, req.TaskRunID, req.InstanceTag, req.AdditionalInstanceTags)
+	if err != nil {
+		return nil, err
+	}
+	return &LaunchInstanceResponse{InstanceID: string(instanceID)}, nil
+}
+
+func (s providerServer) TerminateInstance(ctx context.Context, req *InstanceRequest) (*TerminateInstanceResponse, error) {
+	if err := s.provider(req.PlatformRequest).TerminateInstance(s.kubeClient, ctx, cloud.InstanceIdentifier(req.InstanceID)); err != nil {
+		return nil, err
+	}
+	return &TerminateInstanceResponse{}, nil
+}
+
+func (s providerServer) GetInstanceAddress(ctx context.Context, req *InstanceRequest) (*GetInstanceAddressResponse, error) {
+	address, err := s.provider(req.PlatformRequest).GetInstanceAddress(s.kubeClient, ctx, cloud.InstanceIdentifier(req.InstanceID))
+	if err != nil {
+		return nil, err
+	}
+	return &GetInstanceAddressResponse{Address: address}, nil
+}
+
+func (s providerServer) CountInstances(ctx context.Context, req *InstanceTagRequest) (*CountInstancesResponse, error) {
+	count, err := s.provider(req.PlatformRequest).CountInstances(s.kubeClient, ctx, req.InstanceTag)
+	if err != nil {
+		return nil, err
+	}
+	return &CountInstancesResponse{Count: count}, nil
+}
+
+func (s providerServer) ListInstances(ctx context.Context, req *InstanceTagRequest) (*ListInstancesResponse, error) {
+	vmInstances, err := s.provider(req.PlatformRequest).ListInstances(s.kubeClient, ctx, req.InstanceTag)
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]Instance, 0, len(vmInstances))
+	for _, instance := range vmInstances {
+		instances = append(instances, Instance{
+			InstanceID: string(instance.InstanceId),
+			StartTime:  instance.StartTime,
+			Address:    instance.Address,
+			TaskRunID:  instance.TaskRunID,
+		})
+	}
+	return &ListInstancesResponse{Instances: instances}, nil
+}
+
+func (s providerServer) GetState(ctx context.Context, req *InstanceRequest) (*GetStateResponse, error) {
+	state, err := s.provider(req.PlatformRequest).GetState(s.kubeClient, ctx, cloud.InstanceIdentifier(req.InstanceID))
+	if err != nil {
+		return nil, err
+	}
+	return &GetStateResponse{State: string(state)}, nil
+}
+
+func (s providerServer) SshUser(ctx context.Context, req *PlatformRequest) (*SshUserResponse, error) {
+	return &SshUserResponse{User: s.provider(*req).SshUser()}, nil
+}
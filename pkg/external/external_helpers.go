@@ -0,0 +1,64 @@
+package external
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// connectionKey identifies the plugin a connection is made to.
+type connectionKey struct {
+	address  string
+	insecure bool
+}
+
+var (
+	// connections holds the connections to the plugins, which are shared by every platform they serve and survive
+	// host configuration reloads, since connecting to a plugin is much more expensive than calling it.
+	connections   = map[connectionKey]*grpc.ClientConn{}
+	connectionsMu sync.Mutex
+)
+
+// platformRequest returns the PlatformRequest identifying the platform of e.
+func (e ExternalDynamicConfig) platformRequest() PlatformRequest {
+	return PlatformRequest{Platform: e.Platform, Config: e.PlatformConfig}
+}
+
+// getConnection returns the connection to the plugin of e, creating it if needed. Connections are established
+// lazily by gRPC, so a plugin that is not reachable yet only fails the calls made until it is.
+func (e ExternalDynamicConfig) getConnection() (*grpc.ClientConn, error) {
+	if e.Address == "" {
+		return nil, fmt.Errorf("no address configured for external cloud provider of platform %s", e.Platform)
+	}
+	key := connectionKey{address: e.Address, insecure: e.Insecure}
+	connectionsMu.Lock()
+	defer connectionsMu.Unlock()
+	if conn, ok := connections[key]; ok {
+		return conn, nil
+	}
+
+	transportCredentials := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if e.Insecure {
+		transportCredentials = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(e.Address, append([]grpc.DialOption{grpc.WithTransportCredentials(transportCredentials)}, e.dialOptions...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a connection to external cloud provider at %s: %w", e.Address, err)
+	}
+	connections[key] = conn
+	return conn, nil
+}
+
+// call calls method of the plugin of e with req.
+func call[Resp any](ctx context.Context, e ExternalDynamicConfig, method string, req any) (*Resp, error) {
+	conn, err := e.getConnection()
+	if err != nil {
+		return nil, err
+	}
+	return invoke[Resp](ctx, conn, method, req)
+}
@@ -0,0 +1,198 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud/fake"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const systemNamespace = "multi-platform-controller"
+
+// recordingServer is a CloudProviderServer recording the last PlatformRequest it was called with.
+type recordingServer struct {
+	CloudProviderServer
+	last PlatformRequest
+}
+
+func (r *recordingServer) CountInstances(ctx context.Context, req *InstanceTagRequest) (*CountInstancesResponse, error) {
+	r.last = req.PlatformRequest
+	return &CountInstancesResponse{Count: 3}, nil
+}
+
+func (r *recordingServer) GetState(ctx context.Context, req *InstanceRequest) (*GetStateResponse, error) {
+	return nil, errors.New("hypervisor API unavailable")
+}
+
+func (r *recordingServer) SshUser(ctx context.Context, req *PlatformRequest) (*SshUserResponse, error) {
+	return &SshUserResponse{}, nil
+}
+
+var _ = Describe("External cloud provider", func() {
+	var (
+		server   *grpc.Server
+		listener *bufconn.Listener
+	)
+
+	// startServer serves srv in process and returns the configuration of a platform calling it.
+	startServer := func(srv CloudProviderServer, config map[string]string) ExternalDynamicConfig {
+		listener = bufconn.Listen(1024 * 1024)
+		server = grpc.NewServer()
+		RegisterCloudProviderServer(server, srv)
+		go func() {
+			defer GinkgoRecover()
+			Expect(server.Serve(listener)).Should(Succeed())
+		}()
+
+		config["dynamic.linux-arm64.address"] = "passthrough:///plugin"
+		config["dynamic.linux-arm64.insecure"] = "true"
+		e := CreateExternalCloudConfig("linux-arm64", config, systemNamespace).(ExternalDynamicConfig)
+		e.dialOptions = []grpc.DialOption{grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		})}
+		return e
+	}
+
+	BeforeEach(func() {
+		connectionsMu.Lock()
+		for key, conn := range connections {
+			Expect(conn.Close()).Should(Succeed())
+			delete(connections, key)
+		}
+		connectionsMu.Unlock()
+	})
+
+	AfterEach(func() {
+		server.Stop()
+	})
+
+	Describe("The CreateExternalCloudConfig function", func() {
+		It("should only pass the configuration of its platform on to the plugin", func() {
+			e := CreateExternalCloudConfig("linux-arm64", map[string]string{
+				"dynamic.linux-arm64.address":    "plugin:50051",
+				"dynamic.linux-arm64.ssh-user":   "core",
+				"dynamic.linux-arm64.datacenter": "east",
+				"dynamic.linux-amd64.datacenter": "west",
+				"instance-tag":                   "tag",
+			}, systemNamespace).(ExternalDynamicConfig)
+			Expect(e.Address).Should(Equal("plugin:50051"))
+			Expect(e.Insecure).Should(BeFalse())
+			Expect(e.User).Should(Equal("core"))
+			Expect(e.PlatformConfig).Should(Equal(map[string]string{
+				"dynamic.linux-arm64.address":    "plugin:50051",
+				"dynamic.linux-arm64.ssh-user":   "core",
+				"dynamic.linux-arm64.datacenter": "east",
+			}))
+			server = grpc.NewServer()
+		})
+	})
+
+	When("the plugin serves a CloudProvider", func() {
+		var e ExternalDynamicConfig
+
+		BeforeEach(func() {
+			e = startServer(NewCloudProviderServer(nil, func(platform string, config map[string]string) cloud.CloudProvider {
+				return fake.CreateFakeCloudConfig(platform, config, systemNamespace)
+			}), map[string]string{
+				"dynamic.linux-arm64.ssh-user":      "core",
+				"dynamic.linux-arm64.quota":         "1",
+				"dynamic.linux-arm64.address-delay": "0s",
+			})
+			e.User = ""
+		})
+
+		It("should forward the whole instance lifecycle to the plugin", func(ctx SpecContext) {
+			instanceID, err := e.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "external-tag", map[string]string{"team": "build"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instanceID).ShouldNot(BeEmpty())
+
+			count, err := e.CountInstances(nil, ctx, "external-tag")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(count).Should(Equal(1))
+
+			address, err := e.GetInstanceAddress(nil, ctx, instanceID)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(address).ShouldNot(BeEmpty())
+
+			instances, err := e.ListInstances(nil, ctx, "external-tag")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(instances).Should(HaveLen(1))
+			Expect(instances[0].InstanceId).Should(Equal(instanceID))
+			Expect(instances[0].TaskRunID).Should(Equal("test-namespace:test-taskrun"))
+			Expect(instances[0].StartTime).ShouldNot(BeZero())
+
+			state, err := e.GetState(nil, ctx, instanceID)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(state).Should(Equal(cloud.OKState))
+
+			Expect(e.TerminateInstance(nil, ctx, instanceID)).Should(Succeed())
+			count, err = e.CountInstances(nil, ctx, "external-tag")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(count).Should(Equal(0))
+		})
+
+		It("should return the errors of the plugin", func(ctx SpecContext) {
+			instanceID, err := e.LaunchInstance(nil, ctx, "test-namespace:first", "external-tag", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = e.LaunchInstance(nil, ctx, "test-namespace:second", "external-tag", nil)
+			Expect(err).Should(MatchError(ContainSubstring("instance quota exceeded")))
+			Expect(e.TerminateInstance(nil, ctx, instanceID)).Should(Succeed())
+		})
+
+		It("should not call the plugin with an invalid TaskRun ID", func(ctx SpecContext) {
+			_, err := e.LaunchInstance(nil, ctx, "invalid", "external-tag", nil)
+			Expect(err).Should(MatchError(ContainSubstring("invalid TaskRun ID")))
+		})
+
+		It("should ask the plugin for its SSH user unless it is configured", func() {
+			Expect(e.SshUser()).Should(Equal("core"))
+			e.User = "ec2-user"
+			Expect(e.SshUser()).Should(Equal("ec2-user"))
+		})
+	})
+
+	When("the plugin implements the service itself", func() {
+		var (
+			e   ExternalDynamicConfig
+			srv *recordingServer
+		)
+
+		BeforeEach(func() {
+			srv = &recordingServer{}
+			e = startServer(srv, map[string]string{"dynamic.linux-arm64.datacenter": "east", "instance-tag": "tag"})
+		})
+
+		It("should identify the platform and pass its configuration on with every call", func(ctx SpecContext) {
+			count, err := e.CountInstances(nil, ctx, "external-tag")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(count).Should(Equal(3))
+			Expect(srv.last.Platform).Should(Equal("linux-arm64"))
+			Expect(srv.last.Config).Should(HaveKeyWithValue("dynamic.linux-arm64.datacenter", "east"))
+			Expect(srv.last.Config).ShouldNot(HaveKey("instance-tag"))
+		})
+
+		It("should return the errors of the plugin", func(ctx SpecContext) {
+			_, err := e.GetState(nil, ctx, "instance")
+			Expect(err).Should(MatchError(ContainSubstring("hypervisor API unavailable")))
+		})
+
+		It("should fall back to the default SSH user when the plugin does not report one", func() {
+			Expect(e.SshUser()).Should(Equal("root"))
+		})
+	})
+
+	When("no address is configured", func() {
+		It("should fail the calls", func(ctx SpecContext) {
+			server = grpc.NewServer()
+			e := CreateExternalCloudConfig("linux-arm64", map[string]string{}, systemNamespace)
+			_, err := e.CountInstances(nil, ctx, "external-tag")
+			Expect(err).Should(MatchError(ContainSubstring("no address configured")))
+		})
+	})
+})
@@ -0,0 +1,301 @@
+// Package gcp implements methods described in the [cloud] package for interacting with Google Cloud instances.
+// Currently only Compute Engine (GCE) instances are supported.
+//
+// All methods of the CloudProvider interface are implemented and separated from other helper functions used
+// across the methods.
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// MultiPlatformManaged is the GCE label marking instances that were created by this controller.
+	// GCE label keys must be lowercase, so this is the label form of the AWS MultiPlatformManaged tag.
+	MultiPlatformManaged = "multi-platform-managed"
+
+	defaultSshUser  = "cloud-user"
+	defaultDiskType = "pd-balanced"
+)
+
+// CreateGceCloudConfig returns a Google Compute Engine cloud configuration that implements the CloudProvider interface.
+func CreateGceCloudConfig(platformName string, config map[string]string, systemNamespace string) cloud.CloudProvider {
+	disk, err := strconv.ParseInt(config["dynamic."+platformName+".disk"], 10, 64)
+	if err != nil {
+		disk = 40
+	}
+	diskType := config["dynamic."+platformName+".disk-type"]
+	if diskType == "" {
+		diskType = defaultDiskType
+	}
+	sshUser := config["dynamic."+platformName+".ssh-user"]
+	if sshUser == "" {
+		sshUser = defaultSshUser
+	}
+	privateIp, _ := strconv.ParseBool(config["dynamic."+platformName+".private-ip"])
+
+	return GCEDynamicConfig{
+		Project:         config["dynamic."+platformName+".project"],
+		Zone:            config["dynamic."+platformName+".zone"],
+		MachineType:     config["dynamic."+platformName+".machine-type"],
+		Image:           config["dynamic."+platformName+".image"],
+		Network:         config["dynamic."+platformName+".network"],
+		Subnetwork:      config["dynamic."+platformName+".subnetwork"],
+		ServiceAccount:  config["dynamic."+platformName+".service-account"],
+		SshPublicKey:    config["dynamic."+platformName+".ssh-public-key"],
+		Secret:          config["dynamic."+platformName+".gcp-secret"],
+		UserData:        config["dynamic."+platformName+".user-data"],
+		User:            sshUser,
+		Disk:            disk,
+		DiskType:        diskType,
+		PrivateIP:       privateIp,
+		SystemNamespace: systemNamespace,
+	}
+}
+
+// LaunchInstance creates a GCE instance and returns its identifier, which is the instance name.
+func (gc GCEDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (cloud.InstanceIdentifier, error) {
+	err := cloud.ValidateTaskRunID(taskRunID)
+	if err != nil {
+		return "", fmt.Errorf("invalid TaskRun ID: %w", err)
+	}
+	log := logr.FromContextOrDiscard(ctx)
+
+	instanceName, err := createInstanceName(instanceTag)
+	if err != nil {
+		return "", fmt.Errorf("failed to create an instance name: %w", err)
+	}
+	log.Info("Attempting to launch GCE instance", "instanceName", instanceName, "taskRunID", taskRunID)
+
+	service, err := gc.getComputeService(kubeClient, ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a Compute Engine client: %w", err)
+	}
+
+	instance := gc.configureInstance(instanceName, taskRunID, instanceTag, additionalInstanceTags)
+	_, err = service.Instances.Insert(gc.Project, gc.Zone, instance).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to launch GCE instance %s: %w", instanceName, err)
+	}
+	return cloud.InstanceIdentifier(instanceName), nil
+}
+
+// CountInstances returns the number of GCE instances labelled with instanceTag.
+func (gc GCEDynamicConfig) CountInstances(kubeClient client.Client, ctx context.Context, instanceTag string) (int, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to count GCE instances")
+
+	instances, err := gc.listTaggedInstances(kubeClient, ctx, instanceTag)
+	if err != nil {
+		log.Error(err, "failed to retrieve GCE instances", "instanceTag", instanceTag)
+		return -1, err
+	}
+
+	count := 0
+	for _, instance := range instances {
+		if instance.Status != "TERMINATED" {
+			log.Info("Counting instance towards running count", "instanceName", instance.Name)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetInstanceAddress returns the IP Address associated with the instanceID GCE instance. If none is found, an empty
+// string is returned.
+func (gc GCEDynamicConfig) GetInstanceAddress(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to get GCE instance's IP address", "instanceID", instanceID)
+
+	service, err := gc.getComputeService(kubeClient, ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a Compute Engine client: %w", err)
+	}
+
+	instance, err := service.Instances.Get(gc.Project, gc.Zone, string(instanceID)).Context(ctx).Do()
+	if err != nil {
+		// This might be a transient error, so only log it
+		log.Error(err, "failed to retrieve instance", "instanceID", instanceID)
+		return "", nil
+	}
+
+	ip, err := gc.validateIPAddress(ctx, instance)
+	// This might be a transient error, so only log it; wait longer for
+	// the instance to be ready
+	if err != nil {
+		return "", nil
+	}
+	return ip, nil
+}
+
+// TerminateInstance tries to delete the instanceID GCE instance.
+func (gc GCEDynamicConfig) TerminateInstance(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) error {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to terminate GCE instance", "instanceID", instanceID)
+
+	service, err := gc.getComputeService(kubeClient, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create a Compute Engine client: %w", err)
+	}
+
+	_, err = service.Instances.Delete(gc.Project, gc.Zone, string(instanceID)).Context(ctx).Do()
+	if isNotFound(err) {
+		// Already gone, nothing to do
+		return nil
+	}
+	return err
+}
+
+// ListInstances returns a collection of accessible GCE instances labelled with instanceTag.
+func (gc GCEDynamicConfig) ListInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]cloud.CloudVMInstance, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to list GCE instances")
+
+	instances, err := gc.listTaggedInstances(kubeClient, ctx, instanceTag)
+	if err != nil {
+		log.Error(err, "failed to retrieve GCE instances", "instanceTag", instanceTag)
+		return nil, err
+	}
+
+	vmInstances := []cloud.CloudVMInstance{}
+	for _, instance := range instances {
+		if instance.Status != "RUNNING" {
+			continue
+		}
+		// Only list instance if it has an accessible IP
+		ip, err := gc.validateIPAddress(ctx, instance)
+		if err != nil {
+			continue
+		}
+		startTime, err := time.Parse(time.RFC3339, instance.CreationTimestamp)
+		if err != nil {
+			log.Error(err, "failed to parse instance creation timestamp", "instanceName", instance.Name)
+			continue
+		}
+		vmInstances = append(vmInstances, cloud.CloudVMInstance{
+			InstanceId: cloud.InstanceIdentifier(instance.Name),
+			StartTime:  startTime,
+			Address:    ip,
+			TaskRunID:  instanceTaskRunID(instance),
+		})
+		log.Info("Counting instance towards running count", "instanceName", instance.Name)
+	}
+	return vmInstances, nil
+}
+
+func (gc GCEDynamicConfig) SshUser() string {
+	return gc.User
+}
+
+// GetState returns instanceID's VM state from Compute Engine. See
+// https://cloud.google.com/compute/docs/instances/instance-life-cycle
+// for valid states.
+func (gc GCEDynamicConfig) GetState(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (cloud.VMState, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to get GCE instance's state", "instanceID", instanceID)
+	// GCE states considered to be OK
+	okStates := []string{
+		"PROVISIONING",
+		"STAGING",
+		"RUNNING",
+		"STOPPING",
+		"SUSPENDING",
+		"SUSPENDED",
+		"TERMINATED",
+	}
+
+	service, err := gc.getComputeService(kubeClient, ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a Compute Engine client: %w", err)
+	}
+	instance, err := service.Instances.Get(gc.Project, gc.Zone, string(instanceID)).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve instance %s: %w", instanceID, err)
+	}
+
+	if slices.Contains(okStates, instance.Status) {
+		return cloud.OKState, nil
+	}
+	return cloud.FailedState, nil
+}
+
+// isNotFound returns true if err is a Compute Engine API error with a 404 status code.
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// A GCEDynamicConfig represents a configuration for a Google Compute Engine instance.
+// The zero value (where each field will be assigned its type's zero value) is not a
+// valid GCEDynamicConfig.
+type GCEDynamicConfig struct {
+	// Project is the ID of the Google Cloud project the instances are created in.
+	Project string
+
+	// Zone is the Compute Engine zone (e.g. "us-central1-a") the instances are created in.
+	Zone string
+
+	// MachineType is the name of the Compute Engine machine type (e.g. "t2a-standard-4").
+	MachineType string
+
+	// Image is the source image of the boot disk, either a full or partial URL
+	// (e.g. "projects/rhel-cloud/global/images/family/rhel-9-arm64").
+	Image string
+
+	// Network is the name or URL of the VPC network for the instance. If empty, the
+	// project's default network is used.
+	Network string
+
+	// Subnetwork is the name or URL of the subnetwork for the instance.
+	Subnetwork string
+
+	// ServiceAccount is the email of the service account attached to the instance, if any.
+	ServiceAccount string
+
+	// SshPublicKey is the public SSH key added to the instance metadata for User.
+	SshPublicKey string
+
+	// Secret is the name of the Kubernetes secret that contains the service
+	// account key used to authenticate with the Compute Engine API.
+	Secret string
+
+	// SystemNamespace is the name of the Kubernetes namespace where the specified
+	// secrets are stored.
+	SystemNamespace string
+
+	// UserData is passed to the instance as the "user-data" metadata value.
+	UserData string
+
+	// User is the SSH user used to connect to the instance.
+	User string
+
+	// Disk is the size (in GB) of the boot disk.
+	Disk int64
+
+	// DiskType is the name of the boot disk type (e.g. "pd-balanced").
+	DiskType string
+
+	// PrivateIP specifies whether the instance is created without an external address
+	// and reached through its internal IP address.
+	PrivateIP bool
+
+	// computeOptions allows tests to point the Compute Engine client at a fake API.
+	// When nil, getComputeService builds a client from the service account key secret.
+	computeOptions []option.ClientOption
+
+	// pingFunc allows tests to inject a mock for SSH connectivity checks.
+	// When nil, the real pingIPAddress (TCP dial to port 22) is used.
+	pingFunc func(ip string) error
+}
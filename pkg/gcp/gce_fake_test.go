@@ -0,0 +1,129 @@
+package gcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+)
+
+// filterTerm matches a single `labels.<key> = "<value>"` term of a Compute Engine list filter.
+var filterTerm = regexp.MustCompile(`labels\.([a-z0-9_-]+) = "([^"]*)"`)
+
+// fakeComputeAPI is an in-memory fake of the Compute Engine instances API served over httptest.
+// Instances are keyed by name; Inserted captures the last instance received by an insert call.
+type fakeComputeAPI struct {
+	mu        sync.Mutex
+	server    *httptest.Server
+	Instances map[string]*compute.Instance
+	Inserted  *compute.Instance
+	Deleted   []string
+	// FailInsert, FailList and FailGet make the corresponding call return a 500 error.
+	FailInsert bool
+	FailList   bool
+	FailGet    bool
+}
+
+func newFakeComputeAPI() *fakeComputeAPI {
+	f := &fakeComputeAPI{Instances: map[string]*compute.Instance{}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+// options returns the client options pointing a Compute Engine client at the fake.
+func (f *fakeComputeAPI) options() []option.ClientOption {
+	return []option.ClientOption{
+		option.WithEndpoint(f.server.URL + "/"),
+		option.WithHTTPClient(f.server.Client()),
+	}
+}
+
+func (f *fakeComputeAPI) Close() {
+	f.server.Close()
+}
+
+func (f *fakeComputeAPI) handle(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Paths are /projects/<project>/zones/<zone>/instances[/<name>]
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) < 5 || parts[4] != "instances" {
+		http.NotFound(w, req)
+		return
+	}
+	zone := parts[3]
+
+	switch {
+	case len(parts) == 5 && req.Method == http.MethodPost:
+		if f.FailInsert {
+			writeError(w, http.StatusInternalServerError, "insert failed")
+			return
+		}
+		instance := &compute.Instance{}
+		if err := json.NewDecoder(req.Body).Decode(instance); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		instance.Status = "PROVISIONING"
+		instance.Zone = zone
+		instance.CreationTimestamp = "2024-01-01T00:00:00.000-07:00"
+		f.Inserted = instance
+		f.Instances[instance.Name] = instance
+		writeJSON(w, &compute.Operation{Name: "op-insert-" + instance.Name, Status: "RUNNING"})
+	case len(parts) == 5 && req.Method == http.MethodGet:
+		if f.FailList {
+			writeError(w, http.StatusInternalServerError, "list failed")
+			return
+		}
+		terms := filterTerm.FindAllStringSubmatch(req.URL.Query().Get("filter"), -1)
+		list := &compute.InstanceList{}
+		for _, instance := range f.Instances {
+			matches := true
+			for _, term := range terms {
+				if instance.Labels[term[1]] != term[2] {
+					matches = false
+				}
+			}
+			if matches {
+				list.Items = append(list.Items, instance)
+			}
+		}
+		writeJSON(w, list)
+	case len(parts) == 6:
+		instance, ok := f.Instances[parts[5]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "instance not found")
+			return
+		}
+		if req.Method == http.MethodGet && f.FailGet {
+			writeError(w, http.StatusInternalServerError, "get failed")
+			return
+		}
+		if req.Method == http.MethodDelete {
+			delete(f.Instances, parts[5])
+			f.Deleted = append(f.Deleted, parts[5])
+			writeJSON(w, &compute.Operation{Name: "op-delete-" + parts[5], Status: "RUNNING"})
+			return
+		}
+		writeJSON(w, instance)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": code, "message": message}})
+}
@@ -0,0 +1,239 @@
+package gcp
+
+import (
+	"context"
+	// #nosec is added to bypass the golang security scan since the cryptographic
+	// strength doesn't matter here
+	"crypto/md5" //#nosec
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	v1 "k8s.io/api/core/v1"
+	types2 "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// serviceAccountKey is the key in the GCP secret holding the JSON service account key.
+	serviceAccountKey = "service-account-key"
+
+	// maxLabelLength is the maximum length of a GCE label key or value.
+	maxLabelLength = 63
+)
+
+var (
+	// invalidLabelChars matches every character not allowed in GCE label keys and values.
+	invalidLabelChars = regexp.MustCompile(`[^a-z0-9_-]`)
+
+	// validInstanceTagPattern validates that an instance tag can prefix a GCE instance name.
+	validInstanceTagPattern = regexp.MustCompile(`^[a-z][-a-z0-9]*$`)
+)
+
+// pingIPAddress tries to connect to the SSH port on ipAddress with a 60-second timeout.
+// An error is returned if the connection fails.
+func pingIPAddress(ipAddress string) error {
+	conn, err := net.DialTimeout("tcp", ipAddress+":22", 60*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// createInstanceName returns a unique instance name in the format <instance_tag>-<hash>.
+// GCE instance names must be lowercase RFC 1035 labels of at most 63 characters, so the
+// instance tag is lowercased and truncated to leave room for the 16-character hash suffix.
+func createInstanceName(instanceTag string) (string, error) {
+	instanceTag = strings.ReplaceAll(strings.ToLower(instanceTag), "_", "-")
+	if !validInstanceTagPattern.MatchString(instanceTag) {
+		return "", fmt.Errorf("instance tag must start with a letter and contain only alphanumeric characters and hyphens, got: %s", instanceTag)
+	}
+	if len(instanceTag) > maxLabelLength-17 {
+		instanceTag = strings.TrimRight(instanceTag[:maxLabelLength-17], "-")
+	}
+
+	now := time.Now()
+	hashInput := fmt.Sprintf("%s-%d-%d", instanceTag, now.Unix(), now.Nanosecond())
+	// #nosec is added to bypass the golang security scan since the cryptographic
+	// strength doesn't matter here
+	md5Hash := md5.Sum([]byte(hashInput)) //#nosec
+	return fmt.Sprintf("%s-%s", instanceTag, hex.EncodeToString(md5Hash[:])[0:16]), nil
+}
+
+// sanitizeLabel converts s into a valid GCE label key or value: lowercase letters, digits,
+// underscores and hyphens only, at most 63 characters.
+func sanitizeLabel(s string) string {
+	s = invalidLabelChars.ReplaceAllString(strings.ToLower(s), "_")
+	if len(s) > maxLabelLength {
+		s = s[:maxLabelLength]
+	}
+	return s
+}
+
+// instanceLabels returns the GCE labels applied to an instance. Tag keys and values are sanitized
+// to satisfy GCE label restrictions; the raw taskRunID is additionally kept in the instance metadata
+// since its '<namespace>:<name>' format is not a valid label value.
+func instanceLabels(taskRunID string, instanceTag string, additionalInstanceTags map[string]string) map[string]string {
+	labels := map[string]string{}
+	for k, v := range additionalInstanceTags {
+		labels[sanitizeLabel(k)] = sanitizeLabel(v)
+	}
+	labels[MultiPlatformManaged] = "true"
+	labels[cloud.InstanceTag] = sanitizeLabel(instanceTag)
+	labels[sanitizeLabel(cloud.TaskRunTagKey)] = sanitizeLabel(taskRunID)
+	return labels
+}
+
+// instanceTaskRunID returns the raw taskRunID kept in instance's metadata, or an empty string if it has none.
+func instanceTaskRunID(instance *compute.Instance) string {
+	if instance.Metadata == nil {
+		return ""
+	}
+	for _, item := range instance.Metadata.Items {
+		if item != nil && item.Key == cloud.TaskRunTagKey && item.Value != nil {
+			return *item.Value
+		}
+	}
+	return ""
+}
+
+// configureInstance creates and returns a GCE instance configuration.
+func (gc GCEDynamicConfig) configureInstance(instanceName string, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) *compute.Instance {
+	metadata := []*compute.MetadataItems{
+		{Key: cloud.TaskRunTagKey, Value: ptr(taskRunID)},
+	}
+	if gc.SshPublicKey != "" {
+		metadata = append(metadata, &compute.MetadataItems{Key: "ssh-keys", Value: ptr(gc.User + ":" + gc.SshPublicKey)})
+	}
+	if gc.UserData != "" {
+		metadata = append(metadata, &compute.MetadataItems{Key: "user-data", Value: ptr(gc.UserData)})
+	}
+
+	networkInterface := &compute.NetworkInterface{
+		Network:    gc.Network,
+		Subnetwork: gc.Subnetwork,
+	}
+	if !gc.PrivateIP {
+		networkInterface.AccessConfigs = []*compute.AccessConfig{{Name: "External NAT", Type: "ONE_TO_ONE_NAT"}}
+	}
+
+	var serviceAccounts []*compute.ServiceAccount
+	if gc.ServiceAccount != "" {
+		serviceAccounts = []*compute.ServiceAccount{{
+			Email:  gc.ServiceAccount,
+			Scopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+		}}
+	}
+
+	labels := instanceLabels(taskRunID, instanceTag, additionalInstanceTags)
+	return &compute.Instance{
+		Name:        instanceName,
+		MachineType: fmt.Sprintf("zones/%s/machineTypes/%s", gc.Zone, gc.MachineType),
+		Labels:      labels,
+		Disks: []*compute.AttachedDisk{{
+			Boot:       true,
+			AutoDelete: true,
+			InitializeParams: &compute.AttachedDiskInitializeParams{
+				SourceImage: gc.Image,
+				DiskSizeGb:  gc.Disk,
+				DiskType:    fmt.Sprintf("zones/%s/diskTypes/%s", gc.Zone, gc.DiskType),
+				Labels:      labels,
+			},
+		}},
+		NetworkInterfaces: []*compute.NetworkInterface{networkInterface},
+		Metadata:          &compute.Metadata{Items: metadata},
+		ServiceAccounts:   serviceAccounts,
+	}
+}
+
+// listTaggedInstances returns all instances in the configured zone that were created by this
+// controller and carry instanceTag.
+func (gc GCEDynamicConfig) listTaggedInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]*compute.Instance, error) {
+	service, err := gc.getComputeService(kubeClient, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a Compute Engine client: %w", err)
+	}
+
+	filter := fmt.Sprintf(`(labels.%s = "%s") AND (labels.%s = "true")`, cloud.InstanceTag, sanitizeLabel(instanceTag), MultiPlatformManaged)
+	var instances []*compute.Instance
+	err = service.Instances.List(gc.Project, gc.Zone).Filter(filter).Pages(ctx, func(page *compute.InstanceList) error {
+		instances = append(instances, page.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve GCE instances with tag %s: %w", instanceTag, err)
+	}
+	return instances, nil
+}
+
+// validateIPAddress returns the IP address of the GCE instance after verifying SSH connectivity.
+// The external NAT address is preferred unless PrivateIP is set, in which case the internal address is used.
+func (gc GCEDynamicConfig) validateIPAddress(ctx context.Context, instance *compute.Instance) (string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	var ip string
+	for _, ni := range instance.NetworkInterfaces {
+		if gc.PrivateIP {
+			ip = ni.NetworkIP
+		} else {
+			for _, ac := range ni.AccessConfigs {
+				if ac.NatIP != "" {
+					ip = ac.NatIP
+					break
+				}
+			}
+		}
+		if ip != "" {
+			break
+		}
+	}
+	if ip == "" {
+		return "", fmt.Errorf("instance %s has no accessible IP address", instance.Name)
+	}
+
+	// Verify SSH connectivity
+	ping := pingIPAddress
+	if gc.pingFunc != nil {
+		ping = gc.pingFunc
+	}
+	if err := ping(ip); err != nil {
+		log.Error(err, "failed to connect to GCE instance via SSH", "instanceName", instance.Name, "ipAddress", ip)
+		return "", fmt.Errorf("failed to resolve IP address %s: %w", ip, err)
+	}
+
+	log.Info("Successfully validated IP address", "instanceName", instance.Name, "ipAddress", ip)
+	return ip, nil
+}
+
+// getComputeService returns a Compute Engine client authenticated with the service account key stored in
+// the GCP secret. If no Kubernetes client is available, Application Default Credentials are used.
+func (gc GCEDynamicConfig) getComputeService(kubeClient client.Client, ctx context.Context) (*compute.Service, error) {
+	if gc.computeOptions != nil {
+		return compute.NewService(ctx, gc.computeOptions...)
+	}
+	if kubeClient == nil {
+		return compute.NewService(ctx)
+	}
+
+	s := v1.Secret{}
+	nameSpacedSecret := types2.NamespacedName{Name: gc.Secret, Namespace: gc.SystemNamespace}
+	if err := kubeClient.Get(ctx, nameSpacedSecret, &s); err != nil {
+		return nil, fmt.Errorf("failed to retrieve the secret %v from the Kubernetes client: %w", nameSpacedSecret, err)
+	}
+	key := s.Data[serviceAccountKey]
+	if len(key) == 0 {
+		return nil, errors.New("the GCP secret is missing the " + serviceAccountKey + " field")
+	}
+	return compute.NewService(ctx, option.WithCredentialsJSON(key))
+}
+
+func ptr[V any](s V) *V {
+	return &s
+}
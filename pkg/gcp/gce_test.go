@@ -0,0 +1,307 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+const systemNamespace = "multi-platform-controller"
+
+var _ = Describe("GCE Unit Test Suite", func() {
+
+	Describe("Testing CreateGceCloudConfig", func() {
+
+		DescribeTable("Testing the creation of GCEDynamicConfig properly no matter the values",
+			func(platformName string, testConfig map[string]string, expectedDisk int64, expectedDiskType string, expectedUser string, expectedPrivateIP bool) {
+				config := map[string]string{
+					"dynamic." + platformName + ".project":         "test-project",
+					"dynamic." + platformName + ".zone":            "test-zone",
+					"dynamic." + platformName + ".machine-type":    "test-machine-type",
+					"dynamic." + platformName + ".image":           "test-image",
+					"dynamic." + platformName + ".network":         "test-network",
+					"dynamic." + platformName + ".subnetwork":      "test-subnetwork",
+					"dynamic." + platformName + ".service-account": "test-service-account",
+					"dynamic." + platformName + ".ssh-public-key":  "test-ssh-public-key",
+					"dynamic." + platformName + ".gcp-secret":      "test-secret",
+					"dynamic." + platformName + ".disk":            testConfig["disk"],
+					"dynamic." + platformName + ".disk-type":       testConfig["disk-type"],
+					"dynamic." + platformName + ".ssh-user":        testConfig["ssh-user"],
+					"dynamic." + platformName + ".private-ip":      testConfig["private-ip"],
+				}
+				provider := CreateGceCloudConfig(platformName, config, systemNamespace)
+				Expect(provider).ToNot(BeNil())
+				providerConfig := provider.(GCEDynamicConfig)
+
+				Expect(providerConfig.Project).To(Equal("test-project"))
+				Expect(providerConfig.Zone).To(Equal("test-zone"))
+				Expect(providerConfig.MachineType).To(Equal("test-machine-type"))
+				Expect(providerConfig.Image).To(Equal("test-image"))
+				Expect(providerConfig.Network).To(Equal("test-network"))
+				Expect(providerConfig.Subnetwork).To(Equal("test-subnetwork"))
+				Expect(providerConfig.ServiceAccount).To(Equal("test-service-account"))
+				Expect(providerConfig.SshPublicKey).To(Equal("test-ssh-public-key"))
+				Expect(providerConfig.Secret).To(Equal("test-secret"))
+				Expect(providerConfig.SystemNamespace).To(Equal(systemNamespace))
+				Expect(providerConfig.Disk).To(Equal(expectedDisk))
+				Expect(providerConfig.DiskType).To(Equal(expectedDiskType))
+				Expect(providerConfig.SshUser()).To(Equal(expectedUser))
+				Expect(providerConfig.PrivateIP).To(Equal(expectedPrivateIP))
+			},
+			Entry("Positive - valid config map keys", "linux-arm64", map[string]string{
+				"disk":       "200",
+				"disk-type":  "pd-ssd",
+				"ssh-user":   "builder",
+				"private-ip": "true"},
+				int64(200), "pd-ssd", "builder", true),
+			Entry("Negative - missing config data", "linux-amd64", map[string]string{
+				"disk":       "",
+				"disk-type":  "",
+				"ssh-user":   "",
+				"private-ip": ""},
+				int64(40), defaultDiskType, defaultSshUser, false),
+			Entry("Negative - config data with bad data types", "linux-mlarge-amd64", map[string]string{
+				"disk":       "koko-hazamar",
+				"disk-type":  "",
+				"ssh-user":   "",
+				"private-ip": "koko-hazamar"},
+				int64(40), defaultDiskType, defaultSshUser, false),
+		)
+	})
+
+	Describe("CloudProvider methods", func() {
+		var (
+			fake *fakeComputeAPI
+			cfg  GCEDynamicConfig
+			ctx  context.Context
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			fake = newFakeComputeAPI()
+			cfg = GCEDynamicConfig{
+				Project:        "test-project",
+				Zone:           "us-central1-a",
+				MachineType:    "t2a-standard-4",
+				Image:          "projects/rhel-cloud/global/images/family/rhel-9-arm64",
+				SshPublicKey:   "ssh-rsa AAAA",
+				User:           "cloud-user",
+				Disk:           40,
+				DiskType:       defaultDiskType,
+				computeOptions: fake.options(),
+				pingFunc:       func(string) error { return nil },
+			}
+		})
+
+		AfterEach(func() {
+			fake.Close()
+		})
+
+		// addInstance stores an instance in the fake as if it had been created by the controller.
+		addInstance := func(name, tag, status, natIP, networkIP string) {
+			fake.Instances[name] = &compute.Instance{
+				Name:              name,
+				Status:            status,
+				CreationTimestamp: "2024-01-01T00:00:00Z",
+				Labels:            map[string]string{cloud.InstanceTag: tag, MultiPlatformManaged: "true"},
+				NetworkInterfaces: []*compute.NetworkInterface{{
+					NetworkIP:     networkIP,
+					AccessConfigs: []*compute.AccessConfig{{NatIP: natIP}},
+				}},
+			}
+		}
+
+		Describe("LaunchInstance", func() {
+			It("should create an instance with the controller's labels and metadata", func() {
+				id, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{"Cost-Center": "Konflux"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(id)).To(HavePrefix("prod-arm64-"))
+
+				inserted := fake.Inserted
+				Expect(inserted).ToNot(BeNil())
+				Expect(inserted.Name).To(Equal(string(id)))
+				Expect(inserted.MachineType).To(Equal("zones/us-central1-a/machineTypes/t2a-standard-4"))
+				Expect(inserted.Labels).To(HaveKeyWithValue(cloud.InstanceTag, "prod-arm64"))
+				Expect(inserted.Labels).To(HaveKeyWithValue(MultiPlatformManaged, "true"))
+				Expect(inserted.Labels).To(HaveKeyWithValue("taskrunid", "test-namespace_test-taskrun"))
+				Expect(inserted.Labels).To(HaveKeyWithValue("cost-center", "konflux"))
+				Expect(inserted.Disks).To(HaveLen(1))
+				Expect(inserted.Disks[0].InitializeParams.SourceImage).To(Equal(cfg.Image))
+				Expect(inserted.Disks[0].InitializeParams.DiskSizeGb).To(Equal(int64(40)))
+				Expect(inserted.NetworkInterfaces[0].AccessConfigs).To(HaveLen(1))
+
+				metadata := map[string]string{}
+				for _, item := range inserted.Metadata.Items {
+					metadata[item.Key] = *item.Value
+				}
+				Expect(metadata).To(HaveKeyWithValue(cloud.TaskRunTagKey, "test-namespace:test-taskrun"))
+				Expect(metadata).To(HaveKeyWithValue("ssh-keys", "cloud-user:ssh-rsa AAAA"))
+			})
+
+			It("should not request an external address when private-ip is set", func() {
+				cfg.PrivateIP = true
+				_, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fake.Inserted.NetworkInterfaces[0].AccessConfigs).To(BeEmpty())
+			})
+
+			It("should reject an invalid TaskRun ID", func() {
+				_, err := cfg.LaunchInstance(nil, ctx, "invalid-id", "prod-arm64", map[string]string{})
+				Expect(err).To(MatchError(ContainSubstring("invalid TaskRun ID")))
+				Expect(fake.Inserted).To(BeNil())
+			})
+
+			It("should return an error when the API call fails", func() {
+				fake.FailInsert = true
+				_, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{})
+				Expect(err).To(MatchError(ContainSubstring("failed to launch GCE instance")))
+			})
+		})
+
+		Describe("CountInstances", func() {
+			It("should only count non-terminated instances with the instance tag", func() {
+				addInstance("prod-arm64-1", "prod-arm64", "RUNNING", "1.2.3.4", "10.0.0.1")
+				addInstance("prod-arm64-2", "prod-arm64", "PROVISIONING", "", "")
+				addInstance("prod-arm64-3", "prod-arm64", "TERMINATED", "", "")
+				addInstance("other-amd64-1", "other-amd64", "RUNNING", "1.2.3.5", "10.0.0.2")
+
+				Expect(cfg.CountInstances(nil, ctx, "prod-arm64")).To(Equal(2))
+			})
+
+			It("should return an error when the API call fails", func() {
+				fake.FailList = true
+				count, err := cfg.CountInstances(nil, ctx, "prod-arm64")
+				Expect(err).To(HaveOccurred())
+				Expect(count).To(Equal(-1))
+			})
+		})
+
+		Describe("ListInstances", func() {
+			It("should only list running and reachable instances", func() {
+				addInstance("prod-arm64-1", "prod-arm64", "RUNNING", "1.2.3.4", "10.0.0.1")
+				fake.Instances["prod-arm64-1"].Metadata = &compute.Metadata{Items: []*compute.MetadataItems{{Key: cloud.TaskRunTagKey, Value: ptr("test-namespace:test-taskrun")}}}
+				addInstance("prod-arm64-2", "prod-arm64", "STAGING", "", "")
+				addInstance("prod-arm64-3", "prod-arm64", "RUNNING", "1.2.3.6", "10.0.0.3")
+				cfg.pingFunc = func(ip string) error {
+					if ip == "1.2.3.6" {
+						return errors.New("unreachable")
+					}
+					return nil
+				}
+
+				instances, err := cfg.ListInstances(nil, ctx, "prod-arm64")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instances).To(HaveLen(1))
+				Expect(instances[0].InstanceId).To(Equal(cloud.InstanceIdentifier("prod-arm64-1")))
+				Expect(instances[0].Address).To(Equal("1.2.3.4"))
+				Expect(instances[0].StartTime).To(Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+				Expect(instances[0].TaskRunID).To(Equal("test-namespace:test-taskrun"))
+			})
+		})
+
+		Describe("GetInstanceAddress", func() {
+			It("should return the external address by default", func() {
+				addInstance("prod-arm64-1", "prod-arm64", "RUNNING", "1.2.3.4", "10.0.0.1")
+				Expect(cfg.GetInstanceAddress(nil, ctx, "prod-arm64-1")).To(Equal("1.2.3.4"))
+			})
+
+			It("should return the internal address when private-ip is set", func() {
+				cfg.PrivateIP = true
+				addInstance("prod-arm64-1", "prod-arm64", "RUNNING", "1.2.3.4", "10.0.0.1")
+				Expect(cfg.GetInstanceAddress(nil, ctx, "prod-arm64-1")).To(Equal("10.0.0.1"))
+			})
+
+			It("should return an empty address while the instance has no IP yet", func() {
+				addInstance("prod-arm64-1", "prod-arm64", "PROVISIONING", "", "")
+				Expect(cfg.GetInstanceAddress(nil, ctx, "prod-arm64-1")).To(BeEmpty())
+			})
+
+			It("should treat a missing instance as transient", func() {
+				address, err := cfg.GetInstanceAddress(nil, ctx, "does-not-exist")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(address).To(BeEmpty())
+			})
+		})
+
+		Describe("GetState", func() {
+			DescribeTable("should map GCE statuses onto VM states",
+				func(status string, expected cloud.VMState) {
+					addInstance("prod-arm64-1", "prod-arm64", status, "", "")
+					Expect(cfg.GetState(nil, ctx, "prod-arm64-1")).To(Equal(expected))
+				},
+				Entry("provisioning", "PROVISIONING", cloud.OKState),
+				Entry("running", "RUNNING", cloud.OKState),
+				Entry("terminated", "TERMINATED", cloud.OKState),
+				Entry("repairing", "REPAIRING", cloud.FailedState),
+			)
+
+			It("should return an error when the instance cannot be retrieved", func() {
+				addInstance("prod-arm64-1", "prod-arm64", "RUNNING", "", "")
+				fake.FailGet = true
+				_, err := cfg.GetState(nil, ctx, "prod-arm64-1")
+				Expect(err).To(MatchError(ContainSubstring("get failed")))
+			})
+		})
+
+		Describe("TerminateInstance", func() {
+			It("should delete the instance", func() {
+				addInstance("prod-arm64-1", "prod-arm64", "RUNNING", "1.2.3.4", "10.0.0.1")
+				Expect(cfg.TerminateInstance(nil, ctx, "prod-arm64-1")).To(Succeed())
+				Expect(fake.Deleted).To(ConsistOf("prod-arm64-1"))
+			})
+
+			It("should not fail for an instance that is already gone", func() {
+				Expect(cfg.TerminateInstance(nil, ctx, "does-not-exist")).To(Succeed())
+			})
+		})
+	})
+
+	Describe("Testing helper functions", func() {
+		DescribeTable("sanitizeLabel produces valid GCE label values",
+			func(input, expected string) {
+				Expect(sanitizeLabel(input)).To(Equal(expected))
+			},
+			Entry("lowercases", "MultiPlatform", "multiplatform"),
+			Entry("replaces invalid characters", "ns:name.x", "ns_name_x"),
+			Entry("truncates to 63 characters", strings.Repeat("a", 70), strings.Repeat("a", 63)),
+		)
+
+		It("createInstanceName creates unique, valid names", func() {
+			first, err := createInstanceName("Prod_ARM64")
+			Expect(err).ToNot(HaveOccurred())
+			second, err := createInstanceName("Prod_ARM64")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(first).To(HavePrefix("prod-arm64-"))
+			Expect(first).ToNot(Equal(second))
+
+			long, err := createInstanceName(strings.Repeat("a", 80))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(long)).To(BeNumerically("<=", 63))
+		})
+
+		It("createInstanceName rejects tags that cannot start an instance name", func() {
+			_, err := createInstanceName("1-starts-with-a-digit")
+			Expect(err).To(HaveOccurred())
+		})
+
+		DescribeTable("isNotFound detects missing resources",
+			func(err error, expected bool) {
+				Expect(isNotFound(err)).To(Equal(expected))
+			},
+			Entry("nil", nil, false),
+			Entry("not found", &googleapi.Error{Code: http.StatusNotFound}, true),
+			Entry("wrapped not found", fmt.Errorf("failed to get instance: %w", &googleapi.Error{Code: http.StatusNotFound}), true),
+			Entry("other API error", &googleapi.Error{Code: http.StatusForbidden}, false),
+			Entry("other error", errors.New("connection refused"), false),
+		)
+	})
+})
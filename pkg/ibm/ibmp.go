@@ -24,9 +24,17 @@ func CreateIBMPowerCloudConfig(platform string, config map[string]string, system
 	if err != nil {
 		mem = 2
 	}
+	procType := config["dynamic."+platform+".proc-type"]
+	if procType == "" {
+		procType = "shared"
+	}
 	cores, err := strconv.ParseFloat(config["dynamic."+platform+".cores"], 64)
 	if err != nil {
+		// Dedicated processors can only be allocated as whole cores
 		cores = 0.25
+		if procType == "dedicated" {
+			cores = 1
+		}
 	}
 	volumeSize, err := strconv.ParseFloat(config["dynamic."+platform+".disk"], 64)
 	// IBM docs says it is potentially unwanted to downsize the bootable volume
@@ -53,13 +61,14 @@ func CreateIBMPowerCloudConfig(platform string, config map[string]string, system
 		Disk:            volumeSize,
 		SystemNamespace: systemNamespace,
 		UserData:        base64userData,
-		ProcType:        "shared",
+		ProcType:        procType,
+		StorageTier:     config["dynamic."+platform+".storage-tier"],
 	}
 }
 
 // LaunchInstance creates a Power Systems VM instance and returns its identifier. This function is implemented as
-// part of the CloudProvider interface, which is why some of the arguments are unused for this particular implementation.
-func (pw IBMPowerDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, _ map[string]string) (cloud.InstanceIdentifier, error) {
+// part of the CloudProvider interface. additionalInstanceTags are applied as IBM Cloud user tags, alongside taskRunID.
+func (pw IBMPowerDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (cloud.InstanceIdentifier, error) {
 	service, err := pw.createAuthenticatedBaseService(ctx, kubeClient)
 	if err != nil {
 		return "", fmt.Errorf("failed to create an authenticated base service: %w", err)
@@ -79,7 +88,7 @@ func (pw IBMPowerDynamicConfig) LaunchInstance(kubeClient client.Client, ctx con
 		"name":              instanceName,
 		cloud.TaskRunTagKey: taskRunID,
 	}
-	instance, err := pw.launchInstance(ctx, service, additionalInfo)
+	instance, err := pw.launchInstance(ctx, service, additionalInfo, additionalUserTags(ctx, additionalInstanceTags))
 	if err != nil {
 		err = fmt.Errorf("failed to create a Power Systems instance: %w", err)
 	}
@@ -183,6 +192,21 @@ func (pw IBMPowerDynamicConfig) ListInstances(kubeClient client.Client, ctx cont
 	return vmInstances, nil
 }
 
+// GetInstanceTaskRunID returns the TaskRun ID in the user tags of the instanceID Power Systems VM instance, which
+// are not included in the listed instances.
+func (pw IBMPowerDynamicConfig) GetInstanceTaskRunID(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (string, error) {
+	service, err := pw.createAuthenticatedBaseService(ctx, kubeClient)
+	if err != nil {
+		return "", fmt.Errorf("failed to create an authenticated base service: %w", err)
+	}
+
+	instance, err := pw.getInstance(ctx, service, string(instanceID))
+	if err != nil {
+		return "", fmt.Errorf("failed to get the Power Systems instance %s: %w", instanceID, err)
+	}
+	return taskRunIDFromUserTags(instance.UserTags), nil
+}
+
 // TerminateInstance tries to delete a specific Power Systems VM instance on the pw cloud for 10 minutes
 // or until the instance is deleted.
 func (pw IBMPowerDynamicConfig) TerminateInstance(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) error {
@@ -298,4 +322,8 @@ type IBMPowerDynamicConfig struct {
 	// ProcessorType is the processor type to be used in the instance.
 	// Possible values are "dedicated", "shared", and "capped".
 	ProcType string
+
+	// StorageTier is the storage tier of the instance's boot volume, e.g. "tier1".
+	// The cloud's default tier is used when it is empty.
+	StorageTier string
 }
@@ -59,8 +59,9 @@ func retrieveInstanceIp(instanceID string, networks []*models.PVMInstanceNetwork
 	return ip, nil
 }
 
-// createAuthenticatedBaseService generates a base communication service with an API key-based IAM (Identity and Access
-// Management) authenticator.
+// createAuthenticatedBaseService returns a base communication service with an API key-based IAM (Identity and Access
+// Management) authenticator. The service is shared between calls for the same secret and URL, and recreated when the
+// API key changes.
 func (pw IBMPowerDynamicConfig) createAuthenticatedBaseService(ctx context.Context, kubeClient client.Client) (*core.BaseService, error) {
 	apiKey := ""
 	if kubeClient == nil { // Get API key from an environment variable
@@ -80,14 +81,16 @@ func (pw IBMPowerDynamicConfig) createAuthenticatedBaseService(ctx context.Conte
 		apiKey = string(apiKeyByte)
 	}
 
-	serviceOptions := &core.ServiceOptions{
-		URL: pw.Url,
-		Authenticator: &core.IamAuthenticator{
-			ApiKey: apiKey,
-		},
-	}
-	baseService, err := core.NewBaseService(serviceOptions)
-	return baseService, err
+	key := serviceCacheKey{secret: pw.Secret, namespace: pw.SystemNamespace, url: pw.Url}
+	return getCachedService(baseServices, key, apiKey, func() (*core.BaseService, error) {
+		serviceOptions := &core.ServiceOptions{
+			URL: pw.Url,
+			Authenticator: &core.IamAuthenticator{
+				ApiKey: apiKey,
+			},
+		}
+		return core.NewBaseService(serviceOptions)
+	})
 }
 
 // listInstances returns all of the Power Systems VM instances on the pw cloud instance.
@@ -160,8 +163,8 @@ func (pw IBMPowerDynamicConfig) parseCRN() (string, error) {
 }
 
 // launchInstance returns the instance ID of the Power Systems VM instance created with an HTTP
-// request on the pw cloud instance.
-func (pw IBMPowerDynamicConfig) launchInstance(ctx context.Context, service *core.BaseService, additionalInfo map[string]string) (cloud.InstanceIdentifier, error) {
+// request on the pw cloud instance. The instance is tagged with its TaskRun ID followed by additionalTags.
+func (pw IBMPowerDynamicConfig) launchInstance(ctx context.Context, service *core.BaseService, additionalInfo map[string]string, additionalTags []string) (cloud.InstanceIdentifier, error) {
 	log := logr.FromContextOrDiscard(ctx)
 	requestBuilder := core.NewRequestBuilder(core.POST)
 	requestBuilder = requestBuilder.WithContext(ctx)
@@ -201,7 +204,8 @@ func (pw IBMPowerDynamicConfig) launchInstance(ctx context.Context, service *cor
 		KeyPairName: pw.Key,
 		SysType:     pw.System,
 		UserData:    pw.UserData,
-		UserTags:    []string{taskRunTag},
+		StorageType: pw.StorageTier,
+		UserTags:    append(taskRunUserTags(taskRunTag), additionalTags...),
 	}
 	_, err = requestBuilder.SetBodyContentJSON(&body)
 	if err != nil {
@@ -303,7 +307,8 @@ func (pw IBMPowerDynamicConfig) deleteInstance(ctx context.Context, service *cor
 	return err
 }
 
-// resizeInstanceVolume asynchronously resizes the id instance's volume.
+// resizeInstanceVolume asynchronously resizes the id instance's volume and, when pw has a storage tier, moves the
+// volume to it. The tier requested at creation time is not honoured for every image, so it is checked here as well.
 func (pw IBMPowerDynamicConfig) resizeInstanceVolume(ctx context.Context, service *core.BaseService, id *string) {
 	log := logr.FromContextOrDiscard(ctx)
 	sleepTime := 10
@@ -331,19 +336,24 @@ func (pw IBMPowerDynamicConfig) resizeInstanceVolume(ctx context.Context, servic
 				continue
 			}
 
-			log.Info("Current volume", "size", *instance.DiskSize, "instanceID", *id)
-			// Nothing to do since the volume size is the same as the specified size
-			if *instance.DiskSize == pw.Disk {
-				return
-			}
-
+			log.Info("Current volume", "size", *instance.DiskSize, "storageType", instance.StorageType, "instanceID", *id)
 			// This API is quite unstable and randomly throws conflicts or bad requests. Try multiple times.
-			log.Info("Resizing instance volume", "instanceID", *id, "volumeID", instance.VolumeIDs[0], "size", pw.Disk)
-			err = pw.updateVolume(localCtx, service, instance.VolumeIDs[0])
-			if err != nil {
-				continue
+			if *instance.DiskSize != pw.Disk {
+				log.Info("Resizing instance volume", "instanceID", *id, "volumeID", instance.VolumeIDs[0], "size", pw.Disk)
+				err = pw.updateVolume(localCtx, service, instance.VolumeIDs[0])
+				if err != nil {
+					continue
+				}
+				log.Info("Successfully resized instance volume", "instanceID", *id, "volumeID", instance.VolumeIDs[0], "size", pw.Disk)
+			}
+			if pw.StorageTier != "" && instance.StorageType != nil && *instance.StorageType != pw.StorageTier {
+				log.Info("Changing instance volume storage tier", "instanceID", *id, "volumeID", instance.VolumeIDs[0], "storageTier", pw.StorageTier)
+				err = pw.updateVolumeTier(localCtx, service, instance.VolumeIDs[0])
+				if err != nil {
+					continue
+				}
+				log.Info("Successfully changed instance volume storage tier", "instanceID", *id, "volumeID", instance.VolumeIDs[0], "storageTier", pw.StorageTier)
 			}
-			log.Info("Successfully resized instance volume", "instanceID", *id, "volumeID", instance.VolumeIDs[0], "size", pw.Disk)
 			return
 		}
 	}()
@@ -395,3 +405,47 @@ func (pw IBMPowerDynamicConfig) updateVolume(ctx context.Context, service *core.
 	log.Info("Volume size updated", "volumeID", vRef.VolumeID, "size", vRef.Size)
 	return nil
 }
+
+// updateVolumeTier moves volumeID to pw's storage tier via HTTP.
+func (pw IBMPowerDynamicConfig) updateVolumeTier(ctx context.Context, service *core.BaseService, volumeID string) error {
+	requestBuilder := core.NewRequestBuilder(core.POST)
+	requestBuilder = requestBuilder.WithContext(ctx)
+	requestBuilder.EnableGzipCompression = service.GetEnableGzipCompression()
+
+	// Parameterize the request
+	cloudID, err := pw.parseCRN()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve cloud service instance ID: %w", err)
+	}
+	pathParamsMap := map[string]string{
+		"cloud":  cloudID,
+		"volume": volumeID,
+	}
+	_, err = requestBuilder.ResolveRequestURL(pw.Url, `/pcloud/v1/cloud-instances/{cloud}/volumes/{volume}/action`, pathParamsMap)
+	if err != nil {
+		return fmt.Errorf("failed to encode the request with parameters: %w", err)
+	}
+
+	// Set request body and headers
+	body := models.VolumeAction{
+		TargetStorageTier: &pw.StorageTier,
+	}
+	_, err = requestBuilder.SetBodyContentJSON(&body)
+	if err != nil {
+		return err
+	}
+	requestBuilder.AddHeader("CRN", pw.CRN)
+	requestBuilder.AddHeader("Content-Type", "application/json")
+	requestBuilder.AddHeader("Accept", "application/json")
+
+	// Build and execute POST request
+	request, err := requestBuilder.Build()
+	if err != nil {
+		return fmt.Errorf("failed to build the HTTP request: %w", err)
+	}
+	_, err = service.Request(request, nil)
+	if err != nil {
+		return fmt.Errorf("failed to change the storage tier of volume %s: %w", volumeID, err)
+	}
+	return nil
+}
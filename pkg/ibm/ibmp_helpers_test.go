@@ -1,6 +1,11 @@
 package ibm
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/IBM/go-sdk-core/v5/core"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -174,4 +179,38 @@ var _ = Describe("IBM Power Cloud Helper Functions", func() {
 			)
 		})
 	})
+
+	// A unit test for updateVolumeTier, verifying the volume action request that moves a boot volume to the
+	// configured storage tier.
+	Describe("updateVolumeTier helper function tests", func() {
+		var (
+			server  *httptest.Server
+			request *http.Request
+			body    map[string]any
+		)
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request = r
+				Expect(json.NewDecoder(r.Body).Decode(&body)).Should(Succeed())
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			DeferCleanup(server.Close)
+		})
+
+		It("should request the configured storage tier for the volume", func(ctx SpecContext) {
+			powerConfig := IBMPowerDynamicConfig{
+				CRN:         "crn:v1:bluemix:public:power-iaas:dal10:a/123456789:service-guid-1234::",
+				Url:         server.URL,
+				StorageTier: "tier1",
+			}
+			service, err := core.NewBaseService(&core.ServiceOptions{URL: server.URL, Authenticator: &core.NoAuthAuthenticator{}})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(powerConfig.updateVolumeTier(ctx, service, "volume-1")).Should(Succeed())
+			Expect(request.Method).Should(Equal(http.MethodPost))
+			Expect(request.URL.Path).Should(Equal("/pcloud/v1/cloud-instances/service-guid-1234/volumes/volume-1/action"))
+			Expect(body).Should(Equal(map[string]any{"targetStorageTier": "tier1"}))
+		})
+	})
 })
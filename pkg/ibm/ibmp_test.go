@@ -92,6 +92,24 @@ var _ = DescribeTable("IBMPowerProvider unit test",
 		"disk":     "42"}, commonUserData, "64.0", "8.0", "100"),
 )
 
+var _ = DescribeTable("IBMPowerProvider processor and storage configuration",
+	func(testConfig map[string]string, expectedProcType string, expectedCores float64, expectedStorageTier string) {
+		config := map[string]string{}
+		for k, v := range testConfig {
+			config["dynamic.ppc6."+k] = v
+		}
+
+		providerConfig := CreateIBMPowerCloudConfig("ppc6", config, systemNamespace).(IBMPowerDynamicConfig)
+		Expect(providerConfig.ProcType).To(Equal(expectedProcType))
+		Expect(providerConfig.Cores).To(Equal(expectedCores))
+		Expect(providerConfig.StorageTier).To(Equal(expectedStorageTier))
+	},
+	Entry("Default - shared processors on the default storage tier", map[string]string{}, "shared", 0.25, ""),
+	Entry("Capped processors", map[string]string{"proc-type": "capped", "cores": "0.5"}, "capped", 0.5, ""),
+	Entry("Dedicated processors on a faster storage tier", map[string]string{"proc-type": "dedicated", "cores": "2", "storage-tier": "tier0"}, "dedicated", 2.0, "tier0"),
+	Entry("Dedicated processors default to a whole core", map[string]string{"proc-type": "dedicated"}, "dedicated", 1.0, ""),
+)
+
 var commonUserData = `|-
 Content-Type: multipart/mixed; boundary="//"
 MIME-Version: 1.0
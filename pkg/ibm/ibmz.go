@@ -33,13 +33,15 @@ func CreateIbmZCloudConfig(arch string, config map[string]string, systemNamespac
 		Profile:         config["dynamic."+arch+".profile"],
 		PrivateIP:       privateIp,
 		Disk:            volumeSize,
+		TaggingUrl:      config["dynamic."+arch+".tagging-url"],
 		SystemNamespace: systemNamespace,
 	}
 }
 
 // LaunchInstance creates a System Z Virtual Server instance and returns its identifier. This function is implemented as
-// part of the CloudProvider interface, which is why some of the arguments are unused for this particular implementation.
-func (iz IBMZDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, _ map[string]string) (cloud.InstanceIdentifier, error) {
+// part of the CloudProvider interface. additionalInstanceTags are applied as IBM Cloud user tags, alongside taskRunID,
+// to both the boot volume and the instance.
+func (iz IBMZDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (cloud.InstanceIdentifier, error) {
 	vpcService, err := iz.createAuthenticatedVpcService(ctx, kubeClient)
 	if err != nil {
 		return "", fmt.Errorf("failed to create an authenticated VPC service: %w", err)
@@ -71,6 +73,8 @@ func (iz IBMZDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context
 	if err != nil {
 		return "", err
 	}
+	additionalTags := additionalUserTags(ctx, additionalInstanceTags)
+	userTags := append(taskRunUserTags(taskRunID), additionalTags...)
 
 	vpcInstance, _, err := vpcService.CreateInstance(&vpcv1.CreateInstanceOptions{
 		InstancePrototype: &vpcv1.InstancePrototype{
@@ -90,7 +94,7 @@ func (iz IBMZDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context
 					Profile: &vpcv1.VolumeProfileIdentity{
 						Name: ptr("general-purpose"),
 					},
-					UserTags: []string{taskRunID},
+					UserTags: userTags,
 				},
 			},
 			PrimaryNetworkAttachment: &vpcv1.InstanceNetworkAttachmentPrototype{
@@ -117,6 +121,14 @@ func (iz IBMZDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context
 		return "", fmt.Errorf("failed to create the System Z virtual server instance %s: %w", instanceName, err)
 	}
 
+	// Instance prototypes cannot carry user tags, so the additional tags are attached once the instance exists.
+	// A failure here is not worth losing the instance over, so it is only logged.
+	if len(additionalTags) > 0 {
+		if err := iz.attachUserTags(ctx, vpcService.Service.Options.Authenticator, *vpcInstance.CRN, userTags); err != nil {
+			logr.FromContextOrDiscard(ctx).Error(err, "failed to tag the System Z virtual server instance", "instanceID", *vpcInstance.ID)
+		}
+	}
+
 	return cloud.InstanceIdentifier(*vpcInstance.ID), nil
 }
 
@@ -190,6 +202,28 @@ func (iz IBMZDynamicConfig) ListInstances(kubeClient client.Client, ctx context.
 	return vmInstances, nil
 }
 
+// GetInstanceTaskRunID returns the TaskRun ID in the user tags of the instanceID System Z virtual server instance.
+// The user tags of an instance are always set on its boot volume, which the listed instances only reference.
+func (iz IBMZDynamicConfig) GetInstanceTaskRunID(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (string, error) {
+	vpcService, err := iz.createAuthenticatedVpcService(ctx, kubeClient)
+	if err != nil {
+		return "", fmt.Errorf("failed to create an authenticated VPC service: %w", err)
+	}
+
+	instance, _, err := vpcService.GetInstance(&vpcv1.GetInstanceOptions{ID: ptr(string(instanceID))})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the VPC instance %s: %w", instanceID, err)
+	}
+	if instance.BootVolumeAttachment == nil || instance.BootVolumeAttachment.Volume == nil {
+		return "", nil
+	}
+	volume, _, err := vpcService.GetVolume(&vpcv1.GetVolumeOptions{ID: instance.BootVolumeAttachment.Volume.ID})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the boot volume of the VPC instance %s: %w", instanceID, err)
+	}
+	return taskRunIDFromUserTags(volume.UserTags), nil
+}
+
 // GetInstanceAddress returns the IP Address associated with the instanceID System Z virtual server instance.
 func (iz IBMZDynamicConfig) GetInstanceAddress(kubeClient client.Client, ctx context.Context, instanceId cloud.InstanceIdentifier) (string, error) {
 	log := logr.FromContextOrDiscard(ctx)
@@ -339,6 +373,10 @@ type IBMZDynamicConfig struct {
 	// Disk is the amount of permanent storage (in GB) allocated to the cloud instance.
 	Disk int
 
+	// TaggingUrl is the url of the IBM Cloud Global Tagging API used to attach user
+	// tags to the cloud instance. The public endpoint is used when it is empty.
+	TaggingUrl string
+
 	// PrivateIP is whether the cloud instance will use an IP address provided by the
 	// associated Virtual Private Cloud service.
 	PrivateIP bool
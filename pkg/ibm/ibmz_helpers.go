@@ -12,12 +12,14 @@ import (
 	"net"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/IBM/go-sdk-core/v5/core"
 	"github.com/IBM/vpc-go-sdk/vpcv1"
 	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
 	v1 "k8s.io/api/core/v1"
 	types2 "k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -31,6 +33,15 @@ var validInstanceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_-]*$`
 // length of the hash created from an instanceTag and the timestamp to add to an Instance name that is unique
 var hashLength = 16
 
+// defaultTaggingUrl is the public endpoint of the IBM Cloud Global Tagging API.
+const defaultTaggingUrl = "https://tags.global-search-tagging.cloud.ibm.com"
+
+// maxUserTagLength is the maximum length of an IBM Cloud user tag, in its "key:value" form.
+const maxUserTagLength = 128
+
+// validUserTagPattern matches the characters IBM Cloud allows in user tags, e.g. "cost-center:1234".
+var validUserTagPattern = regexp.MustCompile(`^[A-Za-z0-9 _.:-]+$`)
+
 // createInstanceName returns a unique instance name in the format <instance_tag>-<hash>x
 // This function generates cloud instance names for IBM Cloud (System Z and Power PC) that comply with
 // platform naming requirements. It creates a unique name by combining the instance tag with a 16-character
@@ -95,6 +106,109 @@ func checkIfIpIsLive(ctx context.Context, ip string) error {
 	return nil
 }
 
+// additionalUserTags returns additionalInstanceTags as sorted IBM Cloud user tags in the "key:value" format.
+// The TaskRun ID is skipped since callers always tag instances with it on their own. The additional instance tags
+// are shared with other cloud providers, so the ones that are not valid user tags are skipped with a warning
+// instead of failing the launch.
+//
+// Used in for Both IBM System Z & IBM Power PC.
+func additionalUserTags(ctx context.Context, additionalInstanceTags map[string]string) []string {
+	log := logr.FromContextOrDiscard(ctx)
+	userTags := make([]string, 0, len(additionalInstanceTags))
+	for k, v := range additionalInstanceTags {
+		if k == cloud.TaskRunTagKey {
+			continue
+		}
+		userTag := k + ":" + v
+		if len(userTag) > maxUserTagLength || !validUserTagPattern.MatchString(userTag) {
+			log.Info("WARN: skipping additional instance tag that is not a valid IBM Cloud user tag", "tag", userTag)
+			continue
+		}
+		userTags = append(userTags, userTag)
+	}
+	sort.Strings(userTags)
+	return userTags
+}
+
+// taskRunUserTags returns the user tags identifying the TaskRun an instance is launched for: the TaskRun ID itself,
+// and the TaskRun ID prefixed with cloud.TaskRunTagKey so that taskRunIDFromUserTags can tell it apart from the
+// additional "key:value" tags. The prefixed tag is left out when it is too long to be a user tag.
+//
+// Used in for Both IBM System Z & IBM Power PC.
+func taskRunUserTags(taskRunID string) []string {
+	userTags := []string{taskRunID}
+	if userTag := cloud.TaskRunTagKey + ":" + taskRunID; len(userTag) <= maxUserTagLength {
+		userTags = append(userTags, userTag)
+	}
+	return userTags
+}
+
+// taskRunIDFromUserTags returns the TaskRun ID in the user tags of an instance, or an empty string if the instance
+// was not tagged by taskRunUserTags.
+//
+// Used in for Both IBM System Z & IBM Power PC.
+func taskRunIDFromUserTags(userTags []string) string {
+	for _, userTag := range userTags {
+		if taskRunID, ok := strings.CutPrefix(userTag, cloud.TaskRunTagKey+":"); ok {
+			return taskRunID
+		}
+	}
+	return ""
+}
+
+// tagResults is the response of the Global Tagging API's attach operation.
+type tagResults struct {
+	Results []struct {
+		ResourceID string `json:"resource_id"`
+		IsError    bool   `json:"is_error"`
+	} `json:"results"`
+}
+
+// attachUserTags attaches userTags to the resource identified by crn with the IBM Cloud Global Tagging API.
+func (iz IBMZDynamicConfig) attachUserTags(ctx context.Context, authenticator core.Authenticator, crn string, userTags []string) error {
+	taggingUrl := iz.TaggingUrl
+	if taggingUrl == "" {
+		taggingUrl = defaultTaggingUrl
+	}
+	service, err := core.NewBaseService(&core.ServiceOptions{URL: taggingUrl, Authenticator: authenticator})
+	if err != nil {
+		return fmt.Errorf("failed to create the Global Tagging service: %w", err)
+	}
+
+	requestBuilder := core.NewRequestBuilder(core.POST)
+	requestBuilder = requestBuilder.WithContext(ctx)
+	_, err = requestBuilder.ResolveRequestURL(taggingUrl, `/v3/tags/attach`, nil)
+	if err != nil {
+		return fmt.Errorf("failed to encode the request with parameters: %w", err)
+	}
+	requestBuilder.AddQuery("tag_type", "user")
+	requestBuilder.AddHeader("Content-Type", "application/json")
+	requestBuilder.AddHeader("Accept", "application/json")
+	_, err = requestBuilder.SetBodyContentJSON(map[string]any{
+		"resources": []map[string]string{{"resource_id": crn}},
+		"tag_names": userTags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set the body of the request: %w", err)
+	}
+
+	request, err := requestBuilder.Build()
+	if err != nil {
+		return fmt.Errorf("failed to build the HTTP request: %w", err)
+	}
+	results := tagResults{}
+	_, err = service.Request(request, &results)
+	if err != nil {
+		return fmt.Errorf("failed to attach user tags to %s: %w", crn, err)
+	}
+	for _, result := range results.Results {
+		if result.IsError {
+			return fmt.Errorf("failed to attach user tags to %s", result.ResourceID)
+		}
+	}
+	return nil
+}
+
 func ptr[V any](s V) *V {
 	return &s
 }
@@ -162,8 +276,9 @@ func (iz IBMZDynamicConfig) lookUpVpc(vpcService *vpcv1.VpcV1) (*vpcv1.VPC, erro
 	return vpc, nil
 }
 
-// createAuthenticatedVpcService generates a Virtual Private Cloud service with an API key-based IAM (Identity and Access
-// Management) authenticator.
+// createAuthenticatedVpcService returns a Virtual Private Cloud service with an API key-based IAM (Identity and Access
+// Management) authenticator. The service is shared between calls for the same secret and URL, and recreated when the
+// API key changes.
 func (iz IBMZDynamicConfig) createAuthenticatedVpcService(ctx context.Context, kubeClient client.Client) (*vpcv1.VpcV1, error) {
 	apiKey := ""
 	if kubeClient == nil { // Get API key from an environment variable
@@ -187,13 +302,15 @@ func (iz IBMZDynamicConfig) createAuthenticatedVpcService(ctx context.Context, k
 	}
 
 	// Instantiate the VPC service
-	vpcService, err := vpcv1.NewVpcV1(&vpcv1.VpcV1Options{
-		URL: iz.Url,
-		Authenticator: &core.IamAuthenticator{
-			ApiKey: apiKey,
-		},
+	key := serviceCacheKey{secret: iz.Secret, namespace: iz.SystemNamespace, url: iz.Url}
+	return getCachedService(vpcServices, key, apiKey, func() (*vpcv1.VpcV1, error) {
+		return vpcv1.NewVpcV1(&vpcv1.VpcV1Options{
+			URL: iz.Url,
+			Authenticator: &core.IamAuthenticator{
+				ApiKey: apiKey,
+			},
+		})
 	})
-	return vpcService, err
 }
 
 // assignNetworkInterfaceFloatingIP returns an IP address that is already associated with the instance
@@ -1,9 +1,15 @@
 package ibm
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"regexp"
+	"strings"
 
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
 	"github.com/konflux-ci/multi-platform-controller/test/utils"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -101,4 +107,85 @@ var _ = Describe("IBM s390x Helper Functions", func() {
 				"System Z Max Tag Length"),
 		)
 	})
+
+	Describe("The additionalUserTags function", func() {
+		It("should return sorted key:value tags without the TaskRun ID", func(ctx SpecContext) {
+			additionalInstanceTags := map[string]string{
+				"team":              "build",
+				"cost-center":       "1234",
+				cloud.TaskRunTagKey: "test-ns:test-taskrun",
+			}
+			Expect(additionalUserTags(ctx, additionalInstanceTags)).Should(Equal([]string{"cost-center:1234", "team:build"}))
+			Expect(additionalInstanceTags).Should(HaveLen(3))
+		})
+
+		It("should skip tags that are not valid user tags", func(ctx SpecContext) {
+			additionalInstanceTags := map[string]string{
+				"team":        "build",
+				"owner":       "build@example.com",
+				"path":        "a/b",
+				"description": strings.Repeat("a", 117),
+			}
+			Expect(additionalUserTags(ctx, additionalInstanceTags)).Should(Equal([]string{"team:build"}))
+		})
+
+		It("should return no tags when none are configured", func(ctx SpecContext) {
+			Expect(additionalUserTags(ctx, nil)).Should(BeEmpty())
+		})
+	})
+
+	Describe("The TaskRun user tags", func() {
+		It("should be read back from the user tags of an instance", func() {
+			userTags := append(taskRunUserTags("test-ns:test-taskrun"), "team:build")
+			Expect(userTags).Should(ContainElement("test-ns:test-taskrun"))
+			Expect(taskRunIDFromUserTags(userTags)).Should(Equal("test-ns:test-taskrun"))
+		})
+
+		It("should not mistake additional tags for the TaskRun ID", func() {
+			Expect(taskRunIDFromUserTags([]string{"test-ns:test-taskrun", "team:build"})).Should(BeEmpty())
+		})
+
+		It("should leave out the prefixed tag when it is too long to be a user tag", func() {
+			taskRunID := "test-ns:" + strings.Repeat("a", 120)
+			Expect(taskRunUserTags(taskRunID)).Should(Equal([]string{taskRunID}))
+		})
+	})
+
+	Describe("The attachUserTags function", func() {
+		var (
+			server       *httptest.Server
+			request      *http.Request
+			body         map[string]any
+			responseBody string
+		)
+
+		BeforeEach(func() {
+			responseBody = `{"results":[{"resource_id":"crn:v1:instance","is_error":false}]}`
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request = r
+				Expect(json.NewDecoder(r.Body).Decode(&body)).Should(Succeed())
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(responseBody))
+			}))
+			DeferCleanup(server.Close)
+		})
+
+		It("should attach the user tags to the resource", func(ctx SpecContext) {
+			iz := IBMZDynamicConfig{TaggingUrl: server.URL}
+			err := iz.attachUserTags(ctx, &core.NoAuthAuthenticator{}, "crn:v1:instance", []string{"test-ns:test-taskrun", "team:build"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(request.Method).Should(Equal(http.MethodPost))
+			Expect(request.URL.Path).Should(Equal("/v3/tags/attach"))
+			Expect(request.URL.Query().Get("tag_type")).Should(Equal("user"))
+			Expect(body).Should(HaveKeyWithValue("resources", ConsistOf(HaveKeyWithValue("resource_id", "crn:v1:instance"))))
+			Expect(body).Should(HaveKeyWithValue("tag_names", ConsistOf("test-ns:test-taskrun", "team:build")))
+		})
+
+		It("should return an error when the resource could not be tagged", func(ctx SpecContext) {
+			responseBody = `{"results":[{"resource_id":"crn:v1:instance","is_error":true}]}`
+			iz := IBMZDynamicConfig{TaggingUrl: server.URL}
+			err := iz.attachUserTags(ctx, &core.NoAuthAuthenticator{}, "crn:v1:instance", []string{"team:build"})
+			Expect(err).Should(MatchError(ContainSubstring("failed to attach user tags to crn:v1:instance")))
+		})
+	})
 })
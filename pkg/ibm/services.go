@@ -0,0 +1,48 @@
+package ibm
+
+import (
+	"sync"
+
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/vpc-go-sdk/vpcv1"
+)
+
+// serviceCacheKey identifies the account and endpoint an authenticated service client talks to. Platforms whose
+// host-config entries change get a different key, and therefore a new client.
+type serviceCacheKey struct {
+	secret    string
+	namespace string
+	url       string
+}
+
+// cachedService is an authenticated service client along with the API key it was created with, so that it can be
+// replaced when the key in the secret is rotated.
+type cachedService[S any] struct {
+	apiKey  string
+	service S
+}
+
+var (
+	// vpcServices and baseServices hold the authenticated service clients. Their IAM authenticators cache the access
+	// token and only request a new one when it is about to expire, instead of for every IBM Cloud API call.
+	vpcServices  = map[serviceCacheKey]cachedService[*vpcv1.VpcV1]{}
+	baseServices = map[serviceCacheKey]cachedService[*core.BaseService]{}
+	servicesMu   sync.Mutex
+)
+
+// getCachedService returns the client in services for key if it was created with apiKey, or creates it with create
+// and caches it otherwise.
+func getCachedService[S any](services map[serviceCacheKey]cachedService[S], key serviceCacheKey, apiKey string, create func() (S, error)) (S, error) {
+	servicesMu.Lock()
+	defer servicesMu.Unlock()
+	if cached, ok := services[key]; ok && cached.apiKey == apiKey {
+		return cached.service, nil
+	}
+
+	service, err := create()
+	if err != nil {
+		return service, err
+	}
+	services[key] = cachedService[S]{apiKey: apiKey, service: service}
+	return service, nil
+}
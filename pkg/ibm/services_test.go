@@ -0,0 +1,90 @@
+package ibm
+
+import (
+	"github.com/IBM/go-sdk-core/v5/core"
+	"github.com/IBM/vpc-go-sdk/vpcv1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Authenticated IBM Cloud service clients", func() {
+	var (
+		secret     *corev1.Secret
+		kubeClient client.Client
+	)
+
+	BeforeEach(func() {
+		servicesMu.Lock()
+		vpcServices = map[serviceCacheKey]cachedService[*vpcv1.VpcV1]{}
+		baseServices = map[serviceCacheKey]cachedService[*core.BaseService]{}
+		servicesMu.Unlock()
+
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ibm-api-key", Namespace: systemNamespace},
+			Data:       map[string][]byte{"api-key": []byte("first-key")},
+		}
+		kubeClient = fake.NewClientBuilder().WithObjects(secret).Build()
+	})
+
+	// rotateAPIKey replaces the API key stored in the secret.
+	rotateAPIKey := func(ctx SpecContext) {
+		secret.Data["api-key"] = []byte("second-key")
+		Expect(kubeClient.Update(ctx, secret)).Should(Succeed())
+	}
+
+	Describe("The createAuthenticatedVpcService function", func() {
+		iz := IBMZDynamicConfig{Secret: "ibm-api-key", SystemNamespace: systemNamespace, Url: "https://us-east.iaas.cloud.ibm.com/v1"}
+
+		It("should reuse the service for the same platform configuration", func(ctx SpecContext) {
+			first, err := iz.createAuthenticatedVpcService(ctx, kubeClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			second, err := iz.createAuthenticatedVpcService(ctx, kubeClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(second).Should(BeIdenticalTo(first))
+		})
+
+		It("should create a new service when the API key is rotated", func(ctx SpecContext) {
+			first, err := iz.createAuthenticatedVpcService(ctx, kubeClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			rotateAPIKey(ctx)
+
+			second, err := iz.createAuthenticatedVpcService(ctx, kubeClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(second).ShouldNot(BeIdenticalTo(first))
+			Expect(second.Service.Options.Authenticator.(*core.IamAuthenticator).ApiKey).Should(Equal("second-key"))
+		})
+
+		It("should create a new service when the platform configuration changes", func(ctx SpecContext) {
+			first, err := iz.createAuthenticatedVpcService(ctx, kubeClient)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			moved := iz
+			moved.Url = "https://eu-de.iaas.cloud.ibm.com/v1"
+			second, err := moved.createAuthenticatedVpcService(ctx, kubeClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(second).ShouldNot(BeIdenticalTo(first))
+			Expect(second.GetServiceURL()).Should(Equal(moved.Url))
+		})
+	})
+
+	Describe("The createAuthenticatedBaseService function", func() {
+		pw := IBMPowerDynamicConfig{Secret: "ibm-api-key", SystemNamespace: systemNamespace, Url: "https://us-east.power-iaas.cloud.ibm.com"}
+
+		It("should reuse the service until the API key is rotated", func(ctx SpecContext) {
+			first, err := pw.createAuthenticatedBaseService(ctx, kubeClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			second, err := pw.createAuthenticatedBaseService(ctx, kubeClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(second).Should(BeIdenticalTo(first))
+
+			rotateAPIKey(ctx)
+			third, err := pw.createAuthenticatedBaseService(ctx, kubeClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(third).ShouldNot(BeIdenticalTo(first))
+		})
+	})
+})
@@ -0,0 +1,231 @@
+// Package kubevirt implements methods described in the [cloud] package for interacting with KubeVirt
+// VirtualMachines running in the same cluster as the controller.
+//
+// KubeVirt resources are handled as unstructured objects so the controller does not depend on the KubeVirt API
+// module. All methods of the CloudProvider interface are implemented and separated from other helper functions
+// used across the methods.
+package kubevirt
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// MultiPlatformManaged is the label marking VirtualMachines that were created by this controller.
+	MultiPlatformManaged = "multi-platform-managed"
+
+	defaultSshUser = "cloud-user"
+)
+
+// CreateKubeVirtCloudConfig returns a KubeVirt cloud configuration that implements the CloudProvider interface.
+func CreateKubeVirtCloudConfig(platformName string, config map[string]string, systemNamespace string) cloud.CloudProvider {
+	namespace := config["dynamic."+platformName+".namespace"]
+	if namespace == "" {
+		namespace = systemNamespace
+	}
+	sshUser := config["dynamic."+platformName+".ssh-user"]
+	if sshUser == "" {
+		sshUser = defaultSshUser
+	}
+
+	return KubeVirtDynamicConfig{
+		Namespace:  namespace,
+		VMTemplate: config["dynamic."+platformName+".vm-template"],
+		User:       sshUser,
+	}
+}
+
+// LaunchInstance creates a KubeVirt VirtualMachine from the configured template and returns its identifier,
+// which is the VirtualMachine name.
+func (kv KubeVirtDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (cloud.InstanceIdentifier, error) {
+	err := cloud.ValidateTaskRunID(taskRunID)
+	if err != nil {
+		return "", fmt.Errorf("invalid TaskRun ID: %w", err)
+	}
+	log := logr.FromContextOrDiscard(ctx)
+
+	vmName, err := createInstanceName(instanceTag)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a VirtualMachine name: %w", err)
+	}
+	log.Info("Attempting to launch KubeVirt VirtualMachine", "vmName", vmName, "taskRunID", taskRunID)
+
+	template, err := kv.getVirtualMachine(kubeClient, ctx, kv.VMTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve VirtualMachine template %s/%s: %w", kv.Namespace, kv.VMTemplate, err)
+	}
+	vm, err := kv.configureVirtualMachine(ctx, template, vmName, taskRunID, instanceTag, additionalInstanceTags)
+	if err != nil {
+		return "", fmt.Errorf("failed to configure VirtualMachine %s: %w", vmName, err)
+	}
+	if err := kubeClient.Create(ctx, vm); err != nil {
+		return "", fmt.Errorf("failed to launch KubeVirt VirtualMachine for %s: %w", taskRunID, err)
+	}
+	return cloud.InstanceIdentifier(vmName), nil
+}
+
+// CountInstances returns the number of KubeVirt VirtualMachines labeled with instanceTag that are not being deleted.
+func (kv KubeVirtDynamicConfig) CountInstances(kubeClient client.Client, ctx context.Context, instanceTag string) (int, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to count KubeVirt VirtualMachines")
+
+	vms, err := kv.listTaggedVirtualMachines(kubeClient, ctx, instanceTag)
+	if err != nil {
+		log.Error(err, "failed to retrieve KubeVirt VirtualMachines", "instanceTag", instanceTag)
+		return -1, fmt.Errorf("failed to retrieve KubeVirt VirtualMachines labeled with %s: %w", instanceTag, err)
+	}
+
+	count := 0
+	for _, vm := range vms {
+		if vm.GetDeletionTimestamp() == nil {
+			log.Info("Counting instance towards running count", "vmName", vm.GetName())
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetInstanceAddress returns the IP address of the instanceID VirtualMachine's running VirtualMachineInstance.
+// If none is found, an empty string is returned.
+func (kv KubeVirtDynamicConfig) GetInstanceAddress(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to get KubeVirt VirtualMachineInstance's IP address", "instanceID", instanceID)
+
+	vmi, err := kv.getVirtualMachineInstance(kubeClient, ctx, string(instanceID))
+	if err != nil {
+		// The VirtualMachineInstance is only created once the VirtualMachine starts, and other
+		// errors might be transient, so only log it
+		log.Error(err, "failed to retrieve instance", "instanceID", instanceID)
+		return "", nil
+	}
+
+	ip, err := kv.validateIPAddress(ctx, vmi)
+	// This might be a transient error, so only log it; wait longer for
+	// the instance to be ready
+	if err != nil {
+		return "", nil
+	}
+	return ip, nil
+}
+
+// TerminateInstance tries to delete the instanceID VirtualMachine along with its VirtualMachineInstance and
+// DataVolumes. A VirtualMachine that no longer exists is not an error.
+func (kv KubeVirtDynamicConfig) TerminateInstance(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) error {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to terminate KubeVirt VirtualMachine", "instanceID", instanceID)
+
+	vm := &unstructured.Unstructured{}
+	vm.SetGroupVersionKind(virtualMachineGVK)
+	vm.SetNamespace(kv.Namespace)
+	vm.SetName(string(instanceID))
+	err := kubeClient.Delete(ctx, vm, client.PropagationPolicy("Background"))
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete KubeVirt VirtualMachine %s: %w", instanceID, err)
+	}
+	return nil
+}
+
+// ListInstances returns a collection of accessible KubeVirt VirtualMachines labeled with instanceTag.
+func (kv KubeVirtDynamicConfig) ListInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]cloud.CloudVMInstance, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to list KubeVirt VirtualMachines")
+
+	vms, err := kv.listTaggedVirtualMachines(kubeClient, ctx, instanceTag)
+	if err != nil {
+		log.Error(err, "failed to retrieve KubeVirt VirtualMachines", "instanceTag", instanceTag)
+		return nil, fmt.Errorf("failed to retrieve KubeVirt VirtualMachines labeled with %s: %w", instanceTag, err)
+	}
+
+	vmInstances := []cloud.CloudVMInstance{}
+	for _, vm := range vms {
+		if vm.GetDeletionTimestamp() != nil {
+			continue
+		}
+		vmi, err := kv.getVirtualMachineInstance(kubeClient, ctx, vm.GetName())
+		if err != nil {
+			continue
+		}
+		// Only list VirtualMachines that have an accessible IP
+		ip, err := kv.validateIPAddress(ctx, vmi)
+		if err != nil {
+			continue
+		}
+		vmInstances = append(vmInstances, cloud.CloudVMInstance{
+			InstanceId: cloud.InstanceIdentifier(vm.GetName()),
+			StartTime:  vm.GetCreationTimestamp().Time,
+			Address:    ip,
+			TaskRunID:  vm.GetAnnotations()[cloud.TaskRunTagKey],
+		})
+		log.Info("Counting instance towards running count", "vmName", vm.GetName())
+	}
+	return vmInstances, nil
+}
+
+func (kv KubeVirtDynamicConfig) SshUser() string {
+	return kv.User
+}
+
+// GetState returns instanceID's VM state from the phase of its VirtualMachineInstance. See
+// https://kubevirt.io/api-reference/main/definitions.html#_v1_virtualmachineinstancestatus
+// for valid phases. A VirtualMachine whose VirtualMachineInstance has not been created yet is OK.
+func (kv KubeVirtDynamicConfig) GetState(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (cloud.VMState, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to get KubeVirt VirtualMachineInstance's phase", "instanceID", instanceID)
+	// VirtualMachineInstance phases considered to be OK
+	okPhases := []string{
+		"Pending",
+		"Scheduling",
+		"Scheduled",
+		"Running",
+		"Succeeded",
+	}
+
+	if _, err := kv.getVirtualMachine(kubeClient, ctx, string(instanceID)); err != nil {
+		if errors.IsNotFound(err) {
+			return cloud.FailedState, nil
+		}
+		return "", fmt.Errorf("failed to retrieve instance %s: %w", instanceID, err)
+	}
+	vmi, err := kv.getVirtualMachineInstance(kubeClient, ctx, string(instanceID))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return cloud.OKState, nil
+		}
+		return "", fmt.Errorf("failed to retrieve instance %s: %w", instanceID, err)
+	}
+
+	phase, _, _ := unstructured.NestedString(vmi.Object, "status", "phase")
+	if phase == "" || slices.Contains(okPhases, phase) {
+		return cloud.OKState, nil
+	}
+	return cloud.FailedState, nil
+}
+
+// A KubeVirtDynamicConfig represents a configuration for KubeVirt VirtualMachines.
+// The zero value (where each field will be assigned its type's zero value) is not a
+// valid KubeVirtDynamicConfig.
+type KubeVirtDynamicConfig struct {
+	// Namespace is the Kubernetes namespace the VirtualMachine template resides in and
+	// VirtualMachines are created in.
+	Namespace string
+
+	// VMTemplate is the name of the VirtualMachine whose spec is copied for every new VirtualMachine.
+	// The template is typically kept halted and defines the instance type, disks, network and
+	// cloud-init configuration, including the SSH key of User.
+	VMTemplate string
+
+	// User is the SSH user of the VirtualMachines.
+	User string
+
+	// pingFunc allows tests to inject a mock for SSH connectivity checks.
+	// When nil, the real pingIPAddress (TCP dial to port 22) is used.
+	pingFunc func(ip string) error
+}
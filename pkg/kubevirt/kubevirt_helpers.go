@@ -0,0 +1,222 @@
+package kubevirt
+
+import (
+	"context"
+	// #nosec is added to bypass the golang security scan since the cryptographic
+	// strength doesn't matter here
+	"crypto/md5" //#nosec
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	types2 "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxNameLength is the maximum length of a VirtualMachine name, which must be a DNS-1123 label
+// since it is used as the hostname of the VirtualMachineInstance.
+const maxNameLength = 63
+
+var (
+	virtualMachineGVK         = schema.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: "VirtualMachine"}
+	virtualMachineListGVK     = schema.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: "VirtualMachineList"}
+	virtualMachineInstanceGVK = schema.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: "VirtualMachineInstance"}
+
+	// validInstanceTagPattern validates that an instance tag can prefix a VirtualMachine name.
+	validInstanceTagPattern = regexp.MustCompile(`^[a-z][-a-z0-9]*$`)
+)
+
+// pingIPAddress tries to connect to the SSH port on ipAddress with a 60-second timeout.
+// An error is returned if the connection fails.
+func pingIPAddress(ipAddress string) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ipAddress, "22"), 60*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// createInstanceName returns a unique VirtualMachine name in the format <instance_tag>-<hash>. The instance tag
+// is lowercased and truncated so the name is a valid DNS-1123 label.
+func createInstanceName(instanceTag string) (string, error) {
+	instanceTag = strings.ReplaceAll(strings.ToLower(instanceTag), "_", "-")
+	if !validInstanceTagPattern.MatchString(instanceTag) {
+		return "", fmt.Errorf("instance tag must start with a letter and contain only alphanumeric characters and hyphens, got: %s", instanceTag)
+	}
+	if len(instanceTag) > maxNameLength-17 {
+		instanceTag = strings.TrimRight(instanceTag[:maxNameLength-17], "-")
+	}
+
+	now := time.Now()
+	hashInput := fmt.Sprintf("%s-%d-%d", instanceTag, now.Unix(), now.Nanosecond())
+	// #nosec is added to bypass the golang security scan since the cryptographic
+	// strength doesn't matter here
+	md5Hash := md5.Sum([]byte(hashInput)) //#nosec
+	return fmt.Sprintf("%s-%s", instanceTag, hex.EncodeToString(md5Hash[:])[0:16]), nil
+}
+
+// instanceLabels returns the labels used to find the VirtualMachines created for instanceTag.
+func instanceLabels(instanceTag string) map[string]string {
+	return map[string]string{
+		MultiPlatformManaged: "true",
+		cloud.InstanceTag:    instanceTag,
+	}
+}
+
+// getVirtualMachine returns the VirtualMachine called name in kv.Namespace.
+func (kv KubeVirtDynamicConfig) getVirtualMachine(kubeClient client.Client, ctx context.Context, name string) (*unstructured.Unstructured, error) {
+	vm := &unstructured.Unstructured{}
+	vm.SetGroupVersionKind(virtualMachineGVK)
+	err := kubeClient.Get(ctx, types2.NamespacedName{Namespace: kv.Namespace, Name: name}, vm)
+	return vm, err
+}
+
+// getVirtualMachineInstance returns the VirtualMachineInstance of the VirtualMachine called name in kv.Namespace.
+func (kv KubeVirtDynamicConfig) getVirtualMachineInstance(kubeClient client.Client, ctx context.Context, name string) (*unstructured.Unstructured, error) {
+	vmi := &unstructured.Unstructured{}
+	vmi.SetGroupVersionKind(virtualMachineInstanceGVK)
+	err := kubeClient.Get(ctx, types2.NamespacedName{Namespace: kv.Namespace, Name: name}, vmi)
+	return vmi, err
+}
+
+// listTaggedVirtualMachines returns the VirtualMachines in kv.Namespace that are labeled with instanceTag and
+// are managed by this controller.
+func (kv KubeVirtDynamicConfig) listTaggedVirtualMachines(kubeClient client.Client, ctx context.Context, instanceTag string) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(virtualMachineListGVK)
+	err := kubeClient.List(ctx, list, client.InNamespace(kv.Namespace), client.MatchingLabels(instanceLabels(instanceTag)))
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// configureVirtualMachine creates and returns a VirtualMachine called vmName from the template VirtualMachine.
+// The template's spec is copied with its DataVolume templates renamed after the new VirtualMachine, and the
+// VirtualMachine is set to start immediately.
+func (kv KubeVirtDynamicConfig) configureVirtualMachine(ctx context.Context, template *unstructured.Unstructured, vmName string, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (*unstructured.Unstructured, error) {
+	spec, found, err := unstructured.NestedMap(template.Object, "spec")
+	if err != nil {
+		return nil, fmt.Errorf("invalid VirtualMachine template spec: %w", err)
+	}
+	if !found {
+		return nil, errors.New("VirtualMachine template has no spec")
+	}
+
+	// The template is usually halted, so replace its run strategy
+	delete(spec, "running")
+	spec["runStrategy"] = "Always"
+
+	// DataVolume names must be unique, so prefix them with the VirtualMachine name and update the
+	// volumes referencing them
+	renamedVolumes := map[string]string{}
+	if dataVolumeTemplates, ok := spec["dataVolumeTemplates"].([]interface{}); ok {
+		for _, dvt := range dataVolumeTemplates {
+			dvtMap, ok := dvt.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			oldName, _, _ := unstructured.NestedString(dvtMap, "metadata", "name")
+			newName := vmName + "-" + oldName
+			if err := unstructured.SetNestedField(dvtMap, newName, "metadata", "name"); err != nil {
+				return nil, fmt.Errorf("invalid DataVolume template %s: %w", oldName, err)
+			}
+			renamedVolumes[oldName] = newName
+		}
+	}
+	if volumes, ok, _ := unstructured.NestedSlice(spec, "template", "spec", "volumes"); ok {
+		for _, volume := range volumes {
+			volumeMap, ok := volume.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, path := range [][]string{{"dataVolume", "name"}, {"persistentVolumeClaim", "claimName"}} {
+				if name, ok, _ := unstructured.NestedString(volumeMap, path...); ok && renamedVolumes[name] != "" {
+					_ = unstructured.SetNestedField(volumeMap, renamedVolumes[name], path...)
+				}
+			}
+		}
+		if err := unstructured.SetNestedSlice(spec, volumes, "template", "spec", "volumes"); err != nil {
+			return nil, fmt.Errorf("invalid VirtualMachine template volumes: %w", err)
+		}
+	}
+
+	// Label the VirtualMachineInstance too, so it can be traced back to this controller
+	templateLabels, _, _ := unstructured.NestedStringMap(spec, "template", "metadata", "labels")
+	if templateLabels == nil {
+		templateLabels = map[string]string{}
+	}
+	for k, v := range instanceLabels(instanceTag) {
+		templateLabels[k] = v
+	}
+	if err := unstructured.SetNestedStringMap(spec, templateLabels, "template", "metadata", "labels"); err != nil {
+		return nil, fmt.Errorf("invalid VirtualMachine template labels: %w", err)
+	}
+
+	// Annotation values are unrestricted, unlike label values, so the TaskRun ID and additional
+	// tags are stored as annotations. Tags that are valid elsewhere, e.g. for AWS, may not be valid annotation keys,
+	// and are skipped so that they do not prevent VirtualMachines from being created.
+	annotations := map[string]string{}
+	for k, v := range additionalInstanceTags {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			logr.FromContextOrDiscard(ctx).Info("WARN: skipping additional instance tag that is not a valid annotation key", "tag", k, "reason", strings.Join(errs, "; "))
+			continue
+		}
+		annotations[k] = v
+	}
+	annotations[cloud.TaskRunTagKey] = taskRunID
+
+	vm := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	vm.SetGroupVersionKind(virtualMachineGVK)
+	vm.SetNamespace(kv.Namespace)
+	vm.SetName(vmName)
+	vm.SetLabels(instanceLabels(instanceTag))
+	vm.SetAnnotations(annotations)
+	return vm, nil
+}
+
+// validateIPAddress returns the IP address of a running VirtualMachineInstance after verifying SSH connectivity.
+// Returns an error if no IP is available or if the instance is not reachable via SSH.
+func (kv KubeVirtDynamicConfig) validateIPAddress(ctx context.Context, vmi *unstructured.Unstructured) (string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	phase, _, _ := unstructured.NestedString(vmi.Object, "status", "phase")
+	if phase != "Running" {
+		return "", fmt.Errorf("VirtualMachineInstance %s is not running", vmi.GetName())
+	}
+	var ip string
+	interfaces, _, _ := unstructured.NestedSlice(vmi.Object, "status", "interfaces")
+	for _, iface := range interfaces {
+		ifaceMap, ok := iface.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if ip, _, _ = unstructured.NestedString(ifaceMap, "ipAddress"); ip != "" {
+			break
+		}
+	}
+	if ip == "" {
+		return "", fmt.Errorf("VirtualMachineInstance %s has no IP address", vmi.GetName())
+	}
+
+	// Verify SSH connectivity
+	ping := pingIPAddress
+	if kv.pingFunc != nil {
+		ping = kv.pingFunc
+	}
+	if err := ping(ip); err != nil {
+		log.Error(err, "failed to connect to KubeVirt VirtualMachineInstance via SSH", "vmName", vmi.GetName(), "ipAddress", ip)
+		return "", fmt.Errorf("failed to resolve IP address %s: %w", ip, err)
+	}
+	log.Info("Successfully validated IP address", "vmName", vmi.GetName(), "ipAddress", ip)
+	return ip, nil
+}
@@ -0,0 +1,13 @@
+package kubevirt
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestKubeVirt(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "KubeVirt Suite")
+}
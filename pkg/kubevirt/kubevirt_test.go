@@ -0,0 +1,297 @@
+package kubevirt
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const systemNamespace = "multi-platform-controller"
+
+// newTemplate returns a halted VirtualMachine template with a DataVolume root disk.
+func newTemplate() *unstructured.Unstructured {
+	template := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"running": false,
+			"dataVolumeTemplates": []interface{}{
+				map[string]interface{}{
+					"metadata": map[string]interface{}{"name": "rootdisk"},
+					"spec": map[string]interface{}{
+						"sourceRef": map[string]interface{}{"kind": "DataSource", "name": "rhel9"},
+					},
+				},
+			},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{"kubevirt.io/domain": "builder"},
+				},
+				"spec": map[string]interface{}{
+					"volumes": []interface{}{
+						map[string]interface{}{"name": "rootdisk", "dataVolume": map[string]interface{}{"name": "rootdisk"}},
+						map[string]interface{}{"name": "cloudinit", "cloudInitNoCloud": map[string]interface{}{"userData": "#cloud-config"}},
+					},
+				},
+			},
+		},
+	}}
+	template.SetGroupVersionKind(virtualMachineGVK)
+	template.SetNamespace(systemNamespace)
+	template.SetName("builder-template")
+	return template
+}
+
+// newVMI returns a VirtualMachineInstance for the VirtualMachine called name.
+func newVMI(name string, phase string, ip string) *unstructured.Unstructured {
+	status := map[string]interface{}{"phase": phase}
+	if ip != "" {
+		status["interfaces"] = []interface{}{map[string]interface{}{"name": "default", "ipAddress": ip}}
+	}
+	vmi := &unstructured.Unstructured{Object: map[string]interface{}{"status": status}}
+	vmi.SetGroupVersionKind(virtualMachineInstanceGVK)
+	vmi.SetNamespace(systemNamespace)
+	vmi.SetName(name)
+	return vmi
+}
+
+var _ = Describe("KubeVirt Unit Test Suite", func() {
+
+	Describe("Testing CreateKubeVirtCloudConfig", func() {
+
+		DescribeTable("Testing the creation of KubeVirtDynamicConfig properly no matter the values",
+			func(testConfig map[string]string, expectedNamespace string, expectedUser string) {
+				config := map[string]string{
+					"dynamic.linux-arm64.vm-template": "builder-template",
+					"dynamic.linux-arm64.namespace":   testConfig["namespace"],
+					"dynamic.linux-arm64.ssh-user":    testConfig["ssh-user"],
+				}
+				provider := CreateKubeVirtCloudConfig("linux-arm64", config, systemNamespace)
+				Expect(provider).ToNot(BeNil())
+				providerConfig := provider.(KubeVirtDynamicConfig)
+
+				Expect(providerConfig.VMTemplate).To(Equal("builder-template"))
+				Expect(providerConfig.Namespace).To(Equal(expectedNamespace))
+				Expect(providerConfig.SshUser()).To(Equal(expectedUser))
+			},
+			Entry("Positive - valid config map keys", map[string]string{"namespace": "vms", "ssh-user": "builder"}, "vms", "builder"),
+			Entry("Negative - missing config data", map[string]string{}, systemNamespace, defaultSshUser),
+		)
+	})
+
+	Describe("CloudProvider methods", func() {
+		var (
+			kubeClient client.Client
+			cfg        KubeVirtDynamicConfig
+			ctx        context.Context
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			kubeClient = fake.NewClientBuilder().WithObjects(newTemplate()).Build()
+			cfg = KubeVirtDynamicConfig{
+				Namespace:  systemNamespace,
+				VMTemplate: "builder-template",
+				User:       defaultSshUser,
+				pingFunc:   func(string) error { return nil },
+			}
+		})
+
+		// launch creates a VirtualMachine through LaunchInstance for the given instance tag.
+		launch := func(instanceTag string) cloud.InstanceIdentifier {
+			id, err := cfg.LaunchInstance(kubeClient, ctx, "test-namespace:test-taskrun", instanceTag, map[string]string{})
+			Expect(err).ToNot(HaveOccurred())
+			return id
+		}
+
+		Describe("LaunchInstance", func() {
+			It("should create a running VirtualMachine from the template", func() {
+				id, err := cfg.LaunchInstance(kubeClient, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{"cost-center": "konflux"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(id)).To(HavePrefix("prod-arm64-"))
+
+				vm, err := cfg.getVirtualMachine(kubeClient, ctx, string(id))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vm.GetLabels()).To(HaveKeyWithValue(cloud.InstanceTag, "prod-arm64"))
+				Expect(vm.GetLabels()).To(HaveKeyWithValue(MultiPlatformManaged, "true"))
+				Expect(vm.GetAnnotations()).To(HaveKeyWithValue(cloud.TaskRunTagKey, "test-namespace:test-taskrun"))
+				Expect(vm.GetAnnotations()).To(HaveKeyWithValue("cost-center", "konflux"))
+
+				runStrategy, _, _ := unstructured.NestedString(vm.Object, "spec", "runStrategy")
+				Expect(runStrategy).To(Equal("Always"))
+				_, found, _ := unstructured.NestedFieldNoCopy(vm.Object, "spec", "running")
+				Expect(found).To(BeFalse())
+
+				dataVolumeTemplates, _, _ := unstructured.NestedSlice(vm.Object, "spec", "dataVolumeTemplates")
+				dataVolumeName, _, _ := unstructured.NestedString(dataVolumeTemplates[0].(map[string]interface{}), "metadata", "name")
+				Expect(dataVolumeName).To(Equal(string(id) + "-rootdisk"))
+				volumes, _, _ := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "volumes")
+				volumeName, _, _ := unstructured.NestedString(volumes[0].(map[string]interface{}), "dataVolume", "name")
+				Expect(volumeName).To(Equal(string(id) + "-rootdisk"))
+
+				templateLabels, _, _ := unstructured.NestedStringMap(vm.Object, "spec", "template", "metadata", "labels")
+				Expect(templateLabels).To(HaveKeyWithValue("kubevirt.io/domain", "builder"))
+				Expect(templateLabels).To(HaveKeyWithValue(cloud.InstanceTag, "prod-arm64"))
+
+				// The template itself is left untouched
+				template, err := cfg.getVirtualMachine(kubeClient, ctx, "builder-template")
+				Expect(err).ToNot(HaveOccurred())
+				templateRunning, found, _ := unstructured.NestedBool(template.Object, "spec", "running")
+				Expect(found).To(BeTrue())
+				Expect(templateRunning).To(BeFalse())
+			})
+
+			It("should fail when the template does not exist", func() {
+				cfg.VMTemplate = "does-not-exist"
+				_, err := cfg.LaunchInstance(kubeClient, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{})
+				Expect(err).To(MatchError(ContainSubstring("failed to retrieve VirtualMachine template")))
+			})
+
+			It("should skip additional tags that are not valid annotation keys", func() {
+				id, err := cfg.LaunchInstance(kubeClient, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{"cost center": "konflux", "a/b/c": "x", "team": "build"})
+				Expect(err).ToNot(HaveOccurred())
+
+				vm, err := cfg.getVirtualMachine(kubeClient, ctx, string(id))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vm.GetAnnotations()).To(HaveKeyWithValue("team", "build"))
+				Expect(vm.GetAnnotations()).ToNot(HaveKey("cost center"))
+				Expect(vm.GetAnnotations()).ToNot(HaveKey("a/b/c"))
+			})
+
+			It("should reject an invalid TaskRun ID", func() {
+				_, err := cfg.LaunchInstance(kubeClient, ctx, "invalid-id", "prod-arm64", map[string]string{})
+				Expect(err).To(MatchError(ContainSubstring("invalid TaskRun ID")))
+			})
+		})
+
+		Describe("CountInstances", func() {
+			It("should only count VirtualMachines with the instance tag", func() {
+				launch("prod-arm64")
+				launch("prod-arm64")
+				launch("other-amd64")
+
+				Expect(cfg.CountInstances(kubeClient, ctx, "prod-arm64")).To(Equal(2))
+			})
+		})
+
+		Describe("ListInstances", func() {
+			It("should only list running and reachable VirtualMachines", func() {
+				running := launch("prod-arm64")
+				Expect(kubeClient.Create(ctx, newVMI(string(running), "Running", "10.128.0.10"))).To(Succeed())
+				scheduling := launch("prod-arm64")
+				Expect(kubeClient.Create(ctx, newVMI(string(scheduling), "Scheduling", ""))).To(Succeed())
+				launch("prod-arm64")
+				unreachable := launch("prod-arm64")
+				Expect(kubeClient.Create(ctx, newVMI(string(unreachable), "Running", "10.128.0.11"))).To(Succeed())
+				cfg.pingFunc = func(ip string) error {
+					if ip == "10.128.0.11" {
+						return errors.New("unreachable")
+					}
+					return nil
+				}
+
+				instances, err := cfg.ListInstances(kubeClient, ctx, "prod-arm64")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instances).To(HaveLen(1))
+				Expect(instances[0].InstanceId).To(Equal(running))
+				Expect(instances[0].Address).To(Equal("10.128.0.10"))
+				Expect(instances[0].TaskRunID).To(Equal("test-namespace:test-taskrun"))
+			})
+		})
+
+		Describe("GetInstanceAddress", func() {
+			It("should return the address of the running VirtualMachineInstance", func() {
+				id := launch("prod-arm64")
+				Expect(kubeClient.Create(ctx, newVMI(string(id), "Running", "10.128.0.10"))).To(Succeed())
+				Expect(cfg.GetInstanceAddress(kubeClient, ctx, id)).To(Equal("10.128.0.10"))
+			})
+
+			It("should return an empty address while the VirtualMachineInstance is being scheduled", func() {
+				id := launch("prod-arm64")
+				Expect(kubeClient.Create(ctx, newVMI(string(id), "Scheduled", ""))).To(Succeed())
+				Expect(cfg.GetInstanceAddress(kubeClient, ctx, id)).To(BeEmpty())
+			})
+
+			It("should treat a missing VirtualMachineInstance as transient", func() {
+				id := launch("prod-arm64")
+				address, err := cfg.GetInstanceAddress(kubeClient, ctx, id)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(address).To(BeEmpty())
+			})
+		})
+
+		Describe("GetState", func() {
+			DescribeTable("should map VirtualMachineInstance phases onto VM states",
+				func(phase string, expected cloud.VMState) {
+					id := launch("prod-arm64")
+					Expect(kubeClient.Create(ctx, newVMI(string(id), phase, ""))).To(Succeed())
+					Expect(cfg.GetState(kubeClient, ctx, id)).To(Equal(expected))
+				},
+				Entry("pending", "Pending", cloud.OKState),
+				Entry("scheduling", "Scheduling", cloud.OKState),
+				Entry("running", "Running", cloud.OKState),
+				Entry("succeeded", "Succeeded", cloud.OKState),
+				Entry("failed", "Failed", cloud.FailedState),
+				Entry("unknown", "Unknown", cloud.FailedState),
+			)
+
+			It("should report a VirtualMachine without a VirtualMachineInstance yet as OK", func() {
+				id := launch("prod-arm64")
+				Expect(cfg.GetState(kubeClient, ctx, id)).To(Equal(cloud.OKState))
+			})
+
+			It("should report a VirtualMachine that no longer exists as failed", func() {
+				Expect(cfg.GetState(kubeClient, ctx, "does-not-exist")).To(Equal(cloud.FailedState))
+			})
+
+			It("should return an error when the VirtualMachine cannot be retrieved", func() {
+				id := launch("prod-arm64")
+				failingClient := interceptor.NewClient(kubeClient.(client.WithWatch), interceptor.Funcs{
+					Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+						return errors.New("connection refused")
+					},
+				})
+				_, err := cfg.GetState(failingClient, ctx, id)
+				Expect(err).To(MatchError(ContainSubstring("connection refused")))
+			})
+		})
+
+		Describe("TerminateInstance", func() {
+			It("should delete the VirtualMachine", func() {
+				id := launch("prod-arm64")
+				Expect(cfg.TerminateInstance(kubeClient, ctx, id)).To(Succeed())
+				Expect(cfg.CountInstances(kubeClient, ctx, "prod-arm64")).To(Equal(0))
+			})
+
+			It("should not fail for a VirtualMachine that is already gone", func() {
+				Expect(cfg.TerminateInstance(kubeClient, ctx, "does-not-exist")).To(Succeed())
+			})
+		})
+	})
+
+	Describe("Testing helper functions", func() {
+		It("createInstanceName creates unique, valid names", func() {
+			first, err := createInstanceName("Prod_ARM64")
+			Expect(err).ToNot(HaveOccurred())
+			second, err := createInstanceName("Prod_ARM64")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(first).To(HavePrefix("prod-arm64-"))
+			Expect(first).ToNot(Equal(second))
+
+			long, err := createInstanceName(strings.Repeat("a", 80))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(long)).To(BeNumerically("<=", maxNameLength))
+		})
+
+		It("createInstanceName rejects tags that cannot start a VirtualMachine name", func() {
+			_, err := createInstanceName("1-starts-with-a-digit")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,266 @@
+// Package libvirt implements methods described in the [cloud] package for interacting with libvirt domains on
+// hypervisors owned by the cluster operators.
+//
+// Every instance is a clone of a base domain: its disk is a copy-on-write overlay of the base domain's disk and
+// it is tagged with the instance tag and TaskRun ID through the domain metadata. All methods of the CloudProvider
+// interface are implemented and separated from other helper functions used across the methods.
+package libvirt
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	golibvirt "github.com/digitalocean/go-libvirt"
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultSshUser     = "root"
+	defaultHostUser    = "root"
+	defaultSocket      = "/var/run/libvirt/libvirt-sock"
+	defaultStoragePool = "default"
+)
+
+// CreateLibvirtCloudConfig returns a libvirt cloud configuration that implements the CloudProvider interface.
+func CreateLibvirtCloudConfig(platformName string, config map[string]string, systemNamespace string) cloud.CloudProvider {
+	withDefault := func(key string, defaultValue string) string {
+		if value := config["dynamic."+platformName+"."+key]; value != "" {
+			return value
+		}
+		return defaultValue
+	}
+	addressSource, err := parseAddressSource(config["dynamic."+platformName+".address-source"])
+	if err != nil {
+		addressSource = golibvirt.DomainInterfaceAddressesSrcLease
+	}
+	disk, err := strconv.ParseUint(config["dynamic."+platformName+".disk"], 10, 64)
+	if err != nil {
+		disk = 0
+	}
+
+	return LibvirtDynamicConfig{
+		Host:            config["dynamic."+platformName+".host"],
+		HostUser:        withDefault("host-user", defaultHostUser),
+		Socket:          withDefault("socket", defaultSocket),
+		Secret:          config["dynamic."+platformName+".libvirt-secret"],
+		BaseDomain:      config["dynamic."+platformName+".base-domain"],
+		StoragePool:     withDefault("storage-pool", defaultStoragePool),
+		Disk:            disk,
+		AddressSource:   addressSource,
+		User:            withDefault("ssh-user", defaultSshUser),
+		SystemNamespace: systemNamespace,
+	}
+}
+
+// LaunchInstance clones the base domain into a new running domain and returns its identifier, which is the
+// domain name.
+func (lv LibvirtDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (cloud.InstanceIdentifier, error) {
+	err := cloud.ValidateTaskRunID(taskRunID)
+	if err != nil {
+		return "", fmt.Errorf("invalid TaskRun ID: %w", err)
+	}
+	log := logr.FromContextOrDiscard(ctx)
+
+	domainName, err := createInstanceName(instanceTag)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a domain name: %w", err)
+	}
+	log.Info("Attempting to launch libvirt domain", "domainName", domainName, "host", lv.Host, "taskRunID", taskRunID)
+
+	l, disconnect, err := lv.getLibvirtClient(kubeClient, ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a libvirt client: %w", err)
+	}
+	defer disconnect()
+
+	if err := lv.cloneDomain(l, domainName, taskRunID, instanceTag, additionalInstanceTags); err != nil {
+		return "", fmt.Errorf("failed to launch libvirt domain for %s: %w", taskRunID, err)
+	}
+	return cloud.InstanceIdentifier(domainName), nil
+}
+
+// CountInstances returns the number of libvirt domains tagged with instanceTag.
+func (lv LibvirtDynamicConfig) CountInstances(kubeClient client.Client, ctx context.Context, instanceTag string) (int, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to count libvirt domains", "host", lv.Host)
+
+	l, disconnect, err := lv.getLibvirtClient(kubeClient, ctx)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create a libvirt client: %w", err)
+	}
+	defer disconnect()
+
+	domains, err := listTaggedDomains(l, instanceTag)
+	if err != nil {
+		log.Error(err, "failed to retrieve libvirt domains", "instanceTag", instanceTag)
+		return -1, fmt.Errorf("failed to retrieve libvirt domains tagged with %s: %w", instanceTag, err)
+	}
+	for _, domain := range domains {
+		log.Info("Counting instance towards running count", "domainName", domain.Name)
+	}
+	return len(domains), nil
+}
+
+// GetInstanceAddress returns the IP address of the instanceID domain. If none is found, an empty string is returned.
+func (lv LibvirtDynamicConfig) GetInstanceAddress(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to get libvirt domain's IP address", "instanceID", instanceID)
+
+	l, disconnect, err := lv.getLibvirtClient(kubeClient, ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a libvirt client: %w", err)
+	}
+	defer disconnect()
+
+	domain, err := l.DomainLookupByName(string(instanceID))
+	if err != nil {
+		// This might be a transient error, so only log it
+		log.Error(err, "failed to retrieve instance", "instanceID", instanceID)
+		return "", nil
+	}
+	ip, err := lv.validateIPAddress(ctx, l, domain)
+	// This might be a transient error, so only log it; wait longer for
+	// the instance to be ready
+	if err != nil {
+		return "", nil
+	}
+	return ip, nil
+}
+
+// TerminateInstance destroys and undefines the instanceID domain and deletes its disk. A domain that no longer
+// exists is not an error.
+func (lv LibvirtDynamicConfig) TerminateInstance(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) error {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to terminate libvirt domain", "instanceID", instanceID)
+
+	l, disconnect, err := lv.getLibvirtClient(kubeClient, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create a libvirt client: %w", err)
+	}
+	defer disconnect()
+
+	if err := lv.deleteDomain(l, string(instanceID)); err != nil {
+		return fmt.Errorf("failed to delete libvirt domain %s: %w", instanceID, err)
+	}
+	return nil
+}
+
+// ListInstances returns a collection of accessible libvirt domains tagged with instanceTag.
+func (lv LibvirtDynamicConfig) ListInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]cloud.CloudVMInstance, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to list libvirt domains", "host", lv.Host)
+
+	l, disconnect, err := lv.getLibvirtClient(kubeClient, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a libvirt client: %w", err)
+	}
+	defer disconnect()
+
+	domains, err := listTaggedDomains(l, instanceTag)
+	if err != nil {
+		log.Error(err, "failed to retrieve libvirt domains", "instanceTag", instanceTag)
+		return nil, fmt.Errorf("failed to retrieve libvirt domains tagged with %s: %w", instanceTag, err)
+	}
+
+	vmInstances := []cloud.CloudVMInstance{}
+	for _, domain := range domains {
+		// Only list domains that have an accessible IP
+		ip, err := lv.validateIPAddress(ctx, l, domain.Domain)
+		if err != nil {
+			continue
+		}
+		startTime, _ := time.Parse(time.RFC3339, domain.Metadata.Created)
+		vmInstances = append(vmInstances, cloud.CloudVMInstance{
+			InstanceId: cloud.InstanceIdentifier(domain.Name),
+			StartTime:  startTime,
+			Address:    ip,
+			TaskRunID:  domain.Metadata.TaskRunID,
+		})
+		log.Info("Counting instance towards running count", "domainName", domain.Name)
+	}
+	return vmInstances, nil
+}
+
+func (lv LibvirtDynamicConfig) SshUser() string {
+	return lv.User
+}
+
+// GetState returns instanceID's VM state from libvirt. A crashed domain is in a failed state; see
+// https://libvirt.org/html/libvirt-libvirt-domain.html#virDomainState for valid states.
+func (lv LibvirtDynamicConfig) GetState(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (cloud.VMState, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to get libvirt domain's state", "instanceID", instanceID)
+
+	l, disconnect, err := lv.getLibvirtClient(kubeClient, ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a libvirt client: %w", err)
+	}
+	defer disconnect()
+
+	domain, err := l.DomainLookupByName(string(instanceID))
+	if err != nil {
+		if golibvirt.IsNotFound(err) {
+			return cloud.FailedState, nil
+		}
+		return "", fmt.Errorf("failed to retrieve instance %s: %w", instanceID, err)
+	}
+	state, _, err := l.DomainGetState(domain, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve the state of instance %s: %w", instanceID, err)
+	}
+	if golibvirt.DomainState(state) == golibvirt.DomainCrashed {
+		return cloud.FailedState, nil
+	}
+	return cloud.OKState, nil
+}
+
+// A LibvirtDynamicConfig represents a configuration for libvirt domains on a single hypervisor.
+// The zero value (where each field will be assigned its type's zero value) is not a
+// valid LibvirtDynamicConfig.
+type LibvirtDynamicConfig struct {
+	// Host is the address of the hypervisor, optionally with an SSH port ("host" or "host:port").
+	Host string
+
+	// HostUser is the SSH user on the hypervisor that has access to the libvirtd socket.
+	HostUser string
+
+	// Socket is the path of the libvirtd UNIX socket on the hypervisor.
+	Socket string
+
+	// Secret is the name of the Kubernetes secret that contains the SSH private key ("id_rsa")
+	// for HostUser and, optionally, the hypervisor's host keys ("known_hosts").
+	Secret string
+
+	// SystemNamespace is the name of the Kubernetes namespace where the specified
+	// secrets are stored.
+	SystemNamespace string
+
+	// BaseDomain is the name of the shut-off domain that is cloned for every instance.
+	BaseDomain string
+
+	// StoragePool is the name of the storage pool the instances' disks are created in.
+	StoragePool string
+
+	// Disk is the size (in GB) of the instances' disks. When it is zero or smaller than the
+	// base domain's disk, the size of the base domain's disk is used.
+	Disk uint64
+
+	// AddressSource is where libvirt looks up the instances' IP addresses: DHCP leases,
+	// the QEMU guest agent or the hypervisor's ARP table.
+	AddressSource golibvirt.DomainInterfaceAddressesSource
+
+	// User is the SSH user of the instances.
+	User string
+
+	// libvirtClient allows tests to inject a mock libvirt API client.
+	// When nil, getLibvirtClient connects to the libvirtd of Host.
+	libvirtClient libvirtAPI
+
+	// pingFunc allows tests to inject a mock for SSH connectivity checks.
+	// When nil, the real pingIPAddress (TCP dial to port 22) is used.
+	pingFunc func(ip string) error
+}
@@ -0,0 +1,139 @@
+package libvirt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	golibvirt "github.com/digitalocean/go-libvirt"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	v1 "k8s.io/api/core/v1"
+	types2 "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// libvirtAPI is the subset of the libvirt RPC API used by this package. It is implemented by
+// *golibvirt.Libvirt; tests can substitute a mock.
+type libvirtAPI interface {
+	ConnectListAllDomains(NeedResults int32, Flags golibvirt.ConnectListAllDomainsFlags) ([]golibvirt.Domain, uint32, error)
+	DomainLookupByName(Name string) (golibvirt.Domain, error)
+	DomainGetXMLDesc(Dom golibvirt.Domain, Flags golibvirt.DomainXMLFlags) (string, error)
+	DomainDefineXML(XML string) (golibvirt.Domain, error)
+	DomainCreate(Dom golibvirt.Domain) error
+	DomainDestroy(Dom golibvirt.Domain) error
+	DomainUndefineFlags(Dom golibvirt.Domain, Flags golibvirt.DomainUndefineFlagsValues) error
+	DomainGetState(Dom golibvirt.Domain, Flags uint32) (int32, int32, error)
+	DomainGetMetadata(Dom golibvirt.Domain, Type int32, Uri golibvirt.OptString, Flags golibvirt.DomainModificationImpact) (string, error)
+	DomainInterfaceAddresses(Dom golibvirt.Domain, Source uint32, Flags uint32) ([]golibvirt.DomainInterface, error)
+	StoragePoolLookupByName(Name string) (golibvirt.StoragePool, error)
+	StorageVolLookupByName(Pool golibvirt.StoragePool, Name string) (golibvirt.StorageVol, error)
+	StorageVolLookupByPath(Path string) (golibvirt.StorageVol, error)
+	StorageVolGetInfo(Vol golibvirt.StorageVol) (int8, uint64, uint64, error)
+	StorageVolGetPath(Vol golibvirt.StorageVol) (string, error)
+	StorageVolCreateXML(Pool golibvirt.StoragePool, XML string, Flags golibvirt.StorageVolCreateFlags) (golibvirt.StorageVol, error)
+	StorageVolDelete(Vol golibvirt.StorageVol, Flags golibvirt.StorageVolDeleteFlags) error
+}
+
+// sshDialer implements the go-libvirt socket Dialer interface by tunneling the libvirt RPC
+// protocol over SSH to the hypervisor's libvirtd UNIX socket.
+type sshDialer struct {
+	address string
+	socket  string
+	config  *ssh.ClientConfig
+}
+
+// Dial opens an SSH connection to the hypervisor and connects to its libvirtd socket.
+func (d sshDialer) Dial() (net.Conn, error) {
+	sshClient, err := ssh.Dial("tcp", d.address, d.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s via SSH: %w", d.address, err)
+	}
+	conn, err := sshClient.Dial("unix", d.socket)
+	if err != nil {
+		_ = sshClient.Close()
+		return nil, fmt.Errorf("failed to connect to libvirt socket %s on %s: %w", d.socket, d.address, err)
+	}
+	return sshConn{Conn: conn, client: sshClient}, nil
+}
+
+// sshConn is a connection tunneled over SSH; closing it also closes the SSH client.
+type sshConn struct {
+	net.Conn
+	client *ssh.Client
+}
+
+func (c sshConn) Close() error {
+	return errors.Join(c.Conn.Close(), c.client.Close())
+}
+
+// getLibvirtClient returns the injected mock client if set, otherwise connects to the libvirtd of
+// lv.Host over SSH using the private key from lv.Secret. The returned function closes the connection.
+func (lv LibvirtDynamicConfig) getLibvirtClient(kubeClient client.Client, ctx context.Context) (libvirtAPI, func(), error) {
+	if lv.libvirtClient != nil {
+		return lv.libvirtClient, func() {}, nil
+	}
+	if kubeClient == nil {
+		return nil, nil, errors.New("a Kubernetes client is required to read the libvirt SSH secret")
+	}
+
+	s := v1.Secret{}
+	nameSpacedSecret := types2.NamespacedName{Name: lv.Secret, Namespace: lv.SystemNamespace}
+	if err := kubeClient.Get(ctx, nameSpacedSecret, &s); err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve the secret %v from the Kubernetes client: %w", nameSpacedSecret, err)
+	}
+	signer, err := ssh.ParsePrivateKey(s.Data["id_rsa"])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the SSH private key in secret %v: %w", nameSpacedSecret, err)
+	}
+	hostKeys, err := hostKeyCallback(s.Data["known_hosts"])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the known hosts in secret %v: %w", nameSpacedSecret, err)
+	}
+
+	address := lv.Host
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "22")
+	}
+	dialer := sshDialer{
+		address: address,
+		socket:  lv.Socket,
+		config: &ssh.ClientConfig{
+			User:            lv.HostUser,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: hostKeys,
+			Timeout:         30 * time.Second,
+		},
+	}
+	l := golibvirt.NewWithDialer(dialer)
+	if err := l.ConnectToURI(golibvirt.QEMUSystem); err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to libvirt on %s: %w", lv.Host, err)
+	}
+	return l, func() { _ = l.Disconnect() }, nil
+}
+
+// hostKeyCallback returns an SSH host key callback verifying against knownHosts, a known_hosts file
+// content. If knownHosts is empty, host keys are not verified, matching how the controller connects to
+// other hosts.
+func hostKeyCallback(knownHosts []byte) (ssh.HostKeyCallback, error) {
+	if len(knownHosts) == 0 {
+		// #nosec G106 -- build hosts are not verified elsewhere either (StrictHostKeyChecking=no)
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	f, err := os.CreateTemp("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(knownHosts); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return knownhosts.New(f.Name())
+}
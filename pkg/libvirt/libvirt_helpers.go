@@ -0,0 +1,352 @@
+package libvirt
+
+import (
+	"context"
+	// #nosec is added to bypass the golang security scan since the cryptographic
+	// strength doesn't matter here
+	"crypto/md5" //#nosec
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	golibvirt "github.com/digitalocean/go-libvirt"
+	"github.com/go-logr/logr"
+	"libvirt.org/go/libvirtxml"
+)
+
+const (
+	// metadataNamespace is the XML namespace of the domain metadata element written by this controller.
+	metadataNamespace = "https://konflux-ci.dev/multi-platform-controller"
+
+	// maxNameLength is the maximum length of a domain name, which is also used as the guest hostname.
+	maxNameLength = 63
+)
+
+// validInstanceTagPattern validates that an instance tag can prefix a domain name.
+var validInstanceTagPattern = regexp.MustCompile(`^[a-z][-a-z0-9]*$`)
+
+// instanceMetadata is the domain metadata element identifying the domains created by this controller.
+type instanceMetadata struct {
+	XMLName     xml.Name      `xml:"https://konflux-ci.dev/multi-platform-controller instance"`
+	InstanceTag string        `xml:"instanceTag"`
+	TaskRunID   string        `xml:"taskRunID"`
+	Created     string        `xml:"created"`
+	Tags        []metadataTag `xml:"tag"`
+}
+
+// metadataTag is an additional instance tag stored in the domain metadata.
+type metadataTag struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// taggedDomain is a domain together with the metadata written by this controller.
+type taggedDomain struct {
+	golibvirt.Domain
+	Metadata instanceMetadata
+}
+
+// pingIPAddress tries to connect to the SSH port on ipAddress with a 60-second timeout.
+// An error is returned if the connection fails.
+func pingIPAddress(ipAddress string) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ipAddress, "22"), 60*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// createInstanceName returns a unique domain name in the format <instance_tag>-<hash>. The instance tag is
+// lowercased and truncated so the name is also a valid hostname.
+func createInstanceName(instanceTag string) (string, error) {
+	instanceTag = strings.ReplaceAll(strings.ToLower(instanceTag), "_", "-")
+	if !validInstanceTagPattern.MatchString(instanceTag) {
+		return "", fmt.Errorf("instance tag must start with a letter and contain only alphanumeric characters and hyphens, got: %s", instanceTag)
+	}
+	if len(instanceTag) > maxNameLength-17 {
+		instanceTag = strings.TrimRight(instanceTag[:maxNameLength-17], "-")
+	}
+
+	now := time.Now()
+	hashInput := fmt.Sprintf("%s-%d-%d", instanceTag, now.Unix(), now.Nanosecond())
+	// #nosec is added to bypass the golang security scan since the cryptographic
+	// strength doesn't matter here
+	md5Hash := md5.Sum([]byte(hashInput)) //#nosec
+	return fmt.Sprintf("%s-%s", instanceTag, hex.EncodeToString(md5Hash[:])[0:16]), nil
+}
+
+// volumeName returns the name of the disk volume of the domain called domainName.
+func volumeName(domainName string) string {
+	return domainName + ".qcow2"
+}
+
+// parseAddressSource converts an address-source configuration value into a libvirt address source.
+// An empty value selects DHCP leases.
+func parseAddressSource(source string) (golibvirt.DomainInterfaceAddressesSource, error) {
+	switch source {
+	case "", "lease":
+		return golibvirt.DomainInterfaceAddressesSrcLease, nil
+	case "agent":
+		return golibvirt.DomainInterfaceAddressesSrcAgent, nil
+	case "arp":
+		return golibvirt.DomainInterfaceAddressesSrcArp, nil
+	default:
+		return 0, fmt.Errorf("invalid address source: expect 'lease', 'agent' or 'arp', got '%s'", source)
+	}
+}
+
+// marshalMetadata returns the metadata element for a domain created for taskRunID.
+func marshalMetadata(taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (string, error) {
+	metadata := instanceMetadata{
+		InstanceTag: instanceTag,
+		TaskRunID:   taskRunID,
+		Created:     time.Now().UTC().Format(time.RFC3339),
+	}
+	for k, v := range additionalInstanceTags {
+		metadata.Tags = append(metadata.Tags, metadataTag{Name: k, Value: v})
+	}
+	sort.Slice(metadata.Tags, func(i, j int) bool { return metadata.Tags[i].Name < metadata.Tags[j].Name })
+	out, err := xml.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// getMetadata returns the metadata written by this controller for domain. The second return value is false
+// if the domain has no such metadata, i.e. it was not created by this controller.
+func getMetadata(l libvirtAPI, domain golibvirt.Domain) (instanceMetadata, bool, error) {
+	raw, err := l.DomainGetMetadata(domain, int32(golibvirt.DomainMetadataElement), golibvirt.OptString{metadataNamespace}, golibvirt.DomainAffectCurrent)
+	if err != nil {
+		if isLibvirtError(err, golibvirt.ErrNoDomainMetadata) {
+			return instanceMetadata{}, false, nil
+		}
+		return instanceMetadata{}, false, err
+	}
+	metadata := instanceMetadata{}
+	if err := xml.Unmarshal([]byte(raw), &metadata); err != nil {
+		return instanceMetadata{}, false, fmt.Errorf("invalid metadata of domain %s: %w", domain.Name, err)
+	}
+	return metadata, true, nil
+}
+
+// listTaggedDomains returns the domains on the hypervisor that were created by this controller for instanceTag.
+func listTaggedDomains(l libvirtAPI, instanceTag string) ([]taggedDomain, error) {
+	domains, _, err := l.ConnectListAllDomains(1, 0)
+	if err != nil {
+		return nil, err
+	}
+	var tagged []taggedDomain
+	for _, domain := range domains {
+		metadata, found, err := getMetadata(l, domain)
+		if err != nil {
+			if golibvirt.IsNotFound(err) {
+				// The domain was undefined after it was listed
+				continue
+			}
+			return nil, err
+		}
+		if found && metadata.InstanceTag == instanceTag {
+			tagged = append(tagged, taggedDomain{Domain: domain, Metadata: metadata})
+		}
+	}
+	return tagged, nil
+}
+
+// cloneDomain defines and starts a domain called domainName from the base domain. The first disk of the base
+// domain is replaced by a copy-on-write overlay in the storage pool; the domain's UUID and MAC addresses are
+// regenerated by libvirt.
+func (lv LibvirtDynamicConfig) cloneDomain(l libvirtAPI, domainName string, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) error {
+	base, err := l.DomainLookupByName(lv.BaseDomain)
+	if err != nil {
+		return fmt.Errorf("failed to look up base domain %s: %w", lv.BaseDomain, err)
+	}
+	baseXML, err := l.DomainGetXMLDesc(base, golibvirt.DomainXMLInactive)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the definition of base domain %s: %w", lv.BaseDomain, err)
+	}
+	domainConfig := &libvirtxml.Domain{}
+	if err := domainConfig.Unmarshal(baseXML); err != nil {
+		return fmt.Errorf("invalid definition of base domain %s: %w", lv.BaseDomain, err)
+	}
+
+	var rootDisk *libvirtxml.DomainDisk
+	if domainConfig.Devices != nil {
+		for i := range domainConfig.Devices.Disks {
+			disk := &domainConfig.Devices.Disks[i]
+			if (disk.Device == "" || disk.Device == "disk") && disk.Source != nil && disk.Source.File != nil {
+				rootDisk = disk
+				break
+			}
+		}
+	}
+	if rootDisk == nil {
+		return fmt.Errorf("base domain %s has no file-backed disk", lv.BaseDomain)
+	}
+	baseFormat := "qcow2"
+	if rootDisk.Driver != nil && rootDisk.Driver.Type != "" {
+		baseFormat = rootDisk.Driver.Type
+	}
+
+	// Create the overlay disk
+	pool, err := l.StoragePoolLookupByName(lv.StoragePool)
+	if err != nil {
+		return fmt.Errorf("failed to look up storage pool %s: %w", lv.StoragePool, err)
+	}
+	baseVolume, err := l.StorageVolLookupByPath(rootDisk.Source.File.File)
+	if err != nil {
+		return fmt.Errorf("failed to look up the disk of base domain %s: %w", lv.BaseDomain, err)
+	}
+	_, capacity, _, err := l.StorageVolGetInfo(baseVolume)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the disk size of base domain %s: %w", lv.BaseDomain, err)
+	}
+	if diskBytes := lv.Disk * 1024 * 1024 * 1024; diskBytes > capacity {
+		capacity = diskBytes
+	}
+	volumeConfig := &libvirtxml.StorageVolume{
+		Name:     volumeName(domainName),
+		Capacity: &libvirtxml.StorageVolumeSize{Unit: "bytes", Value: capacity},
+		Target:   &libvirtxml.StorageVolumeTarget{Format: &libvirtxml.StorageVolumeTargetFormat{Type: "qcow2"}},
+		BackingStore: &libvirtxml.StorageVolumeBackingStore{
+			Path:   rootDisk.Source.File.File,
+			Format: &libvirtxml.StorageVolumeTargetFormat{Type: baseFormat},
+		},
+	}
+	volumeXML, err := volumeConfig.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to create the disk definition: %w", err)
+	}
+	volume, err := l.StorageVolCreateXML(pool, volumeXML, 0)
+	if err != nil {
+		return fmt.Errorf("failed to create the disk of domain %s: %w", domainName, err)
+	}
+	volumePath, err := l.StorageVolGetPath(volume)
+	if err != nil {
+		_ = l.StorageVolDelete(volume, 0)
+		return fmt.Errorf("failed to retrieve the disk path of domain %s: %w", domainName, err)
+	}
+
+	// Define and start the clone
+	domainConfig.Name = domainName
+	domainConfig.UUID = ""
+	domainConfig.ID = nil
+	rootDisk.Source.File.File = volumePath
+	rootDisk.BackingStore = nil
+	if rootDisk.Driver == nil {
+		rootDisk.Driver = &libvirtxml.DomainDiskDriver{Name: "qemu"}
+	}
+	rootDisk.Driver.Type = "qcow2"
+	for i := range domainConfig.Devices.Interfaces {
+		domainConfig.Devices.Interfaces[i].MAC = nil
+	}
+	metadata, err := marshalMetadata(taskRunID, instanceTag, additionalInstanceTags)
+	if err != nil {
+		_ = l.StorageVolDelete(volume, 0)
+		return fmt.Errorf("failed to create the metadata of domain %s: %w", domainName, err)
+	}
+	if domainConfig.Metadata == nil {
+		domainConfig.Metadata = &libvirtxml.DomainMetadata{}
+	}
+	domainConfig.Metadata.XML += metadata
+
+	domainXML, err := domainConfig.Marshal()
+	if err != nil {
+		_ = l.StorageVolDelete(volume, 0)
+		return fmt.Errorf("failed to create the definition of domain %s: %w", domainName, err)
+	}
+	domain, err := l.DomainDefineXML(domainXML)
+	if err != nil {
+		_ = l.StorageVolDelete(volume, 0)
+		return fmt.Errorf("failed to define domain %s: %w", domainName, err)
+	}
+	if err := l.DomainCreate(domain); err != nil {
+		_ = lv.deleteDomain(l, domainName)
+		return fmt.Errorf("failed to start domain %s: %w", domainName, err)
+	}
+	return nil
+}
+
+// deleteDomain stops and undefines the domain called domainName and deletes its disk. Missing domains and disks
+// are ignored.
+func (lv LibvirtDynamicConfig) deleteDomain(l libvirtAPI, domainName string) error {
+	domain, err := l.DomainLookupByName(domainName)
+	if err == nil {
+		// Destroying a domain that is not running fails with an invalid operation error
+		if err := l.DomainDestroy(domain); err != nil && !isLibvirtError(err, golibvirt.ErrOperationInvalid) {
+			return fmt.Errorf("failed to stop domain: %w", err)
+		}
+		err = l.DomainUndefineFlags(domain, golibvirt.DomainUndefineManagedSave|golibvirt.DomainUndefineSnapshotsMetadata|golibvirt.DomainUndefineNvram)
+		if err != nil && !golibvirt.IsNotFound(err) {
+			return fmt.Errorf("failed to undefine domain: %w", err)
+		}
+	} else if !golibvirt.IsNotFound(err) {
+		return err
+	}
+
+	pool, err := l.StoragePoolLookupByName(lv.StoragePool)
+	if err != nil {
+		return fmt.Errorf("failed to look up storage pool %s: %w", lv.StoragePool, err)
+	}
+	volume, err := l.StorageVolLookupByName(pool, volumeName(domainName))
+	if err != nil {
+		if isLibvirtError(err, golibvirt.ErrNoStorageVol) {
+			return nil
+		}
+		return fmt.Errorf("failed to look up the disk: %w", err)
+	}
+	if err := l.StorageVolDelete(volume, 0); err != nil {
+		return fmt.Errorf("failed to delete the disk: %w", err)
+	}
+	return nil
+}
+
+// validateIPAddress returns the first IPv4 address of the domain after verifying SSH connectivity.
+// Returns an error if no IP is available or if the domain is not reachable via SSH.
+func (lv LibvirtDynamicConfig) validateIPAddress(ctx context.Context, l libvirtAPI, domain golibvirt.Domain) (string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	interfaces, err := l.DomainInterfaceAddresses(domain, uint32(lv.AddressSource), 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve the addresses of domain %s: %w", domain.Name, err)
+	}
+	var ip string
+	for _, iface := range interfaces {
+		for _, addr := range iface.Addrs {
+			if addr.Type == int32(golibvirt.IPAddrTypeIpv4) && !strings.HasPrefix(addr.Addr, "127.") {
+				ip = addr.Addr
+				break
+			}
+		}
+		if ip != "" {
+			break
+		}
+	}
+	if ip == "" {
+		return "", fmt.Errorf("domain %s has no IP address", domain.Name)
+	}
+
+	// Verify SSH connectivity
+	ping := pingIPAddress
+	if lv.pingFunc != nil {
+		ping = lv.pingFunc
+	}
+	if err := ping(ip); err != nil {
+		log.Error(err, "failed to connect to libvirt domain via SSH", "domainName", domain.Name, "ipAddress", ip)
+		return "", fmt.Errorf("failed to resolve IP address %s: %w", ip, err)
+	}
+	log.Info("Successfully validated IP address", "domainName", domain.Name, "ipAddress", ip)
+	return ip, nil
+}
+
+// isLibvirtError reports whether err is a libvirt error with the given error number.
+func isLibvirtError(err error, number golibvirt.ErrorNumber) bool {
+	var libvirtErr golibvirt.Error
+	return errors.As(err, &libvirtErr) && libvirtErr.Code == uint32(number)
+}
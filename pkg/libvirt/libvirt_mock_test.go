@@ -0,0 +1,230 @@
+package libvirt
+
+import (
+	"strings"
+	"sync"
+
+	golibvirt "github.com/digitalocean/go-libvirt"
+	"libvirt.org/go/libvirtxml"
+)
+
+// mockDomain is a domain defined in mockLibvirtAPI.
+type mockDomain struct {
+	XML     string
+	State   golibvirt.DomainState
+	Address string
+}
+
+// mockLibvirtAPI is an in-memory implementation of libvirtAPI with a single storage pool. Started domains are
+// given the next address from Addresses, if any.
+type mockLibvirtAPI struct {
+	mu          sync.Mutex
+	Domains     map[string]*mockDomain
+	Volumes     map[string]uint64
+	Addresses   []string
+	CreateError error
+	LookupError error
+}
+
+func newMockLibvirtAPI() *mockLibvirtAPI {
+	return &mockLibvirtAPI{
+		Domains: map[string]*mockDomain{},
+		Volumes: map[string]uint64{},
+	}
+}
+
+// libvirtError returns the error libvirt reports for the given error number.
+func libvirtError(number golibvirt.ErrorNumber) error {
+	return golibvirt.Error{Code: uint32(number), Message: "mock libvirt error"}
+}
+
+func (m *mockLibvirtAPI) ConnectListAllDomains(_ int32, _ golibvirt.ConnectListAllDomainsFlags) ([]golibvirt.Domain, uint32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var domains []golibvirt.Domain
+	for name := range m.Domains {
+		domains = append(domains, golibvirt.Domain{Name: name})
+	}
+	return domains, uint32(len(domains)), nil
+}
+
+func (m *mockLibvirtAPI) DomainLookupByName(name string) (golibvirt.Domain, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.LookupError != nil {
+		return golibvirt.Domain{}, m.LookupError
+	}
+	if _, ok := m.Domains[name]; !ok {
+		return golibvirt.Domain{}, libvirtError(golibvirt.ErrNoDomain)
+	}
+	return golibvirt.Domain{Name: name}, nil
+}
+
+func (m *mockLibvirtAPI) DomainGetXMLDesc(dom golibvirt.Domain, _ golibvirt.DomainXMLFlags) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	domain, ok := m.Domains[dom.Name]
+	if !ok {
+		return "", libvirtError(golibvirt.ErrNoDomain)
+	}
+	return domain.XML, nil
+}
+
+func (m *mockLibvirtAPI) DomainDefineXML(xml string) (golibvirt.Domain, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	domainConfig := &libvirtxml.Domain{}
+	if err := domainConfig.Unmarshal(xml); err != nil {
+		return golibvirt.Domain{}, libvirtError(golibvirt.ErrXMLError)
+	}
+	m.Domains[domainConfig.Name] = &mockDomain{XML: xml, State: golibvirt.DomainShutoff}
+	return golibvirt.Domain{Name: domainConfig.Name}, nil
+}
+
+func (m *mockLibvirtAPI) DomainCreate(dom golibvirt.Domain) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.CreateError != nil {
+		return m.CreateError
+	}
+	domain, ok := m.Domains[dom.Name]
+	if !ok {
+		return libvirtError(golibvirt.ErrNoDomain)
+	}
+	domain.State = golibvirt.DomainRunning
+	if len(m.Addresses) > 0 {
+		domain.Address, m.Addresses = m.Addresses[0], m.Addresses[1:]
+	}
+	return nil
+}
+
+func (m *mockLibvirtAPI) DomainDestroy(dom golibvirt.Domain) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	domain, ok := m.Domains[dom.Name]
+	if !ok {
+		return libvirtError(golibvirt.ErrNoDomain)
+	}
+	if domain.State != golibvirt.DomainRunning {
+		return libvirtError(golibvirt.ErrOperationInvalid)
+	}
+	domain.State = golibvirt.DomainShutoff
+	return nil
+}
+
+func (m *mockLibvirtAPI) DomainUndefineFlags(dom golibvirt.Domain, _ golibvirt.DomainUndefineFlagsValues) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Domains[dom.Name]; !ok {
+		return libvirtError(golibvirt.ErrNoDomain)
+	}
+	delete(m.Domains, dom.Name)
+	return nil
+}
+
+func (m *mockLibvirtAPI) DomainGetState(dom golibvirt.Domain, _ uint32) (int32, int32, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	domain, ok := m.Domains[dom.Name]
+	if !ok {
+		return 0, 0, libvirtError(golibvirt.ErrNoDomain)
+	}
+	return int32(domain.State), 0, nil
+}
+
+func (m *mockLibvirtAPI) DomainGetMetadata(dom golibvirt.Domain, _ int32, uri golibvirt.OptString, _ golibvirt.DomainModificationImpact) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	domain, ok := m.Domains[dom.Name]
+	if !ok {
+		return "", libvirtError(golibvirt.ErrNoDomain)
+	}
+	domainConfig := &libvirtxml.Domain{}
+	if err := domainConfig.Unmarshal(domain.XML); err != nil {
+		return "", libvirtError(golibvirt.ErrXMLError)
+	}
+	if domainConfig.Metadata == nil || len(uri) == 0 || !strings.Contains(domainConfig.Metadata.XML, uri[0]) {
+		return "", libvirtError(golibvirt.ErrNoDomainMetadata)
+	}
+	return domainConfig.Metadata.XML, nil
+}
+
+func (m *mockLibvirtAPI) DomainInterfaceAddresses(dom golibvirt.Domain, _ uint32, _ uint32) ([]golibvirt.DomainInterface, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	domain, ok := m.Domains[dom.Name]
+	if !ok {
+		return nil, libvirtError(golibvirt.ErrNoDomain)
+	}
+	if domain.Address == "" {
+		return nil, nil
+	}
+	return []golibvirt.DomainInterface{{
+		Name: "vnet0",
+		Addrs: []golibvirt.DomainIPAddr{
+			{Type: int32(golibvirt.IPAddrTypeIpv6), Addr: "fe80::1", Prefix: 64},
+			{Type: int32(golibvirt.IPAddrTypeIpv4), Addr: domain.Address, Prefix: 24},
+		},
+	}}, nil
+}
+
+func (m *mockLibvirtAPI) StoragePoolLookupByName(name string) (golibvirt.StoragePool, error) {
+	if name != defaultStoragePool {
+		return golibvirt.StoragePool{}, libvirtError(golibvirt.ErrNoStoragePool)
+	}
+	return golibvirt.StoragePool{Name: name}, nil
+}
+
+func (m *mockLibvirtAPI) StorageVolLookupByName(pool golibvirt.StoragePool, name string) (golibvirt.StorageVol, error) {
+	return m.StorageVolLookupByPath(volumePath(name))
+}
+
+func (m *mockLibvirtAPI) StorageVolLookupByPath(path string) (golibvirt.StorageVol, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Volumes[path]; !ok {
+		return golibvirt.StorageVol{}, libvirtError(golibvirt.ErrNoStorageVol)
+	}
+	return golibvirt.StorageVol{Pool: defaultStoragePool, Name: path[strings.LastIndex(path, "/")+1:], Key: path}, nil
+}
+
+func (m *mockLibvirtAPI) StorageVolGetInfo(vol golibvirt.StorageVol) (int8, uint64, uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	capacity, ok := m.Volumes[vol.Key]
+	if !ok {
+		return 0, 0, 0, libvirtError(golibvirt.ErrNoStorageVol)
+	}
+	return 0, capacity, 0, nil
+}
+
+func (m *mockLibvirtAPI) StorageVolGetPath(vol golibvirt.StorageVol) (string, error) {
+	return vol.Key, nil
+}
+
+func (m *mockLibvirtAPI) StorageVolCreateXML(pool golibvirt.StoragePool, xml string, _ golibvirt.StorageVolCreateFlags) (golibvirt.StorageVol, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	volumeConfig := &libvirtxml.StorageVolume{}
+	if err := volumeConfig.Unmarshal(xml); err != nil {
+		return golibvirt.StorageVol{}, libvirtError(golibvirt.ErrXMLError)
+	}
+	path := volumePath(volumeConfig.Name)
+	m.Volumes[path] = volumeConfig.Capacity.Value
+	return golibvirt.StorageVol{Pool: pool.Name, Name: volumeConfig.Name, Key: path}, nil
+}
+
+func (m *mockLibvirtAPI) StorageVolDelete(vol golibvirt.StorageVol, _ golibvirt.StorageVolDeleteFlags) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Volumes[vol.Key]; !ok {
+		return libvirtError(golibvirt.ErrNoStorageVol)
+	}
+	delete(m.Volumes, vol.Key)
+	return nil
+}
+
+// volumePath returns the path of the volume called name in the mock storage pool.
+func volumePath(name string) string {
+	return "/var/lib/libvirt/images/" + name
+}
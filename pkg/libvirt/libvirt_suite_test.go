@@ -0,0 +1,13 @@
+package libvirt
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestLibvirt(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Libvirt Suite")
+}
@@ -0,0 +1,307 @@
+package libvirt
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	golibvirt "github.com/digitalocean/go-libvirt"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"libvirt.org/go/libvirtxml"
+)
+
+const systemNamespace = "multi-platform-controller"
+
+// baseDomainXML is a shut-off base domain with a qcow2 root disk, a cloud-init CD-ROM and a network interface.
+const baseDomainXML = `<domain type="kvm">
+  <name>builder-base</name>
+  <uuid>6b1b0cc6-3a0b-4c0c-9a8f-0a7c2a3e9a11</uuid>
+  <memory unit="GiB">8</memory>
+  <vcpu>4</vcpu>
+  <os><type arch="aarch64" machine="virt">hvm</type></os>
+  <devices>
+    <disk type="file" device="cdrom">
+      <source file="/var/lib/libvirt/images/cloud-init.iso"/>
+      <target dev="sda" bus="scsi"/>
+    </disk>
+    <disk type="file" device="disk">
+      <driver name="qemu" type="qcow2"/>
+      <source file="/var/lib/libvirt/images/builder-base.qcow2"/>
+      <target dev="vda" bus="virtio"/>
+    </disk>
+    <interface type="network">
+      <mac address="52:54:00:12:34:56"/>
+      <source network="default"/>
+      <model type="virtio"/>
+    </interface>
+  </devices>
+</domain>`
+
+// newMockWithBaseDomain returns a mock hypervisor with the base domain and its 20 GiB disk.
+func newMockWithBaseDomain() *mockLibvirtAPI {
+	m := newMockLibvirtAPI()
+	m.Domains["builder-base"] = &mockDomain{XML: baseDomainXML, State: golibvirt.DomainShutoff}
+	m.Volumes[volumePath("builder-base.qcow2")] = 20 * 1024 * 1024 * 1024
+	return m
+}
+
+var _ = Describe("Libvirt Unit Test Suite", func() {
+
+	Describe("Testing CreateLibvirtCloudConfig", func() {
+
+		DescribeTable("Testing the creation of LibvirtDynamicConfig properly no matter the values",
+			func(testConfig map[string]string, expectedDisk uint64, expectedSource golibvirt.DomainInterfaceAddressesSource, expectedPool string, expectedUser string) {
+				config := map[string]string{
+					"dynamic.linux-arm64.host":           "hypervisor.example.com",
+					"dynamic.linux-arm64.libvirt-secret": "libvirt-ssh-key",
+					"dynamic.linux-arm64.base-domain":    "builder-base",
+					"dynamic.linux-arm64.disk":           testConfig["disk"],
+					"dynamic.linux-arm64.address-source": testConfig["address-source"],
+					"dynamic.linux-arm64.storage-pool":   testConfig["storage-pool"],
+					"dynamic.linux-arm64.ssh-user":       testConfig["ssh-user"],
+				}
+				provider := CreateLibvirtCloudConfig("linux-arm64", config, systemNamespace)
+				Expect(provider).ToNot(BeNil())
+				providerConfig := provider.(LibvirtDynamicConfig)
+
+				Expect(providerConfig.Host).To(Equal("hypervisor.example.com"))
+				Expect(providerConfig.HostUser).To(Equal(defaultHostUser))
+				Expect(providerConfig.Socket).To(Equal(defaultSocket))
+				Expect(providerConfig.Secret).To(Equal("libvirt-ssh-key"))
+				Expect(providerConfig.BaseDomain).To(Equal("builder-base"))
+				Expect(providerConfig.SystemNamespace).To(Equal(systemNamespace))
+				Expect(providerConfig.Disk).To(Equal(expectedDisk))
+				Expect(providerConfig.AddressSource).To(Equal(expectedSource))
+				Expect(providerConfig.StoragePool).To(Equal(expectedPool))
+				Expect(providerConfig.SshUser()).To(Equal(expectedUser))
+			},
+			Entry("Positive - valid config map keys",
+				map[string]string{"disk": "100", "address-source": "agent", "storage-pool": "builds", "ssh-user": "fedora"},
+				uint64(100), golibvirt.DomainInterfaceAddressesSrcAgent, "builds", "fedora"),
+			Entry("Negative - missing config data",
+				map[string]string{},
+				uint64(0), golibvirt.DomainInterfaceAddressesSrcLease, defaultStoragePool, defaultSshUser),
+			Entry("Negative - invalid disk size and address source",
+				map[string]string{"disk": "lots", "address-source": "dns"},
+				uint64(0), golibvirt.DomainInterfaceAddressesSrcLease, defaultStoragePool, defaultSshUser),
+		)
+	})
+
+	Describe("CloudProvider methods", func() {
+		var (
+			mockAPI *mockLibvirtAPI
+			cfg     LibvirtDynamicConfig
+			ctx     context.Context
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			mockAPI = newMockWithBaseDomain()
+			cfg = LibvirtDynamicConfig{
+				Host:          "hypervisor.example.com",
+				BaseDomain:    "builder-base",
+				StoragePool:   defaultStoragePool,
+				AddressSource: golibvirt.DomainInterfaceAddressesSrcLease,
+				User:          defaultSshUser,
+				libvirtClient: mockAPI,
+				pingFunc:      func(string) error { return nil },
+			}
+		})
+
+		// launch creates a domain through LaunchInstance for the given instance tag.
+		launch := func(instanceTag string) cloud.InstanceIdentifier {
+			id, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", instanceTag, map[string]string{})
+			Expect(err).ToNot(HaveOccurred())
+			return id
+		}
+
+		Describe("LaunchInstance", func() {
+			It("should start a tagged clone of the base domain on an overlay disk", func() {
+				id, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{"cost-center": "konflux"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(id)).To(HavePrefix("prod-arm64-"))
+				Expect(mockAPI.Domains[string(id)].State).To(Equal(golibvirt.DomainRunning))
+
+				domainConfig := &libvirtxml.Domain{}
+				Expect(domainConfig.Unmarshal(mockAPI.Domains[string(id)].XML)).To(Succeed())
+				Expect(domainConfig.Name).To(Equal(string(id)))
+				Expect(domainConfig.UUID).To(BeEmpty())
+				Expect(domainConfig.Devices.Interfaces[0].MAC).To(BeNil())
+				Expect(domainConfig.Devices.Disks[0].Source.File.File).To(Equal("/var/lib/libvirt/images/cloud-init.iso"))
+				Expect(domainConfig.Devices.Disks[1].Source.File.File).To(Equal(volumePath(volumeName(string(id)))))
+				Expect(domainConfig.Devices.Disks[1].Driver.Type).To(Equal("qcow2"))
+
+				metadata, found, err := getMetadata(mockAPI, golibvirt.Domain{Name: string(id)})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(found).To(BeTrue())
+				Expect(metadata.InstanceTag).To(Equal("prod-arm64"))
+				Expect(metadata.TaskRunID).To(Equal("test-namespace:test-taskrun"))
+				Expect(metadata.Tags).To(ConsistOf(metadataTag{Name: "cost-center", Value: "konflux"}))
+
+				// The overlay disk keeps the size of the base domain's disk
+				Expect(mockAPI.Volumes).To(HaveKeyWithValue(volumePath(volumeName(string(id))), uint64(20*1024*1024*1024)))
+				// The base domain itself is left untouched
+				Expect(mockAPI.Domains["builder-base"].XML).To(Equal(baseDomainXML))
+				Expect(mockAPI.Domains["builder-base"].State).To(Equal(golibvirt.DomainShutoff))
+			})
+
+			It("should grow the overlay disk to the configured size", func() {
+				cfg.Disk = 100
+				id := launch("prod-arm64")
+				Expect(mockAPI.Volumes).To(HaveKeyWithValue(volumePath(volumeName(string(id))), uint64(100*1024*1024*1024)))
+			})
+
+			It("should fail when the base domain does not exist", func() {
+				cfg.BaseDomain = "does-not-exist"
+				_, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{})
+				Expect(err).To(MatchError(ContainSubstring("failed to look up base domain")))
+			})
+
+			It("should clean up the domain and its disk when it cannot be started", func() {
+				mockAPI.CreateError = errors.New("not enough memory")
+				_, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{})
+				Expect(err).To(MatchError(ContainSubstring("not enough memory")))
+				Expect(mockAPI.Domains).To(HaveLen(1))
+				Expect(mockAPI.Volumes).To(HaveLen(1))
+			})
+
+			It("should reject an invalid TaskRun ID", func() {
+				_, err := cfg.LaunchInstance(nil, ctx, "invalid-id", "prod-arm64", map[string]string{})
+				Expect(err).To(MatchError(ContainSubstring("invalid TaskRun ID")))
+			})
+		})
+
+		Describe("CountInstances", func() {
+			It("should only count domains with the instance tag", func() {
+				launch("prod-arm64")
+				launch("prod-arm64")
+				launch("other-amd64")
+
+				Expect(cfg.CountInstances(nil, ctx, "prod-arm64")).To(Equal(2))
+			})
+		})
+
+		Describe("ListInstances", func() {
+			It("should only list reachable domains", func() {
+				mockAPI.Addresses = []string{"192.168.122.10", "192.168.122.11"}
+				reachable := launch("prod-arm64")
+				launch("prod-arm64")
+				launch("prod-arm64")
+				cfg.pingFunc = func(ip string) error {
+					if ip == "192.168.122.11" {
+						return errors.New("unreachable")
+					}
+					return nil
+				}
+
+				instances, err := cfg.ListInstances(nil, ctx, "prod-arm64")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instances).To(HaveLen(1))
+				Expect(instances[0].InstanceId).To(Equal(reachable))
+				Expect(instances[0].Address).To(Equal("192.168.122.10"))
+				Expect(instances[0].StartTime).ToNot(BeZero())
+				Expect(instances[0].TaskRunID).To(Equal("test-namespace:test-taskrun"))
+			})
+		})
+
+		Describe("GetInstanceAddress", func() {
+			It("should return the IPv4 address of the domain", func() {
+				mockAPI.Addresses = []string{"192.168.122.10"}
+				id := launch("prod-arm64")
+				Expect(cfg.GetInstanceAddress(nil, ctx, id)).To(Equal("192.168.122.10"))
+			})
+
+			It("should return an empty address while the domain has no DHCP lease", func() {
+				id := launch("prod-arm64")
+				address, err := cfg.GetInstanceAddress(nil, ctx, id)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(address).To(BeEmpty())
+			})
+		})
+
+		Describe("GetState", func() {
+			DescribeTable("should map domain states onto VM states",
+				func(state golibvirt.DomainState, expected cloud.VMState) {
+					id := launch("prod-arm64")
+					mockAPI.Domains[string(id)].State = state
+					Expect(cfg.GetState(nil, ctx, id)).To(Equal(expected))
+				},
+				Entry("running", golibvirt.DomainRunning, cloud.OKState),
+				Entry("paused", golibvirt.DomainPaused, cloud.OKState),
+				Entry("shut off", golibvirt.DomainShutoff, cloud.OKState),
+				Entry("crashed", golibvirt.DomainCrashed, cloud.FailedState),
+			)
+
+			It("should report a domain that no longer exists as failed", func() {
+				Expect(cfg.GetState(nil, ctx, "does-not-exist")).To(Equal(cloud.FailedState))
+			})
+
+			It("should return an error when the domain cannot be looked up", func() {
+				id := launch("prod-arm64")
+				mockAPI.LookupError = errors.New("connection reset")
+				_, err := cfg.GetState(nil, ctx, id)
+				Expect(err).To(MatchError(ContainSubstring("connection reset")))
+			})
+		})
+
+		Describe("TerminateInstance", func() {
+			It("should destroy and undefine the domain and delete its disk", func() {
+				id := launch("prod-arm64")
+				Expect(cfg.TerminateInstance(nil, ctx, id)).To(Succeed())
+				Expect(mockAPI.Domains).ToNot(HaveKey(string(id)))
+				Expect(mockAPI.Volumes).ToNot(HaveKey(volumePath(volumeName(string(id)))))
+				Expect(cfg.CountInstances(nil, ctx, "prod-arm64")).To(Equal(0))
+			})
+
+			It("should undefine a domain that is already shut off", func() {
+				id := launch("prod-arm64")
+				mockAPI.Domains[string(id)].State = golibvirt.DomainShutoff
+				Expect(cfg.TerminateInstance(nil, ctx, id)).To(Succeed())
+				Expect(mockAPI.Domains).ToNot(HaveKey(string(id)))
+			})
+
+			It("should not fail for a domain that is already gone", func() {
+				Expect(cfg.TerminateInstance(nil, ctx, "does-not-exist")).To(Succeed())
+			})
+		})
+	})
+
+	Describe("Testing helper functions", func() {
+		It("createInstanceName creates unique, valid names", func() {
+			first, err := createInstanceName("Prod_ARM64")
+			Expect(err).ToNot(HaveOccurred())
+			second, err := createInstanceName("Prod_ARM64")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(first).To(HavePrefix("prod-arm64-"))
+			Expect(first).ToNot(Equal(second))
+
+			long, err := createInstanceName(strings.Repeat("a", 80))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(long)).To(BeNumerically("<=", maxNameLength))
+		})
+
+		It("createInstanceName rejects tags that cannot start a domain name", func() {
+			_, err := createInstanceName("1-starts-with-a-digit")
+			Expect(err).To(HaveOccurred())
+		})
+
+		DescribeTable("parseAddressSource",
+			func(source string, expected golibvirt.DomainInterfaceAddressesSource, expectErr bool) {
+				parsed, err := parseAddressSource(source)
+				if expectErr {
+					Expect(err).To(HaveOccurred())
+					return
+				}
+				Expect(err).ToNot(HaveOccurred())
+				Expect(parsed).To(Equal(expected))
+			},
+			Entry("default", "", golibvirt.DomainInterfaceAddressesSrcLease, false),
+			Entry("lease", "lease", golibvirt.DomainInterfaceAddressesSrcLease, false),
+			Entry("agent", "agent", golibvirt.DomainInterfaceAddressesSrcAgent, false),
+			Entry("arp", "arp", golibvirt.DomainInterfaceAddressesSrcArp, false),
+			Entry("invalid", "dns", golibvirt.DomainInterfaceAddressesSource(0), true),
+		)
+	})
+})
@@ -0,0 +1,37 @@
+package mpcmetrics
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var hostHealthGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Subsystem: MetricsSubsystem,
+	Name:      "host_healthy",
+	Help:      "Whether the SSH server of a static host or dynamic pool instance was reachable when it was last probed",
+}, []string{"platform", "host"})
+
+// RegisterHostHealthMetric registers the host health gauge. Registering it again is a no-op.
+func RegisterHostHealthMetric() error {
+	err := metrics.Registry.Register(hostHealthGauge)
+	if are := (prometheus.AlreadyRegisteredError{}); errors.As(err, &are) {
+		return nil
+	}
+	return err
+}
+
+// SetHostHealth records whether host of platform is healthy.
+func SetHostHealth(platform string, host string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	hostHealthGauge.WithLabelValues(platformLabel(platform), host).Set(value)
+}
+
+// DeleteHostHealth forgets the health of host of platform, once it is no longer probed.
+func DeleteHostHealth(platform string, host string) {
+	hostHealthGauge.DeleteLabelValues(platformLabel(platform), host)
+}
@@ -0,0 +1,43 @@
+package mpcmetrics
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var _ = Describe("Host health metric", func() {
+	const hostHealthMetricName = "multi_platform_controller_host_healthy"
+
+	BeforeEach(func() {
+		Expect(RegisterHostHealthMetric()).Should(Succeed())
+	})
+
+	It("should be safe to register more than once", func() {
+		Expect(RegisterHostHealthMetric()).Should(Succeed())
+	})
+
+	It("should record the health of hosts per platform", func() {
+		SetHostHealth("linux/s390x", "host1", true)
+		Expect(getGaugeValue("linux-s390x", hostHealthMetricName, "")).Should(Equal(1))
+
+		SetHostHealth("linux/s390x", "host1", false)
+		Expect(getGaugeValue("linux-s390x", hostHealthMetricName, "")).Should(Equal(0))
+	})
+
+	It("should forget the health of hosts that are no longer probed", func() {
+		SetHostHealth("linux/ppc64le", "host1", true)
+		DeleteHostHealth("linux/ppc64le", "host1")
+
+		mfs, err := metrics.Registry.Gather()
+		Expect(err).ShouldNot(HaveOccurred())
+		for _, mf := range mfs {
+			if mf.GetName() != hostHealthMetricName {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				Expect(hasLabel(m.Label, "platform", "linux-ppc64le")).Should(BeFalse())
+			}
+		}
+	})
+})
@@ -0,0 +1,43 @@
+package mpcmetrics
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	instanceCacheHitsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: MetricsSubsystem,
+		Name:      "instance_cache_hits",
+		Help:      "The number of cloud instance list and count calls answered from the instance cache",
+	}, []string{"operation"})
+
+	instanceCacheMissesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: MetricsSubsystem,
+		Name:      "instance_cache_misses",
+		Help:      "The number of cloud instance list and count calls that had to call the cloud provider",
+	}, []string{"operation"})
+)
+
+// RegisterInstanceCacheMetrics registers the instance cache hit and miss counters. Registering them again is a no-op.
+func RegisterInstanceCacheMetrics() error {
+	for _, collector := range []prometheus.Collector{instanceCacheHitsCounter, instanceCacheMissesCounter} {
+		err := metrics.Registry.Register(collector)
+		if are := (prometheus.AlreadyRegisteredError{}); err != nil && !errors.As(err, &are) {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountInstanceCacheHit counts a call of operation answered from the instance cache.
+func CountInstanceCacheHit(operation string) {
+	instanceCacheHitsCounter.WithLabelValues(operation).Inc()
+}
+
+// CountInstanceCacheMiss counts a call of operation that had to call the cloud provider.
+func CountInstanceCacheMiss(operation string) {
+	instanceCacheMissesCounter.WithLabelValues(operation).Inc()
+}
@@ -0,0 +1,39 @@
+package mpcmetrics
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Instance cache metrics", func() {
+	const (
+		instanceCacheHitsMetricName   = "multi_platform_controller_instance_cache_hits"
+		instanceCacheMissesMetricName = "multi_platform_controller_instance_cache_misses"
+	)
+
+	BeforeEach(func() {
+		Expect(RegisterInstanceCacheMetrics()).Should(Succeed())
+	})
+
+	It("should be safe to register more than once", func() {
+		Expect(RegisterInstanceCacheMetrics()).Should(Succeed())
+	})
+
+	It("should count cache hits and misses per operation", func() {
+		hitsBefore, err := getCounterValue("list", instanceCacheHitsMetricName)
+		Expect(err).ShouldNot(HaveOccurred())
+		missesBefore, err := getCounterValue("list", instanceCacheMissesMetricName)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		CountInstanceCacheMiss("list")
+		CountInstanceCacheHit("list")
+		CountInstanceCacheHit("list")
+
+		hitsAfter, err := getCounterValue("list", instanceCacheHitsMetricName)
+		Expect(err).ShouldNot(HaveOccurred())
+		missesAfter, err := getCounterValue("list", instanceCacheMissesMetricName)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(hitsAfter - hitsBefore).Should(Equal(2))
+		Expect(missesAfter - missesBefore).Should(Equal(1))
+	})
+})
@@ -0,0 +1,28 @@
+package mpcmetrics
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var reclaimedInstancesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Subsystem: MetricsSubsystem,
+	Name:      "reclaimed_instances",
+	Help:      "The number of orphaned cloud instances terminated by the orphaned instance garbage collector",
+}, []string{"platform"})
+
+// RegisterReclaimedInstancesMetric registers the counter of reclaimed instances. Registering it again is a no-op.
+func RegisterReclaimedInstancesMetric() error {
+	err := metrics.Registry.Register(reclaimedInstancesCounter)
+	if are := (prometheus.AlreadyRegisteredError{}); errors.As(err, &are) {
+		return nil
+	}
+	return err
+}
+
+// CountReclaimedInstance counts an orphaned instance of platform that was terminated.
+func CountReclaimedInstance(platform string) {
+	reclaimedInstancesCounter.WithLabelValues(platformLabel(platform)).Inc()
+}
@@ -0,0 +1,30 @@
+package mpcmetrics
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reclaimed instances metric", func() {
+	const reclaimedInstancesMetricName = "multi_platform_controller_reclaimed_instances"
+
+	BeforeEach(func() {
+		Expect(RegisterReclaimedInstancesMetric()).Should(Succeed())
+	})
+
+	It("should be safe to register more than once", func() {
+		Expect(RegisterReclaimedInstancesMetric()).Should(Succeed())
+	})
+
+	It("should count reclaimed instances per platform", func() {
+		before, err := getCounterValue("linux-arm64", reclaimedInstancesMetricName)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		CountReclaimedInstance("linux/arm64")
+		CountReclaimedInstance("linux/arm64")
+
+		after, err := getCounterValue("linux-arm64", reclaimedInstancesMetricName)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(after - before).Should(Equal(2))
+	})
+})
@@ -23,7 +23,7 @@ var (
 
 // PlatformMetrics set of per-platform metrics
 type PlatformMetrics struct {
-	AllocationTime         prometheus.Histogram
+	AllocationTime         *prometheus.HistogramVec // labelled with the instance type, empty when not known
 	WaitTime               prometheus.Histogram
 	TaskRunTime            prometheus.Histogram
 	ProvisionFailures      prometheus.Counter
@@ -40,12 +40,12 @@ func RegisterPlatformMetrics(_ context.Context, platform string, poolSize int) e
 	}
 	pmetrics := PlatformMetrics{}
 
-	pmetrics.AllocationTime = prometheus.NewHistogram(prometheus.HistogramOpts{
+	pmetrics.AllocationTime = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		ConstLabels: map[string]string{"platform": platform},
 		Subsystem:   MetricsSubsystem,
 		Name:        "host_allocation_time",
 		Help:        "The time in seconds it takes to allocate a host, excluding wait time. In practice this is the amount of time it takes a cloud provider to start an instance",
-		Buckets:     smallBuckets})
+		Buckets:     smallBuckets}, []string{"instance_type"})
 	if err := metrics.Registry.Register(pmetrics.AllocationTime); err != nil {
 		return err
 	}
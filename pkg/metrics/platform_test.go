@@ -112,13 +112,26 @@ var _ = Describe("PlatformMetrics", func() {
 				rnd := rand.Float64()
 				expectedValue = rnd
 				HandleMetrics(platform, func(m *PlatformMetrics) {
-					m.AllocationTime.Observe(rnd)
+					m.AllocationTime.WithLabelValues("").Observe(rnd)
 				})
-				result, err := getHistogramValue(platform, allocationTimeMetricName)
+				result, err := getLabelledHistogramValue(platform, allocationTimeMetricName, "instance_type", "")
 				Expect(err).ToNot(HaveOccurred())
 				Expect(result).To(Equal(expectedValue))
 			})
 
+			It("should label host_allocation metric with the instance type", func() {
+				HandleMetrics(platform, func(m *PlatformMetrics) {
+					m.AllocationTime.WithLabelValues("m6g.large").Observe(2)
+					m.AllocationTime.WithLabelValues("m7g.large").Observe(3)
+				})
+				result, err := getLabelledHistogramValue(platform, allocationTimeMetricName, "instance_type", "m6g.large")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(2.0))
+				result, err = getLabelledHistogramValue(platform, allocationTimeMetricName, "instance_type", "m7g.large")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(3.0))
+			})
+
 			It("should increment wait_time metric", func() {
 				rnd := rand.Float64()
 				expectedValue = rnd
@@ -205,3 +218,20 @@ func getHistogramValue(platform, metricName string) (float64, error) {
 	}
 	return 0, err
 }
+
+func getLabelledHistogramValue(platform, metricName, labelName, labelValue string) (float64, error) {
+	mfs, err := metrics.Registry.Gather()
+	if err != nil {
+		return 0, err
+	}
+	for _, mf := range mfs {
+		if mf.GetName() == metricName {
+			for _, m := range mf.GetMetric() {
+				if m.Histogram != nil && hasLabel(m.Label, "platform", platform) && hasLabel(m.Label, labelName, labelValue) {
+					return *m.Histogram.SampleSum, nil
+				}
+			}
+		}
+	}
+	return 0, err
+}
@@ -0,0 +1,284 @@
+// Package openstack implements methods described in the [cloud] package for interacting with OpenStack
+// instances. Currently only Nova servers are supported.
+//
+// Servers are tagged with the instance tag and TaskRun ID through Nova server metadata and are reached through
+// Neutron floating IPs. All methods of the CloudProvider interface are implemented and separated from other
+// helper functions used across the methods.
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultSshUser = "cloud-user"
+)
+
+// CreateOpenStackCloudConfig returns an OpenStack Nova cloud configuration that implements the CloudProvider interface.
+func CreateOpenStackCloudConfig(platformName string, config map[string]string, systemNamespace string) cloud.CloudProvider {
+	sshUser := config["dynamic."+platformName+".ssh-user"]
+	if sshUser == "" {
+		sshUser = defaultSshUser
+	}
+	privateIp, _ := strconv.ParseBool(config["dynamic."+platformName+".private-ip"])
+
+	return OpenStackDynamicConfig{
+		AuthURL:           config["dynamic."+platformName+".auth-url"],
+		Region:            config["dynamic."+platformName+".region"],
+		Secret:            config["dynamic."+platformName+".openstack-secret"],
+		Flavor:            config["dynamic."+platformName+".flavor"],
+		Image:             config["dynamic."+platformName+".image"],
+		Network:           config["dynamic."+platformName+".network"],
+		FloatingIPNetwork: config["dynamic."+platformName+".floating-ip-network"],
+		SecurityGroups:    splitList(config["dynamic."+platformName+".security-groups"]),
+		KeyName:           config["dynamic."+platformName+".key-name"],
+		AvailabilityZone:  config["dynamic."+platformName+".availability-zone"],
+		UserData:          config["dynamic."+platformName+".user-data"],
+		User:              sshUser,
+		PrivateIP:         privateIp,
+		SystemNamespace:   systemNamespace,
+	}
+}
+
+// LaunchInstance creates a Nova server and returns its identifier, which is the server ID.
+func (op OpenStackDynamicConfig) LaunchInstance(kubeClient client.Client, ctx context.Context, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) (cloud.InstanceIdentifier, error) {
+	err := cloud.ValidateTaskRunID(taskRunID)
+	if err != nil {
+		return "", fmt.Errorf("invalid TaskRun ID: %w", err)
+	}
+	log := logr.FromContextOrDiscard(ctx)
+
+	serverName, err := createInstanceName(instanceTag)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a server name: %w", err)
+	}
+	log.Info("Attempting to launch OpenStack server", "serverName", serverName, "taskRunID", taskRunID)
+
+	openstackClient, err := op.getOpenStackClient(kubeClient, ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create an OpenStack client: %w", err)
+	}
+	server, err := openstackClient.CreateServer(ctx, op.configureServer(serverName, taskRunID, instanceTag, additionalInstanceTags))
+	if err != nil {
+		return "", fmt.Errorf("failed to launch OpenStack server for %s: %w", taskRunID, err)
+	}
+	return cloud.InstanceIdentifier(server.ID), nil
+}
+
+// CountInstances returns the number of OpenStack servers tagged with instanceTag.
+func (op OpenStackDynamicConfig) CountInstances(kubeClient client.Client, ctx context.Context, instanceTag string) (int, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to count OpenStack servers")
+
+	openstackClient, err := op.getOpenStackClient(kubeClient, ctx)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create an OpenStack client: %w", err)
+	}
+	taggedServers, err := listTaggedServers(ctx, openstackClient, instanceTag)
+	if err != nil {
+		log.Error(err, "failed to retrieve OpenStack servers", "instanceTag", instanceTag)
+		return -1, fmt.Errorf("failed to retrieve OpenStack servers tagged with %s: %w", instanceTag, err)
+	}
+	for _, server := range taggedServers {
+		log.Info("Counting instance towards running count", "serverID", server.ID)
+	}
+	return len(taggedServers), nil
+}
+
+// GetInstanceAddress returns the IP address associated with the instanceID OpenStack server. A floating IP
+// address is associated with the server first unless private IP addresses are used. If none is found, an empty
+// string is returned.
+func (op OpenStackDynamicConfig) GetInstanceAddress(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to get OpenStack server's IP address", "instanceID", instanceID)
+
+	openstackClient, err := op.getOpenStackClient(kubeClient, ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create an OpenStack client: %w", err)
+	}
+	server, err := openstackClient.GetServer(ctx, string(instanceID))
+	if err != nil {
+		// This might be a transient error, so only log it
+		log.Error(err, "failed to retrieve instance", "instanceID", instanceID)
+		return "", nil
+	}
+
+	switch server.Status {
+	case serverStatusError, serverStatusDeleted:
+		return "", fmt.Errorf("server %s is in status %s", instanceID, server.Status)
+	case serverStatusActive:
+	default:
+		// The server is still being built
+		return "", nil
+	}
+
+	ip, err := op.assignIPToServer(ctx, openstackClient, server)
+	if err != nil {
+		// This might be a transient error, so only log it
+		log.Error(err, "failed to assign an IP address to instance", "instanceID", instanceID)
+		return "", nil
+	}
+	if ip == "" {
+		return "", nil
+	}
+	if err := op.validateIPAddress(ctx, ip, server.ID); err != nil {
+		// This might be a transient error, so only log it; wait longer for
+		// the instance to be ready
+		return "", nil
+	}
+	return ip, nil
+}
+
+// TerminateInstance tries to delete the instanceID OpenStack server. Floating IP addresses allocated for the
+// server are released; others are disassociated. A server that no longer exists is not an error.
+func (op OpenStackDynamicConfig) TerminateInstance(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) error {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to terminate OpenStack server", "instanceID", instanceID)
+
+	openstackClient, err := op.getOpenStackClient(kubeClient, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create an OpenStack client: %w", err)
+	}
+	if err := releaseFloatingIPs(ctx, openstackClient, string(instanceID)); err != nil {
+		// The server is deleted anyway; Neutron disassociates its floating IPs with its ports
+		log.Error(err, "failed to release the floating IP addresses of instance", "instanceID", instanceID)
+	}
+	err = openstackClient.DeleteServer(ctx, string(instanceID))
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete OpenStack server %s: %w", instanceID, err)
+	}
+	return nil
+}
+
+// ListInstances returns a collection of accessible OpenStack servers tagged with instanceTag.
+func (op OpenStackDynamicConfig) ListInstances(kubeClient client.Client, ctx context.Context, instanceTag string) ([]cloud.CloudVMInstance, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to list OpenStack servers")
+
+	openstackClient, err := op.getOpenStackClient(kubeClient, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create an OpenStack client: %w", err)
+	}
+	taggedServers, err := listTaggedServers(ctx, openstackClient, instanceTag)
+	if err != nil {
+		log.Error(err, "failed to retrieve OpenStack servers", "instanceTag", instanceTag)
+		return nil, fmt.Errorf("failed to retrieve OpenStack servers tagged with %s: %w", instanceTag, err)
+	}
+
+	vmInstances := []cloud.CloudVMInstance{}
+	for _, server := range taggedServers {
+		if server.Status != serverStatusActive {
+			continue
+		}
+		// Only list servers that have an accessible IP
+		ip, err := op.lookupServerAddress(ctx, openstackClient, server.ID)
+		if err != nil || ip == "" {
+			continue
+		}
+		if err := op.validateIPAddress(ctx, ip, server.ID); err != nil {
+			continue
+		}
+		vmInstances = append(vmInstances, cloud.CloudVMInstance{
+			InstanceId: cloud.InstanceIdentifier(server.ID),
+			StartTime:  server.Created,
+			Address:    ip,
+			TaskRunID:  server.Metadata[cloud.TaskRunTagKey],
+		})
+		log.Info("Counting instance towards running count", "serverID", server.ID)
+	}
+	return vmInstances, nil
+}
+
+func (op OpenStackDynamicConfig) SshUser() string {
+	return op.User
+}
+
+// GetState returns instanceID's VM state from OpenStack. A server in the ERROR or DELETED status is in a failed
+// state; see https://docs.openstack.org/api-guide/compute/server_concepts.html for valid statuses.
+func (op OpenStackDynamicConfig) GetState(kubeClient client.Client, ctx context.Context, instanceID cloud.InstanceIdentifier) (cloud.VMState, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	log.Info("Attempting to get OpenStack server's state", "instanceID", instanceID)
+
+	openstackClient, err := op.getOpenStackClient(kubeClient, ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create an OpenStack client: %w", err)
+	}
+	server, err := openstackClient.GetServer(ctx, string(instanceID))
+	if err != nil {
+		if isNotFound(err) {
+			return cloud.FailedState, nil
+		}
+		return "", fmt.Errorf("failed to retrieve instance %s: %w", instanceID, err)
+	}
+	switch server.Status {
+	case serverStatusError, serverStatusDeleted:
+		return cloud.FailedState, nil
+	}
+	return cloud.OKState, nil
+}
+
+// An OpenStackDynamicConfig represents a configuration for an OpenStack Nova server.
+// The zero value (where each field will be assigned its type's zero value) is not a
+// valid OpenStackDynamicConfig.
+type OpenStackDynamicConfig struct {
+	// AuthURL is the URL of the Keystone identity service, e.g. "https://keystone.example.com:5000/v3".
+	AuthURL string
+
+	// Region is the OpenStack region the servers are created in. It may be empty for
+	// single-region clouds.
+	Region string
+
+	// Secret is the name of the Kubernetes secret that contains either an application
+	// credential or a user's password credentials.
+	Secret string
+
+	// SystemNamespace is the name of the Kubernetes namespace where the specified
+	// secrets are stored.
+	SystemNamespace string
+
+	// Flavor is the ID of the Nova flavor of the servers.
+	Flavor string
+
+	// Image is the ID of the Glance image the servers boot from.
+	Image string
+
+	// Network is the ID of the Neutron network the servers are attached to.
+	Network string
+
+	// FloatingIPNetwork is the ID of the external Neutron network floating IP addresses
+	// are allocated from.
+	FloatingIPNetwork string
+
+	// SecurityGroups are the names of the security groups applied to the servers.
+	SecurityGroups []string
+
+	// KeyName is the name of the Nova key pair injected into the servers.
+	KeyName string
+
+	// AvailabilityZone is the optional availability zone the servers are created in.
+	AvailabilityZone string
+
+	// UserData is the cloud-init user data passed to the servers.
+	UserData string
+
+	// User is the SSH user of the servers.
+	User string
+
+	// PrivateIP specifies whether the servers are accessed by their fixed IP address on
+	// Network instead of a floating IP address.
+	PrivateIP bool
+
+	// openstackClient allows tests to inject a mock OpenStack API client.
+	// When nil, getOpenStackClient authenticates against AuthURL with the credentials in Secret.
+	openstackClient openstackAPI
+
+	// pingFunc allows tests to inject a mock for SSH connectivity checks.
+	// When nil, the real pingIPAddress (TCP dial to port 22) is used.
+	pingFunc func(ip string) error
+}
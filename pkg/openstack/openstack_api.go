@@ -0,0 +1,99 @@
+package openstack
+
+import (
+	"context"
+
+	"github.com/gophercloud/gophercloud/v2"
+	gophercloudopenstack "github.com/gophercloud/gophercloud/v2/openstack"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/layer3/floatingips"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
+)
+
+// openstackAPI is the subset of the OpenStack Compute (Nova) and Networking (Neutron) APIs used by this package.
+// gophercloud exposes list operations through pagers, so gophercloudClients adapts the service clients to this
+// interface; tests can substitute a mock.
+type openstackAPI interface {
+	CreateServer(ctx context.Context, opts servers.CreateOptsBuilder) (*servers.Server, error)
+	GetServer(ctx context.Context, serverID string) (*servers.Server, error)
+	ListServers(ctx context.Context, opts servers.ListOpts) ([]servers.Server, error)
+	DeleteServer(ctx context.Context, serverID string) error
+	ListPorts(ctx context.Context, opts ports.ListOpts) ([]ports.Port, error)
+	ListFloatingIPs(ctx context.Context, opts floatingips.ListOpts) ([]floatingips.FloatingIP, error)
+	CreateFloatingIP(ctx context.Context, opts floatingips.CreateOpts) (*floatingips.FloatingIP, error)
+	UpdateFloatingIP(ctx context.Context, floatingIPID string, opts floatingips.UpdateOpts) (*floatingips.FloatingIP, error)
+	DeleteFloatingIP(ctx context.Context, floatingIPID string) error
+}
+
+// gophercloudClients implements openstackAPI with the gophercloud service clients of a single region.
+type gophercloudClients struct {
+	compute *gophercloud.ServiceClient
+	network *gophercloud.ServiceClient
+}
+
+// newGophercloudClients authenticates against Keystone with authOptions and returns the Compute and
+// Networking service clients of region.
+func newGophercloudClients(ctx context.Context, authOptions gophercloud.AuthOptions, region string) (*gophercloudClients, error) {
+	provider, err := gophercloudopenstack.AuthenticatedClient(ctx, authOptions)
+	if err != nil {
+		return nil, err
+	}
+	endpointOpts := gophercloud.EndpointOpts{Region: region}
+	compute, err := gophercloudopenstack.NewComputeV2(provider, endpointOpts)
+	if err != nil {
+		return nil, err
+	}
+	network, err := gophercloudopenstack.NewNetworkV2(provider, endpointOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &gophercloudClients{compute: compute, network: network}, nil
+}
+
+func (c *gophercloudClients) CreateServer(ctx context.Context, opts servers.CreateOptsBuilder) (*servers.Server, error) {
+	return servers.Create(ctx, c.compute, opts, nil).Extract()
+}
+
+func (c *gophercloudClients) GetServer(ctx context.Context, serverID string) (*servers.Server, error) {
+	return servers.Get(ctx, c.compute, serverID).Extract()
+}
+
+func (c *gophercloudClients) ListServers(ctx context.Context, opts servers.ListOpts) ([]servers.Server, error) {
+	pages, err := servers.List(c.compute, opts).AllPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return servers.ExtractServers(pages)
+}
+
+func (c *gophercloudClients) DeleteServer(ctx context.Context, serverID string) error {
+	return servers.Delete(ctx, c.compute, serverID).ExtractErr()
+}
+
+func (c *gophercloudClients) ListPorts(ctx context.Context, opts ports.ListOpts) ([]ports.Port, error) {
+	pages, err := ports.List(c.network, opts).AllPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ports.ExtractPorts(pages)
+}
+
+func (c *gophercloudClients) ListFloatingIPs(ctx context.Context, opts floatingips.ListOpts) ([]floatingips.FloatingIP, error) {
+	pages, err := floatingips.List(c.network, opts).AllPages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return floatingips.ExtractFloatingIPs(pages)
+}
+
+func (c *gophercloudClients) CreateFloatingIP(ctx context.Context, opts floatingips.CreateOpts) (*floatingips.FloatingIP, error) {
+	return floatingips.Create(ctx, c.network, opts).Extract()
+}
+
+func (c *gophercloudClients) UpdateFloatingIP(ctx context.Context, floatingIPID string, opts floatingips.UpdateOpts) (*floatingips.FloatingIP, error) {
+	return floatingips.Update(ctx, c.network, floatingIPID, opts).Extract()
+}
+
+func (c *gophercloudClients) DeleteFloatingIP(ctx context.Context, floatingIPID string) error {
+	return floatingips.Delete(ctx, c.network, floatingIPID).ExtractErr()
+}
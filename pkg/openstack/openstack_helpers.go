@@ -0,0 +1,326 @@
+package openstack
+
+import (
+	"context"
+	// #nosec is added to bypass the golang security scan since the cryptographic
+	// strength doesn't matter here
+	"crypto/md5" //#nosec
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/gophercloud/gophercloud/v2"
+	gophercloudopenstack "github.com/gophercloud/gophercloud/v2/openstack"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/keypairs"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/layer3/floatingips"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	v1 "k8s.io/api/core/v1"
+	types2 "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Nova server statuses; see https://docs.openstack.org/api-guide/compute/server_concepts.html.
+	serverStatusActive  = "ACTIVE"
+	serverStatusError   = "ERROR"
+	serverStatusDeleted = "DELETED"
+
+	// floatingIPDescription marks the floating IP addresses allocated by this controller, which are
+	// released when their server is terminated.
+	floatingIPDescription = "Allocated by multi-platform-controller"
+
+	// maxNameLength is the maximum length of a server name, which is also used as the guest hostname.
+	maxNameLength = 63
+)
+
+// validInstanceTagPattern validates that an instance tag can prefix a server name.
+var validInstanceTagPattern = regexp.MustCompile(`^[a-z][-a-z0-9]*$`)
+
+// pingIPAddress tries to connect to the SSH port on ipAddress with a 60-second timeout.
+// An error is returned if the connection fails.
+func pingIPAddress(ipAddress string) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ipAddress, "22"), 60*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// createInstanceName returns a unique server name in the format <instance_tag>-<hash>. The instance tag is
+// lowercased and truncated so the name is also a valid hostname.
+func createInstanceName(instanceTag string) (string, error) {
+	instanceTag = instanceTagPrefix(instanceTag)
+	if !validInstanceTagPattern.MatchString(instanceTag) {
+		return "", fmt.Errorf("instance tag must start with a letter and contain only alphanumeric characters and hyphens, got: %s", instanceTag)
+	}
+
+	now := time.Now()
+	hashInput := fmt.Sprintf("%s-%d-%d", instanceTag, now.Unix(), now.Nanosecond())
+	// #nosec is added to bypass the golang security scan since the cryptographic
+	// strength doesn't matter here
+	md5Hash := md5.Sum([]byte(hashInput)) //#nosec
+	return fmt.Sprintf("%s-%s", instanceTag, hex.EncodeToString(md5Hash[:])[0:16]), nil
+}
+
+// splitList returns the non-empty, trimmed elements of a comma-separated list.
+func splitList(list string) []string {
+	var elements []string
+	for _, element := range strings.Split(list, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// serverMetadata returns the Nova metadata identifying a server created for taskRunID.
+func serverMetadata(taskRunID string, instanceTag string, additionalInstanceTags map[string]string) map[string]string {
+	metadata := map[string]string{
+		cloud.InstanceTag:   instanceTag,
+		cloud.TaskRunTagKey: taskRunID,
+	}
+	for k, v := range additionalInstanceTags {
+		metadata[k] = v
+	}
+	return metadata
+}
+
+// configureServer returns the Nova options for creating a server called serverName.
+func (op OpenStackDynamicConfig) configureServer(serverName string, taskRunID string, instanceTag string, additionalInstanceTags map[string]string) servers.CreateOptsBuilder {
+	createOpts := servers.CreateOpts{
+		Name:             serverName,
+		ImageRef:         op.Image,
+		FlavorRef:        op.Flavor,
+		SecurityGroups:   op.SecurityGroups,
+		AvailabilityZone: op.AvailabilityZone,
+		Networks:         []servers.Network{{UUID: op.Network}},
+		Metadata:         serverMetadata(taskRunID, instanceTag, additionalInstanceTags),
+	}
+	if op.UserData != "" {
+		createOpts.UserData = []byte(op.UserData)
+	}
+	if op.KeyName == "" {
+		return createOpts
+	}
+	return keypairs.CreateOptsExt{CreateOptsBuilder: createOpts, KeyName: op.KeyName}
+}
+
+// listTaggedServers returns the servers that were created by this controller for instanceTag.
+func listTaggedServers(ctx context.Context, openstackClient openstackAPI, instanceTag string) ([]servers.Server, error) {
+	// Nova cannot filter by metadata, so narrow the list down by name (a regular expression) and
+	// filter by the instance tag client-side
+	allServers, err := openstackClient.ListServers(ctx, servers.ListOpts{Name: "^" + regexp.QuoteMeta(instanceTagPrefix(instanceTag))})
+	if err != nil {
+		return nil, err
+	}
+	var taggedServers []servers.Server
+	for _, server := range allServers {
+		if server.Metadata[cloud.InstanceTag] == instanceTag {
+			taggedServers = append(taggedServers, server)
+		}
+	}
+	return taggedServers, nil
+}
+
+// instanceTagPrefix returns the prefix createInstanceName gives the names of servers for instanceTag.
+func instanceTagPrefix(instanceTag string) string {
+	prefix := strings.ReplaceAll(strings.ToLower(instanceTag), "_", "-")
+	if len(prefix) > maxNameLength-17 {
+		prefix = strings.TrimRight(prefix[:maxNameLength-17], "-")
+	}
+	return prefix
+}
+
+// serverPort returns the server's port on the network. An error is returned if there is none.
+func (op OpenStackDynamicConfig) serverPort(ctx context.Context, openstackClient openstackAPI, serverID string) (ports.Port, error) {
+	serverPorts, err := openstackClient.ListPorts(ctx, ports.ListOpts{DeviceID: serverID, NetworkID: op.Network})
+	if err != nil {
+		return ports.Port{}, fmt.Errorf("failed to retrieve the ports of server %s: %w", serverID, err)
+	}
+	if len(serverPorts) == 0 {
+		return ports.Port{}, fmt.Errorf("server %s has no port on network %s", serverID, op.Network)
+	}
+	return serverPorts[0], nil
+}
+
+// fixedIPv4Address returns the first fixed IPv4 address of port, or an empty string if it has none.
+func fixedIPv4Address(port ports.Port) string {
+	for _, fixedIP := range port.FixedIPs {
+		if ip := net.ParseIP(fixedIP.IPAddress); ip != nil && ip.To4() != nil {
+			return fixedIP.IPAddress
+		}
+	}
+	return ""
+}
+
+// lookupServerAddress returns the address the server is accessed by without changing its network
+// configuration: its fixed IP address if private IP addresses are used, otherwise the floating IP address
+// associated with it. An empty string is returned if the server has no such address yet.
+func (op OpenStackDynamicConfig) lookupServerAddress(ctx context.Context, openstackClient openstackAPI, serverID string) (string, error) {
+	port, err := op.serverPort(ctx, openstackClient, serverID)
+	if err != nil {
+		return "", err
+	}
+	if op.PrivateIP {
+		return fixedIPv4Address(port), nil
+	}
+	associated, err := openstackClient.ListFloatingIPs(ctx, floatingips.ListOpts{PortID: port.ID})
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve the floating IP addresses of server %s: %w", serverID, err)
+	}
+	if len(associated) == 0 {
+		return "", nil
+	}
+	return associated[0].FloatingIP, nil
+}
+
+// assignIPToServer returns the address the server is accessed by. Unless private IP addresses are used, a
+// floating IP address is associated with the server's port if it has none: an available floating IP address on
+// the floating IP network is reused if possible, otherwise a new one is allocated.
+func (op OpenStackDynamicConfig) assignIPToServer(ctx context.Context, openstackClient openstackAPI, server *servers.Server) (string, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	// Use the fixed IP or an already associated floating IP if there is one
+	ip, err := op.lookupServerAddress(ctx, openstackClient, server.ID)
+	if err != nil || ip != "" || op.PrivateIP {
+		return ip, err
+	}
+
+	port, err := op.serverPort(ctx, openstackClient, server.ID)
+	if err != nil {
+		return "", err
+	}
+	available, err := openstackClient.ListFloatingIPs(ctx, floatingips.ListOpts{FloatingNetworkID: op.FloatingIPNetwork})
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve any floating IP addresses: %w", err)
+	}
+	for _, fip := range available {
+		if fip.PortID != "" {
+			continue
+		}
+		// The revision number makes the update fail if another reconciler associated the floating IP
+		// address in the meantime
+		revision := fip.RevisionNumber
+		updated, err := openstackClient.UpdateFloatingIP(ctx, fip.ID, floatingips.UpdateOpts{PortID: &port.ID, RevisionNumber: &revision})
+		if err != nil {
+			log.Error(err, "failed to associate floating IP address, trying the next one", "floatingIP", fip.FloatingIP, "serverID", server.ID)
+			continue
+		}
+		return updated.FloatingIP, nil
+	}
+
+	allocated, err := openstackClient.CreateFloatingIP(ctx, floatingips.CreateOpts{
+		Description:       floatingIPDescription,
+		FloatingNetworkID: op.FloatingIPNetwork,
+		PortID:            port.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to allocate a floating IP address for server %s: %w", server.ID, err)
+	}
+	log.Info("Allocated floating IP address", "floatingIP", allocated.FloatingIP, "serverID", server.ID)
+	return allocated.FloatingIP, nil
+}
+
+// releaseFloatingIPs deletes the floating IP addresses this controller allocated for the server and
+// disassociates any other floating IP addresses, so they can be reused.
+func releaseFloatingIPs(ctx context.Context, openstackClient openstackAPI, serverID string) error {
+	serverPorts, err := openstackClient.ListPorts(ctx, ports.ListOpts{DeviceID: serverID})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the ports of server %s: %w", serverID, err)
+	}
+	var errs []error
+	for _, port := range serverPorts {
+		associated, err := openstackClient.ListFloatingIPs(ctx, floatingips.ListOpts{PortID: port.ID})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, fip := range associated {
+			if fip.Description == floatingIPDescription {
+				err = openstackClient.DeleteFloatingIP(ctx, fip.ID)
+			} else {
+				noPort := ""
+				_, err = openstackClient.UpdateFloatingIP(ctx, fip.ID, floatingips.UpdateOpts{PortID: &noPort})
+			}
+			if err != nil && !isNotFound(err) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateIPAddress verifies SSH connectivity to ip, an address of the server serverID.
+// Returns an error if the server is not reachable via SSH.
+func (op OpenStackDynamicConfig) validateIPAddress(ctx context.Context, ip string, serverID string) error {
+	log := logr.FromContextOrDiscard(ctx)
+
+	ping := pingIPAddress
+	if op.pingFunc != nil {
+		ping = op.pingFunc
+	}
+	if err := ping(ip); err != nil {
+		log.Error(err, "failed to connect to OpenStack server via SSH", "serverID", serverID, "ipAddress", ip)
+		return fmt.Errorf("failed to resolve IP address %s: %w", ip, err)
+	}
+	log.Info("Successfully validated IP address", "serverID", serverID, "ipAddress", ip)
+	return nil
+}
+
+// isNotFound reports whether err is an OpenStack API error for a resource that does not exist.
+func isNotFound(err error) bool {
+	return gophercloud.ResponseCodeIs(err, http.StatusNotFound)
+}
+
+// getOpenStackClient returns the injected mock client if set, otherwise authenticates against
+// Keystone with the credentials in op.Secret.
+func (op OpenStackDynamicConfig) getOpenStackClient(kubeClient client.Client, ctx context.Context) (openstackAPI, error) {
+	if op.openstackClient != nil {
+		return op.openstackClient, nil
+	}
+	authOptions, err := op.authOptions(kubeClient, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newGophercloudClients(ctx, authOptions, op.Region)
+}
+
+// authOptions returns the Keystone credentials in op.Secret: either an application credential
+// ("application-credential-id" and "application-credential-secret") or a user's password credentials
+// ("username", "password", "user-domain-name" and "project-id" or "project-name"). Without a Kubernetes
+// client, the standard OS_* environment variables are used instead.
+func (op OpenStackDynamicConfig) authOptions(kubeClient client.Client, ctx context.Context) (gophercloud.AuthOptions, error) {
+	// Use local environment variables for credentials
+	if kubeClient == nil {
+		return gophercloudopenstack.AuthOptionsFromEnv()
+	}
+
+	s := v1.Secret{}
+	nameSpacedSecret := types2.NamespacedName{Name: op.Secret, Namespace: op.SystemNamespace}
+	if err := kubeClient.Get(ctx, nameSpacedSecret, &s); err != nil {
+		return gophercloud.AuthOptions{}, fmt.Errorf("failed to retrieve the secret %v from the Kubernetes client: %w", nameSpacedSecret, err)
+	}
+	authOptions := gophercloud.AuthOptions{IdentityEndpoint: op.AuthURL, AllowReauth: true}
+	if applicationCredentialID := string(s.Data["application-credential-id"]); applicationCredentialID != "" {
+		authOptions.ApplicationCredentialID = applicationCredentialID
+		authOptions.ApplicationCredentialSecret = string(s.Data["application-credential-secret"])
+		return authOptions, nil
+	}
+	if len(s.Data["username"]) == 0 {
+		return gophercloud.AuthOptions{}, fmt.Errorf("the secret %v has neither an application credential nor a username", nameSpacedSecret)
+	}
+	authOptions.Username = string(s.Data["username"])
+	authOptions.Password = string(s.Data["password"])
+	authOptions.DomainName = string(s.Data["user-domain-name"])
+	authOptions.TenantID = string(s.Data["project-id"])
+	authOptions.TenantName = string(s.Data["project-name"])
+	return authOptions, nil
+}
@@ -0,0 +1,218 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/keypairs"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/layer3/floatingips"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
+)
+
+// mockOpenStackAPI is an in-memory implementation of openstackAPI. Created servers are given a port with a fixed
+// IP address on the first network they are attached to; they stay in the BUILD status until a test changes it.
+type mockOpenStackAPI struct {
+	mu              sync.Mutex
+	Servers         map[string]*servers.Server
+	Ports           map[string]*ports.Port
+	FloatingIPs     map[string]*floatingips.FloatingIP
+	Created         servers.CreateOpts
+	CreatedKeyName  string
+	CreateError     error
+	GetError        error
+	UpdateConflicts map[string]bool
+	next            int
+}
+
+func newMockOpenStackAPI() *mockOpenStackAPI {
+	return &mockOpenStackAPI{
+		Servers:         map[string]*servers.Server{},
+		Ports:           map[string]*ports.Port{},
+		FloatingIPs:     map[string]*floatingips.FloatingIP{},
+		UpdateConflicts: map[string]bool{},
+	}
+}
+
+// notFoundError returns the error gophercloud reports for a missing resource.
+func notFoundError() error {
+	return gophercloud.ErrUnexpectedResponseCode{Actual: http.StatusNotFound, Expected: []int{http.StatusOK}}
+}
+
+// addFloatingIP adds an unassociated floating IP address on network to the mock.
+func (m *mockOpenStackAPI) addFloatingIP(network string, address string, description string) *floatingips.FloatingIP {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next++
+	fip := &floatingips.FloatingIP{
+		ID:                fmt.Sprintf("fip-%d", m.next),
+		FloatingNetworkID: network,
+		FloatingIP:        address,
+		Description:       description,
+		Status:            "DOWN",
+	}
+	m.FloatingIPs[fip.ID] = fip
+	return fip
+}
+
+func (m *mockOpenStackAPI) CreateServer(_ context.Context, opts servers.CreateOptsBuilder) (*servers.Server, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.CreateError != nil {
+		return nil, m.CreateError
+	}
+	m.CreatedKeyName = ""
+	if ext, ok := opts.(keypairs.CreateOptsExt); ok {
+		m.CreatedKeyName = ext.KeyName
+		opts = ext.CreateOptsBuilder
+	}
+	m.Created = opts.(servers.CreateOpts)
+
+	m.next++
+	server := &servers.Server{
+		ID:       fmt.Sprintf("server-%d", m.next),
+		Name:     m.Created.Name,
+		Status:   "BUILD",
+		Created:  time.Now(),
+		Metadata: m.Created.Metadata,
+	}
+	m.Servers[server.ID] = server
+	port := &ports.Port{
+		ID:        "port-" + server.ID,
+		NetworkID: m.Created.Networks.([]servers.Network)[0].UUID,
+		DeviceID:  server.ID,
+		FixedIPs:  []ports.IP{{IPAddress: "fd00::10"}, {IPAddress: fmt.Sprintf("10.0.0.%d", m.next)}},
+	}
+	m.Ports[port.ID] = port
+	created := *server
+	return &created, nil
+}
+
+func (m *mockOpenStackAPI) GetServer(_ context.Context, serverID string) (*servers.Server, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.GetError != nil {
+		return nil, m.GetError
+	}
+	server, ok := m.Servers[serverID]
+	if !ok {
+		return nil, notFoundError()
+	}
+	found := *server
+	return &found, nil
+}
+
+func (m *mockOpenStackAPI) ListServers(_ context.Context, opts servers.ListOpts) ([]servers.Server, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name := regexp.MustCompile(opts.Name)
+	var list []servers.Server
+	for _, server := range m.Servers {
+		if name.MatchString(server.Name) {
+			list = append(list, *server)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockOpenStackAPI) DeleteServer(_ context.Context, serverID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Servers[serverID]; !ok {
+		return notFoundError()
+	}
+	delete(m.Servers, serverID)
+	for id, port := range m.Ports {
+		if port.DeviceID == serverID {
+			delete(m.Ports, id)
+			// Neutron disassociates the floating IPs of deleted ports
+			for _, fip := range m.FloatingIPs {
+				if fip.PortID == id {
+					fip.PortID = ""
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (m *mockOpenStackAPI) ListPorts(_ context.Context, opts ports.ListOpts) ([]ports.Port, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []ports.Port
+	for _, port := range m.Ports {
+		if (opts.DeviceID == "" || port.DeviceID == opts.DeviceID) && (opts.NetworkID == "" || port.NetworkID == opts.NetworkID) {
+			list = append(list, *port)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockOpenStackAPI) ListFloatingIPs(_ context.Context, opts floatingips.ListOpts) ([]floatingips.FloatingIP, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []floatingips.FloatingIP
+	for _, fip := range m.FloatingIPs {
+		if (opts.PortID == "" || fip.PortID == opts.PortID) && (opts.FloatingNetworkID == "" || fip.FloatingNetworkID == opts.FloatingNetworkID) {
+			list = append(list, *fip)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockOpenStackAPI) CreateFloatingIP(_ context.Context, opts floatingips.CreateOpts) (*floatingips.FloatingIP, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.next++
+	fip := &floatingips.FloatingIP{
+		ID:                fmt.Sprintf("fip-%d", m.next),
+		FloatingNetworkID: opts.FloatingNetworkID,
+		FloatingIP:        fmt.Sprintf("203.0.113.%d", m.next),
+		Description:       opts.Description,
+		PortID:            opts.PortID,
+		Status:            "ACTIVE",
+	}
+	m.FloatingIPs[fip.ID] = fip
+	created := *fip
+	return &created, nil
+}
+
+func (m *mockOpenStackAPI) UpdateFloatingIP(_ context.Context, floatingIPID string, opts floatingips.UpdateOpts) (*floatingips.FloatingIP, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fip, ok := m.FloatingIPs[floatingIPID]
+	if !ok {
+		return nil, notFoundError()
+	}
+	if m.UpdateConflicts[floatingIPID] || (opts.RevisionNumber != nil && *opts.RevisionNumber != fip.RevisionNumber) {
+		return nil, gophercloud.ErrUnexpectedResponseCode{Actual: http.StatusPreconditionFailed, Expected: []int{http.StatusOK}}
+	}
+	if opts.PortID != nil {
+		fip.PortID = *opts.PortID
+	}
+	fip.RevisionNumber++
+	updated := *fip
+	return &updated, nil
+}
+
+func (m *mockOpenStackAPI) DeleteFloatingIP(_ context.Context, floatingIPID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.FloatingIPs[floatingIPID]; !ok {
+		return notFoundError()
+	}
+	delete(m.FloatingIPs, floatingIPID)
+	return nil
+}
+
+// setStatus sets the status of the server serverID.
+func (m *mockOpenStackAPI) setStatus(serverID string, status string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Servers[serverID].Status = status
+}
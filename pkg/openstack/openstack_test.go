@@ -0,0 +1,349 @@
+package openstack
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/servers"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	systemNamespace = "multi-platform-controller"
+	network         = "private-net"
+	externalNetwork = "public-net"
+)
+
+var _ = Describe("OpenStack Unit Test Suite", func() {
+
+	Describe("Testing CreateOpenStackCloudConfig", func() {
+
+		DescribeTable("Testing the creation of OpenStackDynamicConfig properly no matter the values",
+			func(testConfig map[string]string, expectedSecurityGroups []string, expectedUser string, expectedPrivateIP bool) {
+				config := map[string]string{
+					"dynamic.linux-arm64.auth-url":            "https://keystone.example.com:5000/v3",
+					"dynamic.linux-arm64.region":              "RegionOne",
+					"dynamic.linux-arm64.openstack-secret":    "openstack-credentials",
+					"dynamic.linux-arm64.flavor":              "m1.large",
+					"dynamic.linux-arm64.image":               "fedora-42-aarch64",
+					"dynamic.linux-arm64.network":             network,
+					"dynamic.linux-arm64.floating-ip-network": externalNetwork,
+					"dynamic.linux-arm64.security-groups":     testConfig["security-groups"],
+					"dynamic.linux-arm64.ssh-user":            testConfig["ssh-user"],
+					"dynamic.linux-arm64.private-ip":          testConfig["private-ip"],
+				}
+				provider := CreateOpenStackCloudConfig("linux-arm64", config, systemNamespace)
+				Expect(provider).ToNot(BeNil())
+				providerConfig := provider.(OpenStackDynamicConfig)
+
+				Expect(providerConfig.AuthURL).To(Equal("https://keystone.example.com:5000/v3"))
+				Expect(providerConfig.Region).To(Equal("RegionOne"))
+				Expect(providerConfig.Secret).To(Equal("openstack-credentials"))
+				Expect(providerConfig.Flavor).To(Equal("m1.large"))
+				Expect(providerConfig.Image).To(Equal("fedora-42-aarch64"))
+				Expect(providerConfig.Network).To(Equal(network))
+				Expect(providerConfig.FloatingIPNetwork).To(Equal(externalNetwork))
+				Expect(providerConfig.SystemNamespace).To(Equal(systemNamespace))
+				Expect(providerConfig.SecurityGroups).To(Equal(expectedSecurityGroups))
+				Expect(providerConfig.SshUser()).To(Equal(expectedUser))
+				Expect(providerConfig.PrivateIP).To(Equal(expectedPrivateIP))
+			},
+			Entry("Positive - valid config map keys",
+				map[string]string{"security-groups": "default, ssh ,", "ssh-user": "fedora", "private-ip": "true"},
+				[]string{"default", "ssh"}, "fedora", true),
+			Entry("Negative - missing config data",
+				map[string]string{},
+				nil, defaultSshUser, false),
+			Entry("Negative - invalid private-ip value",
+				map[string]string{"private-ip": "maybe"},
+				nil, defaultSshUser, false),
+		)
+	})
+
+	Describe("CloudProvider methods", func() {
+		var (
+			mockAPI *mockOpenStackAPI
+			cfg     OpenStackDynamicConfig
+			ctx     context.Context
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			mockAPI = newMockOpenStackAPI()
+			cfg = OpenStackDynamicConfig{
+				Flavor:            "m1.large",
+				Image:             "fedora-42-aarch64",
+				Network:           network,
+				FloatingIPNetwork: externalNetwork,
+				User:              defaultSshUser,
+				openstackClient:   mockAPI,
+				pingFunc:          func(string) error { return nil },
+			}
+		})
+
+		// launch creates an active server through LaunchInstance for the given instance tag.
+		launch := func(instanceTag string) cloud.InstanceIdentifier {
+			id, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", instanceTag, map[string]string{})
+			Expect(err).ToNot(HaveOccurred())
+			mockAPI.setStatus(string(id), serverStatusActive)
+			return id
+		}
+
+		Describe("LaunchInstance", func() {
+			It("should create a server tagged through its metadata", func() {
+				cfg.SecurityGroups = []string{"default", "ssh"}
+				cfg.UserData = "#cloud-config"
+				id, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{"cost-center": "konflux"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mockAPI.Servers).To(HaveKey(string(id)))
+
+				created := mockAPI.Created
+				Expect(created.Name).To(HavePrefix("prod-arm64-"))
+				Expect(created.FlavorRef).To(Equal("m1.large"))
+				Expect(created.ImageRef).To(Equal("fedora-42-aarch64"))
+				Expect(created.SecurityGroups).To(Equal([]string{"default", "ssh"}))
+				Expect(created.Networks).To(Equal([]servers.Network{{UUID: network}}))
+				Expect(string(created.UserData)).To(Equal("#cloud-config"))
+				Expect(created.Metadata).To(Equal(map[string]string{
+					cloud.InstanceTag:   "prod-arm64",
+					cloud.TaskRunTagKey: "test-namespace:test-taskrun",
+					"cost-center":       "konflux",
+				}))
+				Expect(mockAPI.CreatedKeyName).To(BeEmpty())
+			})
+
+			It("should inject the configured key pair", func() {
+				cfg.KeyName = "builder"
+				launch("prod-arm64")
+				Expect(mockAPI.CreatedKeyName).To(Equal("builder"))
+			})
+
+			It("should fail when Nova rejects the server", func() {
+				mockAPI.CreateError = errors.New("quota exceeded")
+				_, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{})
+				Expect(err).To(MatchError(ContainSubstring("quota exceeded")))
+			})
+
+			It("should reject an invalid TaskRun ID", func() {
+				_, err := cfg.LaunchInstance(nil, ctx, "invalid-id", "prod-arm64", map[string]string{})
+				Expect(err).To(MatchError(ContainSubstring("invalid TaskRun ID")))
+			})
+		})
+
+		Describe("CountInstances", func() {
+			It("should only count servers with the instance tag", func() {
+				launch("prod-arm64")
+				launch("prod-arm64")
+				launch("prod-arm64-m2xlarge")
+				launch("other-amd64")
+
+				Expect(cfg.CountInstances(nil, ctx, "prod-arm64")).To(Equal(2))
+			})
+		})
+
+		Describe("GetInstanceAddress", func() {
+			It("should reuse an available floating IP address", func() {
+				mockAPI.addFloatingIP(externalNetwork, "198.51.100.10", "")
+				mockAPI.addFloatingIP("other-net", "198.51.100.20", "")
+				id := launch("prod-arm64")
+
+				Expect(cfg.GetInstanceAddress(nil, ctx, id)).To(Equal("198.51.100.10"))
+				Expect(mockAPI.FloatingIPs).To(HaveLen(2))
+				// The associated address is returned on subsequent calls
+				Expect(cfg.GetInstanceAddress(nil, ctx, id)).To(Equal("198.51.100.10"))
+			})
+
+			It("should skip a floating IP address associated concurrently", func() {
+				taken := mockAPI.addFloatingIP(externalNetwork, "198.51.100.10", "")
+				mockAPI.UpdateConflicts[taken.ID] = true
+				id := launch("prod-arm64")
+
+				address, err := cfg.GetInstanceAddress(nil, ctx, id)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(address).To(HavePrefix("203.0.113."))
+			})
+
+			It("should allocate a floating IP address when none is available", func() {
+				id := launch("prod-arm64")
+
+				address, err := cfg.GetInstanceAddress(nil, ctx, id)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(address).To(HavePrefix("203.0.113."))
+				Expect(mockAPI.FloatingIPs).To(HaveLen(1))
+				for _, fip := range mockAPI.FloatingIPs {
+					Expect(fip.Description).To(Equal(floatingIPDescription))
+					Expect(fip.FloatingNetworkID).To(Equal(externalNetwork))
+				}
+			})
+
+			It("should return the fixed IPv4 address when private IP addresses are used", func() {
+				cfg.PrivateIP = true
+				id := launch("prod-arm64")
+
+				address, err := cfg.GetInstanceAddress(nil, ctx, id)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(address).To(HavePrefix("10.0.0."))
+				Expect(mockAPI.FloatingIPs).To(BeEmpty())
+			})
+
+			It("should return an empty address while the server is being built", func() {
+				id, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cfg.GetInstanceAddress(nil, ctx, id)).To(BeEmpty())
+				Expect(mockAPI.FloatingIPs).To(BeEmpty())
+			})
+
+			It("should fail for a server in the ERROR status", func() {
+				id := launch("prod-arm64")
+				mockAPI.setStatus(string(id), serverStatusError)
+				_, err := cfg.GetInstanceAddress(nil, ctx, id)
+				Expect(err).To(MatchError(ContainSubstring("ERROR")))
+			})
+
+			It("should return an empty address for an unreachable server", func() {
+				cfg.pingFunc = func(string) error { return errors.New("unreachable") }
+				id := launch("prod-arm64")
+				Expect(cfg.GetInstanceAddress(nil, ctx, id)).To(BeEmpty())
+			})
+		})
+
+		Describe("ListInstances", func() {
+			It("should only list active and reachable servers with an address", func() {
+				reachable := launch("prod-arm64")
+				Expect(cfg.GetInstanceAddress(nil, ctx, reachable)).ToNot(BeEmpty())
+				unreachable := launch("prod-arm64")
+				unreachableIP, err := cfg.GetInstanceAddress(nil, ctx, unreachable)
+				Expect(err).ToNot(HaveOccurred())
+				// No floating IP address is associated yet
+				launch("prod-arm64")
+				building, err := cfg.LaunchInstance(nil, ctx, "test-namespace:test-taskrun", "prod-arm64", map[string]string{})
+				Expect(err).ToNot(HaveOccurred())
+				cfg.pingFunc = func(ip string) error {
+					if ip == unreachableIP {
+						return errors.New("unreachable")
+					}
+					return nil
+				}
+
+				instances, err := cfg.ListInstances(nil, ctx, "prod-arm64")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(instances).To(HaveLen(1))
+				Expect(instances[0].InstanceId).To(Equal(reachable))
+				Expect(instances[0].InstanceId).ToNot(Equal(building))
+				Expect(instances[0].StartTime).ToNot(BeZero())
+				Expect(instances[0].TaskRunID).To(Equal("test-namespace:test-taskrun"))
+				// Listing does not associate floating IP addresses
+				Expect(mockAPI.FloatingIPs).To(HaveLen(2))
+			})
+		})
+
+		Describe("GetState", func() {
+			DescribeTable("should map server statuses onto VM states",
+				func(status string, expected cloud.VMState) {
+					id := launch("prod-arm64")
+					mockAPI.setStatus(string(id), status)
+					Expect(cfg.GetState(nil, ctx, id)).To(Equal(expected))
+				},
+				Entry("building", "BUILD", cloud.OKState),
+				Entry("active", "ACTIVE", cloud.OKState),
+				Entry("shut off", "SHUTOFF", cloud.OKState),
+				Entry("error", "ERROR", cloud.FailedState),
+				Entry("deleted", "DELETED", cloud.FailedState),
+			)
+
+			It("should report a server that no longer exists as failed", func() {
+				Expect(cfg.GetState(nil, ctx, "does-not-exist")).To(Equal(cloud.FailedState))
+			})
+
+			It("should return an error when the server cannot be retrieved", func() {
+				id := launch("prod-arm64")
+				mockAPI.GetError = errors.New("service unavailable")
+				_, err := cfg.GetState(nil, ctx, id)
+				Expect(err).To(MatchError(ContainSubstring("service unavailable")))
+			})
+		})
+
+		Describe("TerminateInstance", func() {
+			It("should delete the server and release the floating IP address allocated for it", func() {
+				id := launch("prod-arm64")
+				Expect(cfg.GetInstanceAddress(nil, ctx, id)).ToNot(BeEmpty())
+
+				Expect(cfg.TerminateInstance(nil, ctx, id)).To(Succeed())
+				Expect(mockAPI.Servers).ToNot(HaveKey(string(id)))
+				Expect(mockAPI.FloatingIPs).To(BeEmpty())
+			})
+
+			It("should keep a reused floating IP address for the next server", func() {
+				reused := mockAPI.addFloatingIP(externalNetwork, "198.51.100.10", "")
+				id := launch("prod-arm64")
+				Expect(cfg.GetInstanceAddress(nil, ctx, id)).To(Equal("198.51.100.10"))
+
+				Expect(cfg.TerminateInstance(nil, ctx, id)).To(Succeed())
+				Expect(mockAPI.FloatingIPs).To(HaveKey(reused.ID))
+				Expect(mockAPI.FloatingIPs[reused.ID].PortID).To(BeEmpty())
+			})
+
+			It("should not fail for a server that is already gone", func() {
+				Expect(cfg.TerminateInstance(nil, ctx, "does-not-exist")).To(Succeed())
+			})
+		})
+	})
+
+	Describe("Testing helper functions", func() {
+		It("createInstanceName creates unique, valid names", func() {
+			first, err := createInstanceName("Prod_ARM64")
+			Expect(err).ToNot(HaveOccurred())
+			second, err := createInstanceName("Prod_ARM64")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(first).To(HavePrefix("prod-arm64-"))
+			Expect(first).ToNot(Equal(second))
+
+			long, err := createInstanceName(strings.Repeat("a", 80))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(long)).To(BeNumerically("<=", maxNameLength))
+		})
+
+		It("createInstanceName rejects tags that cannot start a server name", func() {
+			_, err := createInstanceName("1-starts-with-a-digit")
+			Expect(err).To(HaveOccurred())
+		})
+
+		DescribeTable("authOptions reads the credentials from the secret",
+			func(data map[string][]byte, expectErr bool, expectedUser string, expectedApplicationCredential string) {
+				secret := &v1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "openstack-credentials", Namespace: systemNamespace},
+					Data:       data,
+				}
+				kubeClient := fake.NewClientBuilder().WithObjects(secret).Build()
+				cfg := OpenStackDynamicConfig{
+					AuthURL:         "https://keystone.example.com:5000/v3",
+					Secret:          "openstack-credentials",
+					SystemNamespace: systemNamespace,
+				}
+
+				authOptions, err := cfg.authOptions(kubeClient, context.Background())
+				if expectErr {
+					Expect(err).To(HaveOccurred())
+					return
+				}
+				Expect(err).ToNot(HaveOccurred())
+				Expect(authOptions.IdentityEndpoint).To(Equal("https://keystone.example.com:5000/v3"))
+				Expect(authOptions.Username).To(Equal(expectedUser))
+				Expect(authOptions.ApplicationCredentialID).To(Equal(expectedApplicationCredential))
+			},
+			Entry("application credential",
+				map[string][]byte{"application-credential-id": []byte("app-id"), "application-credential-secret": []byte("app-secret")},
+				false, "", "app-id"),
+			Entry("password credentials",
+				map[string][]byte{"username": []byte("builder"), "password": []byte("secret"), "project-name": []byte("konflux"), "user-domain-name": []byte("Default")},
+				false, "builder", ""),
+			Entry("no credentials", map[string][]byte{}, true, "", ""),
+		)
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -14,8 +15,23 @@ import (
 // MockInstance represents a simulated cloud instance for testing purposes.
 type MockInstance struct {
 	cloud.CloudVMInstance
-	taskRun  string
-	statusOK bool
+	taskRun     string
+	statusOK    bool
+	interrupted bool
+}
+
+// mockUnavailableError is a transient failure of the mock cloud provider, which counts towards opening the circuit
+// of its account.
+type mockUnavailableError struct {
+	message string
+}
+
+func (e *mockUnavailableError) Error() string {
+	return e.message
+}
+
+func (e *mockUnavailableError) HTTPStatusCode() int {
+	return http.StatusServiceUnavailable
 }
 
 // MockCloud is a mock implementation of the cloud.CloudProvider interface,
@@ -33,6 +49,7 @@ type MockCloud struct {
 	FailListInstances  bool
 	FailTerminate      bool
 	FailCountInstances bool
+	InstanceDetails    cloud.InstanceDetails
 }
 
 func (m *MockCloud) ListInstances(kubeClient runtimeclient.Client, ctx context.Context, instanceTag string) ([]cloud.CloudVMInstance, error) {
@@ -59,7 +76,7 @@ func (m *MockCloud) SshUser() string {
 
 func (m *MockCloud) LaunchInstance(kubeClient runtimeclient.Client, ctx context.Context, taskRunID string, instanceTag string, additionalTags map[string]string) (cloud.InstanceIdentifier, error) {
 	if m.FailLaunch {
-		return "", errors.New("launch failed")
+		return "", &mockUnavailableError{message: "launch failed"}
 	}
 	m.Running++
 	// Check that taskRunID is the correct format
@@ -71,7 +88,7 @@ func (m *MockCloud) LaunchInstance(kubeClient runtimeclient.Client, ctx context.
 	addr := string(name) + ".host.com"
 	identifier := cloud.InstanceIdentifier(name)
 	newInstance := MockInstance{
-		CloudVMInstance: cloud.CloudVMInstance{InstanceId: identifier, StartTime: time.Now(), Address: addr},
+		CloudVMInstance: cloud.CloudVMInstance{InstanceId: identifier, StartTime: time.Now(), Address: addr, TaskRunID: taskRunID},
 		taskRun:         string(name) + " task run",
 		statusOK:        true,
 	}
@@ -115,12 +132,22 @@ func (m *MockCloud) GetState(kubeClient runtimeclient.Client, ctx context.Contex
 	if !ok {
 		return "", nil
 	}
+	if instance.interrupted {
+		return cloud.InterruptedState, nil
+	}
 	if !instance.statusOK {
 		return cloud.FailedState, nil
 	}
 	return cloud.OKState, nil
 }
 
+func (m *MockCloud) GetInstanceDetails(kubeClient runtimeclient.Client, ctx context.Context, instanceId cloud.InstanceIdentifier) (cloud.InstanceDetails, error) {
+	if _, ok := m.Instances[instanceId]; !ok {
+		return cloud.InstanceDetails{}, fmt.Errorf("instance %s not found", instanceId)
+	}
+	return m.InstanceDetails, nil
+}
+
 // cloudImpl is a global mock implementation of the cloud.CloudProvider interface.
 // It allows tests to simulate cloud instance interactions without making real API calls.
 //
@@ -134,3 +161,70 @@ var cloudImpl MockCloud = MockCloud{Instances: map[cloud.InstanceIdentifier]Mock
 func MockCloudSetup(platform string, data map[string]string, systemnamespace string) cloud.CloudProvider {
 	return &cloudImpl
 }
+
+// MockHibernatingCloud is a MockCloud that can also stop and start its instances. Stopped instances are not listed,
+// but they are still counted.
+type MockHibernatingCloud struct {
+	MockCloud
+	Stopped    map[cloud.InstanceIdentifier]MockInstance
+	StartedIDs []cloud.InstanceIdentifier
+	FailStart  bool
+}
+
+func (m *MockHibernatingCloud) StopInstance(kubeClient runtimeclient.Client, ctx context.Context, instanceId cloud.InstanceIdentifier) error {
+	instance, ok := m.Instances[instanceId]
+	if !ok {
+		return fmt.Errorf("instance %s not found", instanceId)
+	}
+	delete(m.Instances, instanceId)
+	m.Stopped[instanceId] = instance
+	return nil
+}
+
+func (m *MockHibernatingCloud) StartInstance(kubeClient runtimeclient.Client, ctx context.Context, instanceId cloud.InstanceIdentifier) error {
+	if m.FailStart {
+		return errors.New("start failed")
+	}
+	instance, ok := m.Stopped[instanceId]
+	if !ok {
+		return fmt.Errorf("instance %s not found", instanceId)
+	}
+	delete(m.Stopped, instanceId)
+	m.Instances[instanceId] = instance
+	m.StartedIDs = append(m.StartedIDs, instanceId)
+	return nil
+}
+
+func (m *MockHibernatingCloud) ListStoppedInstances(kubeClient runtimeclient.Client, ctx context.Context, instanceTag string) ([]cloud.CloudVMInstance, error) {
+	ret := make([]cloud.CloudVMInstance, 0, len(m.Stopped))
+	for _, v := range m.Stopped {
+		ret = append(ret, v.CloudVMInstance)
+	}
+	return ret, nil
+}
+
+func (m *MockHibernatingCloud) TerminateInstance(kubeClient runtimeclient.Client, ctx context.Context, instance cloud.InstanceIdentifier) error {
+	delete(m.Stopped, instance)
+	return m.MockCloud.TerminateInstance(kubeClient, ctx, instance)
+}
+
+// MockTaskRunReportingCloud is a MockCloud whose listed instances do not include their TaskRun, which is looked up
+// for a single instance instead.
+type MockTaskRunReportingCloud struct {
+	*MockCloud
+	TaskRunIDs map[cloud.InstanceIdentifier]string
+	LookedUp   []cloud.InstanceIdentifier
+}
+
+func (m *MockTaskRunReportingCloud) ListInstances(kubeClient runtimeclient.Client, ctx context.Context, instanceTag string) ([]cloud.CloudVMInstance, error) {
+	instances, err := m.MockCloud.ListInstances(kubeClient, ctx, instanceTag)
+	for i := range instances {
+		instances[i].TaskRunID = ""
+	}
+	return instances, err
+}
+
+func (m *MockTaskRunReportingCloud) GetInstanceTaskRunID(kubeClient runtimeclient.Client, ctx context.Context, instanceId cloud.InstanceIdentifier) (string, error) {
+	m.LookedUp = append(m.LookedUp, instanceId)
+	return m.TaskRunIDs[instanceId], nil
+}
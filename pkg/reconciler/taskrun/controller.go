@@ -1,13 +1,17 @@
 package taskrun
 
 import (
+	mpcmetrics "github.com/konflux-ci/multi-platform-controller/pkg/metrics"
 	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 )
 
-func SetupNewReconcilerWithManager(mgr ctrl.Manager, operatorNamespace string, options controller.Options) error {
-	r := newReconciler(mgr, operatorNamespace)
+func SetupNewReconcilerWithManager(mgr ctrl.Manager, operatorNamespace string, state *SharedState, options controller.Options) error {
+	r := newReconciler(mgr, operatorNamespace, state)
+	if err := mpcmetrics.RegisterInstanceCacheMetrics(); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1.TaskRun{}).
 		WithOptions(options).
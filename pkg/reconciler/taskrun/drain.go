@@ -0,0 +1,142 @@
+package taskrun
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"github.com/konflux-ci/multi-platform-controller/pkg/config"
+	kubecore "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const hostDrainInterval = time.Minute
+
+// SetupHostDrainWithManager adds a runnable that periodically reports the draining static hosts and dynamic pool
+// instances whose running TaskRuns finished as drained, terminating the drained dynamic pool instances.
+func SetupHostDrainWithManager(mgr ctrl.Manager, operatorNamespace string, state *SharedState) error {
+	r := newReconciler(mgr, operatorNamespace, state).(*ReconcileTaskRun)
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		log := ctrl.Log.WithName("host-drain")
+		if ok := mgr.GetCache().WaitForCacheSync(ctx); !ok {
+			return context.Canceled
+		}
+		ctx = logr.NewContext(ctx, log)
+		ticker := time.NewTicker(hostDrainInterval)
+		defer ticker.Stop()
+		drained := map[string]bool{}
+		log.Info("starting host drain")
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info("stopping host drain")
+				return nil
+			case <-ticker.C:
+				if err := r.drainHosts(ctx, drained); err != nil {
+					log.Error(err, "failed draining hosts")
+				}
+			}
+		}
+	}))
+}
+
+// drainHosts reports the draining hosts without assigned TaskRuns as drained with an event on the host-config
+// ConfigMap, and terminates them if they are dynamic pool instances. drained holds the static hosts already reported
+// as drained, so that each is reported once for as long as it is draining.
+func (r *ReconcileTaskRun) drainHosts(ctx context.Context, drained map[string]bool) error {
+	log := logr.FromContextOrDiscard(ctx)
+	cm := kubecore.ConfigMap{}
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: r.operatorNamespace, Name: HostConfig}, &cm); err != nil {
+		return err
+	}
+	hostStates, err := config.ParseHostStates(cm.Annotations[CordonedHosts], cm.Annotations[DrainingHosts])
+	if err != nil {
+		return err
+	}
+	draining := map[string]bool{}
+	for host, state := range hostStates {
+		if state == config.HostStateDraining {
+			draining[host] = true
+		}
+	}
+	for host := range drained {
+		if !draining[host] {
+			delete(drained, host)
+		}
+	}
+	if len(draining) == 0 {
+		return nil
+	}
+
+	// Draining dynamic pool instances are terminated once drained
+	dynamicPoolPlatforms, err := config.ParsePlatformList(cm.Data[DynamicPoolPlatforms], config.PlatformTypeDynamicPool)
+	if err != nil {
+		return fmt.Errorf("could not parse dynamic pool platforms: %w", err)
+	}
+	for _, platform := range dynamicPoolPlatforms {
+		platformConfig, err := r.getPlatformConfig(ctx, platform, r.operatorNamespace)
+		if err != nil {
+			log.Error(err, "skipping dynamic pool platform", "platform", platform)
+			continue
+		}
+		pool, ok := platformConfig.(DynamicHostPool)
+		if !ok {
+			continue
+		}
+		instances, err := pool.cloudProvider.ListInstances(r.client, ctx, pool.instanceTag)
+		if err != nil {
+			log.Error(err, "failed to list the instances of dynamic pool platform", "platform", platform)
+			continue
+		}
+		for _, instance := range instances {
+			host := string(instance.InstanceId)
+			if !draining[host] {
+				continue
+			}
+			assigned, err := isAssigned(r, ctx, instance.InstanceId)
+			if err != nil {
+				log.Error(err, "failed to get TaskRuns of draining instance", "instance", host)
+				continue
+			}
+			if assigned {
+				continue
+			}
+			log.Info("terminating drained instance", "instance", host, "platform", platform)
+			if err := pool.cloudProvider.TerminateInstance(r.client, ctx, instance.InstanceId); err != nil {
+				log.Error(err, "failed to terminate drained instance", "instance", host)
+				r.eventRecorder.Event(&cm, "Warning", "TerminateFailed", fmt.Sprintf("Failed to terminate drained instance %s of platform %s: %v", host, platform, err))
+				continue
+			}
+			r.eventRecorder.Event(&cm, "Normal", "HostDrained", fmt.Sprintf("Instance %s of platform %s is drained and was terminated", host, platform))
+		}
+	}
+
+	// Draining static hosts are left in the configuration until they are removed or back in rotation
+	for key := range cm.Data {
+		k, ok := strings.CutPrefix(key, "host.")
+		if !ok || !strings.HasSuffix(k, ".address") {
+			continue
+		}
+		host := strings.TrimSuffix(k, ".address")
+		if !draining[host] || drained[host] {
+			continue
+		}
+		assigned, err := isAssigned(r, ctx, cloud.InstanceIdentifier(host))
+		if err != nil {
+			log.Error(err, "failed to get TaskRuns of draining host", "host", host)
+			continue
+		}
+		if assigned {
+			continue
+		}
+		log.Info("host is drained", "host", host)
+		r.eventRecorder.Event(&cm, "Normal", "HostDrained", fmt.Sprintf("Host %s is drained and can be taken down for maintenance", host))
+		drained[host] = true
+	}
+	return nil
+}
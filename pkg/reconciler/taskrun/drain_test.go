@@ -0,0 +1,155 @@
+// This file contains the tests for taking static hosts and dynamic pool instances
+// out of rotation, by cordoning or draining them.
+
+package taskrun
+
+import (
+	"time"
+
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	. "github.com/konflux-ci/multi-platform-controller/pkg/constant"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pipelinev1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Host drain", func() {
+
+	var client runtimeclient.Client
+	var reconciler *ReconcileTaskRun
+	var recorder *record.FakeRecorder
+
+	// annotateHostConfig merges annotations into the annotations of the host-config ConfigMap.
+	annotateHostConfig := func(ctx SpecContext, annotations map[string]string) {
+		cm := v1.ConfigMap{}
+		Expect(client.Get(ctx, types.NamespacedName{Namespace: systemNamespace, Name: HostConfig}, &cm)).Should(Succeed())
+		if cm.Annotations == nil {
+			cm.Annotations = map[string]string{}
+		}
+		for k, v := range annotations {
+			cm.Annotations[k] = v
+		}
+		Expect(client.Update(ctx, &cm)).Should(Succeed())
+	}
+
+	// createAssignedTaskRun creates a user TaskRun running on host.
+	createAssignedTaskRun := func(ctx SpecContext, name string, host string) *pipelinev1.TaskRun {
+		tr := &pipelinev1.TaskRun{}
+		tr.Name = name
+		tr.Namespace = userNamespace
+		tr.Labels = map[string]string{AssignedHost: host}
+		Expect(client.Create(ctx, tr)).Should(Succeed())
+		return tr
+	}
+
+	setup := func(objs []runtimeclient.Object) {
+		client, reconciler = setupClientAndReconciler(objs)
+		recorder = record.NewFakeRecorder(10)
+		reconciler.eventRecorder = recorder
+	}
+
+	Describe("of static hosts", func() {
+		BeforeEach(func() {
+			setup(createHostConfig())
+		})
+
+		It("should not allocate cordoned or draining hosts", func(ctx SpecContext) {
+			annotateHostConfig(ctx, map[string]string{CordonedHosts: "host1"})
+			tr := runUserPipeline(ctx, client, reconciler, "test-cordoned")
+			Expect(tr.Labels[AssignedHost]).Should(Equal("host2"))
+
+			annotateHostConfig(ctx, map[string]string{CordonedHosts: "", DrainingHosts: "host2"})
+			tr = runUserPipeline(ctx, client, reconciler, "test-draining")
+			Expect(tr.Labels[AssignedHost]).Should(Equal("host1"))
+		})
+
+		It("should wait for a host when all hosts are out of rotation", func(ctx SpecContext) {
+			annotateHostConfig(ctx, map[string]string{CordonedHosts: "host1", DrainingHosts: "host2"})
+
+			createUserTaskRun(ctx, client, "test-out-of-rotation", "linux/arm64")
+			for range 2 {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-out-of-rotation"}})
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+			tr := getUserTaskRun(ctx, client, "test-out-of-rotation")
+			Expect(tr.Labels[AssignedHost]).Should(BeEmpty())
+			Expect(tr.Labels[WaitingForPlatformLabel]).Should(Equal("linux-arm64"))
+		})
+
+		It("should report draining hosts as drained once their TaskRuns finished", func(ctx SpecContext) {
+			annotateHostConfig(ctx, map[string]string{DrainingHosts: "host1"})
+			tr := createAssignedTaskRun(ctx, "test-running", "host1")
+			drained := map[string]bool{}
+
+			Expect(reconciler.drainHosts(ctx, drained)).Should(Succeed())
+			Expect(recorder.Events).ShouldNot(Receive())
+
+			Expect(client.Delete(ctx, tr)).Should(Succeed())
+			Expect(reconciler.drainHosts(ctx, drained)).Should(Succeed())
+			Expect(recorder.Events).Should(Receive(ContainSubstring("HostDrained")))
+
+			Expect(reconciler.drainHosts(ctx, drained)).Should(Succeed())
+			Expect(recorder.Events).ShouldNot(Receive())
+		})
+
+		It("should not report cordoned hosts as drained", func(ctx SpecContext) {
+			annotateHostConfig(ctx, map[string]string{CordonedHosts: "host1"})
+
+			Expect(reconciler.drainHosts(ctx, map[string]bool{})).Should(Succeed())
+			Expect(recorder.Events).ShouldNot(Receive())
+		})
+
+		It("should reject hosts that are both cordoned and draining", func(ctx SpecContext) {
+			annotateHostConfig(ctx, map[string]string{CordonedHosts: "host1", DrainingHosts: "host1"})
+
+			Expect(reconciler.drainHosts(ctx, map[string]bool{})).Should(MatchError(ContainSubstring("both cordoned and draining")))
+			_, err := reconciler.getPlatformConfig(ctx, "linux/arm64", systemNamespace)
+			Expect(err).Should(MatchError(ContainSubstring("both cordoned and draining")))
+		})
+	})
+
+	Describe("of dynamic pool instances", func() {
+		BeforeEach(func(ctx SpecContext) {
+			setup(createDynamicPoolHostConfig())
+			cloudImpl.Instances = map[cloud.InstanceIdentifier]MockInstance{}
+			for _, id := range []cloud.InstanceIdentifier{"idle", "busy", "cordoned"} {
+				cloudImpl.Instances[id] = MockInstance{CloudVMInstance: cloud.CloudVMInstance{InstanceId: id, Address: string(id) + ".host.com", StartTime: time.Now()}, statusOK: true}
+			}
+			cloudImpl.TerminatedIDs = nil
+			cloudImpl.FailTerminate = false
+			annotateHostConfig(ctx, map[string]string{DrainingHosts: "idle,busy", CordonedHosts: "cordoned"})
+		})
+
+		It("should terminate draining instances once their TaskRuns finished", func(ctx SpecContext) {
+			createAssignedTaskRun(ctx, "test-busy", "busy")
+			createAssignedTaskRun(ctx, "test-cordoned", "cordoned")
+
+			Expect(reconciler.drainHosts(ctx, map[string]bool{})).Should(Succeed())
+			Expect(cloudImpl.TerminatedIDs).Should(ConsistOf(cloud.InstanceIdentifier("idle")))
+			Expect(recorder.Events).Should(Receive(ContainSubstring("HostDrained")))
+		})
+
+		It("should not allocate instances out of rotation", func(ctx SpecContext) {
+			pool, err := reconciler.getPlatformConfig(ctx, "linux/arm64", systemNamespace)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(pool.(DynamicHostPool).hostStates).Should(HaveLen(3))
+
+			cloudImpl.Instances["ready"] = MockInstance{CloudVMInstance: cloud.CloudVMInstance{InstanceId: "ready", Address: "ready.host.com", StartTime: time.Now()}, statusOK: true}
+			tr := runUserPipeline(ctx, client, reconciler, "test-pool")
+			Expect(tr.Labels[AssignedHost]).Should(Equal("ready"))
+		})
+
+		It("should report instances that fail to terminate", func(ctx SpecContext) {
+			cloudImpl.FailTerminate = true
+			defer func() { cloudImpl.FailTerminate = false }()
+
+			Expect(reconciler.drainHosts(ctx, map[string]bool{})).Should(Succeed())
+			Expect(recorder.Events).Should(Receive(ContainSubstring("TerminateFailed")))
+		})
+	})
+})
@@ -27,6 +27,13 @@ type DynamicResolver struct {
 	sudoCommands           string
 	additionalInstanceTags map[string]string
 	eventRecorder          record.EventRecorder
+	// quotaGroup is the quota group the instances of the platform count towards, if any, each with vcpus vCPUs.
+	quotaGroup *quotaGroup
+	vcpus      int
+	// minWarm is the number of booted, unassigned instances kept ready for the platform, which are reclaimed once
+	// they are older than maxAge.
+	minWarm int
+	maxAge  time.Duration
 }
 
 func (r DynamicResolver) Deallocate(taskRun *ReconcileTaskRun, ctx context.Context, tr *v1.TaskRun, secretName string, selectedHost string) error {
@@ -82,7 +89,10 @@ func (r DynamicResolver) Allocate(taskRun *ReconcileTaskRun, ctx context.Context
 		log.Info("Attempting to get instance's IP address", "instance", tr.Annotations[CloudInstanceId])
 		//An instance already exists, so get its IP address
 		address, err := r.GetInstanceAddress(taskRun.client, ctx, cloud.InstanceIdentifier(tr.Annotations[CloudInstanceId]))
-		if err != nil { // A permanent error occurred when fetching the IP address for the VM
+		var circuitErr *cloud.CircuitOpenError
+		if errors.As(err, &circuitErr) { // The cloud provider was not called, so the instance may well be fine
+			return reconcile.Result{}, err
+		} else if err != nil { // A permanent error occurred when fetching the IP address for the VM
 			log.Error(err, "failed to get instance address for cloud host")
 			//Try to delete the instance and unassign it from the TaskRun
 			terr := r.TerminateInstance(taskRun.client, ctx, cloud.InstanceIdentifier(tr.Annotations[CloudInstanceId]))
@@ -97,32 +107,7 @@ func (r DynamicResolver) Allocate(taskRun *ReconcileTaskRun, ctx context.Context
 			}
 			return reconcile.Result{}, err
 		} else if address != "" { // An IP address was successfully retrieved for the the VM
-			tr.Labels[constant.AssignedHost] = tr.Annotations[CloudInstanceId]
-			tr.Annotations[CloudAddress] = address
-			err := UpdateTaskRunWithRetry(ctx, taskRun.client, taskRun.apiReader, tr)
-			if err != nil {
-				return reconcile.Result{}, err
-			}
-			message := fmt.Sprintf("starting %s provisioning task for %s", r.instanceTag, tr.Name)
-			log.Info(message)
-			r.eventRecorder.Event(tr, "Normal", "Provisioning", message)
-			err = launchProvisioningTask(taskRun, ctx, tr, secretName, r.sshSecret, address, r.SshUser(), r.platform, r.sudoCommands)
-			if err != nil {
-				//Try to delete the instance and unassign it from the TaskRun
-				terr := r.TerminateInstance(taskRun.client, ctx, cloud.InstanceIdentifier(tr.Annotations[CloudInstanceId]))
-				if terr != nil {
-					message := fmt.Sprintf("failed to terminate %s instance for %s", r.instanceTag, tr.Name)
-					log.Error(terr, message)
-				}
-				unassignErr := r.removeInstanceFromTask(taskRun, ctx, tr)
-				if unassignErr != nil {
-					log.Error(unassignErr, "failed to unassign instance from task after provisioning failure")
-				} else {
-					log.Error(err, "failed to provision cloud host")
-				}
-				return reconcile.Result{}, err
-			}
-			return reconcile.Result{}, nil
+			return r.startProvisioning(taskRun, ctx, tr, secretName, address)
 		} else { // A transient error (that wasn't returned) occurred when fetching the IP address for the VM
 			state, err := r.GetState(taskRun.client, ctx, cloud.InstanceIdentifier(tr.Annotations[CloudInstanceId]))
 			requeueTime := time.Duration(r.checkInterval) * time.Second
@@ -146,11 +131,46 @@ func (r DynamicResolver) Allocate(taskRun *ReconcileTaskRun, ctx context.Context
 					msg := fmt.Sprintf("failed to unassign instance %s from task after instance termination", r.instanceTag)
 					log.Error(unassignErr, msg)
 				}
+			} else if state == cloud.InterruptedState { //VM was reclaimed by the cloud provider; fail the task with a clear error instead of waiting for the timeout
+				message := fmt.Sprintf("%s instance %s for %s was interrupted by the cloud provider", r.instanceTag, tr.Annotations[CloudInstanceId], tr.Name)
+				log.Info(message)
+				r.eventRecorder.Event(tr, "Warning", "Interrupted", message)
+				terr := r.TerminateInstance(taskRun.client, ctx, cloud.InstanceIdentifier(tr.Annotations[CloudInstanceId]))
+				if terr != nil {
+					message := fmt.Sprintf("failed to terminate %s instance for %s", r.instanceTag, tr.Name)
+					r.eventRecorder.Event(tr, "Normal", "TerminateFailed", message)
+					log.Error(terr, message)
+				}
+				unassignErr := r.removeInstanceFromTask(taskRun, ctx, tr)
+				if unassignErr != nil {
+					log.Error(unassignErr, "failed to unassign instance from task after instance interruption")
+				}
+				return reconcile.Result{}, errors.New(message)
 			}
 			//Always try to re-queue the task
 			return reconcile.Result{RequeueAfter: requeueTime}, nil
 		}
 	}
+	// Hand out a warm instance if one is ready, so that the task only waits for provisioning
+	if r.minWarm > 0 {
+		if instance, address := r.claimWarmInstance(taskRun); instance != "" {
+			delete(tr.Labels, constant.WaitingForPlatformLabel)
+			tr.Annotations[AllocationStartTimeAnnotation] = strconv.FormatInt(time.Now().Unix(), 10)
+			tr.Annotations[CloudInstanceId] = string(instance)
+			r.setInstanceDetails(taskRun, ctx, tr, instance)
+			tr.Labels[CloudDynamicPlatform] = platformLabel(r.platform)
+			controllerutil.AddFinalizer(tr, PipelineFinalizer)
+			message := fmt.Sprintf("assigned warm %s instance %s to %s", r.instanceTag, instance, tr.Name)
+			log.Info(message)
+			r.eventRecorder.Event(tr, "Normal", "WarmInstanceAssigned", message)
+			result, err := r.startProvisioning(taskRun, ctx, tr, secretName, address)
+			if err != nil {
+				// The instance was either terminated or not used, so it may be handed out again
+				taskRun.warmInstances.release(instance)
+			}
+			return result, err
+		}
+	}
 	// First check that creating this VM would not exceed the maximum VM platforms configured
 	instanceCount, err := r.CountInstances(taskRun.client, ctx, r.instanceTag)
 	if instanceCount >= r.maxInstances || err != nil {
@@ -160,19 +180,19 @@ func (r DynamicResolver) Allocate(taskRun *ReconcileTaskRun, ctx context.Context
 			return reconcile.Result{}, err
 		}
 		message := fmt.Sprintf("%d of %d maxInstances running for %s, waiting for existing tasks to finish before provisioning for ", instanceCount, r.maxInstances, r.instanceTag)
-		r.eventRecorder.Event(tr, "Warning", "Pending", message)
-		log.Info(message)
-		if tr.Labels[constant.WaitingForPlatformLabel] == platformLabel(r.platform) {
-			//we are already in a waiting state
-			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		return r.waitForCapacity(taskRun, ctx, tr, message)
+	}
+	// Then check that it would not exceed the vCPUs of the quota group shared with other platforms
+	if r.quotaGroup != nil {
+		usedVCPUs, err := r.quotaGroup.usedVCPUs(taskRun, ctx)
+		if err != nil {
+			log.Error(err, "unable to count the vCPUs of the quota group, not launching a new instance out of an abundance of caution")
+			return reconcile.Result{}, err
 		}
-		//no host available
-		//add the waiting label
-		tr.Labels[constant.WaitingForPlatformLabel] = platformLabel(r.platform)
-		if err := UpdateTaskRunWithRetry(ctx, taskRun.client, taskRun.apiReader, tr); err != nil {
-			log.Error(err, "Failed to update task with waiting label. Will retry.")
+		if usedVCPUs+r.vcpus > r.quotaGroup.maxVCPUs {
+			message := fmt.Sprintf("%d of %d vCPUs of quota group %s in use, waiting for existing tasks to finish before provisioning %d vCPUs for %s", usedVCPUs, r.quotaGroup.maxVCPUs, r.quotaGroup.name, r.vcpus, r.instanceTag)
+			return r.waitForCapacity(taskRun, ctx, tr, message)
 		}
-		return reconcile.Result{RequeueAfter: time.Minute}, nil
 	}
 	delete(tr.Labels, constant.WaitingForPlatformLabel)
 	startTime := time.Now().Unix()
@@ -183,7 +203,11 @@ func (r DynamicResolver) Allocate(taskRun *ReconcileTaskRun, ctx context.Context
 	taskRunID := fmt.Sprintf("%s:%s", tr.Namespace, tr.Name)
 	instance, err := r.LaunchInstance(taskRun.client, ctx, taskRunID, r.instanceTag, r.additionalInstanceTags)
 
-	if err != nil {
+	var circuitErr *cloud.CircuitOpenError
+	if errors.As(err, &circuitErr) {
+		// The cloud provider was not called, so this does not count as a launch failure
+		return reconcile.Result{}, err
+	} else if err != nil {
 		launchErr := err
 		//launch failed
 		log.Error(err, "Failed to create cloud host")
@@ -215,6 +239,7 @@ func (r DynamicResolver) Allocate(taskRun *ReconcileTaskRun, ctx context.Context
 
 	// Set the instance ID and platform label, then update with conflict resilience
 	tr.Annotations[CloudInstanceId] = string(instance)
+	r.setInstanceDetails(taskRun, ctx, tr, instance)
 	tr.Labels[CloudDynamicPlatform] = platformLabel(r.platform)
 	//add a finalizer to clean up
 	controllerutil.AddFinalizer(tr, PipelineFinalizer)
@@ -233,10 +258,83 @@ func (r DynamicResolver) Allocate(taskRun *ReconcileTaskRun, ctx context.Context
 	return reconcile.Result{RequeueAfter: 2 * time.Minute}, nil
 }
 
+// setInstanceDetails annotates the task with the details of its instance chosen at launch time, if the cloud provider
+// reports them. The details are informational only, so failing to get them does not fail the allocation.
+func (r DynamicResolver) setInstanceDetails(taskRun *ReconcileTaskRun, ctx context.Context, tr *v1.TaskRun, instance cloud.InstanceIdentifier) {
+	reporter, ok := cloud.DetailsReporter(r.CloudProvider)
+	if !ok {
+		return
+	}
+	details, err := reporter.GetInstanceDetails(taskRun.client, ctx, instance)
+	if err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "failed to get the details of cloud host", "instance", instance)
+		return
+	}
+	if details.InstanceType != "" {
+		tr.Annotations[CloudInstanceType] = details.InstanceType
+	}
+	if details.Image != "" {
+		tr.Annotations[CloudInstanceImage] = details.Image
+	}
+}
+
+// startProvisioning assigns the instance of the task, whose address is known, to the task and launches the task
+// provisioning it. The instance is terminated if the provisioning task cannot be launched.
+func (r DynamicResolver) startProvisioning(taskRun *ReconcileTaskRun, ctx context.Context, tr *v1.TaskRun, secretName string, address string) (reconcile.Result, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	tr.Labels[constant.AssignedHost] = tr.Annotations[CloudInstanceId]
+	tr.Annotations[CloudAddress] = address
+	err := UpdateTaskRunWithRetry(ctx, taskRun.client, taskRun.apiReader, tr)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	message := fmt.Sprintf("starting %s provisioning task for %s", r.instanceTag, tr.Name)
+	log.Info(message)
+	r.eventRecorder.Event(tr, "Normal", "Provisioning", message)
+	err = launchProvisioningTask(taskRun, ctx, tr, secretName, r.sshSecret, address, r.SshUser(), r.platform, r.sudoCommands)
+	if err != nil {
+		//Try to delete the instance and unassign it from the TaskRun
+		terr := r.TerminateInstance(taskRun.client, ctx, cloud.InstanceIdentifier(tr.Annotations[CloudInstanceId]))
+		if terr != nil {
+			message := fmt.Sprintf("failed to terminate %s instance for %s", r.instanceTag, tr.Name)
+			log.Error(terr, message)
+		}
+		unassignErr := r.removeInstanceFromTask(taskRun, ctx, tr)
+		if unassignErr != nil {
+			log.Error(unassignErr, "failed to unassign instance from task after provisioning failure")
+		} else {
+			log.Error(err, "failed to provision cloud host")
+		}
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+// waitForCapacity labels the task as waiting for its platform, so that it is requeued once a task of the platform, or
+// of a platform sharing its quota group, finishes.
+func (r DynamicResolver) waitForCapacity(taskRun *ReconcileTaskRun, ctx context.Context, tr *v1.TaskRun, message string) (reconcile.Result, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	r.eventRecorder.Event(tr, "Warning", "Pending", message)
+	log.Info(message)
+	if tr.Labels[constant.WaitingForPlatformLabel] == platformLabel(r.platform) {
+		//we are already in a waiting state
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	//no host available
+	//add the waiting label
+	tr.Labels[constant.WaitingForPlatformLabel] = platformLabel(r.platform)
+	if err := UpdateTaskRunWithRetry(ctx, taskRun.client, taskRun.apiReader, tr); err != nil {
+		log.Error(err, "Failed to update task with waiting label. Will retry.")
+	}
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
+
 // Tries to remove the instance information from the task and returns a non-nil error if it was unable to.
 func (dr DynamicResolver) removeInstanceFromTask(reconcileTaskRun *ReconcileTaskRun, ctx context.Context, taskRun *v1.TaskRun) error {
 	delete(taskRun.Labels, constant.AssignedHost)
 	delete(taskRun.Annotations, CloudInstanceId)
+	delete(taskRun.Annotations, CloudInstanceType)
+	delete(taskRun.Annotations, CloudInstanceImage)
 	delete(taskRun.Annotations, CloudDynamicPlatform)
 	return UpdateTaskRunWithRetry(ctx, reconcileTaskRun.client, reconcileTaskRun.apiReader, taskRun)
 }
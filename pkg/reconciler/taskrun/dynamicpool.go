@@ -6,16 +6,30 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"github.com/konflux-ci/multi-platform-controller/pkg/config"
 	"github.com/konflux-ci/multi-platform-controller/pkg/constant"
 	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	kubecore "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+const (
+	poolScaleDownInterval = time.Minute
+
+	// pendingStartTimeout is how long a started instance is waited for before another stopped instance of its
+	// platform is started, in case it never becomes ready.
+	pendingStartTimeout = 10 * time.Minute
+)
+
 type DynamicHostPool struct {
 	cloudProvider          cloud.CloudProvider
 	sshSecret              string
@@ -25,9 +39,152 @@ type DynamicHostPool struct {
 	maxAge                 time.Duration
 	instanceTag            string
 	additionalInstanceTags map[string]string
+	// quotaGroup is the quota group the instances of the platform count towards, if any, each with vcpus vCPUs.
+	quotaGroup *quotaGroup
+	vcpus      int
+	// idleTimeout is how long an instance may go without assigned TaskRuns before it is terminated, or 0 to keep
+	// idle instances until they are older than maxAge.
+	idleTimeout time.Duration
+	// hibernate is whether idle instances are stopped rather than terminated, and started again on demand, when the
+	// cloud provider can stop and start its instances.
+	hibernate bool
+	// hostStates are the instances taken out of rotation for maintenance, which no longer receive TaskRuns.
+	hostStates map[string]config.HostState
+}
+
+// hibernator returns the cloud provider of the pool if idle instances are stopped rather than terminated.
+func (a DynamicHostPool) hibernator() (cloud.InstanceHibernator, bool) {
+	if !a.hibernate {
+		return nil, false
+	}
+	return cloud.Hibernator(a.cloudProvider)
+}
+
+// hostActivity tracks when the instances of dynamic pool platforms last had TaskRuns assigned, so that the
+// instances idle for longer than the idle-timeout of their platform are terminated.
+type hostActivity struct {
+	mu       sync.Mutex
+	lastUsed map[cloud.InstanceIdentifier]hostLastUsed
+	// allocations are held for reading while TaskRuns are assigned to the instances of a platform, and for writing
+	// while one of its idle instances is shut down, so that an instance is never shut down as a TaskRun is assigned to
+	// it.
+	allocations map[string]*sync.RWMutex
+}
+
+type hostLastUsed struct {
+	platform string
+	time     time.Time
+}
+
+func newHostActivity() *hostActivity {
+	return &hostActivity{lastUsed: map[cloud.InstanceIdentifier]hostLastUsed{}, allocations: map[string]*sync.RWMutex{}}
+}
+
+// allocationLock returns the lock held while TaskRuns are assigned to the instances of platform.
+func (p *hostActivity) allocationLock(platform string) *sync.RWMutex {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lock, ok := p.allocations[platform]
+	if !ok {
+		lock = &sync.RWMutex{}
+		p.allocations[platform] = lock
+	}
+	return lock
+}
+
+// touch records that instance of platform is in use now.
+func (p *hostActivity) touch(platform string, instance cloud.InstanceIdentifier) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastUsed[instance] = hostLastUsed{platform: platform, time: time.Now()}
+}
+
+// idleFor returns how long instance of platform has not been in use. Instances that were never seen in use are
+// considered idle since they were first seen, as the controller does not know whether they were in use before it
+// started.
+func (p *hostActivity) idleFor(platform string, instance cloud.InstanceIdentifier) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lastUsed, ok := p.lastUsed[instance]
+	if !ok {
+		p.lastUsed[instance] = hostLastUsed{platform: platform, time: time.Now()}
+		return 0
+	}
+	return time.Since(lastUsed.time)
+}
+
+// prune forgets the instances of platform that are not in instances.
+func (p *hostActivity) prune(platform string, instances []cloud.CloudVMInstance) {
+	listed := map[cloud.InstanceIdentifier]bool{}
+	for _, instance := range instances {
+		listed[instance.InstanceId] = true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for instance, lastUsed := range p.lastUsed {
+		if lastUsed.platform == platform && !listed[instance] {
+			delete(p.lastUsed, instance)
+		}
+	}
+}
+
+// instanceStarts holds the stopped instances of dynamic pool platforms that were started, along with their platforms,
+// until they are listed again, so that the TaskRuns waiting for an instance to start do not start another one each time
+// they are requeued.
+type instanceStarts struct {
+	mu      sync.Mutex
+	started map[cloud.InstanceIdentifier]instanceStart
+}
+
+type instanceStart struct {
+	platform string
+	time     time.Time
+}
+
+func newInstanceStarts() *instanceStarts {
+	return &instanceStarts{started: map[cloud.InstanceIdentifier]instanceStart{}}
+}
+
+// add records that instance of platform was started now.
+func (s *instanceStarts) add(platform string, instance cloud.InstanceIdentifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started[instance] = instanceStart{platform: platform, time: time.Now()}
+}
+
+// isPending returns whether an instance of platform was started less than pendingStartTimeout ago and is not listed
+// yet.
+func (s *instanceStarts) isPending(platform string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, started := range s.started {
+		if started.platform == platform && time.Since(started.time) < pendingStartTimeout {
+			return true
+		}
+	}
+	return false
+}
+
+// prune forgets the instances of platform that are in instances, as they are ready, and those started for longer than
+// pendingStartTimeout.
+func (s *instanceStarts) prune(platform string, instances []cloud.CloudVMInstance) {
+	listed := map[cloud.InstanceIdentifier]bool{}
+	for _, instance := range instances {
+		listed[instance.InstanceId] = true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for instance, started := range s.started {
+		if started.platform == platform && (listed[instance] || time.Since(started.time) >= pendingStartTimeout) {
+			delete(s.started, instance)
+		}
+	}
 }
 
-func (a DynamicHostPool) buildHostPool(r *ReconcileTaskRun, ctx context.Context, instanceTag string) (*HostPool, int, error) {
+// buildHostPool builds the pool from the instances of the platform younger than maxAge, terminating the older ones
+// without assigned TaskRuns. When scaleDown is true, the instances without assigned TaskRuns for longer than
+// idleTimeout are terminated as well, or stopped when the pool hibernates them, rather than left in the pool.
+func (a DynamicHostPool) buildHostPool(r *ReconcileTaskRun, ctx context.Context, instanceTag string, scaleDown bool) (*HostPool, int, error) {
 	log := logr.FromContextOrDiscard(ctx)
 	ret := map[string]*Host{}
 	instances, err := a.cloudProvider.ListInstances(r.client, ctx, instanceTag)
@@ -52,15 +209,118 @@ func (a DynamicHostPool) buildHostPool(r *ReconcileTaskRun, ctx context.Context,
 				}
 			}
 		} else {
+			if a.idleTimeout > 0 {
+				idle, err := a.isHostIdle(r, ctx, string(inst.InstanceId))
+				if err != nil {
+					log.Error(err, "unable to check whether instance is idle", "instance", inst.InstanceId)
+				} else if !idle {
+					r.poolHostActivity.touch(a.platform, inst.InstanceId)
+				} else if idleFor := r.poolHostActivity.idleFor(a.platform, inst.InstanceId); scaleDown && idleFor > a.idleTimeout {
+					if a.shutDownIdleInstance(r, ctx, inst.InstanceId, idleFor) {
+						continue
+					}
+				}
+			}
 			log.Info(fmt.Sprintf("found instance %s", inst.InstanceId))
 			ret[string(inst.InstanceId)] = &Host{Name: string(inst.InstanceId), Address: inst.Address, User: a.cloudProvider.SshUser(), Concurrency: a.concurrency, Platform: a.platform, Secret: a.sshSecret, StartTime: &inst.StartTime}
 		}
 	}
-	return &HostPool{hosts: ret, targetPlatform: a.platform}, oldInstanceCount, nil
+	if a.idleTimeout > 0 {
+		r.poolHostActivity.prune(a.platform, instances)
+	}
+	r.pendingStarts.prune(a.platform, instances)
+	if scaleDown {
+		a.terminateOldStoppedInstances(r, ctx, instanceTag)
+	}
+	return &HostPool{hosts: ret, targetPlatform: a.platform, hostStates: a.hostStates}, oldInstanceCount, nil
+}
+
+// shutDownIdleInstance terminates idle instance, or stops it when the pool hibernates its instances, returning
+// whether it did. As a TaskRun may have been assigned to the instance since it was found idle, it is checked again
+// against the API server while no TaskRun is assigned to the instances of the platform.
+func (a DynamicHostPool) shutDownIdleInstance(r *ReconcileTaskRun, ctx context.Context, instance cloud.InstanceIdentifier, idleFor time.Duration) bool {
+	log := logr.FromContextOrDiscard(ctx)
+	lock := r.poolHostActivity.allocationLock(a.platform)
+	lock.Lock()
+	defer lock.Unlock()
+	trs := v1.TaskRunList{}
+	if err := r.apiReader.List(ctx, &trs, client.MatchingLabels{constant.AssignedHost: string(instance)}); err != nil {
+		log.Error(err, "unable to check whether instance is idle", "instance", instance)
+		return false
+	}
+	if len(trs.Items) > 0 {
+		r.poolHostActivity.touch(a.platform, instance)
+		return false
+	}
+	var err error
+	if hibernator, ok := a.hibernator(); ok {
+		log.Info("stopping idle instance", "instance", instance, "idleFor", idleFor.Round(time.Second))
+		err = hibernator.StopInstance(r.client, ctx, instance)
+	} else {
+		log.Info("deallocating idle instance", "instance", instance, "idleFor", idleFor.Round(time.Second))
+		err = a.cloudProvider.TerminateInstance(r.client, ctx, instance)
+	}
+	if err != nil {
+		log.Error(err, "unable to shut down instance", "instance", instance)
+		return false
+	}
+	return true
+}
+
+// terminateOldStoppedInstances terminates the stopped instances of the platform older than maxAge, as they would
+// never be started again.
+func (a DynamicHostPool) terminateOldStoppedInstances(r *ReconcileTaskRun, ctx context.Context, instanceTag string) {
+	hibernator, ok := a.hibernator()
+	if !ok {
+		return
+	}
+	log := logr.FromContextOrDiscard(ctx)
+	stopped, err := hibernator.ListStoppedInstances(r.client, ctx, instanceTag)
+	if err != nil {
+		log.Error(err, "unable to list stopped instances")
+		return
+	}
+	for _, inst := range stopped {
+		if inst.StartTime.Add(a.maxAge).Before(time.Now()) {
+			log.Info("deallocating old stopped instance", "instance", inst.InstanceId)
+			if err := a.cloudProvider.TerminateInstance(r.client, ctx, inst.InstanceId); err != nil {
+				log.Error(err, "unable to shut down instance", "instance", inst.InstanceId)
+			}
+		}
+	}
+}
+
+// startStoppedInstance starts a stopped instance of the platform younger than maxAge unless one is starting already,
+// returning false if the pool does not hibernate its instances or none of them is stopped or starting.
+func (a DynamicHostPool) startStoppedInstance(r *ReconcileTaskRun, ctx context.Context) (bool, error) {
+	hibernator, ok := a.hibernator()
+	if !ok {
+		return false, nil
+	}
+	if r.pendingStarts.isPending(a.platform) {
+		return true, nil
+	}
+	stopped, err := hibernator.ListStoppedInstances(r.client, ctx, a.instanceTag)
+	if err != nil {
+		return false, err
+	}
+	for _, inst := range stopped {
+		if inst.StartTime.Add(a.maxAge).Before(time.Now()) {
+			continue
+		}
+		log := logr.FromContextOrDiscard(ctx)
+		log.Info("starting stopped instance", "instance", inst.InstanceId)
+		if err := hibernator.StartInstance(r.client, ctx, inst.InstanceId); err != nil {
+			return false, err
+		}
+		r.pendingStarts.add(a.platform, inst.InstanceId)
+		return true, nil
+	}
+	return false, nil
 }
 
 func (a DynamicHostPool) Deallocate(r *ReconcileTaskRun, ctx context.Context, tr *v1.TaskRun, secretName string, selectedHost string) error {
-	hostPool, oldInstanceCount, err := a.buildHostPool(r, ctx, a.instanceTag)
+	hostPool, oldInstanceCount, err := a.buildHostPool(r, ctx, a.instanceTag, true)
 	if err != nil {
 		return err
 	}
@@ -68,6 +328,10 @@ func (a DynamicHostPool) Deallocate(r *ReconcileTaskRun, ctx context.Context, tr
 	if err != nil {
 		return err
 	}
+	if a.idleTimeout > 0 && hostPool.hosts[selectedHost] != nil {
+		// The host is idle from now on, unless other tasks are using it
+		r.poolHostActivity.touch(a.platform, cloud.InstanceIdentifier(selectedHost))
+	}
 	if oldInstanceCount > 0 {
 		// Maybe this is an old instance
 		if hostPool.hosts[selectedHost] == nil {
@@ -100,14 +364,21 @@ func (a DynamicHostPool) isHostIdle(r *ReconcileTaskRun, ctx context.Context, se
 
 func (a DynamicHostPool) Allocate(r *ReconcileTaskRun, ctx context.Context, tr *v1.TaskRun, secretName string) (reconcile.Result, error) {
 	log := logr.FromContextOrDiscard(ctx)
-	hostPool, oldInstanceCount, err := a.buildHostPool(r, ctx, a.instanceTag)
+	// Idle instances are kept, so that the task can run on one of them, and are not shut down until it is assigned
+	lock := r.poolHostActivity.allocationLock(a.platform)
+	lock.RLock()
+	hostPool, oldInstanceCount, err := a.buildHostPool(r, ctx, a.instanceTag, false)
 	if err != nil {
+		lock.RUnlock()
 		return reconcile.Result{}, err
 	}
 
 	var allocationErr error
 	if len(hostPool.hosts) > 0 {
 		_, allocationErr = hostPool.Allocate(r, ctx, tr, secretName)
+	}
+	lock.RUnlock()
+	if len(hostPool.hosts) > 0 {
 		if allocationErr != nil && !errors.Is(allocationErr, ErrAllHostsFailed) {
 			log.Error(allocationErr, "could not allocate host from pool")
 			return reconcile.Result{}, allocationErr
@@ -119,6 +390,16 @@ func (a DynamicHostPool) Allocate(r *ReconcileTaskRun, ctx context.Context, tr *
 	}
 	log.Info("could not allocate existing host, attempting to start a new one")
 
+	// Stopped instances are started before new ones are launched, and count towards the total already
+	started, err := a.startStoppedInstance(r, ctx)
+	if err != nil {
+		log.Error(err, "could not start stopped instance, attempting to launch a new one")
+	} else if started {
+		delete(tr.Labels, constant.WaitingForPlatformLabel)
+		// The reconciler will requeue and assign the started instance once it's ready.
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+
 	// Count will handle instances that are not ready yet
 	count, err := a.cloudProvider.CountInstances(r.client, ctx, a.instanceTag)
 	if err != nil {
@@ -131,6 +412,20 @@ func (a DynamicHostPool) Allocate(r *ReconcileTaskRun, ctx context.Context, tr *
 		// Pool is full, and we couldn't allocate. Return the original allocation error.
 		return reconcile.Result{RequeueAfter: time.Minute}, allocationErr
 	}
+	if a.quotaGroup != nil {
+		usedVCPUs, err := a.quotaGroup.usedVCPUs(r, ctx)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if usedVCPUs+a.vcpus > a.quotaGroup.maxVCPUs {
+			if allocationErr != nil {
+				log.Info("cannot provision new instances, quota group is at capacity", "quotaGroup", a.quotaGroup.name, "usedVCPUs", usedVCPUs, "maxVCPUs", a.quotaGroup.maxVCPUs)
+				return reconcile.Result{RequeueAfter: time.Minute}, allocationErr
+			}
+			message := fmt.Sprintf("%d of %d vCPUs of quota group %s in use, waiting for existing tasks to finish before provisioning %d vCPUs for %s", usedVCPUs, a.quotaGroup.maxVCPUs, a.quotaGroup.name, a.vcpus, a.instanceTag)
+			return a.waitForCapacity(r, ctx, tr, message)
+		}
+	}
 	name, err := getRandomString(8)
 	if err != nil {
 		return reconcile.Result{}, err
@@ -152,6 +447,23 @@ func (a DynamicHostPool) Allocate(r *ReconcileTaskRun, ctx context.Context, tr *
 	return reconcile.Result{RequeueAfter: time.Minute}, nil
 }
 
+// waitForCapacity labels tr as waiting for the platform, so that it is requeued as soon as a TaskRun of the platform,
+// or of another platform of its quota group, finishes.
+func (a DynamicHostPool) waitForCapacity(r *ReconcileTaskRun, ctx context.Context, tr *v1.TaskRun, message string) (reconcile.Result, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	r.eventRecorder.Event(tr, "Warning", "Pending", message)
+	log.Info(message)
+	if tr.Labels[constant.WaitingForPlatformLabel] == platformLabel(a.platform) {
+		//we are already in a waiting state
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	tr.Labels[constant.WaitingForPlatformLabel] = platformLabel(a.platform)
+	if err := UpdateTaskRunWithRetry(ctx, r.client, r.apiReader, tr); err != nil {
+		log.Error(err, "Failed to update task with waiting label. Will retry.")
+	}
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
+
 func getRandomString(length int) (string, error) {
 	bytes := make([]byte, length/2+1)
 	if _, err := rand.Read(bytes); err != nil {
@@ -159,3 +471,60 @@ func getRandomString(length int) (string, error) {
 	}
 	return hex.EncodeToString(bytes)[0:length], nil
 }
+
+// SetupDynamicPoolScaleDownWithManager adds a runnable that periodically terminates the instances of dynamic pool
+// platforms that had no TaskRuns assigned for longer than the idle-timeout of their platform, since the pools are
+// otherwise only rebuilt when TaskRuns are allocated or deallocated.
+func SetupDynamicPoolScaleDownWithManager(mgr ctrl.Manager, operatorNamespace string, state *SharedState) error {
+	r := newReconciler(mgr, operatorNamespace, state).(*ReconcileTaskRun)
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		log := ctrl.Log.WithName("dynamic-pool-scale-down")
+		if ok := mgr.GetCache().WaitForCacheSync(ctx); !ok {
+			return context.Canceled
+		}
+		ctx = logr.NewContext(ctx, log)
+		ticker := time.NewTicker(poolScaleDownInterval)
+		defer ticker.Stop()
+		log.Info("starting dynamic pool scale down")
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info("stopping dynamic pool scale down")
+				return nil
+			case <-ticker.C:
+				if err := r.scaleDownDynamicPools(ctx); err != nil {
+					log.Error(err, "failed scaling down dynamic pools")
+				}
+			}
+		}
+	}))
+}
+
+// scaleDownDynamicPools rebuilds the pool of every dynamic pool platform configuring an idle-timeout, terminating its
+// idle instances.
+func (r *ReconcileTaskRun) scaleDownDynamicPools(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+	cm := kubecore.ConfigMap{}
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: r.operatorNamespace, Name: HostConfig}, &cm); err != nil {
+		return err
+	}
+	dynamicPoolPlatforms, err := config.ParsePlatformList(cm.Data[DynamicPoolPlatforms], config.PlatformTypeDynamicPool)
+	if err != nil {
+		return fmt.Errorf("could not parse dynamic pool platforms: %w", err)
+	}
+	for _, platform := range dynamicPoolPlatforms {
+		platformConfig, err := r.getPlatformConfig(ctx, platform, r.operatorNamespace)
+		if err != nil {
+			log.Error(err, "skipping dynamic pool platform", "platform", platform)
+			continue
+		}
+		pool, ok := platformConfig.(DynamicHostPool)
+		if !ok || pool.idleTimeout == 0 {
+			continue
+		}
+		if _, _, err := pool.buildHostPool(r, ctx, pool.instanceTag, true); err != nil {
+			log.Error(err, "failed scaling down dynamic pool", "platform", platform)
+		}
+	}
+	return nil
+}
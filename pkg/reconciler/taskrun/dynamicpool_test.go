@@ -29,7 +29,7 @@ var _ = Describe("DynamicHostPool test", func() {
 		// Initialize the scheme and add the TaskRun type
 		s = runtime.NewScheme()
 		Expect(v1.AddToScheme(s)).Should(Succeed())
-		r = &ReconcileTaskRun{}
+		r = &ReconcileTaskRun{SharedState: NewSharedState()}
 	})
 
 	// basically tests the three scenarios handles by isHostIdle -
@@ -256,4 +256,215 @@ var _ = Describe("DynamicHostPool test", func() {
 			Expect(err).Should(MatchError(ContainSubstring("terminate failed")))
 		})
 	})
+
+	Describe("idle scale down", func() {
+		var mockCloud *MockCloud
+
+		BeforeEach(func() {
+			mockCloud = &MockCloud{Instances: map[cloud.InstanceIdentifier]MockInstance{}}
+			dhp = DynamicHostPool{
+				cloudProvider: mockCloud,
+				platform:      "linux/arm64",
+				maxAge:        time.Hour,
+				idleTimeout:   10 * time.Minute,
+				concurrency:   2,
+				sshSecret:     "test-ssh-secret",
+				instanceTag:   "test-tag",
+			}
+			for _, id := range []cloud.InstanceIdentifier{"idle-host", "busy-host"} {
+				mockCloud.Instances[id] = MockInstance{CloudVMInstance: cloud.CloudVMInstance{InstanceId: id, Address: "1.2.3.4", StartTime: time.Now().Add(-30 * time.Minute)}, statusOK: true}
+			}
+			mockCloud.Running = 2
+			r.client = fake.NewClientBuilder().WithScheme(s).WithObjects(&v1.TaskRun{ObjectMeta: metav1.ObjectMeta{
+				Name: "busy-task", Namespace: "default",
+				Labels: map[string]string{AssignedHost: "busy-host"},
+			}}).Build()
+			r.apiReader = r.client
+		})
+
+		// idleSince marks both hosts as last used at lastUsed.
+		idleSince := func(lastUsed time.Time) {
+			for _, id := range []cloud.InstanceIdentifier{"idle-host", "busy-host"} {
+				r.poolHostActivity.lastUsed[id] = hostLastUsed{platform: dhp.platform, time: lastUsed}
+			}
+		}
+
+		It("should terminate hosts without assigned TaskRuns for longer than the idle timeout", func(ctx SpecContext) {
+			idleSince(time.Now().Add(-20 * time.Minute))
+
+			hostPool, _, err := dhp.buildHostPool(r, ctx, dhp.instanceTag, true)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(mockCloud.TerminatedIDs).Should(ConsistOf(cloud.InstanceIdentifier("idle-host")))
+			Expect(hostPool.hosts).Should(HaveLen(1))
+			Expect(hostPool.hosts).Should(HaveKey("busy-host"))
+			Expect(r.poolHostActivity.idleFor(dhp.platform, "busy-host")).Should(BeNumerically("<", time.Minute))
+		})
+
+		It("should keep idle hosts a TaskRun was assigned to since they were found idle", func(ctx SpecContext) {
+			idleSince(time.Now().Add(-20 * time.Minute))
+			// The TaskRun assigned to the host is not in the cache yet
+			apiServer := r.client
+			r.client = fake.NewClientBuilder().WithScheme(s).Build()
+			Expect(apiServer.Create(ctx, &v1.TaskRun{ObjectMeta: metav1.ObjectMeta{
+				Name: "new-task", Namespace: "default",
+				Labels: map[string]string{AssignedHost: "idle-host"},
+			}})).Should(Succeed())
+
+			hostPool, _, err := dhp.buildHostPool(r, ctx, dhp.instanceTag, true)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(mockCloud.TerminatedIDs).Should(BeEmpty())
+			Expect(hostPool.hosts).Should(HaveKey("idle-host"))
+			Expect(r.poolHostActivity.idleFor(dhp.platform, "idle-host")).Should(BeNumerically("<", time.Minute))
+		})
+
+		It("should keep hosts idle for less than the idle timeout", func(ctx SpecContext) {
+			idleSince(time.Now().Add(-5 * time.Minute))
+
+			hostPool, _, err := dhp.buildHostPool(r, ctx, dhp.instanceTag, true)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(mockCloud.TerminatedIDs).Should(BeEmpty())
+			Expect(hostPool.hosts).Should(HaveLen(2))
+		})
+
+		It("should only start the idle timeout of hosts when they are first seen", func(ctx SpecContext) {
+			_, _, err := dhp.buildHostPool(r, ctx, dhp.instanceTag, true)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(mockCloud.TerminatedIDs).Should(BeEmpty())
+		})
+
+		It("should keep idle hosts for allocation", func(ctx SpecContext) {
+			idleSince(time.Now().Add(-20 * time.Minute))
+
+			hostPool, _, err := dhp.buildHostPool(r, ctx, dhp.instanceTag, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(mockCloud.TerminatedIDs).Should(BeEmpty())
+			Expect(hostPool.hosts).Should(HaveLen(2))
+		})
+
+		It("should forget the hosts that are no longer listed", func(ctx SpecContext) {
+			idleSince(time.Now().Add(-5 * time.Minute))
+			delete(mockCloud.Instances, "idle-host")
+
+			_, _, err := dhp.buildHostPool(r, ctx, dhp.instanceTag, true)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(r.poolHostActivity.lastUsed).ShouldNot(HaveKey(cloud.InstanceIdentifier("idle-host")))
+		})
+	})
+
+	Describe("hibernation", func() {
+		var mockCloud *MockHibernatingCloud
+
+		BeforeEach(func() {
+			mockCloud = &MockHibernatingCloud{
+				MockCloud: MockCloud{Instances: map[cloud.InstanceIdentifier]MockInstance{}},
+				Stopped:   map[cloud.InstanceIdentifier]MockInstance{},
+			}
+			dhp = DynamicHostPool{
+				cloudProvider: mockCloud,
+				platform:      "linux/arm64",
+				maxInstances:  1,
+				maxAge:        time.Hour,
+				idleTimeout:   10 * time.Minute,
+				hibernate:     true,
+				concurrency:   2,
+				sshSecret:     "test-ssh-secret",
+				instanceTag:   "test-tag",
+			}
+			r.client = fake.NewClientBuilder().WithScheme(s).Build()
+			r.apiReader = r.client
+		})
+
+		It("should stop hosts idle for longer than the idle timeout", func(ctx SpecContext) {
+			mockCloud.Instances["idle-host"] = MockInstance{CloudVMInstance: cloud.CloudVMInstance{InstanceId: "idle-host", Address: "1.2.3.4", StartTime: time.Now().Add(-30 * time.Minute)}, statusOK: true}
+			r.poolHostActivity.lastUsed["idle-host"] = hostLastUsed{platform: dhp.platform, time: time.Now().Add(-20 * time.Minute)}
+
+			hostPool, _, err := dhp.buildHostPool(r, ctx, dhp.instanceTag, true)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(hostPool.hosts).Should(BeEmpty())
+			Expect(mockCloud.Stopped).Should(HaveKey(cloud.InstanceIdentifier("idle-host")))
+			Expect(mockCloud.TerminatedIDs).Should(BeEmpty())
+		})
+
+		It("should terminate idle hosts when the pool does not hibernate them", func(ctx SpecContext) {
+			dhp.hibernate = false
+			mockCloud.Instances["idle-host"] = MockInstance{CloudVMInstance: cloud.CloudVMInstance{InstanceId: "idle-host", Address: "1.2.3.4", StartTime: time.Now().Add(-30 * time.Minute)}, statusOK: true}
+			r.poolHostActivity.lastUsed["idle-host"] = hostLastUsed{platform: dhp.platform, time: time.Now().Add(-20 * time.Minute)}
+
+			_, _, err := dhp.buildHostPool(r, ctx, dhp.instanceTag, true)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(mockCloud.Stopped).Should(BeEmpty())
+			Expect(mockCloud.TerminatedIDs).Should(ConsistOf(cloud.InstanceIdentifier("idle-host")))
+		})
+
+		It("should terminate stopped hosts older than the max age", func(ctx SpecContext) {
+			mockCloud.Stopped["old-host"] = MockInstance{CloudVMInstance: cloud.CloudVMInstance{InstanceId: "old-host", StartTime: time.Now().Add(-2 * time.Hour)}}
+			mockCloud.Stopped["young-host"] = MockInstance{CloudVMInstance: cloud.CloudVMInstance{InstanceId: "young-host", StartTime: time.Now().Add(-30 * time.Minute)}}
+
+			_, _, err := dhp.buildHostPool(r, ctx, dhp.instanceTag, true)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(mockCloud.TerminatedIDs).Should(ConsistOf(cloud.InstanceIdentifier("old-host")))
+			Expect(mockCloud.Stopped).Should(HaveKey(cloud.InstanceIdentifier("young-host")))
+		})
+
+		It("should start a stopped host rather than launch a new one", func(ctx SpecContext) {
+			mockCloud.Stopped["stopped-host"] = MockInstance{CloudVMInstance: cloud.CloudVMInstance{InstanceId: "stopped-host", StartTime: time.Now().Add(-30 * time.Minute)}}
+			mockCloud.Running = 1
+			tr := &v1.TaskRun{ObjectMeta: metav1.ObjectMeta{Name: "test-task", Namespace: "default"}}
+
+			result, err := dhp.Allocate(r, ctx, tr, "secret-name")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.RequeueAfter).Should(Equal(time.Minute))
+			Expect(mockCloud.StartedIDs).Should(ConsistOf(cloud.InstanceIdentifier("stopped-host")))
+			Expect(mockCloud.Running).Should(Equal(1))
+		})
+
+		It("should not start another stopped host while one is starting", func(ctx SpecContext) {
+			for _, id := range []cloud.InstanceIdentifier{"stopped-host", "other-stopped-host"} {
+				mockCloud.Stopped[id] = MockInstance{CloudVMInstance: cloud.CloudVMInstance{InstanceId: id, StartTime: time.Now().Add(-30 * time.Minute)}}
+			}
+			mockCloud.Running = 2
+			dhp.maxInstances = 2
+			tr := &v1.TaskRun{ObjectMeta: metav1.ObjectMeta{Name: "test-task", Namespace: "default"}}
+
+			_, err := dhp.Allocate(r, ctx, tr, "secret-name")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(mockCloud.StartedIDs).Should(HaveLen(1))
+			// The started host is not listed until it is ready
+			started := mockCloud.StartedIDs[0]
+			booting := mockCloud.Instances[started]
+			delete(mockCloud.Instances, started)
+
+			result, err := dhp.Allocate(r, ctx, tr, "secret-name")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.RequeueAfter).Should(Equal(time.Minute))
+			Expect(mockCloud.StartedIDs).Should(HaveLen(1))
+
+			// Once it is listed, it is no longer waited for
+			mockCloud.Instances[started] = booting
+			_, _, err = dhp.buildHostPool(r, ctx, dhp.instanceTag, false)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(r.pendingStarts.isPending(dhp.platform)).Should(BeFalse())
+		})
+
+		It("should launch a new host when no host is stopped", func(ctx SpecContext) {
+			tr := &v1.TaskRun{ObjectMeta: metav1.ObjectMeta{Name: "test-task", Namespace: "default"}}
+
+			_, err := dhp.Allocate(r, ctx, tr, "secret-name")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(mockCloud.StartedIDs).Should(BeEmpty())
+			Expect(mockCloud.Running).Should(Equal(1))
+		})
+
+		It("should launch a new host when the stopped host fails to start", func(ctx SpecContext) {
+			mockCloud.Stopped["stopped-host"] = MockInstance{CloudVMInstance: cloud.CloudVMInstance{InstanceId: "stopped-host", StartTime: time.Now().Add(-30 * time.Minute)}}
+			mockCloud.FailStart = true
+			dhp.maxInstances = 2
+			mockCloud.Running = 1
+			tr := &v1.TaskRun{ObjectMeta: metav1.ObjectMeta{Name: "test-task", Namespace: "default"}}
+
+			_, err := dhp.Allocate(r, ctx, tr, "secret-name")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(mockCloud.Running).Should(Equal(2))
+		})
+	})
 })
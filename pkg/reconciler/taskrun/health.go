@@ -0,0 +1,259 @@
+package taskrun
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/config"
+	mpcmetrics "github.com/konflux-ci/multi-platform-controller/pkg/metrics"
+	kubecore "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	hostHealthProbeInterval = time.Minute
+
+	sshPort = "22"
+)
+
+// hostHealthProbes holds the results of probing the SSH servers of static hosts and dynamic pool instances, so that
+// the hosts that failed too many consecutive probes are skipped when hosts are allocated. Hosts that were never
+// probed are healthy.
+type hostHealthProbes struct {
+	mu    sync.Mutex
+	hosts map[string]hostProbeResult
+	// probe checks that the SSH server of the host at address answers within timeout.
+	probe func(ctx context.Context, address string, timeout time.Duration) error
+}
+
+type hostProbeResult struct {
+	platform  string
+	failures  int
+	unhealthy bool
+}
+
+// probedHost is a host to probe along with its platform.
+type probedHost struct {
+	name     string
+	address  string
+	platform string
+}
+
+func newHostHealthProbes() *hostHealthProbes {
+	return &hostHealthProbes{hosts: map[string]hostProbeResult{}, probe: func(ctx context.Context, address string, timeout time.Duration) error {
+		return probeSSH(ctx, net.JoinHostPort(address, sshPort), timeout)
+	}}
+}
+
+// isUnhealthy returns whether host failed too many consecutive probes.
+func (h *hostHealthProbes) isUnhealthy(host string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hosts[host].unhealthy
+}
+
+// record records the result of probing host, which is unhealthy once failureThreshold consecutive probes failed,
+// and returns whether the host is unhealthy and whether it was not before.
+func (h *hostHealthProbes) record(host probedHost, probeErr error, failureThreshold int) (unhealthy bool, changed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	result := h.hosts[host.name]
+	result.platform = host.platform
+	if probeErr == nil {
+		result.failures = 0
+	} else {
+		result.failures++
+	}
+	unhealthy = result.failures >= failureThreshold
+	changed = unhealthy != result.unhealthy
+	result.unhealthy = unhealthy
+	h.hosts[host.name] = result
+	return unhealthy, changed
+}
+
+// prune forgets the hosts that are not in probed, e.g. because they were removed from the configuration or
+// terminated, and returns them along with their platforms.
+func (h *hostHealthProbes) prune(probed []probedHost) map[string]string {
+	listed := map[string]bool{}
+	for _, host := range probed {
+		listed[host.name] = true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pruned := map[string]string{}
+	for host, result := range h.hosts {
+		if !listed[host] {
+			pruned[host] = result.platform
+			delete(h.hosts, host)
+		}
+	}
+	return pruned
+}
+
+// probeSSH checks that an SSH server answers on hostPort within timeout, by reading the identification string SSH
+// servers send to clients once they connect. The prober does not authenticate, so that it does not need the SSH
+// keys of the hosts.
+func probeSSH(ctx context.Context, hostPort string, timeout time.Duration) error {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", hostPort)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	// Servers may send other lines before their identification string
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if strings.HasPrefix(line, "SSH-") {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("no SSH identification string received from %s: %w", hostPort, err)
+		}
+	}
+}
+
+// SetupHostHealthProberWithManager adds a runnable that periodically probes the SSH servers of the static hosts and
+// dynamic pool instances, so that the unreachable ones no longer receive TaskRuns that would fail provisioning. The
+// prober does nothing unless it is enabled in the host-config ConfigMap.
+func SetupHostHealthProberWithManager(mgr ctrl.Manager, operatorNamespace string, state *SharedState) error {
+	r := newReconciler(mgr, operatorNamespace, state).(*ReconcileTaskRun)
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		log := ctrl.Log.WithName("host-health-prober")
+		if ok := mgr.GetCache().WaitForCacheSync(ctx); !ok {
+			return context.Canceled
+		}
+		if err := mpcmetrics.RegisterHostHealthMetric(); err != nil {
+			return err
+		}
+		ctx = logr.NewContext(ctx, log)
+		ticker := time.NewTicker(hostHealthProbeInterval)
+		defer ticker.Stop()
+		log.Info("starting host health prober")
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info("stopping host health prober")
+				return nil
+			case <-ticker.C:
+				if err := r.probeHosts(ctx); err != nil {
+					log.Error(err, "failed probing hosts")
+				}
+			}
+		}
+	}))
+}
+
+// probeHosts probes the SSH server of every static host and dynamic pool instance at once, marking the hosts that
+// failed the configured number of consecutive probes as unhealthy and the others as healthy. Changes in the health of
+// a host are reported as events on the host-config ConfigMap. When the prober is disabled, all hosts are healthy.
+func (r *ReconcileTaskRun) probeHosts(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+	cm := kubecore.ConfigMap{}
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: r.operatorNamespace, Name: HostConfig}, &cm); err != nil {
+		return err
+	}
+	probeConfig, err := config.ParseHostHealthProbeConfig(cm.Data)
+	if err != nil {
+		return err
+	}
+	var hosts []probedHost
+	if probeConfig.Enabled {
+		hosts, err = r.hostsToProbe(ctx, cm.Data)
+		if err != nil {
+			return err
+		}
+	}
+	for host, platform := range r.hostHealth.prune(hosts) {
+		mpcmetrics.DeleteHostHealth(platform, host)
+	}
+
+	probeErrs := make([]error, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeErrs[i] = r.hostHealth.probe(ctx, host.address, probeConfig.Timeout)
+		}()
+	}
+	wg.Wait()
+
+	for i, host := range hosts {
+		unhealthy, changed := r.hostHealth.record(host, probeErrs[i], probeConfig.FailureThreshold)
+		mpcmetrics.SetHostHealth(host.platform, host.name, !unhealthy)
+		if !changed {
+			continue
+		}
+		if unhealthy {
+			log.Info("host is unhealthy, skipping it until it is reachable again", "host", host.name, "platform", host.platform, "error", probeErrs[i].Error())
+			r.eventRecorder.Event(&cm, "Warning", "HostUnhealthy", fmt.Sprintf("Host %s of platform %s failed %d consecutive SSH probes: %v", host.name, host.platform, probeConfig.FailureThreshold, probeErrs[i]))
+		} else {
+			log.Info("host is healthy again", "host", host.name, "platform", host.platform)
+			r.eventRecorder.Event(&cm, "Normal", "HostHealthy", fmt.Sprintf("Host %s of platform %s is reachable over SSH again", host.name, host.platform))
+		}
+	}
+	return nil
+}
+
+// hostsToProbe returns the static hosts configured in data and the instances of its dynamic pool platforms that have
+// an address. Hosts with an invalid configuration and dynamic pool platforms whose instances cannot be listed are
+// skipped.
+func (r *ReconcileTaskRun) hostsToProbe(ctx context.Context, data map[string]string) ([]probedHost, error) {
+	log := logr.FromContextOrDiscard(ctx)
+	var hosts []probedHost
+	hostNames := map[string]bool{}
+	for key := range data {
+		if k, ok := strings.CutPrefix(key, "host."); ok {
+			if pos := strings.LastIndex(k, "."); pos != -1 {
+				hostNames[k[0:pos]] = true
+			}
+		}
+	}
+	for hostName := range hostNames {
+		hostConfig, err := config.ParseStaticHostConfig(data, hostName)
+		if err != nil {
+			log.Error(err, "skipping static host with invalid configuration", "host", hostName)
+			continue
+		}
+		hosts = append(hosts, probedHost{name: hostName, address: hostConfig.Address, platform: hostConfig.Platform})
+	}
+
+	dynamicPoolPlatforms, err := config.ParsePlatformList(data[DynamicPoolPlatforms], config.PlatformTypeDynamicPool)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse dynamic pool platforms: %w", err)
+	}
+	for _, platform := range dynamicPoolPlatforms {
+		platformConfig, err := r.getPlatformConfig(ctx, platform, r.operatorNamespace)
+		if err != nil {
+			log.Error(err, "skipping dynamic pool platform", "platform", platform)
+			continue
+		}
+		pool, ok := platformConfig.(DynamicHostPool)
+		if !ok {
+			continue
+		}
+		instances, err := pool.cloudProvider.ListInstances(r.client, ctx, pool.instanceTag)
+		if err != nil {
+			log.Error(err, "failed to list the instances of dynamic pool platform", "platform", platform)
+			continue
+		}
+		for _, instance := range instances {
+			if instance.Address != "" {
+				hosts = append(hosts, probedHost{name: string(instance.InstanceId), address: instance.Address, platform: platform})
+			}
+		}
+	}
+	return hosts, nil
+}
@@ -0,0 +1,202 @@
+// This file contains the tests for the host health prober, which probes the SSH
+// servers of static hosts and dynamic pool instances so that unreachable hosts
+// are skipped when hosts are allocated.
+
+package taskrun
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	. "github.com/konflux-ci/multi-platform-controller/pkg/constant"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Host health prober", func() {
+
+	var client runtimeclient.Client
+	var reconciler *ReconcileTaskRun
+	var recorder *record.FakeRecorder
+	// unreachable are the addresses whose probes fail, and probed the addresses that were probed.
+	var unreachable map[string]bool
+	var probed []string
+	var probedMu sync.Mutex
+
+	// updateHostConfig merges data into the host-config ConfigMap.
+	updateHostConfig := func(ctx SpecContext, data map[string]string) {
+		cm := v1.ConfigMap{}
+		Expect(client.Get(ctx, types.NamespacedName{Namespace: systemNamespace, Name: HostConfig}, &cm)).Should(Succeed())
+		for k, v := range data {
+			cm.Data[k] = v
+		}
+		Expect(client.Update(ctx, &cm)).Should(Succeed())
+	}
+
+	setup := func(ctx SpecContext, objs []runtimeclient.Object) {
+		client, reconciler = setupClientAndReconciler(objs)
+		recorder = record.NewFakeRecorder(10)
+		reconciler.eventRecorder = recorder
+		unreachable = map[string]bool{}
+		probed = nil
+		reconciler.hostHealth.probe = func(ctx context.Context, address string, timeout time.Duration) error {
+			probedMu.Lock()
+			defer probedMu.Unlock()
+			probed = append(probed, address)
+			if unreachable[address] {
+				return errors.New("connection refused")
+			}
+			return nil
+		}
+		updateHostConfig(ctx, map[string]string{"host-health-probe.enabled": "true", "host-health-probe.failure-threshold": "2"})
+	}
+
+	Describe("of static hosts", func() {
+		BeforeEach(func(ctx SpecContext) {
+			setup(ctx, createHostConfig())
+		})
+
+		It("should mark hosts unhealthy after consecutive failed probes", func(ctx SpecContext) {
+			unreachable["192.0.2.1"] = true
+
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+			Expect(reconciler.hostHealth.isUnhealthy("host1")).Should(BeFalse())
+			Expect(recorder.Events).ShouldNot(Receive())
+
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+			Expect(reconciler.hostHealth.isUnhealthy("host1")).Should(BeTrue())
+			Expect(reconciler.hostHealth.isUnhealthy("host2")).Should(BeFalse())
+			Expect(recorder.Events).Should(Receive(ContainSubstring("HostUnhealthy")))
+		})
+
+		It("should mark unhealthy hosts healthy once they are reachable again", func(ctx SpecContext) {
+			unreachable["192.0.2.1"] = true
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+			Expect(recorder.Events).Should(Receive(ContainSubstring("HostUnhealthy")))
+
+			delete(unreachable, "192.0.2.1")
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+			Expect(reconciler.hostHealth.isUnhealthy("host1")).Should(BeFalse())
+			Expect(recorder.Events).Should(Receive(ContainSubstring("HostHealthy")))
+		})
+
+		It("should forget the health of hosts once it is disabled", func(ctx SpecContext) {
+			unreachable["192.0.2.1"] = true
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+
+			updateHostConfig(ctx, map[string]string{"host-health-probe.enabled": "false"})
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+			Expect(reconciler.hostHealth.isUnhealthy("host1")).Should(BeFalse())
+			Expect(reconciler.hostHealth.hosts).Should(BeEmpty())
+		})
+
+		It("should not allocate unhealthy hosts", func(ctx SpecContext) {
+			unreachable["192.0.2.1"] = true
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+
+			for _, name := range []string{"test-healthy-1", "test-healthy-2"} {
+				tr := runUserPipeline(ctx, client, reconciler, name)
+				Expect(tr.Labels[AssignedHost]).Should(Equal("host2"))
+			}
+		})
+
+		It("should wait for a host when all hosts are unhealthy", func(ctx SpecContext) {
+			unreachable["192.0.2.1"] = true
+			unreachable["192.0.2.2"] = true
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+
+			createUserTaskRun(ctx, client, "test-unhealthy", "linux/arm64")
+			for range 2 {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-unhealthy"}})
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+			tr := getUserTaskRun(ctx, client, "test-unhealthy")
+			Expect(tr.Labels[AssignedHost]).Should(BeEmpty())
+			Expect(tr.Labels[WaitingForPlatformLabel]).Should(Equal("linux-arm64"))
+		})
+	})
+
+	Describe("of dynamic pool instances", func() {
+		BeforeEach(func(ctx SpecContext) {
+			setup(ctx, createDynamicPoolHostConfig())
+			// The instances listed by the prober must not be cached across probes
+			updateHostConfig(ctx, map[string]string{"cloud-api.instance-cache-ttl": "0s"})
+			cloudImpl.Instances = map[cloud.InstanceIdentifier]MockInstance{
+				"ready":   {CloudVMInstance: cloud.CloudVMInstance{InstanceId: "ready", Address: "192.0.2.10", StartTime: time.Now()}, statusOK: true},
+				"booting": {CloudVMInstance: cloud.CloudVMInstance{InstanceId: "booting", StartTime: time.Now()}, statusOK: true},
+			}
+		})
+
+		It("should probe the instances that have an address", func(ctx SpecContext) {
+			unreachable["192.0.2.10"] = true
+
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+			Expect(probed).Should(ConsistOf("192.0.2.10", "192.0.2.10"))
+			Expect(reconciler.hostHealth.isUnhealthy("ready")).Should(BeTrue())
+			Expect(reconciler.hostHealth.hosts).ShouldNot(HaveKey("booting"))
+		})
+
+		It("should forget the instances that are no longer listed", func(ctx SpecContext) {
+			unreachable["192.0.2.10"] = true
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+
+			delete(cloudImpl.Instances, "ready")
+			Expect(reconciler.probeHosts(ctx)).Should(Succeed())
+			Expect(reconciler.hostHealth.isUnhealthy("ready")).Should(BeFalse())
+		})
+	})
+
+	Describe("probeSSH", func() {
+		// serve accepts a single connection on a local port and writes banner to it.
+		serve := func(banner string) string {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ShouldNot(HaveOccurred())
+			DeferCleanup(listener.Close)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				_, _ = conn.Write([]byte(banner))
+			}()
+			return listener.Addr().String()
+		}
+
+		It("should succeed when an SSH server answers", func(ctx SpecContext) {
+			Expect(probeSSH(ctx, serve("SSH-2.0-OpenSSH_9.6\r\n"), time.Second)).Should(Succeed())
+		})
+
+		It("should succeed when an SSH server sends other lines before its identification string", func(ctx SpecContext) {
+			Expect(probeSSH(ctx, serve("Welcome\r\nSSH-2.0-OpenSSH_9.6\r\n"), time.Second)).Should(Succeed())
+		})
+
+		It("should fail when the server is not an SSH server", func(ctx SpecContext) {
+			Expect(probeSSH(ctx, serve("HTTP/1.1 400 Bad Request\r\n"), time.Second)).Should(MatchError(ContainSubstring("no SSH identification string")))
+		})
+
+		It("should fail when nothing listens on the port", func(ctx SpecContext) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ShouldNot(HaveOccurred())
+			address := listener.Addr().String()
+			Expect(listener.Close()).Should(Succeed())
+
+			Expect(probeSSH(ctx, address, time.Second)).ShouldNot(Succeed())
+		})
+	})
+})
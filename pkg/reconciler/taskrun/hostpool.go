@@ -14,6 +14,7 @@ import (
 	"knative.dev/pkg/kmeta"
 
 	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/config"
 	"github.com/konflux-ci/multi-platform-controller/pkg/constant"
 	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	v12 "k8s.io/api/core/v1"
@@ -30,6 +31,8 @@ var ErrAllHostsFailed = errors.New("all available hosts for the platform have fa
 type HostPool struct {
 	hosts          map[string]*Host
 	targetPlatform string
+	// hostStates are the hosts taken out of rotation for maintenance, which no longer receive TaskRuns.
+	hostStates map[string]config.HostState
 }
 
 func (hp HostPool) Allocate(r *ReconcileTaskRun, ctx context.Context, tr *v1.TaskRun, secretName string) (reconcile.Result, error) {
@@ -82,6 +85,15 @@ func (hp HostPool) Allocate(r *ReconcileTaskRun, ctx context.Context, tr *v1.Tas
 
 		// If we've gotten this far, we've found a host for our platform that hasn't failed.
 		allPlatformHostsFailed = false
+		if r.hostHealth.isUnhealthy(k) {
+			// Unhealthy hosts are skipped without being failed, so that the TaskRun waits for them to be reachable again
+			log.Info("ignoring unhealthy host", "host", k, "targetPlatform", hp.targetPlatform)
+			continue
+		}
+		if state := hp.hostStates[k]; state != "" {
+			log.Info("ignoring host out of rotation", "host", k, "targetPlatform", hp.targetPlatform, "state", state)
+			continue
+		}
 		free := v.Concurrency - hostCount[k]
 
 		log.Info("considering host", "host", k, "freeSlots", free)
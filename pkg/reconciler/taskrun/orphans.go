@@ -0,0 +1,201 @@
+package taskrun
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"github.com/konflux-ci/multi-platform-controller/pkg/config"
+	mpcmetrics "github.com/konflux-ci/multi-platform-controller/pkg/metrics"
+	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	kubecore "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8sRuntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const orphanCollectionInterval = 10 * time.Minute
+
+// SetupOrphanedInstanceCollectorWithManager adds a runnable that periodically terminates dynamic platform instances
+// that were leaked, e.g. because the controller crashed before recording the instance on its TaskRun, or because
+// the TaskRun was force-deleted. The collector does nothing unless it is enabled in the host-config ConfigMap.
+func SetupOrphanedInstanceCollectorWithManager(mgr ctrl.Manager, operatorNamespace string, state *SharedState) error {
+	r := newReconciler(mgr, operatorNamespace, state).(*ReconcileTaskRun)
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		log := ctrl.Log.WithName("orphaned-instance-collector")
+		if ok := mgr.GetCache().WaitForCacheSync(ctx); !ok {
+			return context.Canceled
+		}
+		if err := mpcmetrics.RegisterReclaimedInstancesMetric(); err != nil {
+			return err
+		}
+		ctx = logr.NewContext(ctx, log)
+		ticker := time.NewTicker(orphanCollectionInterval)
+		defer ticker.Stop()
+		log.Info("starting orphaned instance collector")
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info("stopping orphaned instance collector")
+				return nil
+			case <-ticker.C:
+				if err := r.collectOrphanedInstances(ctx); err != nil {
+					log.Error(err, "failed collecting orphaned instances")
+				}
+			}
+		}
+	}))
+}
+
+// collectOrphanedInstances lists the instances of every dynamic platform and terminates those whose TaskRun no
+// longer exists, finished more than the grace period ago, or is running on a different instance. The TaskRun of an
+// instance is looked up when its provider does not list it. Instances that are younger than the grace period or
+// that were not launched for a TaskRun are left alone, as are instances of dynamic pool platforms, which outlive
+// the TaskRun they were launched for.
+func (r *ReconcileTaskRun) collectOrphanedInstances(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+	cm := kubecore.ConfigMap{}
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: r.operatorNamespace, Name: HostConfig}, &cm); err != nil {
+		return err
+	}
+	data := cm.Data
+	gcConfig, err := config.ParseOrphanedInstanceGCConfig(data)
+	if err != nil {
+		return err
+	}
+	if !gcConfig.Enabled {
+		return nil
+	}
+
+	dynamicPoolPlatforms, err := config.ParsePlatformList(data[DynamicPoolPlatforms], config.PlatformTypeDynamicPool)
+	if err != nil {
+		return fmt.Errorf("could not parse dynamic pool platforms: %w", err)
+	}
+	poolInstanceTags := map[string]bool{}
+	for _, platform := range dynamicPoolPlatforms {
+		poolConfig, err := config.ParseDynamicPoolPlatformConfig(data, platform)
+		if err != nil {
+			return err
+		}
+		instanceTag := poolConfig.InstanceTag
+		if instanceTag == "" {
+			instanceTag = data[DefaultInstanceTag]
+		}
+		poolInstanceTags[instanceTag] = true
+	}
+
+	dynamicPlatforms, err := config.ParsePlatformList(data[DynamicPlatforms], config.PlatformTypeDynamic)
+	if err != nil {
+		return fmt.Errorf("could not parse dynamic platforms: %w", err)
+	}
+	for _, platform := range dynamicPlatforms {
+		dynamicConfig, err := config.ParseDynamicPlatformConfig(data, platform)
+		if err != nil {
+			log.Error(err, "skipping dynamic platform with invalid configuration", "platform", platform)
+			continue
+		}
+		instanceTag := dynamicConfig.InstanceTag
+		if instanceTag == "" {
+			instanceTag = data[DefaultInstanceTag]
+		}
+		if poolInstanceTags[instanceTag] {
+			log.Info("skipping dynamic platform sharing its instance tag with a dynamic pool platform", "platform", platform, "instanceTag", instanceTag)
+			continue
+		}
+		platformConfigName := strings.ReplaceAll(platform, "/", "-")
+		provider, err := r.newCloudProvider(dynamicConfig.Type, platformConfigName, data)
+		if err != nil {
+			log.Error(err, "skipping dynamic platform", "platform", platform)
+			continue
+		}
+		if err := r.collectOrphanedPlatformInstances(ctx, &cm, gcConfig, provider, platform, instanceTag, dynamicConfig.MinWarm > 0); err != nil {
+			log.Error(err, "failed collecting orphaned instances", "platform", platform)
+		}
+	}
+	return nil
+}
+
+// collectOrphanedPlatformInstances terminates the orphaned instances of a single dynamic platform. Warm instances are
+// left to the warm pool of the platform, which reclaims them once they are older than their max age, unless the
+// platform no longer keeps a warm pool.
+func (r *ReconcileTaskRun) collectOrphanedPlatformInstances(ctx context.Context, hostConfig *kubecore.ConfigMap, gcConfig config.OrphanedInstanceGCConfig, provider cloud.CloudProvider, platform string, instanceTag string, warmPool bool) error {
+	log := logr.FromContextOrDiscard(ctx).WithValues("platform", platform)
+	instances, err := provider.ListInstances(r.client, ctx, instanceTag)
+	if err != nil {
+		return err
+	}
+	taskRunReporter, reportsTaskRuns := cloud.TaskRunReporter(provider)
+	for _, instance := range instances {
+		if time.Since(instance.StartTime) < gcConfig.GracePeriod {
+			continue
+		}
+		if instance.TaskRunID == "" && reportsTaskRuns {
+			instance.TaskRunID, err = taskRunReporter.GetInstanceTaskRunID(r.client, ctx, instance.InstanceId)
+			if err != nil {
+				log.Error(err, "failed to look up the TaskRun of instance", "instance", instance.InstanceId)
+				continue
+			}
+		}
+		if instance.TaskRunID == "" {
+			continue
+		}
+		namespace, name, found := strings.Cut(instance.TaskRunID, ":")
+		if !found {
+			continue
+		}
+		tr := tektonapi.TaskRun{}
+		var owner k8sRuntime.Object = hostConfig
+		var reason string
+		if isWarmInstance(instance, r.operatorNamespace) {
+			if warmPool {
+				continue
+			}
+			assigned, err := isAssigned(r, ctx, instance.InstanceId)
+			if err != nil {
+				log.Error(err, "failed to get TaskRun of warm instance", "instance", instance.InstanceId)
+				continue
+			}
+			if assigned {
+				continue
+			}
+			reason = "the warm pool of its platform is disabled"
+		} else if err := r.apiReader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &tr); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				log.Error(err, "failed to get TaskRun of instance", "instance", instance.InstanceId, "taskRunID", instance.TaskRunID)
+				continue
+			}
+			reason = "its TaskRun no longer exists"
+		} else {
+			owner = &tr
+			switch {
+			case tr.IsDone() && tr.Status.CompletionTime != nil && time.Since(tr.Status.CompletionTime.Time) > gcConfig.GracePeriod:
+				reason = "its TaskRun finished more than " + gcConfig.GracePeriod.String() + " ago"
+			case tr.Annotations[CloudInstanceId] != "" && tr.Annotations[CloudInstanceId] != string(instance.InstanceId):
+				reason = "its TaskRun is running on instance " + tr.Annotations[CloudInstanceId]
+			default:
+				continue
+			}
+		}
+
+		msg := fmt.Sprintf("Instance %s of platform %s launched for TaskRun %s is orphaned: %s", instance.InstanceId, platform, instance.TaskRunID, reason)
+		if gcConfig.DryRun {
+			log.Info("found orphaned instance, not terminating it in dry-run mode", "instance", instance.InstanceId, "taskRunID", instance.TaskRunID, "reason", reason)
+			r.eventRecorder.Event(owner, "Normal", "OrphanedInstance", msg)
+			continue
+		}
+		log.Info("terminating orphaned instance", "instance", instance.InstanceId, "taskRunID", instance.TaskRunID, "reason", reason)
+		if err := provider.TerminateInstance(r.client, ctx, instance.InstanceId); err != nil {
+			log.Error(err, "failed to terminate orphaned instance", "instance", instance.InstanceId)
+			r.eventRecorder.Event(owner, "Warning", "TerminateFailed", fmt.Sprintf("Failed to terminate orphaned instance %s: %v", instance.InstanceId, err))
+			continue
+		}
+		r.eventRecorder.Event(owner, "Normal", "OrphanedInstanceTerminated", msg)
+		mpcmetrics.CountReclaimedInstance(platform)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// This file contains the tests for the orphaned instance garbage collector, which
+// terminates dynamic platform instances whose TaskRun is gone or finished long ago.
+
+package taskrun
+
+import (
+	"time"
+
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	pipelinev1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"knative.dev/pkg/apis"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Orphaned instance garbage collector", func() {
+
+	var client runtimeclient.Client
+	var reconciler *ReconcileTaskRun
+	var recorder *record.FakeRecorder
+
+	// addInstance adds an instance launched for taskRunID at startTime to the mock cloud.
+	addInstance := func(id string, taskRunID string, startTime time.Time) {
+		cloudImpl.Instances[cloud.InstanceIdentifier(id)] = MockInstance{
+			CloudVMInstance: cloud.CloudVMInstance{InstanceId: cloud.InstanceIdentifier(id), StartTime: startTime, TaskRunID: taskRunID},
+			statusOK:        true,
+		}
+	}
+
+	// updateHostConfig merges data into the host-config ConfigMap.
+	updateHostConfig := func(ctx SpecContext, data map[string]string) {
+		cm := v1.ConfigMap{}
+		Expect(client.Get(ctx, types.NamespacedName{Namespace: systemNamespace, Name: HostConfig}, &cm)).Should(Succeed())
+		for k, v := range data {
+			cm.Data[k] = v
+		}
+		Expect(client.Update(ctx, &cm)).Should(Succeed())
+	}
+
+	// createTaskRun creates a user TaskRun, completed at completionTime unless it is zero.
+	createTaskRun := func(ctx SpecContext, name string, instance string, completionTime time.Time) {
+		tr := &pipelinev1.TaskRun{}
+		tr.Name = name
+		tr.Namespace = userNamespace
+		tr.Annotations = map[string]string{CloudInstanceId: instance}
+		Expect(client.Create(ctx, tr)).Should(Succeed())
+		if !completionTime.IsZero() {
+			tr.Status.CompletionTime = &metav1.Time{Time: completionTime}
+			tr.Status.SetCondition(&apis.Condition{Type: apis.ConditionSucceeded, Status: "True"})
+			Expect(client.Status().Update(ctx, tr)).Should(Succeed())
+		}
+	}
+
+	BeforeEach(func(ctx SpecContext) {
+		client, reconciler = setupClientAndReconciler(createDynamicHostConfig())
+		recorder = record.NewFakeRecorder(10)
+		reconciler.eventRecorder = recorder
+		cloudImpl.Instances = map[cloud.InstanceIdentifier]MockInstance{}
+		cloudImpl.TerminatedIDs = nil
+		cloudImpl.FailTerminate = false
+		updateHostConfig(ctx, map[string]string{"orphaned-instance-gc.enabled": "true"})
+	})
+
+	It("should terminate instances whose TaskRun no longer exists", func(ctx SpecContext) {
+		addInstance("leaked", userNamespace+":gone", time.Now().Add(-time.Hour))
+
+		Expect(reconciler.collectOrphanedInstances(ctx)).Should(Succeed())
+		Expect(cloudImpl.TerminatedIDs).Should(ConsistOf(cloud.InstanceIdentifier("leaked")))
+		Expect(recorder.Events).Should(Receive(ContainSubstring("OrphanedInstanceTerminated")))
+	})
+
+	It("should terminate instances whose TaskRun finished more than the grace period ago", func(ctx SpecContext) {
+		createTaskRun(ctx, "finished", "finished", time.Now().Add(-time.Hour))
+		addInstance("finished", userNamespace+":finished", time.Now().Add(-2*time.Hour))
+
+		Expect(reconciler.collectOrphanedInstances(ctx)).Should(Succeed())
+		Expect(cloudImpl.TerminatedIDs).Should(ConsistOf(cloud.InstanceIdentifier("finished")))
+	})
+
+	It("should terminate instances that their running TaskRun no longer uses", func(ctx SpecContext) {
+		createTaskRun(ctx, "relaunched", "second", time.Time{})
+		addInstance("first", userNamespace+":relaunched", time.Now().Add(-time.Hour))
+		addInstance("second", userNamespace+":relaunched", time.Now().Add(-time.Hour))
+
+		Expect(reconciler.collectOrphanedInstances(ctx)).Should(Succeed())
+		Expect(cloudImpl.TerminatedIDs).Should(ConsistOf(cloud.InstanceIdentifier("first")))
+	})
+
+	It("should keep instances of running TaskRuns, young instances and instances without a TaskRun", func(ctx SpecContext) {
+		createTaskRun(ctx, "running", "running", time.Time{})
+		addInstance("running", userNamespace+":running", time.Now().Add(-time.Hour))
+		addInstance("young", userNamespace+":gone", time.Now())
+		addInstance("untagged", "", time.Now().Add(-time.Hour))
+
+		Expect(reconciler.collectOrphanedInstances(ctx)).Should(Succeed())
+		Expect(cloudImpl.TerminatedIDs).Should(BeEmpty())
+	})
+
+	It("should look up the TaskRun of instances listed without it once they are older than the grace period", func(ctx SpecContext) {
+		reporting := &MockTaskRunReportingCloud{MockCloud: &cloudImpl, TaskRunIDs: map[cloud.InstanceIdentifier]string{
+			"leaked": userNamespace + ":gone",
+			"young":  userNamespace + ":gone",
+		}}
+		reconciler.cloudProviders["aws"] = func(platform string, config map[string]string, systemnamespace string) cloud.CloudProvider {
+			return reporting
+		}
+		addInstance("leaked", userNamespace+":gone", time.Now().Add(-time.Hour))
+		addInstance("young", userNamespace+":gone", time.Now())
+		addInstance("untagged", "", time.Now().Add(-time.Hour))
+
+		Expect(reconciler.collectOrphanedInstances(ctx)).Should(Succeed())
+		Expect(reporting.LookedUp).Should(ConsistOf(cloud.InstanceIdentifier("leaked"), cloud.InstanceIdentifier("untagged")))
+		Expect(cloudImpl.TerminatedIDs).Should(ConsistOf(cloud.InstanceIdentifier("leaked")))
+	})
+
+	It("should only report orphaned instances in dry-run mode", func(ctx SpecContext) {
+		updateHostConfig(ctx, map[string]string{"orphaned-instance-gc.dry-run": "true"})
+		addInstance("leaked", userNamespace+":gone", time.Now().Add(-time.Hour))
+
+		Expect(reconciler.collectOrphanedInstances(ctx)).Should(Succeed())
+		Expect(cloudImpl.TerminatedIDs).Should(BeEmpty())
+		Expect(cloudImpl.Instances).Should(HaveKey(cloud.InstanceIdentifier("leaked")))
+		Expect(recorder.Events).Should(Receive(ContainSubstring("OrphanedInstance ")))
+	})
+
+	It("should report instances that fail to terminate", func(ctx SpecContext) {
+		cloudImpl.FailTerminate = true
+		defer func() { cloudImpl.FailTerminate = false }()
+		addInstance("leaked", userNamespace+":gone", time.Now().Add(-time.Hour))
+
+		Expect(reconciler.collectOrphanedInstances(ctx)).Should(Succeed())
+		Expect(recorder.Events).Should(Receive(ContainSubstring("TerminateFailed")))
+	})
+
+	It("should do nothing unless it is enabled", func(ctx SpecContext) {
+		updateHostConfig(ctx, map[string]string{"orphaned-instance-gc.enabled": "false"})
+		addInstance("leaked", userNamespace+":gone", time.Now().Add(-time.Hour))
+
+		Expect(reconciler.collectOrphanedInstances(ctx)).Should(Succeed())
+		Expect(cloudImpl.TerminatedIDs).Should(BeEmpty())
+	})
+
+	It("should skip dynamic platforms sharing their instance tag with a dynamic pool platform", func(ctx SpecContext) {
+		updateHostConfig(ctx, map[string]string{
+			"instance-tag":                      "shared",
+			"dynamic-pool-platforms":            "linux/amd64",
+			"dynamic.linux-amd64.type":          "aws",
+			"dynamic.linux-amd64.max-age":       "20",
+			"dynamic.linux-amd64.max-instances": "2",
+			"dynamic.linux-amd64.concurrency":   "2",
+			"dynamic.linux-amd64.ssh-secret":    "awskeys",
+		})
+		addInstance("pooled", userNamespace+":gone", time.Now().Add(-time.Hour))
+
+		Expect(reconciler.collectOrphanedInstances(ctx)).Should(Succeed())
+		Expect(cloudImpl.TerminatedIDs).Should(BeEmpty())
+	})
+})
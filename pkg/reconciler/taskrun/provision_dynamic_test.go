@@ -6,6 +6,7 @@
 package taskrun
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
@@ -16,6 +17,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"knative.dev/pkg/apis"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -86,6 +88,19 @@ var _ = Describe("Test Dynamic Host Provisioning", func() {
 			// Verify step 6: The cloud instance is terminated as part of cleanup
 			Expect(cloudImpl.Instances).ShouldNot(HaveKey(cloud.InstanceIdentifier("multi-platform-builder-test-dynamic-alloc")))
 		})
+
+		It("should record the instance type and image the cloud host was launched with", func(ctx SpecContext) {
+			cloudImpl.InstanceDetails = cloud.InstanceDetails{InstanceType: "m7g.large", Image: "ami-0abc"}
+			defer func() { cloudImpl.InstanceDetails = cloud.InstanceDetails{} }()
+
+			createUserTaskRun(ctx, client, "test-instance-type", "linux/arm64")
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-instance-type"}})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			tr := getUserTaskRun(ctx, client, "test-instance-type")
+			Expect(tr.Annotations).Should(HaveKeyWithValue(CloudInstanceType, "m7g.large"))
+			Expect(tr.Annotations).Should(HaveKeyWithValue(CloudInstanceImage, "ami-0abc"))
+		})
 	})
 
 	When("when provisioning fails", func() {
@@ -195,6 +210,35 @@ var _ = Describe("Test Dynamic Host Provisioning", func() {
 			Expect(cloudImpl.Running).Should(Equal(0))
 		})
 
+		It("should terminate the instance and fail the task with an error secret when GetState returns InterruptedState", func(ctx SpecContext) {
+			cloudImpl.TimeoutGetAddress = true
+			defer func() { cloudImpl.TimeoutGetAddress = false }()
+
+			createUserTaskRun(ctx, client, "test-interrupted", "linux/arm64")
+			// 1st reconcile: launches instance
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-interrupted"}})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			// Mark the instance as interrupted
+			tr := getUserTaskRun(ctx, client, "test-interrupted")
+			instanceId := cloud.InstanceIdentifier(tr.Annotations[CloudInstanceId])
+			Expect(instanceId).ShouldNot(BeEmpty())
+			inst := cloudImpl.Instances[instanceId]
+			inst.interrupted = true
+			cloudImpl.Instances[instanceId] = inst
+
+			// 2nd reconcile: address is empty, GetState returns InterruptedState → terminate + unassign + error secret
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-interrupted"}})
+			Expect(err).Should(MatchError(ContainSubstring("was interrupted by the cloud provider")))
+
+			tr = getUserTaskRun(ctx, client, "test-interrupted")
+			Expect(tr.Annotations[CloudInstanceId]).Should(BeEmpty())
+			Expect(cloudImpl.Running).Should(Equal(0))
+			Expect(cloudImpl.TerminatedIDs).Should(ContainElement(instanceId))
+			secret := getSecret(ctx, client, tr)
+			Expect(string(secret.Data["error"])).Should(ContainSubstring("was interrupted by the cloud provider"))
+		})
+
 		It("should requeue quickly when GetState returns an error", func(ctx SpecContext) {
 			cloudImpl.TimeoutGetAddress = true
 			cloudImpl.FailGetState = true
@@ -258,6 +302,71 @@ var _ = Describe("Test Dynamic Host Provisioning", func() {
 		})
 	})
 
+	When("the cloud provider keeps failing", func() {
+
+		// It verifies that once the circuit of the cloud provider account opens, TaskRuns wait for it to recover
+		// with an event instead of failing, and the cloud provider is no longer called.
+		It("should wait for the circuit to close instead of failing the TaskRun", func(ctx SpecContext) {
+			cm := corev1.ConfigMap{}
+			Expect(client.Get(ctx, types.NamespacedName{Namespace: systemNamespace, Name: HostConfig}, &cm)).Should(Succeed())
+			cm.Data["cloud-api.failure-threshold"] = "1"
+			Expect(client.Update(ctx, &cm)).Should(Succeed())
+			recorder := record.NewFakeRecorder(10)
+			reconciler.eventRecorder = recorder
+			cloudImpl.FailLaunch = true
+			defer func() { cloudImpl.FailLaunch = false }()
+
+			createUserTaskRun(ctx, client, "test-circuit-open", "linux/arm64")
+			// 1st reconcile: the launch fails and opens the circuit
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-circuit-open"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			tr := getUserTaskRun(ctx, client, "test-circuit-open")
+			Expect(tr.Annotations[CloudFailures]).Should(Equal("1"))
+
+			// 2nd reconcile: the cloud provider is not called, and the TaskRun waits
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-circuit-open"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.RequeueAfter).Should(BeNumerically("~", time.Minute, time.Second))
+			Eventually(recorder.Events).Should(Receive(ContainSubstring("CloudUnavailable")))
+
+			tr = getUserTaskRun(ctx, client, "test-circuit-open")
+			Expect(tr.Annotations[CloudFailures]).Should(Equal("1"))
+			secret := corev1.Secret{}
+			Expect(client.Get(ctx, types.NamespacedName{Namespace: userNamespace, Name: SecretPrefix + "test-circuit-open"}, &secret)).ShouldNot(Succeed())
+		})
+	})
+
+	When("the cloud provider does not recover", func() {
+
+		// It verifies that TaskRuns wait for the circuit of the cloud provider account to close for no longer than
+		// the allocation timeout of the platform, and then fail with the error that opened the circuit.
+		It("should fail the TaskRun once it waited for longer than the allocation timeout", func(ctx SpecContext) {
+			cm := corev1.ConfigMap{}
+			Expect(client.Get(ctx, types.NamespacedName{Namespace: systemNamespace, Name: HostConfig}, &cm)).Should(Succeed())
+			cm.Data["cloud-api.failure-threshold"] = "1"
+			Expect(client.Update(ctx, &cm)).Should(Succeed())
+			cloudImpl.FailLaunch = true
+			defer func() { cloudImpl.FailLaunch = false }()
+
+			createUserTaskRun(ctx, client, "test-circuit-timeout", "linux/arm64")
+			for range 2 {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-circuit-timeout"}})
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+			tr := getUserTaskRun(ctx, client, "test-circuit-timeout")
+			Expect(tr.Annotations[CloudUnavailableSinceAnnotation]).ShouldNot(BeEmpty())
+
+			// The allocation timeout of the platform is 2 seconds
+			tr.Annotations[CloudUnavailableSinceAnnotation] = strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+			Expect(client.Update(ctx, tr)).Should(Succeed())
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-circuit-timeout"}})
+			Expect(err).Should(MatchError(ContainSubstring("did not recover")))
+			Expect(err).Should(MatchError(ContainSubstring("launch failed")))
+			secret := corev1.Secret{}
+			Expect(client.Get(ctx, types.NamespacedName{Namespace: userNamespace, Name: SecretPrefix + "test-circuit-timeout"}, &secret)).Should(Succeed())
+		})
+	})
+
 	When("the instance pool is at capacity", func() {
 
 		It("should wait and requeue when maxInstances is reached", func(ctx SpecContext) {
@@ -0,0 +1,115 @@
+package taskrun
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"github.com/konflux-ci/multi-platform-controller/pkg/config"
+)
+
+// quotaGroupMember is a dynamic or dynamic pool platform whose instances count towards a quota group.
+type quotaGroupMember struct {
+	platform      string
+	instanceTag   string
+	vcpus         int
+	cloudProvider cloud.CloudProvider
+}
+
+// quotaGroup limits the vCPUs of the instances of several dynamic and dynamic pool platforms, e.g. because they share
+// the vCPU quota of a cloud provider account, on top of the max-instances limit of each platform.
+type quotaGroup struct {
+	name     string
+	maxVCPUs int
+	members  []quotaGroupMember
+}
+
+// usedVCPUs returns the vCPUs of the instances of all the members of the group.
+func (g *quotaGroup) usedVCPUs(r *ReconcileTaskRun, ctx context.Context) (int, error) {
+	used := 0
+	for _, member := range g.members {
+		count, err := member.cloudProvider.CountInstances(r.client, ctx, member.instanceTag)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count the instances of %s in quota group %s: %w", member.platform, g.name, err)
+		}
+		used += count * member.vcpus
+	}
+	return used, nil
+}
+
+// platforms returns the platforms of the members of the group.
+func (g *quotaGroup) platforms() []string {
+	platforms := make([]string, 0, len(g.members))
+	for _, member := range g.members {
+		platforms = append(platforms, member.platform)
+	}
+	return platforms
+}
+
+// quotaGroupPlatforms returns the platforms sharing a quota group with platformConfig, or nil if it is not in one.
+func quotaGroupPlatforms(platformConfig PlatformConfig) []string {
+	switch p := platformConfig.(type) {
+	case DynamicResolver:
+		if p.quotaGroup != nil {
+			return p.quotaGroup.platforms()
+		}
+	case DynamicHostPool:
+		if p.quotaGroup != nil {
+			return p.quotaGroup.platforms()
+		}
+	}
+	return nil
+}
+
+// buildQuotaGroup builds the quota group called name from the dynamic and dynamic pool platforms of the ConfigMap
+// whose quota-group it is. Every member gets its own cloud provider, so that the group can count the instances of
+// all of them.
+func (r *ReconcileTaskRun) buildQuotaGroup(name string, data map[string]string) (*quotaGroup, error) {
+	groupConfig, err := config.ParseQuotaGroupConfig(data, name)
+	if err != nil {
+		return nil, err
+	}
+	group := &quotaGroup{name: name, maxVCPUs: groupConfig.MaxVCPUs}
+
+	dynamicPlatforms, err := config.ParsePlatformList(data[DynamicPlatforms], config.PlatformTypeDynamic)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse dynamic platforms: %w", err)
+	}
+	dynamicPoolPlatforms, err := config.ParsePlatformList(data[DynamicPoolPlatforms], config.PlatformTypeDynamicPool)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse dynamic pool platforms: %w", err)
+	}
+
+	for _, platform := range slices.Concat(dynamicPlatforms, dynamicPoolPlatforms) {
+		platformConfigName := strings.ReplaceAll(platform, "/", "-")
+		if strings.TrimSpace(data["dynamic."+platformConfigName+".quota-group"]) != name || slices.Contains(group.platforms(), platform) {
+			continue
+		}
+		var providerType, instanceTag string
+		var vcpus int
+		if slices.Contains(dynamicPlatforms, platform) {
+			dynamicConfig, err := config.ParseDynamicPlatformConfig(data, platform)
+			if err != nil {
+				return nil, fmt.Errorf("quota group '%s': %w", name, err)
+			}
+			providerType, instanceTag, vcpus = dynamicConfig.Type, dynamicConfig.InstanceTag, dynamicConfig.VCPUs
+		} else {
+			poolConfig, err := config.ParseDynamicPoolPlatformConfig(data, platform)
+			if err != nil {
+				return nil, fmt.Errorf("quota group '%s': %w", name, err)
+			}
+			providerType, instanceTag, vcpus = poolConfig.Type, poolConfig.InstanceTag, poolConfig.VCPUs
+		}
+		if instanceTag == "" {
+			instanceTag = data[DefaultInstanceTag]
+		}
+		cloudProvider, err := r.newCloudProvider(providerType, platformConfigName, data)
+		if err != nil {
+			return nil, fmt.Errorf("quota group '%s': %w", name, err)
+		}
+		group.members = append(group.members, quotaGroupMember{platform: platform, instanceTag: instanceTag, vcpus: vcpus, cloudProvider: cloudProvider})
+	}
+	return group, nil
+}
@@ -0,0 +1,209 @@
+// This file contains the tests for quota groups, which limit the vCPUs of the
+// instances launched for several dynamic and dynamic pool platforms sharing a
+// cloud provider account quota.
+
+package taskrun
+
+import (
+	"time"
+
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+
+	. "github.com/konflux-ci/multi-platform-controller/pkg/constant"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// createQuotaGroupHostConfig is a factory function for a configuration where linux/arm64, whose instances have 2 vCPUs,
+// shares a quota group of 10 vCPUs with the dynamic platform linux-m2xlarge/arm64, whose instances have 8 vCPUs.
+// linux/arm64 is a dynamic platform, or a dynamic pool platform when pool is true. The platforms are of different
+// types, so that each gets its own mock cloud.
+func createQuotaGroupHostConfig(pool bool) []runtimeclient.Object {
+	cm := corev1.ConfigMap{}
+	cm.Name = HostConfig
+	cm.Namespace = systemNamespace
+	cm.Labels = map[string]string{ConfigMapLabel: "hosts"}
+	cm.Data = map[string]string{
+		"dynamic-platforms":                               "linux/arm64,linux-m2xlarge/arm64",
+		"cloud-api.instance-cache-ttl":                    "0s",
+		"quota-group.aws-arm.max-vcpus":                   "10",
+		"dynamic.linux-arm64.type":                        "aws",
+		"dynamic.linux-arm64.aws-secret":                  "awsiam",
+		"dynamic.linux-arm64.ssh-secret":                  "awskeys",
+		"dynamic.linux-arm64.max-instances":               "5",
+		"dynamic.linux-arm64.quota-group":                 "aws-arm",
+		"dynamic.linux-arm64.vcpus":                       "2",
+		"dynamic.linux-m2xlarge-arm64.type":               "gcp",
+		"dynamic.linux-m2xlarge-arm64.ssh-secret":         "awskeys",
+		"dynamic.linux-m2xlarge-arm64.max-instances":      "5",
+		"dynamic.linux-m2xlarge-arm64.quota-group":        "aws-arm",
+		"dynamic.linux-m2xlarge-arm64.vcpus":              "8",
+		"dynamic.linux-m2xlarge-arm64.allocation-timeout": "2",
+	}
+	if pool {
+		cm.Data["dynamic-platforms"] = "linux-m2xlarge/arm64"
+		cm.Data["dynamic-pool-platforms"] = "linux/arm64"
+		cm.Data["dynamic.linux-arm64.concurrency"] = "1"
+		cm.Data["dynamic.linux-arm64.max-age"] = "60"
+	}
+	sec := corev1.Secret{}
+	sec.Name = "awskeys"
+	sec.Namespace = systemNamespace
+	sec.Labels = map[string]string{MultiPlatformSecretLabel: "true"}
+	return []runtimeclient.Object{&cm, &sec}
+}
+
+var _ = Describe("Test Quota Groups", func() {
+
+	var client runtimeclient.Client
+	var reconciler *ReconcileTaskRun
+	var otherCloud *MockCloud
+
+	setup := func(pool bool) {
+		client, reconciler = setupClientAndReconciler(createQuotaGroupHostConfig(pool))
+		cloudImpl.Instances = map[cloud.InstanceIdentifier]MockInstance{}
+		cloudImpl.Running = 0
+		cloudImpl.Terminated = 0
+		otherCloud = &MockCloud{Instances: map[cloud.InstanceIdentifier]MockInstance{}}
+		reconciler.cloudProviders["gcp"] = func(platform string, config map[string]string, systemNamespace string) cloud.CloudProvider {
+			return otherCloud
+		}
+	}
+
+	When("dynamic platforms share a quota group", func() {
+
+		BeforeEach(func() {
+			setup(false)
+		})
+
+		It("should build the quota group from all of its platforms", func(ctx SpecContext) {
+			configIface, err := reconciler.getPlatformConfig(ctx, "linux/arm64", userNamespace)
+			Expect(err).ShouldNot(HaveOccurred())
+			config := configIface.(DynamicResolver)
+			Expect(config.vcpus).Should(Equal(2))
+			Expect(config.quotaGroup.name).Should(Equal("aws-arm"))
+			Expect(config.quotaGroup.maxVCPUs).Should(Equal(10))
+			Expect(config.quotaGroup.platforms()).Should(ConsistOf("linux/arm64", "linux-m2xlarge/arm64"))
+		})
+
+		It("should wait for any platform of the group to free capacity before launching", func(ctx SpecContext) {
+			// An 8 vCPUs instance of the other platform leaves room for a single 2 vCPUs instance
+			_, err := otherCloud.LaunchInstance(nil, ctx, userNamespace+":test-quota-other", "", nil)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			createUserTaskRun(ctx, client, "test-quota-1", "linux/arm64")
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-quota-1"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cloudImpl.Running).Should(Equal(1))
+
+			// The group is now full, although linux/arm64 is far from its max-instances
+			createUserTaskRun(ctx, client, "test-quota-2", "linux/arm64")
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-quota-2"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.RequeueAfter).Should(Equal(time.Minute))
+			Expect(cloudImpl.Running).Should(Equal(1))
+			Expect(getUserTaskRun(ctx, client, "test-quota-2").Labels[WaitingForPlatformLabel]).Should(Equal("linux-arm64"))
+
+			// Completing the TaskRun of the other platform releases the waiting linux/arm64 TaskRun
+			createUserTaskRun(ctx, client, "test-quota-other", "linux-m2xlarge/arm64")
+			other := getUserTaskRun(ctx, client, "test-quota-other")
+			other.Labels[AssignedHost] = "test-quota-other"
+			other.Annotations = map[string]string{CloudInstanceId: "test-quota-other"}
+			Expect(client.Update(ctx, other)).ShouldNot(HaveOccurred())
+			other.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+			other.Status.SetCondition(&apis.Condition{
+				Type:               apis.ConditionSucceeded,
+				Status:             "True",
+				LastTransitionTime: apis.VolatileTime{Inner: metav1.Time{Time: time.Now()}},
+			})
+			Expect(client.Status().Update(ctx, other)).ShouldNot(HaveOccurred())
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-quota-other"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(otherCloud.TerminatedIDs).Should(ConsistOf(cloud.InstanceIdentifier("test-quota-other")))
+			Expect(getUserTaskRun(ctx, client, "test-quota-2").Labels[FinishedWaitingLabel]).Should(Equal("true"))
+
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-quota-2"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cloudImpl.Running).Should(Equal(2))
+			Expect(getUserTaskRun(ctx, client, "test-quota-2").Labels).ShouldNot(HaveKey(WaitingForPlatformLabel))
+		})
+
+		It("should not launch an instance when the instances of the group cannot be counted", func(ctx SpecContext) {
+			otherCloud.FailCountInstances = true
+
+			createUserTaskRun(ctx, client, "test-quota-count-fail", "linux/arm64")
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-quota-count-fail"}})
+			Expect(err).Should(MatchError(ContainSubstring("failed to count the instances of linux-m2xlarge/arm64 in quota group aws-arm")))
+			Expect(cloudImpl.Running).Should(Equal(0))
+		})
+	})
+
+	When("a dynamic pool platform shares a quota group", func() {
+
+		BeforeEach(func() {
+			setup(true)
+		})
+
+		It("should not grow the pool beyond the vCPUs of the group", func(ctx SpecContext) {
+			// Two 8 vCPUs instances of the other platform exceed the group on their own
+			for _, name := range []string{"test-quota-other-1", "test-quota-other-2"} {
+				_, err := otherCloud.LaunchInstance(nil, ctx, userNamespace+":"+name, "", nil)
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+
+			createUserTaskRun(ctx, client, "test-quota-pool", "linux/arm64")
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-quota-pool"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(result.RequeueAfter).Should(Equal(time.Minute))
+			Expect(cloudImpl.Running).Should(Equal(0))
+
+			// Once the other platform frees capacity, the pool grows again
+			Expect(otherCloud.TerminateInstance(nil, ctx, "test-quota-other-2")).Should(Succeed())
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-quota-pool"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cloudImpl.Running).Should(Equal(1))
+		})
+
+		It("should wake the pool TaskRuns waiting for capacity when a TaskRun of a dynamic platform of the group finishes", func(ctx SpecContext) {
+			// Two 8 vCPUs instances of the other platform exceed the group on their own
+			for _, name := range []string{"test-quota-other-1", "test-quota-other-2"} {
+				_, err := otherCloud.LaunchInstance(nil, ctx, userNamespace+":"+name, "", nil)
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+
+			createUserTaskRun(ctx, client, "test-quota-pool", "linux/arm64")
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-quota-pool"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cloudImpl.Running).Should(Equal(0))
+			Expect(getUserTaskRun(ctx, client, "test-quota-pool").Labels[WaitingForPlatformLabel]).Should(Equal("linux-arm64"))
+
+			// Completing the TaskRun of the dynamic platform releases the waiting pool TaskRun
+			createUserTaskRun(ctx, client, "test-quota-other-2", "linux-m2xlarge/arm64")
+			other := getUserTaskRun(ctx, client, "test-quota-other-2")
+			other.Labels[AssignedHost] = "test-quota-other-2"
+			other.Annotations = map[string]string{CloudInstanceId: "test-quota-other-2"}
+			Expect(client.Update(ctx, other)).ShouldNot(HaveOccurred())
+			other.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+			other.Status.SetCondition(&apis.Condition{
+				Type:               apis.ConditionSucceeded,
+				Status:             "True",
+				LastTransitionTime: apis.VolatileTime{Inner: metav1.Time{Time: time.Now()}},
+			})
+			Expect(client.Status().Update(ctx, other)).ShouldNot(HaveOccurred())
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-quota-other-2"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(otherCloud.TerminatedIDs).Should(ConsistOf(cloud.InstanceIdentifier("test-quota-other-2")))
+			Expect(getUserTaskRun(ctx, client, "test-quota-pool").Labels[FinishedWaitingLabel]).Should(Equal("true"))
+
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-quota-pool"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(cloudImpl.Running).Should(Equal(1))
+		})
+	})
+})
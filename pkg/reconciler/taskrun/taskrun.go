@@ -19,10 +19,17 @@ import (
 	"github.com/konflux-ci/multi-platform-controller/pkg/util"
 
 	"github.com/konflux-ci/multi-platform-controller/pkg/aws"
+	"github.com/konflux-ci/multi-platform-controller/pkg/azure"
 	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud/fake"
 	"github.com/konflux-ci/multi-platform-controller/pkg/config"
 	"github.com/konflux-ci/multi-platform-controller/pkg/constant"
+	"github.com/konflux-ci/multi-platform-controller/pkg/external"
+	"github.com/konflux-ci/multi-platform-controller/pkg/gcp"
 	"github.com/konflux-ci/multi-platform-controller/pkg/ibm"
+	"github.com/konflux-ci/multi-platform-controller/pkg/kubevirt"
+	"github.com/konflux-ci/multi-platform-controller/pkg/libvirt"
+	"github.com/konflux-ci/multi-platform-controller/pkg/openstack"
 	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	kubecore "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -52,6 +59,8 @@ const (
 
 	FailedHosts            = "build.appstudio.redhat.com/failed-hosts"
 	CloudInstanceId        = "build.appstudio.redhat.com/cloud-instance-id"
+	CloudInstanceType      = "build.appstudio.redhat.com/cloud-instance-type"  // may be a fallback instance type
+	CloudInstanceImage     = "build.appstudio.redhat.com/cloud-instance-image" // may be resolved at launch time
 	CloudFailures          = "build.appstudio.redhat.com/cloud-failure-count"
 	CloudAddress           = "build.appstudio.redhat.com/cloud-address"
 	CloudDynamicPlatform   = "build.appstudio.redhat.com/cloud-dynamic-platform"
@@ -62,6 +71,13 @@ const (
 	AllocationStartTimeAnnotation = "build.appstudio.redhat.com/allocation-start-time"
 	//BuildStartTimeAnnotation The time the build actually starts
 	BuildStartTimeAnnotation = "build.appstudio.redhat.com/build-start-time"
+	//CloudUnavailableSinceAnnotation The time the TaskRun started waiting for the cloud provider of its platform to recover
+	CloudUnavailableSinceAnnotation = "build.appstudio.redhat.com/cloud-unavailable-since"
+
+	// CordonedHosts and DrainingHosts annotate the host-config ConfigMap with the comma-separated static hosts and
+	// dynamic pool instances taken out of rotation for maintenance
+	CordonedHosts = "build.appstudio.redhat.com/cordoned-hosts"
+	DrainingHosts = "build.appstudio.redhat.com/draining-hosts"
 
 	UserTaskName      = "build.appstudio.redhat.com/user-task-name"
 	UserTaskNamespace = "build.appstudio.redhat.com/user-task-namespace"
@@ -94,6 +110,10 @@ const (
 	ParamInstanceTag       = "INSTANCE_TAG"
 )
 
+// defaultCloudUnavailableTimeout is how long the TaskRuns of dynamic pool platforms wait for their cloud provider to
+// recover, which is the default allocation timeout of dynamic platforms.
+const defaultCloudUnavailableTimeout = 10 * time.Minute
+
 type ReconcileTaskRun struct {
 	apiReader                client.Reader
 	client                   client.Client
@@ -103,8 +123,43 @@ type ReconcileTaskRun struct {
 	configMapResourceVersion string
 	platformConfig           map[string]PlatformConfig
 	cloudProviders           map[string]func(platform string, config map[string]string, systemNamespace string) cloud.CloudProvider
+	*SharedState
+}
+
+// SharedState is the state of the hosts and cloud provider accounts shared by the TaskRun reconciler and the
+// runnables working on them alongside it. It is created once and passed to each of them.
+type SharedState struct {
+	// cloudLimiters apply the limits of each cloud provider account to all of the calls made to it.
+	cloudLimiters *cloud.Limiters
+	// instanceCache is invalidated by the instances launched or terminated through any of the providers it wraps.
+	instanceCache *cloud.InstanceCache
+	// warmInstances keep the warm pool from counting or reclaiming the warm instances being handed out.
+	warmInstances *warmInstanceClaims
+	// poolHostActivity holds when the dynamic pool instances were last found in use.
+	poolHostActivity *hostActivity
+	// pendingStarts holds the stopped dynamic pool instances that were started and are not ready yet.
+	pendingStarts *instanceStarts
+	// hostHealth holds the hosts the host health prober found unhealthy, which are not allocated.
+	hostHealth *hostHealthProbes
+}
+
+// NewSharedState returns the state to share between the TaskRun reconciler and its runnables.
+func NewSharedState() *SharedState {
+	return &SharedState{
+		cloudLimiters:    cloud.NewLimiters(),
+		instanceCache:    cloud.NewInstanceCache(),
+		warmInstances:    newWarmInstanceClaims(),
+		poolHostActivity: newHostActivity(),
+		pendingStarts:    newInstanceStarts(),
+		hostHealth:       newHostHealthProbes(),
+	}
 }
 
+// cloudCredentialKeys are the platform configuration keys of the secret holding the credentials of each cloud
+// provider type, which identifies the cloud provider account along with the region. External plugins are identified
+// by their address instead.
+var cloudCredentialKeys = map[string]string{"aws": "aws-secret", "gcp": "gcp-secret", "azure": "azure-secret", "libvirt": "libvirt-secret", "openstack": "openstack-secret", "external": "address", "ibmz": "secret", "ibmp": "secret"}
+
 //+kubebuilder:rbac:groups="tekton.dev",resources=taskruns,verbs=create;delete;deletecollection;get;list;patch;update;watch
 //+kubebuilder:rbac:groups="tekton.dev",resources=taskruns/status,verbs=create;delete;deletecollection;get;list;patch;update;watch
 //+kubebuilder:rbac:groups="apiextensions.k8s.io",resources=customresourcedefinitions,verbs=get
@@ -112,8 +167,10 @@ type ReconcileTaskRun struct {
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="kubevirt.io",resources=virtualmachines,verbs=get;list;create;delete
+//+kubebuilder:rbac:groups="kubevirt.io",resources=virtualmachineinstances,verbs=get;list
 
-func newReconciler(mgr ctrl.Manager, operatorNamespace string) reconcile.Reconciler {
+func newReconciler(mgr ctrl.Manager, operatorNamespace string, state *SharedState) reconcile.Reconciler {
 	return &ReconcileTaskRun{
 		apiReader:         mgr.GetAPIReader(),
 		client:            util.NewRetryClient(mgr.GetClient(), retry.DefaultBackoff),
@@ -121,7 +178,8 @@ func newReconciler(mgr ctrl.Manager, operatorNamespace string) reconcile.Reconci
 		eventRecorder:     mgr.GetEventRecorderFor("MultiPlatformTaskRun"),
 		operatorNamespace: operatorNamespace,
 		platformConfig:    map[string]PlatformConfig{},
-		cloudProviders:    map[string]func(platform string, config map[string]string, systemNamespace string) cloud.CloudProvider{"aws": aws.CreateEc2CloudConfig, "ibmz": ibm.CreateIbmZCloudConfig, "ibmp": ibm.CreateIBMPowerCloudConfig},
+		cloudProviders:    map[string]func(platform string, config map[string]string, systemNamespace string) cloud.CloudProvider{"aws": aws.CreateEc2CloudConfig, "gcp": gcp.CreateGceCloudConfig, "azure": azure.CreateAzureCloudConfig, "kubevirt": kubevirt.CreateKubeVirtCloudConfig, "libvirt": libvirt.CreateLibvirtCloudConfig, "openstack": openstack.CreateOpenStackCloudConfig, "external": external.CreateExternalCloudConfig, "fake": fake.CreateFakeCloudConfig, "ibmz": ibm.CreateIbmZCloudConfig, "ibmp": ibm.CreateIBMPowerCloudConfig},
+		SharedState:       state,
 	}
 }
 
@@ -517,6 +575,31 @@ func (r *ReconcileTaskRun) handleHostAllocation(ctx context.Context, tr *tektona
 	ret, err := hosts.Allocate(r, ctx, tr, secretName)
 	isWaiting := tr.Labels[constant.WaitingForPlatformLabel] != ""
 
+	// The cloud provider is failing for every TaskRun of the account, so wait for it to recover instead of failing,
+	// for no longer than the allocation timeout of the platform
+	var circuitErr *cloud.CircuitOpenError
+	if errors.As(err, &circuitErr) {
+		waitingSince, parseErr := strconv.ParseInt(tr.Annotations[CloudUnavailableSinceAnnotation], 10, 64)
+		if parseErr != nil {
+			waitingSince = time.Now().Unix()
+			tr.Annotations[CloudUnavailableSinceAnnotation] = strconv.FormatInt(waitingSince, 10)
+			if updateErr := UpdateTaskRunWithRetry(ctx, r.client, r.apiReader, tr); updateErr != nil {
+				return reconcile.Result{}, updateErr
+			}
+		}
+		if timeout := cloudUnavailableTimeout(hosts); time.Since(time.Unix(waitingSince, 0)) > timeout {
+			err = fmt.Errorf("cloud provider of %s did not recover within %s: %w", targetPlatform, timeout, err)
+		} else {
+			message := fmt.Sprintf("waiting for cloud provider of %s to recover: %v", targetPlatform, circuitErr)
+			log.Info(message)
+			r.eventRecorder.Event(tr, "Warning", "CloudUnavailable", message)
+			return reconcile.Result{RequeueAfter: circuitErr.RetryAfter}, nil
+		}
+	} else {
+		// Only the time spent waiting for the current outage counts towards the timeout
+		delete(tr.Annotations, CloudUnavailableSinceAnnotation)
+	}
+
 	if err != nil {
 		log.Error(err, "host allocation failed")
 		mpcmetrics.HandleMetrics(targetPlatform, func(metrics *mpcmetrics.PlatformMetrics) {
@@ -531,7 +614,7 @@ func (r *ReconcileTaskRun) handleHostAllocation(ctx context.Context, tr *tektona
 	if assignedHost := tr.Labels[constant.AssignedHost]; assignedHost != "" {
 		log.Info("host assigned successfully", "host", assignedHost)
 		mpcmetrics.HandleMetrics(targetPlatform, func(metrics *mpcmetrics.PlatformMetrics) {
-			metrics.AllocationTime.Observe(float64(time.Now().Unix() - startTime))
+			metrics.AllocationTime.WithLabelValues(tr.Annotations[CloudInstanceType]).Observe(float64(time.Now().Unix() - startTime))
 		})
 	}
 
@@ -642,7 +725,14 @@ func (r *ReconcileTaskRun) handleHostAssigned(ctx context.Context, tr *tektonapi
 
 	// Handle waiting tasks - try to allocate next waiting task
 	log.Info("checking for waiting tasks to requeue")
-	result, err := r.handleWaitingTasks(ctx, platform)
+	// A task of a platform sharing a quota group frees capacity for all the platforms of the group
+	waitingPlatforms := []string{platform}
+	for _, member := range quotaGroupPlatforms(platformConfig) {
+		if member != platform {
+			waitingPlatforms = append(waitingPlatforms, member)
+		}
+	}
+	result, err := r.handleWaitingTasks(ctx, waitingPlatforms...)
 	if err != nil {
 		log.Error(err, "failed to handle waiting tasks")
 		// Don't fail the reconciliation for this
@@ -653,19 +743,23 @@ func (r *ReconcileTaskRun) handleHostAssigned(ctx context.Context, tr *tektonapi
 	return result, nil
 }
 
-// called when a task has finished, we look for waiting tasks
-// and then potentially requeue one of them
-func (r *ReconcileTaskRun) handleWaitingTasks(ctx context.Context, platform string) (reconcile.Result, error) {
-	log := logr.FromContextOrDiscard(ctx).WithValues("platform", platform)
+// called when a task has finished, we look for waiting tasks of its platforms
+// and then potentially requeue the oldest of them
+func (r *ReconcileTaskRun) handleWaitingTasks(ctx context.Context, platforms ...string) (reconcile.Result, error) {
+	log := logr.FromContextOrDiscard(ctx).WithValues("platforms", platforms)
 	log.Info("checking for waiting tasks to requeue")
 
 	//try and requeue a waiting task if one exists
 	taskList := tektonapi.TaskRunList{}
 
-	err := r.client.List(ctx, &taskList, client.MatchingLabels{constant.WaitingForPlatformLabel: platformLabel(platform)})
-	if err != nil {
-		log.Error(err, "failed to list waiting tasks")
-		return reconcile.Result{}, fmt.Errorf("failed to list waiting tasks: %w", err)
+	for _, platform := range platforms {
+		platformTaskList := tektonapi.TaskRunList{}
+		err := r.client.List(ctx, &platformTaskList, client.MatchingLabels{constant.WaitingForPlatformLabel: platformLabel(platform)})
+		if err != nil {
+			log.Error(err, "failed to list waiting tasks", "platform", platform)
+			return reconcile.Result{}, fmt.Errorf("failed to list waiting tasks: %w", err)
+		}
+		taskList.Items = append(taskList.Items, platformTaskList.Items...)
 	}
 
 	if len(taskList.Items) == 0 {
@@ -688,7 +782,7 @@ func (r *ReconcileTaskRun) handleWaitingTasks(ctx context.Context, platform stri
 	oldest.Labels[FinishedWaitingLabel] = "true"
 
 	// Update the task
-	err = r.client.Update(ctx, oldest)
+	err := r.client.Update(ctx, oldest)
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("failed to update waiting task %s/%s: %w", oldest.Namespace, oldest.Name, err)
 	}
@@ -787,6 +881,12 @@ func (r *ReconcileTaskRun) getPlatformConfig(ctx context.Context, targetPlatform
 		return ret, nil
 	}
 
+	// Hosts taken out of rotation apply to dynamic pool instances and static hosts
+	hostStates, err := config.ParseHostStates(cm.Annotations[CordonedHosts], cm.Annotations[DrainingHosts])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse hosts out of rotation: %w", err)
+	}
+
 	// No match? Check DYNAMIC POOL platforms
 	dynamicPoolPlatforms, err := config.ParsePlatformList(data[DynamicPoolPlatforms], config.PlatformTypeDynamicPool)
 	if err != nil {
@@ -802,13 +902,14 @@ func (r *ReconcileTaskRun) getPlatformConfig(ctx context.Context, targetPlatform
 		if err != nil {
 			return nil, err
 		}
+		ret.hostStates = hostStates
 		r.platformConfig[targetPlatform] = ret
 		return ret, nil
 	}
 
 	// Still no match?? Check STATIC platforms
 	// Collect all hosts for this platform
-	ret := HostPool{hosts: map[string]*Host{}, targetPlatform: targetPlatform}
+	ret := HostPool{hosts: map[string]*Host{}, targetPlatform: targetPlatform, hostStates: hostStates}
 	hostNames := make(map[string]bool)
 
 	// First, find all unique host names
@@ -867,7 +968,7 @@ func (r *ReconcileTaskRun) getPlatformConfig(ctx context.Context, targetPlatform
 //
 // Cloud Provider Initialization:
 // - Looks up cloud provider constructor function from r.cloudProviders map using config.Type
-// - Supported types: "aws", "ibmz", "ibmp"
+// - Supported types: "aws", "gcp", "azure", "kubevirt", "libvirt", "openstack", "external", "fake", "ibmz", "ibmp"
 // - Constructor receives platformConfigName, full ConfigMap data, and operator namespace
 // - Returns error if cloud provider type is unknown
 //
@@ -883,7 +984,10 @@ func (r *ReconcileTaskRun) getPlatformConfig(ctx context.Context, targetPlatform
 // - DynamicResolver: Fully initialized dynamic platform resolver
 // - error: Cloud provider lookup error or metrics registration error
 func (r *ReconcileTaskRun) buildDynamicResolver(ctx context.Context, dynamicConfig config.DynamicPlatformConfig, platform string, platformConfigName string, additionalInstanceTags map[string]string, data map[string]string) (DynamicResolver, error) {
-	allocfunc := r.cloudProviders[dynamicConfig.Type]
+	cloudProvider, err := r.newCloudProvider(dynamicConfig.Type, platformConfigName, data)
+	if err != nil {
+		return DynamicResolver{}, err
+	}
 
 	// Use instance tag from config, fall back to default if empty
 	instanceTag := dynamicConfig.InstanceTag
@@ -892,7 +996,7 @@ func (r *ReconcileTaskRun) buildDynamicResolver(ctx context.Context, dynamicConf
 	}
 
 	ret := DynamicResolver{
-		CloudProvider:          allocfunc(platformConfigName, data, r.operatorNamespace),
+		CloudProvider:          cloudProvider,
 		sshSecret:              dynamicConfig.SSHSecret,
 		platform:               platform,
 		maxInstances:           dynamicConfig.MaxInstances,
@@ -902,9 +1006,18 @@ func (r *ReconcileTaskRun) buildDynamicResolver(ctx context.Context, dynamicConf
 		sudoCommands:           dynamicConfig.SudoCommands,
 		additionalInstanceTags: additionalInstanceTags,
 		eventRecorder:          r.eventRecorder,
+		vcpus:                  dynamicConfig.VCPUs,
+		minWarm:                dynamicConfig.MinWarm,
+		maxAge:                 time.Minute * time.Duration(dynamicConfig.MaxAge),
+	}
+	if dynamicConfig.QuotaGroup != "" {
+		ret.quotaGroup, err = r.buildQuotaGroup(dynamicConfig.QuotaGroup, data)
+		if err != nil {
+			return DynamicResolver{}, err
+		}
 	}
 
-	err := mpcmetrics.RegisterPlatformMetrics(ctx, platform, dynamicConfig.MaxInstances)
+	err = mpcmetrics.RegisterPlatformMetrics(ctx, platform, dynamicConfig.MaxInstances)
 	if err != nil {
 		return DynamicResolver{}, err
 	}
@@ -920,7 +1033,7 @@ func (r *ReconcileTaskRun) buildDynamicResolver(ctx context.Context, dynamicConf
 //
 // Cloud Provider Initialization:
 // - Looks up cloud provider constructor function from r.cloudProviders map using config.Type
-// - Supported types: "aws", "ibmz", "ibmp"
+// - Supported types: "aws", "gcp", "azure", "kubevirt", "libvirt", "openstack", "external", "fake", "ibmz", "ibmp"
 // - Constructor receives platformConfigName, full ConfigMap data, and operator namespace
 // - Returns error if cloud provider type is unknown
 //
@@ -936,7 +1049,10 @@ func (r *ReconcileTaskRun) buildDynamicResolver(ctx context.Context, dynamicConf
 // - DynamicHostPool: Fully initialized dynamic pool platform resolver
 // - error: Cloud provider lookup error or metrics registration error
 func (r *ReconcileTaskRun) buildDynamicHostPool(ctx context.Context, poolConfig config.DynamicPoolPlatformConfig, platform string, platformConfigName string, additionalInstanceTags map[string]string, data map[string]string) (DynamicHostPool, error) {
-	allocfunc := r.cloudProviders[poolConfig.Type]
+	cloudProvider, err := r.newCloudProvider(poolConfig.Type, platformConfigName, data)
+	if err != nil {
+		return DynamicHostPool{}, err
+	}
 
 	// Use instance tag from config, fall back to default if empty
 	instanceTag := poolConfig.InstanceTag
@@ -945,7 +1061,7 @@ func (r *ReconcileTaskRun) buildDynamicHostPool(ctx context.Context, poolConfig
 	}
 
 	ret := DynamicHostPool{
-		cloudProvider:          allocfunc(platformConfigName, data, r.operatorNamespace),
+		cloudProvider:          cloudProvider,
 		sshSecret:              poolConfig.SSHSecret,
 		platform:               platform,
 		maxInstances:           poolConfig.MaxInstances,
@@ -953,9 +1069,18 @@ func (r *ReconcileTaskRun) buildDynamicHostPool(ctx context.Context, poolConfig
 		concurrency:            poolConfig.Concurrency,
 		instanceTag:            instanceTag,
 		additionalInstanceTags: additionalInstanceTags,
+		vcpus:                  poolConfig.VCPUs,
+		idleTimeout:            time.Minute * time.Duration(poolConfig.IdleTimeout),
+		hibernate:              poolConfig.Hibernate,
+	}
+	if poolConfig.QuotaGroup != "" {
+		ret.quotaGroup, err = r.buildQuotaGroup(poolConfig.QuotaGroup, data)
+		if err != nil {
+			return DynamicHostPool{}, err
+		}
 	}
 
-	err := mpcmetrics.RegisterPlatformMetrics(ctx, platform, poolConfig.MaxInstances)
+	err = mpcmetrics.RegisterPlatformMetrics(ctx, platform, poolConfig.MaxInstances)
 	if err != nil {
 		return DynamicHostPool{}, err
 	}
@@ -963,6 +1088,44 @@ func (r *ReconcileTaskRun) buildDynamicHostPool(ctx context.Context, poolConfig
 	return ret, nil
 }
 
+// newCloudProvider creates the cloud provider of a dynamic or dynamic pool platform, limiting its calls along with
+// the calls of the other platforms using the same cloud provider account, and caching the instances it lists and
+// counts.
+func (r *ReconcileTaskRun) newCloudProvider(providerType string, platformConfigName string, data map[string]string) (cloud.CloudProvider, error) {
+	allocfunc := r.cloudProviders[providerType]
+	if allocfunc == nil {
+		return nil, fmt.Errorf("unknown cloud provider type '%s'", providerType)
+	}
+	limitsConfig, err := config.ParseCloudAPILimitsConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	account := providerType
+	if key := cloudCredentialKeys[providerType]; key != "" {
+		account += "/" + data["dynamic."+platformConfigName+"."+key]
+	}
+	if region := data["dynamic."+platformConfigName+".region"]; region != "" {
+		account += "/" + region
+	}
+	limits := cloud.Limits{
+		RateLimit:        limitsConfig.RateLimit,
+		Burst:            limitsConfig.Burst,
+		FailureThreshold: limitsConfig.FailureThreshold,
+		OpenDuration:     limitsConfig.OpenDuration,
+	}
+	provider := r.cloudLimiters.Wrap(allocfunc(platformConfigName, data, r.operatorNamespace), account, limits)
+	return r.instanceCache.Wrap(provider, account, platformConfigName, limitsConfig.InstanceCacheTTL), nil
+}
+
+// cloudUnavailableTimeout returns how long the TaskRuns of a platform wait for its cloud provider to recover: the
+// allocation timeout of dynamic platforms, and defaultCloudUnavailableTimeout for the other platforms.
+func cloudUnavailableTimeout(hosts PlatformConfig) time.Duration {
+	if resolver, ok := hosts.(DynamicResolver); ok {
+		return time.Duration(resolver.timeout) * time.Second
+	}
+	return defaultCloudUnavailableTimeout
+}
+
 type PlatformConfig interface {
 	Allocate(r *ReconcileTaskRun, ctx context.Context, tr *tektonapi.TaskRun, secretName string) (reconcile.Result, error)
 	Deallocate(r *ReconcileTaskRun, ctx context.Context, tr *tektonapi.TaskRun, secretName string, selectedHost string) error
@@ -1109,8 +1272,11 @@ var (
 	managedAnnotations = []string{
 		FailedHosts,
 		CloudInstanceId,
+		CloudInstanceType,
+		CloudInstanceImage,
 		ProvisionTaskProcessed,
 		AllocationStartTimeAnnotation,
+		CloudUnavailableSinceAnnotation,
 	}
 )
 
@@ -61,6 +61,7 @@ func setupClientAndReconciler(objs []runtimeclient.Object) (runtimeclient.Client
 		eventRecorder:     &record.FakeRecorder{},
 		operatorNamespace: systemNamespace,
 		cloudProviders:    map[string]func(platform string, config map[string]string, systemnamespace string) cloud.CloudProvider{"aws": MockCloudSetup, "ibmz": MockCloudSetup, "ibmp": MockCloudSetup},
+		SharedState:       NewSharedState(),
 		platformConfig:    map[string]PlatformConfig{},
 	}
 	return client, reconciler
@@ -162,6 +163,7 @@ func runSuccessfulProvisionWithConflict(ctx context.Context, provision *pipeline
 		configMapResourceVersion: reconciler.configMapResourceVersion,
 		platformConfig:           reconciler.platformConfig,
 		cloudProviders:           reconciler.cloudProviders,
+		SharedState:              reconciler.SharedState,
 	}
 
 	// This reconcile will hit the conflict after a succesfull provision but succeed due to UpdateTaskRunWithRetry
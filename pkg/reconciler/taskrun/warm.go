@@ -0,0 +1,295 @@
+package taskrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+	"github.com/konflux-ci/multi-platform-controller/pkg/config"
+	"github.com/konflux-ci/multi-platform-controller/pkg/constant"
+	tektonapi "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	kubecore "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	warmPoolInterval = 30 * time.Second
+
+	// warmInstancePrefix prefixes the name in the TaskRun ID warm instances are launched with, since they are launched
+	// before the TaskRun they are handed out to exists.
+	warmInstancePrefix = "warm-"
+)
+
+// warmInstanceClaims holds the warm instances found ready by the warm pool and those handed out to TaskRuns, along
+// with their platforms, so that TaskRuns are handed out warm instances without calling the cloud provider, concurrent
+// reconciles do not hand out the same warm instance twice, and an instance is never handed out again once its TaskRun
+// no longer exists. Claims are released once their instance is no longer listed.
+type warmInstanceClaims struct {
+	mu      sync.Mutex
+	claimed map[cloud.InstanceIdentifier]string
+	ready   map[cloud.InstanceIdentifier]readyWarmInstance
+}
+
+// readyWarmInstance is a booted, unassigned warm instance that can be handed out to a TaskRun.
+type readyWarmInstance struct {
+	platform  string
+	address   string
+	startTime time.Time
+}
+
+func newWarmInstanceClaims() *warmInstanceClaims {
+	return &warmInstanceClaims{claimed: map[cloud.InstanceIdentifier]string{}, ready: map[cloud.InstanceIdentifier]readyWarmInstance{}}
+}
+
+// markReady records that instance of platform booted with address, unless it was already handed out.
+func (c *warmInstanceClaims) markReady(platform string, instance cloud.CloudVMInstance, address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.claimed[instance.InstanceId]; ok {
+		return
+	}
+	c.ready[instance.InstanceId] = readyWarmInstance{platform: platform, address: address, startTime: instance.StartTime}
+}
+
+func (c *warmInstanceClaims) isReady(instance cloud.InstanceIdentifier) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.ready[instance]
+	return ok
+}
+
+// claimReady claims the oldest ready instance of platform launched after notBefore and returns it along with its
+// address, or an empty instance if none is ready.
+func (c *warmInstanceClaims) claimReady(platform string, notBefore time.Time) (cloud.InstanceIdentifier, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var oldest cloud.InstanceIdentifier
+	for instance, ready := range c.ready {
+		if ready.platform != platform || !ready.startTime.After(notBefore) {
+			continue
+		}
+		if oldest == "" || ready.startTime.Before(c.ready[oldest].startTime) {
+			oldest = instance
+		}
+	}
+	if oldest == "" {
+		return "", ""
+	}
+	address := c.ready[oldest].address
+	delete(c.ready, oldest)
+	c.claimed[oldest] = platform
+	return oldest, address
+}
+
+func (c *warmInstanceClaims) isClaimed(instance cloud.InstanceIdentifier) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.claimed[instance]
+	return ok
+}
+
+// release forgets instance, so that it is handed out again once the warm pool finds it ready.
+func (c *warmInstanceClaims) release(instance cloud.InstanceIdentifier) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.claimed, instance)
+	delete(c.ready, instance)
+}
+
+// prune forgets the instances of platform that are not in instances.
+func (c *warmInstanceClaims) prune(platform string, instances []cloud.CloudVMInstance) {
+	listed := map[cloud.InstanceIdentifier]bool{}
+	for _, instance := range instances {
+		listed[instance.InstanceId] = true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for instance, claimedFor := range c.claimed {
+		if claimedFor == platform && !listed[instance] {
+			delete(c.claimed, instance)
+		}
+	}
+	for instance, ready := range c.ready {
+		if ready.platform == platform && !listed[instance] {
+			delete(c.ready, instance)
+		}
+	}
+}
+
+// isWarmInstance returns whether instance was launched as a warm instance by the controller running in
+// operatorNamespace.
+func isWarmInstance(instance cloud.CloudVMInstance, operatorNamespace string) bool {
+	namespace, name, _ := strings.Cut(instance.TaskRunID, ":")
+	return namespace == operatorNamespace && strings.HasPrefix(name, warmInstancePrefix)
+}
+
+// isAssigned returns whether a TaskRun is labelled with instance as its assigned host.
+func isAssigned(taskRun *ReconcileTaskRun, ctx context.Context, instance cloud.InstanceIdentifier) (bool, error) {
+	trs := tektonapi.TaskRunList{}
+	if err := taskRun.client.List(ctx, &trs, client.MatchingLabels{constant.AssignedHost: string(instance)}); err != nil {
+		return false, err
+	}
+	return len(trs.Items) > 0, nil
+}
+
+// unassignedWarmInstances returns the warm instances of the platform that are not assigned to a TaskRun, along with
+// all of its instances.
+func (r DynamicResolver) unassignedWarmInstances(taskRun *ReconcileTaskRun, ctx context.Context) ([]cloud.CloudVMInstance, []cloud.CloudVMInstance, error) {
+	instances, err := r.ListInstances(taskRun.client, ctx, r.instanceTag)
+	if err != nil {
+		return nil, nil, err
+	}
+	var unassigned []cloud.CloudVMInstance
+	for _, instance := range instances {
+		if !isWarmInstance(instance, taskRun.operatorNamespace) {
+			continue
+		}
+		assigned, err := isAssigned(taskRun, ctx, instance.InstanceId)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !assigned {
+			unassigned = append(unassigned, instance)
+		}
+	}
+	return unassigned, instances, nil
+}
+
+// claimWarmInstance claims the oldest warm instance of the platform the warm pool found ready and returns it along with
+// its address, or an empty instance if none is ready. Instances about to be reclaimed are left alone.
+func (r DynamicResolver) claimWarmInstance(taskRun *ReconcileTaskRun) (cloud.InstanceIdentifier, string) {
+	return taskRun.warmInstances.claimReady(r.platform, time.Now().Add(warmPoolInterval-r.maxAge))
+}
+
+// replenishWarmPool reclaims the unassigned warm instances of the platform older than its max age, including those
+// handed out to a TaskRun that no longer exists, records those that booted as ready to be handed out, and launches new
+// ones until minWarm of them are ready or booting, within the max-instances of the platform and its quota group.
+func (r DynamicResolver) replenishWarmPool(taskRun *ReconcileTaskRun, ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx).WithValues("platform", r.platform)
+	unassigned, instances, err := r.unassignedWarmInstances(taskRun, ctx)
+	if err != nil {
+		return err
+	}
+	taskRun.warmInstances.prune(r.platform, instances)
+	warm := 0
+	for _, instance := range unassigned {
+		if time.Since(instance.StartTime) < r.maxAge {
+			if taskRun.warmInstances.isClaimed(instance.InstanceId) {
+				continue
+			}
+			warm++
+			if !taskRun.warmInstances.isReady(instance.InstanceId) {
+				address, err := r.GetInstanceAddress(taskRun.client, ctx, instance.InstanceId)
+				if err == nil && address != "" { // Otherwise still booting
+					taskRun.warmInstances.markReady(r.platform, instance, address)
+				}
+			}
+			continue
+		}
+		log.Info("reclaiming warm instance older than its max age", "instance", instance.InstanceId, "maxAge", r.maxAge)
+		taskRun.warmInstances.release(instance.InstanceId)
+		if err := r.TerminateInstance(taskRun.client, ctx, instance.InstanceId); err != nil {
+			log.Error(err, "failed to reclaim warm instance", "instance", instance.InstanceId)
+		}
+	}
+
+	for ; warm < r.minWarm; warm++ {
+		count, err := r.CountInstances(taskRun.client, ctx, r.instanceTag)
+		if err != nil {
+			return err
+		}
+		if count >= r.maxInstances {
+			log.Info("cannot launch warm instances, platform is at capacity", "warm", warm, "minWarm", r.minWarm)
+			return nil
+		}
+		if r.quotaGroup != nil {
+			usedVCPUs, err := r.quotaGroup.usedVCPUs(taskRun, ctx)
+			if err != nil {
+				return err
+			}
+			if usedVCPUs+r.vcpus > r.quotaGroup.maxVCPUs {
+				log.Info("cannot launch warm instances, quota group is at capacity", "quotaGroup", r.quotaGroup.name, "warm", warm, "minWarm", r.minWarm)
+				return nil
+			}
+		}
+		name, err := getRandomString(8)
+		if err != nil {
+			return err
+		}
+		taskRunID := fmt.Sprintf("%s:%s%s", taskRun.operatorNamespace, warmInstancePrefix, name)
+		instance, err := r.LaunchInstance(taskRun.client, ctx, taskRunID, r.instanceTag, r.additionalInstanceTags)
+		if err != nil {
+			return fmt.Errorf("failed to launch warm instance: %w", err)
+		}
+		log.Info("launched warm instance", "instance", instance)
+	}
+	return nil
+}
+
+// SetupWarmPoolWithManager adds a runnable that periodically keeps min-warm booted, unassigned instances ready for
+// each dynamic platform configuring it, replacing the warm instances handed out to TaskRuns.
+func SetupWarmPoolWithManager(mgr ctrl.Manager, operatorNamespace string, state *SharedState) error {
+	r := newReconciler(mgr, operatorNamespace, state).(*ReconcileTaskRun)
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		log := ctrl.Log.WithName("warm-pool")
+		if ok := mgr.GetCache().WaitForCacheSync(ctx); !ok {
+			return context.Canceled
+		}
+		ctx = logr.NewContext(ctx, log)
+		ticker := time.NewTicker(warmPoolInterval)
+		defer ticker.Stop()
+		log.Info("starting warm pool")
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info("stopping warm pool")
+				return nil
+			case <-ticker.C:
+				if err := r.replenishWarmPools(ctx); err != nil {
+					log.Error(err, "failed replenishing warm pools")
+				}
+			}
+		}
+	}))
+}
+
+// replenishWarmPools replenishes the warm pool of every dynamic platform configuring min-warm.
+func (r *ReconcileTaskRun) replenishWarmPools(ctx context.Context) error {
+	log := logr.FromContextOrDiscard(ctx)
+	cm := kubecore.ConfigMap{}
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: r.operatorNamespace, Name: HostConfig}, &cm); err != nil {
+		return err
+	}
+	dynamicPlatforms, err := config.ParsePlatformList(cm.Data[DynamicPlatforms], config.PlatformTypeDynamic)
+	if err != nil {
+		return fmt.Errorf("could not parse dynamic platforms: %w", err)
+	}
+	for _, platform := range dynamicPlatforms {
+		platformConfig, err := r.getPlatformConfig(ctx, platform, r.operatorNamespace)
+		if err != nil {
+			log.Error(err, "skipping dynamic platform", "platform", platform)
+			continue
+		}
+		resolver, ok := platformConfig.(DynamicResolver)
+		if !ok || resolver.minWarm == 0 {
+			continue
+		}
+		if err := resolver.replenishWarmPool(r, ctx); err != nil {
+			var circuitErr *cloud.CircuitOpenError
+			if errors.As(err, &circuitErr) {
+				log.Info("waiting for cloud provider to recover before replenishing warm pool", "platform", platform, "retryAfter", circuitErr.RetryAfter)
+				continue
+			}
+			log.Error(err, "failed replenishing warm pool", "platform", platform)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// This file contains the tests for warm pools, which keep booted, unassigned
+// instances of dynamic platforms ready to be handed out to TaskRuns.
+
+package taskrun
+
+import (
+	"time"
+
+	"github.com/konflux-ci/multi-platform-controller/pkg/cloud"
+
+	. "github.com/konflux-ci/multi-platform-controller/pkg/constant"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Warm pool", func() {
+
+	var client runtimeclient.Client
+	var reconciler *ReconcileTaskRun
+
+	// addWarmInstance adds a booted warm instance launched at startTime to the mock cloud.
+	addWarmInstance := func(id string, startTime time.Time) {
+		cloudImpl.Instances[cloud.InstanceIdentifier(id)] = MockInstance{
+			CloudVMInstance: cloud.CloudVMInstance{InstanceId: cloud.InstanceIdentifier(id), StartTime: startTime, Address: id + ".host.com", TaskRunID: systemNamespace + ":" + warmInstancePrefix + id},
+			statusOK:        true,
+		}
+		cloudImpl.Running++
+	}
+
+	// updateHostConfig merges data into the host-config ConfigMap.
+	updateHostConfig := func(ctx SpecContext, data map[string]string) {
+		cm := v1.ConfigMap{}
+		Expect(client.Get(ctx, types.NamespacedName{Namespace: systemNamespace, Name: HostConfig}, &cm)).Should(Succeed())
+		for k, v := range data {
+			cm.Data[k] = v
+		}
+		Expect(client.Update(ctx, &cm)).Should(Succeed())
+	}
+
+	BeforeEach(func(ctx SpecContext) {
+		client, reconciler = setupClientAndReconciler(createDynamicHostConfig())
+		cloudImpl.Instances = map[cloud.InstanceIdentifier]MockInstance{}
+		cloudImpl.Running = 0
+		cloudImpl.Terminated = 0
+		cloudImpl.TerminatedIDs = nil
+		cloudImpl.FailTerminate = false
+		cloudImpl.FailLaunch = false
+		updateHostConfig(ctx, map[string]string{
+			"cloud-api.instance-cache-ttl": "0s",
+			"dynamic.linux-arm64.min-warm": "1",
+			"dynamic.linux-arm64.max-age":  "60",
+		})
+	})
+
+	It("should launch warm instances up to min-warm", func(ctx SpecContext) {
+		Expect(reconciler.replenishWarmPools(ctx)).Should(Succeed())
+		Expect(cloudImpl.Running).Should(Equal(1))
+		for _, instance := range cloudImpl.Instances {
+			Expect(isWarmInstance(instance.CloudVMInstance, systemNamespace)).Should(BeTrue())
+		}
+
+		// The pool is full, so nothing more is launched
+		Expect(reconciler.replenishWarmPools(ctx)).Should(Succeed())
+		Expect(cloudImpl.Running).Should(Equal(1))
+	})
+
+	It("should count warm instances against max-instances", func(ctx SpecContext) {
+		updateHostConfig(ctx, map[string]string{"dynamic.linux-arm64.min-warm": "2"})
+		addInstanceForTask := func(id string) {
+			cloudImpl.Instances[cloud.InstanceIdentifier(id)] = MockInstance{
+				CloudVMInstance: cloud.CloudVMInstance{InstanceId: cloud.InstanceIdentifier(id), StartTime: time.Now(), TaskRunID: userNamespace + ":" + id},
+				statusOK:        true,
+			}
+			cloudImpl.Running++
+		}
+		addInstanceForTask("busy")
+
+		Expect(reconciler.replenishWarmPools(ctx)).Should(Succeed())
+		Expect(cloudImpl.Running).Should(Equal(2))
+	})
+
+	It("should hand out a warm instance and replace it", func(ctx SpecContext) {
+		addWarmInstance("ready", time.Now().Add(-time.Minute))
+		Expect(reconciler.replenishWarmPools(ctx)).Should(Succeed())
+		Expect(cloudImpl.Running).Should(Equal(1))
+
+		createUserTaskRun(ctx, client, "test-warm", "linux/arm64")
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-warm"}})
+		Expect(err).ShouldNot(HaveOccurred())
+		tr := getUserTaskRun(ctx, client, "test-warm")
+		Expect(tr.Labels[AssignedHost]).Should(Equal("ready"))
+		Expect(tr.Annotations[CloudInstanceId]).Should(Equal("ready"))
+		Expect(tr.Annotations[CloudAddress]).Should(Equal("ready.host.com"))
+		Expect(getProvisionTaskRun(ctx, client, tr)).ShouldNot(BeNil())
+		Expect(cloudImpl.Running).Should(Equal(1))
+
+		// The handed out instance is replaced in the background
+		Expect(reconciler.replenishWarmPools(ctx)).Should(Succeed())
+		Expect(cloudImpl.Running).Should(Equal(2))
+		Expect(cloudImpl.TerminatedIDs).Should(BeEmpty())
+	})
+
+	It("should launch an instance when no warm instance is ready", func(ctx SpecContext) {
+		createUserTaskRun(ctx, client, "test-cold", "linux/arm64")
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-cold"}})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(cloudImpl.Instances).Should(HaveKey(cloud.InstanceIdentifier("test-cold")))
+	})
+
+	It("should only hand out warm instances the warm pool found ready", func(ctx SpecContext) {
+		addWarmInstance("unchecked", time.Now().Add(-time.Minute))
+
+		createUserTaskRun(ctx, client, "test-cold", "linux/arm64")
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-cold"}})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(getUserTaskRun(ctx, client, "test-cold").Annotations[CloudInstanceId]).Should(Equal("test-cold"))
+	})
+
+	It("should annotate a handed out warm instance with its details", func(ctx SpecContext) {
+		cloudImpl.InstanceDetails = cloud.InstanceDetails{InstanceType: "m6g.large", Image: "ami-123"}
+		defer func() { cloudImpl.InstanceDetails = cloud.InstanceDetails{} }()
+		addWarmInstance("ready", time.Now().Add(-time.Minute))
+		Expect(reconciler.replenishWarmPools(ctx)).Should(Succeed())
+
+		createUserTaskRun(ctx, client, "test-warm", "linux/arm64")
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: userNamespace, Name: "test-warm"}})
+		Expect(err).ShouldNot(HaveOccurred())
+		tr := getUserTaskRun(ctx, client, "test-warm")
+		Expect(tr.Annotations[CloudInstanceId]).Should(Equal("ready"))
+		Expect(tr.Annotations[CloudInstanceType]).Should(Equal("m6g.large"))
+		Expect(tr.Annotations[CloudInstanceImage]).Should(Equal("ami-123"))
+	})
+
+	It("should reclaim unassigned warm instances older than max-age", func(ctx SpecContext) {
+		addWarmInstance("stale", time.Now().Add(-2*time.Hour))
+
+		Expect(reconciler.replenishWarmPools(ctx)).Should(Succeed())
+		Expect(cloudImpl.TerminatedIDs).Should(ConsistOf(cloud.InstanceIdentifier("stale")))
+		Expect(cloudImpl.Running).Should(Equal(1))
+	})
+
+	It("should leave warm instances to the warm pool when collecting orphaned instances", func(ctx SpecContext) {
+		updateHostConfig(ctx, map[string]string{"orphaned-instance-gc.enabled": "true"})
+		addWarmInstance("ready", time.Now().Add(-time.Hour))
+
+		Expect(reconciler.collectOrphanedInstances(ctx)).Should(Succeed())
+		Expect(cloudImpl.TerminatedIDs).Should(BeEmpty())
+
+		// Once the platform no longer keeps a warm pool, its unassigned warm instances are orphaned
+		updateHostConfig(ctx, map[string]string{"dynamic.linux-arm64.min-warm": "0"})
+		Expect(reconciler.collectOrphanedInstances(ctx)).Should(Succeed())
+		Expect(cloudImpl.TerminatedIDs).Should(ConsistOf(cloud.InstanceIdentifier("ready")))
+	})
+})